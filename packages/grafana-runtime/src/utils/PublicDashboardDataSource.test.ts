@@ -30,19 +30,19 @@ describe('PublicDashboardDatasource', () => {
 
     const ds = new PublicDashboardDataSource();
     const panelId = 1;
-    const publicDashboardUid = 'abc123';
+    const publicDashboardAccessToken = 'abc123';
 
     ds.query({
       maxDataPoints: 10,
       intervalMs: 5000,
       targets: [{ refId: 'A' }, { refId: 'B', datasource: { type: 'sample' } }],
       panelId,
-      publicDashboardUid,
+      publicDashboardAccessToken,
     } as DataQueryRequest);
 
     const mock = mockDatasourceRequest.mock;
 
     expect(mock.calls.length).toBe(1);
-    expect(mock.lastCall[0].url).toEqual(`/api/public/dashboards/${publicDashboardUid}/panels/${panelId}/query`);
+    expect(mock.lastCall[0].url).toEqual(`/api/public/dashboards/${publicDashboardAccessToken}/panels/${panelId}/query`);
   });
 });
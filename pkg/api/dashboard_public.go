@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -12,30 +14,35 @@ import (
 	"github.com/grafana/grafana/pkg/web"
 )
 
+// publicDashboardPasswordHeader is the header viewers of password protected public dashboards send the password in,
+// both to get the public dashboard and to run its queries
+const publicDashboardPasswordHeader = "X-Grafana-Public-Dashboard-Password"
+
 // gets public dashboard
 func (hs *HTTPServer) GetPublicDashboard(c *models.ReqContext) response.Response {
-	publicDashboardUid := web.Params(c.Req)[":uid"]
+	accessToken := web.Params(c.Req)[":accessToken"]
+	password := c.Req.Header.Get(publicDashboardPasswordHeader)
 
-	dash, err := hs.dashboardService.GetPublicDashboard(c.Req.Context(), publicDashboardUid)
+	dash, err := hs.dashboardService.GetPublicDashboardForRendering(c.Req.Context(), accessToken, password, c.SignedInUser)
 	if err != nil {
 		return handleDashboardErr(http.StatusInternalServerError, "Failed to get public dashboard", err)
 	}
 
 	meta := dtos.DashboardMeta{
-		Slug:               dash.Slug,
-		Type:               models.DashTypeDB,
-		CanStar:            false,
-		CanSave:            false,
-		CanEdit:            false,
-		CanAdmin:           false,
-		CanDelete:          false,
-		Created:            dash.Created,
-		Updated:            dash.Updated,
-		Version:            dash.Version,
-		IsFolder:           false,
-		FolderId:           dash.FolderId,
-		IsPublic:           dash.IsPublic,
-		PublicDashboardUid: publicDashboardUid,
+		Slug:                       dash.Slug,
+		Type:                       models.DashTypeDB,
+		CanStar:                    false,
+		CanSave:                    false,
+		CanEdit:                    false,
+		CanAdmin:                   false,
+		CanDelete:                  false,
+		Created:                    dash.Created,
+		Updated:                    dash.Updated,
+		Version:                    dash.Version,
+		IsFolder:                   false,
+		FolderId:                   dash.FolderId,
+		IsPublic:                   dash.IsPublic,
+		PublicDashboardAccessToken: accessToken,
 	}
 
 	dto := dtos.DashboardFullWithMeta{Meta: meta, Dashboard: dash.Data}
@@ -49,6 +56,14 @@ func (hs *HTTPServer) GetPublicDashboardConfig(c *models.ReqContext) response.Re
 	if err != nil {
 		return handleDashboardErr(http.StatusInternalServerError, "Failed to get public dashboard config", err)
 	}
+
+	// resolving the users requires an extra query, so it's opt in
+	if c.QueryBool("withUsers") {
+		if err := hs.dashboardService.ResolvePublicDashboardUsers(c.Req.Context(), pdc); err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to resolve public dashboard users", err)
+		}
+	}
+
 	return response.JSON(http.StatusOK, pdc)
 }
 
@@ -61,6 +76,7 @@ func (hs *HTTPServer) SavePublicDashboardConfig(c *models.ReqContext) response.R
 
 	dto := dashboards.SavePublicDashboardConfigDTO{
 		OrgId:                 c.OrgId,
+		UserId:                c.UserId,
 		DashboardUid:          web.Params(c.Req)[":uid"],
 		PublicDashboardConfig: pdc,
 	}
@@ -73,29 +89,66 @@ func (hs *HTTPServer) SavePublicDashboardConfig(c *models.ReqContext) response.R
 	return response.JSON(http.StatusOK, pdc)
 }
 
+// DeletePublicDashboardConfig deletes the public dashboard configuration of a dashboard
+// DELETE /api/dashboards/uid/:uid/public-config
+func (hs *HTTPServer) DeletePublicDashboardConfig(c *models.ReqContext) response.Response {
+	err := hs.dashboardService.DeletePublicDashboardConfig(c.Req.Context(), c.OrgId, c.UserId, web.Params(c.Req)[":uid"])
+	if err != nil {
+		return handleDashboardErr(http.StatusInternalServerError, "Failed to delete public dashboard configuration", err)
+	}
+
+	return response.Success("Public dashboard configuration deleted")
+}
+
 // QueryPublicDashboard returns all results for a given panel on a public dashboard
-// POST /api/public/dashboard/:uid/panels/:panelId/query
+// POST /api/public/dashboards/:accessToken/panels/:panelId/query
 func (hs *HTTPServer) QueryPublicDashboard(c *models.ReqContext) response.Response {
 	panelId, err := strconv.ParseInt(web.Params(c.Req)[":panelId"], 10, 64)
 	if err != nil {
 		return response.Error(http.StatusBadRequest, "invalid panel ID", err)
 	}
 
-	reqDTO, err := hs.dashboardService.BuildPublicDashboardMetricRequest(
+	queryDTO := dtos.PublicDashboardQueryDTO{}
+	if err := web.Bind(c.Req, &queryDTO); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	reqDTO, queryOpts, err := hs.dashboardService.BuildPublicDashboardMetricRequest(
 		c.Req.Context(),
-		web.Params(c.Req)[":uid"],
+		web.Params(c.Req)[":accessToken"],
+		c.Req.Header.Get(publicDashboardPasswordHeader),
 		panelId,
+		queryDTO,
+		c.SignedInUser,
 	)
 	if err != nil {
 		return handleDashboardErr(http.StatusInternalServerError, "Failed to get queries for public dashboard", err)
 	}
 
-	resp, err := hs.queryDataService.QueryDataMultipleSources(c.Req.Context(), nil, c.SkipCache, reqDTO, true)
+	// queries of anonymous viewers are cancelled after the max query duration of the public dashboard,
+	// so they can't keep expensive queries running on the data sources
+	ctx := c.Req.Context()
+	if queryOpts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, queryOpts.MaxDuration)
+		defer cancel()
+	}
+
+	resp, err := hs.queryDataService.QueryDataMultipleSources(ctx, nil, c.SkipCache, reqDTO, true)
 
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return response.Error(http.StatusGatewayTimeout, "Public dashboard query exceeded its maximum duration", err)
+		}
 		return hs.handleQueryMetricsError(err)
 	}
-	return hs.toJsonStreamingResponse(resp)
+
+	res := hs.toJsonStreamingResponse(resp)
+	// only complete responses are cached, so a failing data source isn't served from the cache
+	if queryOpts.CacheTTL > 0 && res.Status() == http.StatusOK {
+		c.Resp.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(queryOpts.CacheTTL.Seconds())))
+	}
+	return res
 }
 
 // util to help us unpack a dashboard err or use default http code and message
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -31,7 +33,7 @@ func TestAPIGetPublicDashboard(t *testing.T) {
 	t.Run("It should 404 if featureflag is not enabled", func(t *testing.T) {
 		sc := setupHTTPServerWithMockDb(t, false, false, featuremgmt.WithFeatures())
 		dashSvc := dashboards.NewFakeDashboardService(t)
-		dashSvc.On("GetPublicDashboard", mock.Anything, mock.AnythingOfType("string")).
+		dashSvc.On("GetPublicDashboardForRendering", mock.Anything, mock.AnythingOfType("string"), mock.Anything, mock.Anything).
 			Return(&models.Dashboard{}, nil).Maybe()
 		sc.hs.dashboardService = dashSvc
 
@@ -81,6 +83,27 @@ func TestAPIGetPublicDashboard(t *testing.T) {
 			uid:                   pubdashUid,
 			expectedHttpResponse:  http.StatusNotFound,
 			publicDashboardResult: nil,
+			publicDashboardErr:    models.ErrPublicDashboardDisabled,
+		},
+		{
+			name:                  "It should return 401 if the public dashboard requires viewers to sign in",
+			uid:                   pubdashUid,
+			expectedHttpResponse:  http.StatusUnauthorized,
+			publicDashboardResult: nil,
+			publicDashboardErr:    models.ErrPublicDashboardAuthRequired,
+		},
+		{
+			name:                  "It should return 401 if the public dashboard is password protected",
+			uid:                   pubdashUid,
+			expectedHttpResponse:  http.StatusUnauthorized,
+			publicDashboardResult: nil,
+			publicDashboardErr:    models.ErrPublicDashboardPasswordRequired,
+		},
+		{
+			name:                  "It should return 404 if public dashboard is not found",
+			uid:                   pubdashUid,
+			expectedHttpResponse:  http.StatusNotFound,
+			publicDashboardResult: nil,
 			publicDashboardErr:    models.ErrPublicDashboardNotFound,
 		},
 	}
@@ -88,12 +111,13 @@ func TestAPIGetPublicDashboard(t *testing.T) {
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
 			sc := setupHTTPServerWithMockDb(t, false, false, featuremgmt.WithFeatures(featuremgmt.FlagPublicDashboards))
+			setInitCtxSignedInViewer(sc.initCtx)
 			dashSvc := dashboards.NewFakeDashboardService(t)
-			dashSvc.On("GetPublicDashboard", mock.Anything, mock.AnythingOfType("string")).
+			// the viewer is passed on, as authenticated public dashboards can only be viewed by users of their org
+			dashSvc.On("GetPublicDashboardForRendering", mock.Anything, mock.AnythingOfType("string"), "", sc.initCtx.SignedInUser).
 				Return(test.publicDashboardResult, test.publicDashboardErr)
 			sc.hs.dashboardService = dashSvc
 
-			setInitCtxSignedInViewer(sc.initCtx)
 			response := callAPI(
 				sc.server,
 				http.MethodGet,
@@ -124,6 +148,23 @@ func TestAPIGetPublicDashboard(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("It passes the password the viewer entered on", func(t *testing.T) {
+		sc := setupHTTPServerWithMockDb(t, false, false, featuremgmt.WithFeatures(featuremgmt.FlagPublicDashboards))
+		setInitCtxSignedInViewer(sc.initCtx)
+		dashSvc := dashboards.NewFakeDashboardService(t)
+		dashSvc.On("GetPublicDashboardForRendering", mock.Anything, pubdashUid, "s3cret", sc.initCtx.SignedInUser).
+			Return(&models.Dashboard{Data: simplejson.New(), IsPublic: true}, nil)
+		sc.hs.dashboardService = dashSvc
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/public/dashboards/%v", pubdashUid), nil)
+		require.NoError(t, err)
+		req.Header.Set(publicDashboardPasswordHeader, "s3cret")
+		response := httptest.NewRecorder()
+		sc.server.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+	})
 }
 
 func TestAPIGetPublicDashboardConfig(t *testing.T) {
@@ -188,6 +229,37 @@ func TestAPIGetPublicDashboardConfig(t *testing.T) {
 	}
 }
 
+func TestAPIGetPublicDashboardConfigWithUsers(t *testing.T) {
+	sc := setupHTTPServerWithMockDb(t, false, false, featuremgmt.WithFeatures(featuremgmt.FlagPublicDashboards))
+	dashSvc := dashboards.NewFakeDashboardService(t)
+	dashSvc.On("GetPublicDashboardConfig", mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("string")).
+		Return(&models.PublicDashboardConfig{IsPublic: true, PublicDashboard: models.PublicDashboard{CreatedBy: 1, UpdatedBy: 1}}, nil)
+	dashSvc.On("ResolvePublicDashboardUsers", mock.Anything, mock.AnythingOfType("*models.PublicDashboardConfig")).
+		Run(func(args mock.Arguments) {
+			pdc := args.Get(1).(*models.PublicDashboardConfig)
+			pdc.CreatedByUser = &models.PublicDashboardUser{Id: 1, Login: "admin", Name: "Admin"}
+			pdc.UpdatedByUser = pdc.CreatedByUser
+		}).
+		Return(nil)
+	sc.hs.dashboardService = dashSvc
+
+	setInitCtxSignedInViewer(sc.initCtx)
+	response := callAPI(
+		sc.server,
+		http.MethodGet,
+		"/api/dashboards/uid/1/public-config?withUsers=true",
+		nil,
+		t,
+	)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var pdcResp models.PublicDashboardConfig
+	err := json.Unmarshal(response.Body.Bytes(), &pdcResp)
+	require.NoError(t, err)
+	assert.Equal(t, &models.PublicDashboardUser{Id: 1, Login: "admin", Name: "Admin"}, pdcResp.CreatedByUser)
+	assert.Equal(t, &models.PublicDashboardUser{Id: 1, Login: "admin", Name: "Admin"}, pdcResp.UpdatedByUser)
+}
+
 func TestApiSavePublicDashboardConfig(t *testing.T) {
 	testCases := []struct {
 		name                  string
@@ -247,9 +319,49 @@ func TestApiSavePublicDashboardConfig(t *testing.T) {
 	}
 }
 
+func TestApiDeletePublicDashboardConfig(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		deleteError          error
+		expectedHttpResponse int
+	}{
+		{
+			name:                 "returns 200 when deleted",
+			expectedHttpResponse: http.StatusOK,
+		},
+		{
+			name:                 "returns 404 when public dashboard not found",
+			deleteError:          models.ErrPublicDashboardNotFound,
+			expectedHttpResponse: http.StatusNotFound,
+		},
+		{
+			name:                 "returns 500 when not deleted",
+			deleteError:          errors.New("backend failed to delete"),
+			expectedHttpResponse: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			sc := setupHTTPServerWithMockDb(t, false, false, featuremgmt.WithFeatures(featuremgmt.FlagPublicDashboards))
+
+			dashSvc := dashboards.NewFakeDashboardService(t)
+			dashSvc.On("DeletePublicDashboardConfig", mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("int64"), "1").
+				Return(test.deleteError)
+			sc.hs.dashboardService = dashSvc
+
+			setInitCtxSignedInViewer(sc.initCtx)
+			response := callAPI(sc.server, http.MethodDelete, "/api/dashboards/uid/1/public-config", nil, t)
+
+			assert.Equal(t, test.expectedHttpResponse, response.Code)
+		})
+	}
+}
+
 // `/public/dashboards/:uid/query`` endpoint test
 func TestAPIQueryPublicDashboard(t *testing.T) {
 	queryReturnsError := false
+	queryBlocks := false
 
 	qds := query.ProvideService(
 		nil,
@@ -268,6 +380,10 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 				if queryReturnsError {
 					return nil, errors.New("error")
 				}
+				if queryBlocks {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
 
 				resp := backend.Responses{}
 
@@ -330,7 +446,10 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 			"BuildPublicDashboardMetricRequest",
 			mock.Anything,
 			"abc123",
+			"",
 			int64(2),
+			dtos.PublicDashboardQueryDTO{},
+			mock.Anything,
 		).Return(dtos.MetricRequest{
 			Queries: []*simplejson.Json{
 				simplejson.MustJson([]byte(`
@@ -347,7 +466,7 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 					}
 				`)),
 			},
-		}, nil)
+		}, models.PublicDashboardQueryOptions{MaxDuration: time.Minute}, nil)
 		req := server.NewPostRequest(
 			"/api/public/dashboards/abc123/panels/2/query",
 			strings.NewReader("{}"),
@@ -382,6 +501,33 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 		require.Equal(t, http.StatusOK, resp.StatusCode)
 	})
 
+	t.Run("Sets Cache-Control when the public dashboard has a cache TTL", func(t *testing.T) {
+		server, fakeDashboardService := setup(true)
+
+		fakeDashboardService.On(
+			"BuildPublicDashboardMetricRequest",
+			mock.Anything,
+			"abc123",
+			"",
+			int64(2),
+			dtos.PublicDashboardQueryDTO{},
+			mock.Anything,
+		).Return(dtos.MetricRequest{
+			Queries: []*simplejson.Json{
+				simplejson.MustJson([]byte(`{"datasource": {"type": "prometheus", "uid": "promds"}, "refId": "A"}`)),
+			},
+		}, models.PublicDashboardQueryOptions{CacheTTL: 5 * time.Minute}, nil)
+		req := server.NewPostRequest(
+			"/api/public/dashboards/abc123/panels/2/query",
+			strings.NewReader("{}"),
+		)
+		resp, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "public, max-age=300", resp.Header.Get("Cache-Control"))
+	})
+
 	t.Run("Status code is 500 when the query fails", func(t *testing.T) {
 		server, fakeDashboardService := setup(true)
 
@@ -389,7 +535,10 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 			"BuildPublicDashboardMetricRequest",
 			mock.Anything,
 			"abc123",
+			"",
 			int64(2),
+			dtos.PublicDashboardQueryDTO{},
+			mock.Anything,
 		).Return(dtos.MetricRequest{
 			Queries: []*simplejson.Json{
 				simplejson.MustJson([]byte(`
@@ -406,17 +555,45 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 					}
 				`)),
 			},
-		}, nil)
+		}, models.PublicDashboardQueryOptions{MaxDuration: time.Minute}, nil)
 		req := server.NewPostRequest(
 			"/api/public/dashboards/abc123/panels/2/query",
 			strings.NewReader("{}"),
 		)
 		queryReturnsError = true
+		t.Cleanup(func() { queryReturnsError = false })
 		resp, err := server.SendJSON(req)
 		require.NoError(t, err)
 		require.NoError(t, resp.Body.Close())
 		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
-		queryReturnsError = false
+	})
+
+	t.Run("Status code is 504 when the query exceeds the max query duration", func(t *testing.T) {
+		server, fakeDashboardService := setup(true)
+
+		fakeDashboardService.On(
+			"BuildPublicDashboardMetricRequest",
+			mock.Anything,
+			"abc123",
+			"",
+			int64(2),
+			dtos.PublicDashboardQueryDTO{},
+			mock.Anything,
+		).Return(dtos.MetricRequest{
+			Queries: []*simplejson.Json{
+				simplejson.MustJson([]byte(`{"datasource": {"type": "prometheus", "uid": "promds"}, "refId": "A"}`)),
+			},
+		}, models.PublicDashboardQueryOptions{MaxDuration: 10 * time.Millisecond}, nil)
+		req := server.NewPostRequest(
+			"/api/public/dashboards/abc123/panels/2/query",
+			strings.NewReader("{}"),
+		)
+		queryBlocks = true
+		t.Cleanup(func() { queryBlocks = false })
+		resp, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
 	})
 
 	t.Run("Status code is 200 when a panel has queries from multiple datasources", func(t *testing.T) {
@@ -426,7 +603,10 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 			"BuildPublicDashboardMetricRequest",
 			mock.Anything,
 			"abc123",
+			"",
 			int64(2),
+			dtos.PublicDashboardQueryDTO{},
+			mock.Anything,
 		).Return(dtos.MetricRequest{
 			Queries: []*simplejson.Json{
 				simplejson.MustJson([]byte(`
@@ -456,7 +636,7 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 					}
 				`)),
 			},
-		}, nil)
+		}, models.PublicDashboardQueryOptions{MaxDuration: time.Minute}, nil)
 		req := server.NewPostRequest(
 			"/api/public/dashboards/abc123/panels/2/query",
 			strings.NewReader("{}"),
@@ -504,4 +684,68 @@ func TestAPIQueryPublicDashboard(t *testing.T) {
 		require.NoError(t, resp.Body.Close())
 		require.Equal(t, http.StatusOK, resp.StatusCode)
 	})
+
+	t.Run("Status code is 400 when the requested time range is out of bounds", func(t *testing.T) {
+		server, fakeDashboardService := setup(true)
+
+		fakeDashboardService.On(
+			"BuildPublicDashboardMetricRequest",
+			mock.Anything,
+			"abc123",
+			"",
+			int64(2),
+			dtos.PublicDashboardQueryDTO{From: "now-2y", To: "now-1y"},
+			mock.Anything,
+		).Return(dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardTimeRangeOutOfBounds)
+		req := server.NewPostRequest(
+			"/api/public/dashboards/abc123/panels/2/query",
+			strings.NewReader(`{"from": "now-2y", "to": "now-1y"}`),
+		)
+		resp, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Passes the password the viewer entered on to run the queries of password protected public dashboards", func(t *testing.T) {
+		server, fakeDashboardService := setup(true)
+
+		fakeDashboardService.On(
+			"BuildPublicDashboardMetricRequest",
+			mock.Anything,
+			"abc123",
+			"",
+			int64(2),
+			dtos.PublicDashboardQueryDTO{},
+			mock.Anything,
+		).Return(dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardPasswordRequired)
+		fakeDashboardService.On(
+			"BuildPublicDashboardMetricRequest",
+			mock.Anything,
+			"abc123",
+			"s3cret",
+			int64(2),
+			dtos.PublicDashboardQueryDTO{},
+			mock.Anything,
+		).Return(dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, nil)
+
+		req := server.NewPostRequest(
+			"/api/public/dashboards/abc123/panels/2/query",
+			strings.NewReader("{}"),
+		)
+		resp, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		req = server.NewPostRequest(
+			"/api/public/dashboards/abc123/panels/2/query",
+			strings.NewReader("{}"),
+		)
+		req.Header.Set(publicDashboardPasswordHeader, "s3cret")
+		resp, err = server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
 }
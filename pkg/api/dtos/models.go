@@ -73,6 +73,13 @@ type MetricRequest struct {
 	HTTPRequest *http.Request `json:"-"`
 }
 
+// PublicDashboardQueryDTO is the time range a viewer of a public dashboard requests data for.
+// It's only honored for public dashboards that bound the time range viewers can request.
+type PublicDashboardQueryDTO struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
 func (mr *MetricRequest) CloneWithQueries(queries []*simplejson.Json) MetricRequest {
 	return MetricRequest{
 		From:        mr.From,
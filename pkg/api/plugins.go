@@ -379,6 +379,18 @@ func (hs *HTTPServer) InstallPlugin(c *models.ReqContext) response.Response {
 		if errors.Is(err, plugins.ErrInstallCorePlugin) {
 			return response.Error(http.StatusForbidden, "Cannot install or change a Core plugin", err)
 		}
+		if errors.Is(err, plugins.ErrPluginPinned) {
+			return response.Error(http.StatusConflict, "Plugin is pinned to a different version", err)
+		}
+		if errors.Is(err, plugins.ErrPluginURLNotAllowed) {
+			return response.Error(http.StatusForbidden, "Installing plugins from this URL is not allowed", err)
+		}
+		if errors.Is(err, plugins.ErrPluginNotAllowed) {
+			return response.Error(http.StatusForbidden, "Installing this plugin is not allowed", err)
+		}
+		if errors.Is(err, plugins.ErrRepoUnavailable) {
+			return response.Error(http.StatusBadGateway, "Plugin repository unavailable", err)
+		}
 
 		return response.Error(http.StatusInternalServerError, "Failed to install plugin", err)
 	}
@@ -400,6 +412,17 @@ func (hs *HTTPServer) UninstallPlugin(c *models.ReqContext) response.Response {
 		if errors.Is(err, plugins.ErrUninstallOutsideOfPluginDir) {
 			return response.Error(http.StatusForbidden, "Cannot uninstall a plugin outside of the plugins directory", err)
 		}
+		if errors.Is(err, plugins.ErrPluginManagedExternally) {
+			return response.Error(http.StatusForbidden, "Cannot uninstall a provisioned plugin", err)
+		}
+		var hasDependentsErr plugins.ErrPluginHasDependents
+		if errors.As(err, &hasDependentsErr) {
+			return response.Error(http.StatusConflict, "Cannot uninstall a plugin other plugins depend on", err)
+		}
+		var inUseErr plugins.ErrPluginInUseByDashboards
+		if errors.As(err, &inUseErr) {
+			return response.Error(http.StatusConflict, "Cannot uninstall a data source plugin dashboards use", err)
+		}
 
 		return response.Error(http.StatusInternalServerError, "Failed to uninstall plugin", err)
 	}
@@ -57,7 +57,7 @@ func InstallPlugin(pluginID, version string, c utils.CommandLine) error {
 	skipTLSVerify := c.Bool("insecure")
 
 	i := installer.New(skipTLSVerify, services.GrafanaVersion, services.Logger)
-	return i.Install(context.Background(), pluginID, version, c.PluginDirectory(), c.PluginURL(), c.PluginRepoURL())
+	return i.Install(context.Background(), pluginID, version, c.PluginDirectory(), c.PluginURL(), "", c.PluginRepoURL(), nil)
 }
 
 func osAndArchString() string {
@@ -70,3 +70,17 @@ type FolderUpdated struct {
 	UID       string    `json:"uid"`
 	OrgID     int64     `json:"org_id"`
 }
+
+type PluginInstalledEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	PluginID  string    `json:"plugin_id"`
+	Version   string    `json:"version"`
+	IsUpdate  bool      `json:"is_update"`
+}
+
+type PluginUninstalledEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	PluginID  string    `json:"plugin_id"`
+	Version   string    `json:"version"`
+	IsUpdate  bool      `json:"is_update"`
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
 	"github.com/grafana/grafana/pkg/components/simplejson"
 )
 
@@ -28,6 +32,155 @@ func GetQueriesFromDashboard(dashboard *simplejson.Json) map[int64][]*simplejson
 	return result
 }
 
+// GetQuerySignatureFromDashboard returns a signature of the queries of the panels of the dashboard, as returned
+// by GetQueriesFromDashboard. It changes whenever a query is added, removed or modified, or moves to another panel.
+func GetQuerySignatureFromDashboard(dashboard *simplejson.Json) (string, error) {
+	// maps are encoded with sorted keys, so equal queries always encode the same
+	queries, err := json.Marshal(GetQueriesFromDashboard(dashboard))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(queries)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// GetVariableNamesFromDashboard returns the names of the template variables of the dashboard
+func GetVariableNamesFromDashboard(dashboard *simplejson.Json) []string {
+	var names []string
+	for _, variableObj := range dashboard.GetPath("templating", "list").MustArray() {
+		name := simplejson.NewFromAny(variableObj).Get("name").MustString()
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// GetPanelTypesFromDashboard returns the distinct panel types of the dashboard, including the ones
+// of panels in collapsed rows, in the order they first appear
+func GetPanelTypesFromDashboard(dashboard *simplejson.Json) []string {
+	var types []string
+	seen := make(map[string]struct{})
+
+	var collect func(panels []interface{})
+	collect = func(panels []interface{}) {
+		for _, panelObj := range panels {
+			panel := simplejson.NewFromAny(panelObj)
+			if panelType := panel.Get("type").MustString(); panelType != "" {
+				if _, exists := seen[panelType]; !exists {
+					seen[panelType] = struct{}{}
+					types = append(types, panelType)
+				}
+			}
+			collect(panel.Get("panels").MustArray())
+		}
+	}
+	collect(dashboard.Get("panels").MustArray())
+
+	return types
+}
+
+// GetPanelIdsFromDashboard returns the ids of the panels of the dashboard, including the ones of panels
+// in collapsed rows
+func GetPanelIdsFromDashboard(dashboard *simplejson.Json) []int64 {
+	var ids []int64
+
+	var collect func(panels []interface{})
+	collect = func(panels []interface{}) {
+		for _, panelObj := range panels {
+			panel := simplejson.NewFromAny(panelObj)
+			if id, err := panel.Get("id").Int64(); err == nil {
+				ids = append(ids, id)
+			}
+			collect(panel.Get("panels").MustArray())
+		}
+	}
+	collect(dashboard.Get("panels").MustArray())
+
+	return ids
+}
+
+// GetDatasourceUidsFromDashboard returns the distinct uids of the data sources queried by the panels of the
+// dashboard, as returned by GetQueriesFromDashboard, in the order they first appear. Queries that don't refer
+// to their data source by uid are skipped.
+func GetDatasourceUidsFromDashboard(dashboard *simplejson.Json) []string {
+	var uids []string
+	seen := make(map[string]struct{})
+
+	for _, panelObj := range dashboard.Get("panels").MustArray() {
+		panel := simplejson.NewFromAny(panelObj)
+
+		for _, queryObj := range panel.Get("targets").MustArray() {
+			query := simplejson.NewFromAny(queryObj)
+
+			datasource, ok := query.CheckGet("datasource")
+			if !ok {
+				datasource = panel.Get("datasource")
+			}
+			uid := datasource.Get("uid").MustString()
+			if uid == "" {
+				continue
+			}
+			if _, exists := seen[uid]; !exists {
+				seen[uid] = struct{}{}
+				uids = append(uids, uid)
+			}
+		}
+	}
+
+	return uids
+}
+
+// DashboardReferencesDatasource reports whether any panel, query, template variable or annotation of the
+// dashboard refers to the data source, either by uid or, as older dashboards do, by uid or name alone.
+// Panels nested in collapsed rows are included.
+func DashboardReferencesDatasource(dashboard *simplejson.Json, uid, name string) bool {
+	refersTo := func(datasource *simplejson.Json) bool {
+		if ref, err := datasource.String(); err == nil {
+			return ref != "" && (ref == uid || ref == name)
+		}
+		ref := datasource.Get("uid").MustString()
+		return ref != "" && ref == uid
+	}
+
+	var inPanels func(panels []interface{}) bool
+	inPanels = func(panels []interface{}) bool {
+		for _, panelObj := range panels {
+			panel := simplejson.NewFromAny(panelObj)
+			if refersTo(panel.Get("datasource")) {
+				return true
+			}
+			for _, queryObj := range panel.Get("targets").MustArray() {
+				if refersTo(simplejson.NewFromAny(queryObj).Get("datasource")) {
+					return true
+				}
+			}
+			if inPanels(panel.Get("panels").MustArray()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if inPanels(dashboard.Get("panels").MustArray()) {
+		return true
+	}
+	for _, list := range [][]interface{}{
+		dashboard.GetPath("templating", "list").MustArray(),
+		dashboard.GetPath("annotations", "list").MustArray(),
+	} {
+		for _, itemObj := range list {
+			if refersTo(simplejson.NewFromAny(itemObj).Get("datasource")) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func GroupQueriesByDataSource(queries []*simplejson.Json) (result [][]*simplejson.Json) {
 	byDataSource := make(map[string][]*simplejson.Json)
 
@@ -208,3 +208,159 @@ func TestGroupQueriesByDataSource(t *testing.T) {
 		}`))})
 	})
 }
+
+func TestGetQuerySignatureFromDashboard(t *testing.T) {
+	signature := func(t *testing.T, dashboard string) string {
+		t.Helper()
+		json, err := simplejson.NewJson([]byte(dashboard))
+		require.NoError(t, err)
+		s, err := GetQuerySignatureFromDashboard(json)
+		require.NoError(t, err)
+		return s
+	}
+
+	original := signature(t, dashboardWithQueries)
+	require.Len(t, original, 64)
+
+	t.Run("is stable", func(t *testing.T) {
+		require.Equal(t, original, signature(t, dashboardWithQueries))
+	})
+
+	t.Run("ignores changes besides queries", func(t *testing.T) {
+		json, err := simplejson.NewJson([]byte(dashboardWithQueries))
+		require.NoError(t, err)
+		json.Set("title", "renamed")
+		s, err := GetQuerySignatureFromDashboard(json)
+		require.NoError(t, err)
+		require.Equal(t, original, s)
+	})
+
+	t.Run("changes when a query is modified", func(t *testing.T) {
+		json, err := simplejson.NewJson([]byte(dashboardWithQueries))
+		require.NoError(t, err)
+		json.Get("panels").GetIndex(0).Get("targets").GetIndex(0).Set("expr", "sum(up)")
+		s, err := GetQuerySignatureFromDashboard(json)
+		require.NoError(t, err)
+		require.NotEqual(t, original, s)
+	})
+
+	t.Run("changes when queries are removed", func(t *testing.T) {
+		require.NotEqual(t, original, signature(t, dashboardWithNoQueries))
+	})
+}
+
+func TestGetVariableNamesFromDashboard(t *testing.T) {
+	t.Run("returns variable names", func(t *testing.T) {
+		json, err := simplejson.NewJson([]byte(`{
+			"templating": {
+				"list": [
+					{"name": "env", "type": "custom"},
+					{"type": "query"},
+					{"name": "instance", "type": "query"}
+				]
+			}
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, []string{"env", "instance"}, GetVariableNamesFromDashboard(json))
+	})
+
+	t.Run("returns nothing if dashboard has no variables", func(t *testing.T) {
+		require.Empty(t, GetVariableNamesFromDashboard(simplejson.New()))
+	})
+}
+
+func TestGetPanelTypesFromDashboard(t *testing.T) {
+	t.Run("returns distinct panel types including collapsed rows", func(t *testing.T) {
+		json, err := simplejson.NewJson([]byte(`{
+			"panels": [
+				{"id": 1, "type": "timeseries"},
+				{"id": 2, "type": "row", "collapsed": true, "panels": [
+					{"id": 3, "type": "dashlist"},
+					{"id": 4, "type": "timeseries"}
+				]},
+				{"id": 5}
+			]
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, []string{"timeseries", "row", "dashlist"}, GetPanelTypesFromDashboard(json))
+	})
+
+	t.Run("returns nothing if dashboard has no panels", func(t *testing.T) {
+		require.Empty(t, GetPanelTypesFromDashboard(simplejson.New()))
+	})
+}
+
+func TestGetPanelIdsFromDashboard(t *testing.T) {
+	t.Run("returns panel ids including collapsed rows", func(t *testing.T) {
+		json, err := simplejson.NewJson([]byte(`{
+			"panels": [
+				{"id": 1, "type": "timeseries"},
+				{"id": 2, "type": "row", "collapsed": true, "panels": [
+					{"id": 3, "type": "dashlist"}
+				]},
+				{"type": "text"}
+			]
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, []int64{1, 2, 3}, GetPanelIdsFromDashboard(json))
+	})
+
+	t.Run("returns nothing if dashboard has no panels", func(t *testing.T) {
+		require.Empty(t, GetPanelIdsFromDashboard(simplejson.New()))
+	})
+}
+
+func TestGetDatasourceUidsFromDashboard(t *testing.T) {
+	t.Run("returns distinct data source uids of queries and their panels", func(t *testing.T) {
+		json, err := simplejson.NewJson([]byte(`{
+			"panels": [
+				{"id": 1, "datasource": {"uid": "panelds"}, "targets": [
+					{"refId": "A"},
+					{"refId": "B", "datasource": {"uid": "queryds"}}
+				]},
+				{"id": 2, "targets": [
+					{"refId": "A", "datasource": {"uid": "queryds"}},
+					{"refId": "B", "datasource": "legacy-name"}
+				]}
+			]
+		}`))
+		require.NoError(t, err)
+		require.Equal(t, []string{"panelds", "queryds"}, GetDatasourceUidsFromDashboard(json))
+	})
+
+	t.Run("returns nothing if dashboard has no queries", func(t *testing.T) {
+		json, err := simplejson.NewJson([]byte(dashboardWithNoQueries))
+		require.NoError(t, err)
+		require.Empty(t, GetDatasourceUidsFromDashboard(json))
+	})
+}
+
+func TestDashboardReferencesDatasource(t *testing.T) {
+	json, err := simplejson.NewJson([]byte(`{
+		"panels": [
+			{"id": 1, "datasource": {"uid": "panelds"}, "targets": [{"refId": "A"}]},
+			{"id": 2, "type": "row", "collapsed": true, "panels": [
+				{"id": 3, "targets": [{"refId": "A", "datasource": {"uid": "nestedds"}}]}
+			]},
+			{"id": 4, "targets": [{"refId": "A", "datasource": "Legacy name"}]}
+		],
+		"templating": {"list": [{"name": "host", "datasource": {"uid": "variableds"}}]},
+		"annotations": {"list": [{"name": "deploys", "datasource": "annotationds"}]}
+	}`))
+	require.NoError(t, err)
+
+	t.Run("finds data sources referred to by uid", func(t *testing.T) {
+		for _, uid := range []string{"panelds", "nestedds", "variableds", "annotationds"} {
+			require.True(t, DashboardReferencesDatasource(json, uid, "name"), uid)
+		}
+	})
+
+	t.Run("finds data sources referred to by name", func(t *testing.T) {
+		require.True(t, DashboardReferencesDatasource(json, "uid", "Legacy name"))
+	})
+
+	t.Run("doesn't find other data sources", func(t *testing.T) {
+		require.False(t, DashboardReferencesDatasource(json, "otherds", "Other name"))
+		require.False(t, DashboardReferencesDatasource(json, "", ""))
+	})
+}
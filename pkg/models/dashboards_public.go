@@ -1,5 +1,52 @@
 package models
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PublicDashboardAccessInterval is how often the last access of a public dashboard is recorded at most
+const PublicDashboardAccessInterval = time.Minute
+
+// PublicDashboardModelVersion is the version of the stored public dashboard model. Bump it when the structure of
+// stored fields, such as TimeSettings, changes and add an upgrade from the previous version to the store.
+const PublicDashboardModelVersion = 1
+
+// DefaultPublicDashboardMaxQueryDurationSeconds is how long the queries of a public dashboard may run if
+// neither the public dashboard nor the configuration set a limit
+const DefaultPublicDashboardMaxQueryDurationSeconds = 30
+
+// PublicDashboardRecoveryWindow is how long a deleted public dashboard can be restored before it is purged
+const PublicDashboardRecoveryWindow = 30 * 24 * time.Hour
+
+// Themes a public dashboard can be displayed with. An empty theme uses the org default.
+const (
+	PublicDashboardThemeLight = "light"
+	PublicDashboardThemeDark  = "dark"
+)
+
+// Time zones a public dashboard can be displayed in, besides IANA time zone names. Public dashboards are
+// displayed in the time zone of the viewer's browser by default.
+const (
+	PublicDashboardTimezoneBrowser = "browser"
+	PublicDashboardTimezoneUTC     = "utc"
+)
+
+// PublicDashboardRefreshOff turns off the auto-refresh of a public dashboard, regardless of the refresh of its dashboard
+const PublicDashboardRefreshOff = "off"
+
+// Share types of a public dashboard. Authenticated public dashboards are shared through their link,
+// but can only be viewed by users signed in to the org.
+const (
+	PublicDashboardShareTypePublic        = "public"
+	PublicDashboardShareTypeAuthenticated = "authenticated"
+)
+
 var (
 	ErrPublicDashboardFailedGenerateUniqueUid = DashboardErr{
 		Reason:     "Failed to generate unique dashboard id",
@@ -10,6 +57,11 @@ var (
 		StatusCode: 404,
 		Status:     "not-found",
 	}
+	ErrPublicDashboardDisabled = DashboardErr{
+		Reason:     "Public dashboard is disabled",
+		StatusCode: 404,
+		Status:     "disabled",
+	}
 	ErrPublicDashboardPanelNotFound = DashboardErr{
 		Reason:     "Panel not found in dashboard",
 		StatusCode: 404,
@@ -19,24 +71,471 @@ var (
 		Reason:     "No Uid for public dashboard specified",
 		StatusCode: 400,
 	}
+	ErrPublicDashboardInvalidTimeSettings = DashboardErr{
+		Reason:     "Public dashboard time settings must contain a valid from and to",
+		StatusCode: 400,
+		Status:     "invalid-time-settings",
+	}
+	ErrPublicDashboardInvalidTimeBounds = DashboardErr{
+		Reason:     "Public dashboard time settings must contain a valid minTime and maxTime, with minTime before maxTime",
+		StatusCode: 400,
+		Status:     "invalid-time-bounds",
+	}
+	ErrPublicDashboardTimeRangeOutOfBounds = DashboardErr{
+		Reason:     "Requested time range is outside of the time range allowed by the public dashboard",
+		StatusCode: 400,
+		Status:     "time-range-out-of-bounds",
+	}
+	ErrPublicDashboardInvalidMaxQueryDuration = DashboardErr{
+		Reason:     "Public dashboard max query duration must be a positive number of seconds",
+		StatusCode: 400,
+		Status:     "invalid-max-query-duration",
+	}
+	ErrPublicDashboardInvalidRateLimit = DashboardErr{
+		Reason:     "Public dashboard rate limit must be zero or a positive number of requests per minute",
+		StatusCode: 400,
+		Status:     "invalid-rate-limit",
+	}
+	ErrPublicDashboardInvalidCacheTTL = DashboardErr{
+		Reason:     "Public dashboard cache TTL must be zero or a positive number of seconds",
+		StatusCode: 400,
+		Status:     "invalid-cache-ttl",
+	}
+	ErrPublicDashboardInvalidEnabledWindow = DashboardErr{
+		Reason:     "Public dashboard enabled window must be two times of day (HH:MM) or two RFC 3339 timestamps, with from before to. Times of day need a utc or IANA time zone",
+		StatusCode: 400,
+		Status:     "invalid-enabled-window",
+	}
+	ErrPublicDashboardInvalidSlug = DashboardErr{
+		Reason:     "Public dashboard slug must be lowercase letters, digits and single dashes, at most 64 characters, and not a reserved word",
+		StatusCode: 400,
+		Status:     "invalid-slug",
+	}
+	ErrPublicDashboardSlugTaken = DashboardErr{
+		Reason:     "Public dashboard slug is already in use",
+		StatusCode: 409,
+		Status:     "slug-taken",
+	}
+	ErrPublicDashboardInvalidTheme = DashboardErr{
+		Reason:     "Public dashboard theme must be light, dark or empty",
+		StatusCode: 400,
+		Status:     "invalid-theme",
+	}
+	ErrPublicDashboardInvalidTimezone = DashboardErr{
+		Reason:     "Public dashboard time zone must be browser, utc or an IANA time zone name",
+		StatusCode: 400,
+		Status:     "invalid-timezone",
+	}
+	ErrPublicDashboardInvalidRefreshInterval = DashboardErr{
+		Reason:     "Public dashboard refresh interval must be off or a positive duration, such as 30s",
+		StatusCode: 400,
+		Status:     "invalid-refresh-interval",
+	}
+	ErrPublicDashboardInvalidShareType = DashboardErr{
+		Reason:     "Public dashboard share type must be public or authenticated",
+		StatusCode: 400,
+		Status:     "invalid-share-type",
+	}
+	ErrPublicDashboardInvalidPassword = DashboardErr{
+		Reason:     "Public dashboard password must be at most 72 bytes",
+		StatusCode: 400,
+		Status:     "invalid-password",
+	}
+	ErrPublicDashboardPasswordRequired = DashboardErr{
+		Reason:     "Public dashboard is password protected",
+		StatusCode: 401,
+		Status:     "password-required",
+	}
+	ErrPublicDashboardAuthRequired = DashboardErr{
+		Reason:     "Public dashboard can only be viewed by users signed in to its org",
+		StatusCode: 401,
+		Status:     "auth-required",
+	}
+	ErrPublicDashboardWrongPassword = DashboardErr{
+		Reason:     "Public dashboard password is wrong",
+		StatusCode: 401,
+		Status:     "wrong-password",
+	}
+	ErrPublicDashboardUnknownVariable = DashboardErr{
+		Reason:     "Public dashboard allowed variables must exist on the dashboard",
+		StatusCode: 400,
+		Status:     "unknown-variable",
+	}
+	ErrPublicDashboardInvalidVariable = DashboardErr{
+		Reason:     "Public dashboard variable overrides must refer to variables of the dashboard",
+		StatusCode: 400,
+		Status:     "invalid-variable",
+	}
+	ErrPublicDashboardInvalidPanel = DashboardErr{
+		Reason:     "Public dashboard hidden panels must exist on the dashboard",
+		StatusCode: 400,
+		Status:     "invalid-panel",
+	}
+	ErrPublicDashboardUnknownDatasource = DashboardErr{
+		Reason:     "Public dashboard allowed data sources must be data sources of the org or be queried by the dashboard",
+		StatusCode: 400,
+		Status:     "unknown-datasource",
+	}
+	ErrPublicDashboardDatasourceNotAllowed = DashboardErr{
+		Reason:     "Public dashboard isn't allowed to query the data source",
+		StatusCode: 403,
+		Status:     "datasource-not-allowed",
+	}
+	ErrPublicDashboardInvalidOrigin = DashboardErr{
+		Reason:     "Public dashboard allowed origins must be http or https origins, such as https://example.com",
+		StatusCode: 400,
+		Status:     "invalid-origin",
+	}
+	ErrPublicDashboardUnsupportedPanel = DashboardErr{
+		Reason:     "Public dashboard contains panel types that can't be shown publicly",
+		StatusCode: 400,
+		Status:     "unsupported-panel",
+	}
+	ErrPublicDashboardQueriesChanged = DashboardErr{
+		Reason:     "Public dashboard queries changed since it was published, it has to be saved again to publish them",
+		StatusCode: 409,
+		Status:     "queries-changed",
+	}
+	ErrPublicDashboardInvalidExport = DashboardErr{
+		Reason:     "Public dashboard export is invalid",
+		StatusCode: 400,
+		Status:     "invalid-export",
+	}
+	ErrPublicDashboardAccessTokenCollision = DashboardErr{
+		Reason:     "Failed to generate unique access token for public dashboard",
+		StatusCode: 500,
+	}
+	ErrPublicDashboardQuotaReached = DashboardErr{
+		Reason:     "Public dashboard quota reached",
+		StatusCode: 403,
+		Status:     "quota-reached",
+	}
+	ErrPublicDashboardsDisabled = DashboardErr{
+		Reason:     "Public dashboards feature is disabled",
+		StatusCode: 404,
+		Status:     "not-found",
+	}
 )
 
+// PublicDashboardLookupErr is returned when a public dashboard can't be resolved.
+// It wraps the cause so callers can tell a missing public dashboard
+// (ErrPublicDashboardNotFound) apart from a missing dashboard (ErrDashboardNotFound).
+type PublicDashboardLookupErr struct {
+	Uid string
+	Err error
+}
+
+func (e PublicDashboardLookupErr) Error() string {
+	return e.Err.Error()
+}
+
+func (e PublicDashboardLookupErr) Unwrap() error {
+	return e.Err
+}
+
+// PublicDashboardUnsupportedPanelErr is returned when a dashboard with panel types that can't be
+// shown publicly is made public. It wraps ErrPublicDashboardUnsupportedPanel.
+type PublicDashboardUnsupportedPanelErr struct {
+	PanelTypes []string
+}
+
+func (e PublicDashboardUnsupportedPanelErr) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPublicDashboardUnsupportedPanel.Reason, strings.Join(e.PanelTypes, ", "))
+}
+
+func (e PublicDashboardUnsupportedPanelErr) Unwrap() error {
+	return ErrPublicDashboardUnsupportedPanel
+}
+
 type PublicDashboardConfig struct {
 	IsPublic        bool            `json:"isPublic"`
 	PublicDashboard PublicDashboard `json:"publicDashboard"`
+	// CreatedByUser and UpdatedByUser are only set when the users were resolved explicitly
+	CreatedByUser *PublicDashboardUser `json:"createdByUser,omitempty"`
+	UpdatedByUser *PublicDashboardUser `json:"updatedByUser,omitempty"`
+}
+
+// PublicDashboardUser is a user that created or updated a public dashboard.
+// Login and Name are empty when the user was deleted.
+type PublicDashboardUser struct {
+	Id    int64  `json:"id" xorm:"id"`
+	Login string `json:"login" xorm:"login"`
+	Name  string `json:"name" xorm:"name"`
 }
 
 type PublicDashboard struct {
-	Uid          string `json:"uid" xorm:"uid"`
-	DashboardUid string `json:"dashboardUid" xorm:"dashboard_uid"`
-	OrgId        int64  `json:"orgId" xorm:"org_id"`
-	TimeSettings string `json:"timeSettings" xorm:"time_settings"`
+	Uid                string    `json:"uid" xorm:"uid"`
+	DashboardUid       string    `json:"dashboardUid" xorm:"dashboard_uid"`
+	OrgId              int64     `json:"orgId" xorm:"org_id"`
+	TimeSettings       string    `json:"timeSettings" xorm:"time_settings"`
+	CreatedAt          time.Time `json:"createdAt" xorm:"created_at TIMESTAMPZ"`
+	AccessToken        string    `json:"accessToken" xorm:"access_token"`
+	DeletedAt          time.Time `json:"deletedAt" xorm:"deleted_at"`
+	AnnotationsEnabled bool      `json:"annotationsEnabled" xorm:"annotations_enabled"`
+	CreatedBy          int64     `json:"createdBy" xorm:"created_by"`
+	UpdatedBy          int64     `json:"updatedBy" xorm:"updated_by"`
+	Theme              string    `json:"theme" xorm:"theme"`
+	ShareType          string    `json:"shareType" xorm:"share_type"`
+	LastAccessedAt     time.Time `json:"lastAccessedAt" xorm:"last_accessed_at"`
+	ModelVersion       int       `json:"modelVersion" xorm:"model_version"`
+	// Slug optionally identifies the public dashboard in its public URL, in place of its uid
+	Slug string `json:"slug" xorm:"slug"`
+	// QuerySignature is the signature of the panel queries of the dashboard when the public dashboard was
+	// last saved. Only those queries are run for viewers. Empty for public dashboards saved before signatures.
+	QuerySignature string `json:"querySignature" xorm:"query_signature"`
+	// MaxQueryDurationSeconds is how long the queries of the public dashboard may run before they are
+	// cancelled. Zero means the limit configured for all public dashboards applies.
+	MaxQueryDurationSeconds int64 `json:"maxQueryDurationSeconds" xorm:"max_query_duration_seconds"`
+	// AllowedVariables are the names of the template variables viewers can change,
+	// all other variables are hidden and locked to their saved value.
+	AllowedVariables []string `json:"allowedVariables" xorm:"allowed_variables"`
+	// Timezone is the time zone the public dashboard is displayed in, browser, utc or an IANA time zone name
+	Timezone string `json:"timezone" xorm:"timezone"`
+	// HiddenPanelIds are the ids of the panels of the dashboard that aren't shown publicly
+	HiddenPanelIds []int64 `json:"hiddenPanelIds" xorm:"hidden_panel_ids"`
+	// PasswordHash is the bcrypt hash of the password viewers have to enter, if the public dashboard is
+	// password protected. It's never serialized.
+	PasswordHash string `json:"-" xorm:"password_hash"`
+	// RefreshInterval overrides the auto-refresh of the dashboard for the public view, off or a duration such as 30s
+	RefreshInterval string `json:"refreshInterval" xorm:"refresh_interval"`
+	// AllowedOrigins are the origins, such as https://example.com, that should be allowed to embed the public
+	// dashboard. If empty, the embedding settings of the org apply. The origins are only stored, the public
+	// dashboard page doesn't restrict its frame ancestors by them yet.
+	AllowedOrigins []string `json:"allowedOrigins" xorm:"allowed_origins"`
+	// VariableOverrides are the values template variables are set to in the public view, keyed by variable name.
+	// Locked variables are locked to them, allowed variables start out with them.
+	VariableOverrides map[string]string `json:"variableOverrides" xorm:"variable_overrides"`
+	// UpdatedAt is when the public dashboard was last saved. Like CreatedAt, it's stored with millisecond
+	// precision, so public dashboards saved within the same second can still be told apart.
+	UpdatedAt time.Time `json:"updatedAt" xorm:"updated_at TIMESTAMPZ"`
+	// RateLimitPerMinute is the number of requests per minute a viewer should be limited to, counted per access
+	// token and IP address. Zero means the limit of the org applies. The value is only stored, the public
+	// dashboard routes don't enforce it yet.
+	RateLimitPerMinute int `json:"rateLimitPerMinute" xorm:"rate_limit_per_minute"`
+	// CacheTTLSeconds is how long browsers and proxies may cache the query responses of the public dashboard.
+	// Zero means they aren't cached.
+	CacheTTLSeconds int `json:"cacheTtlSeconds" xorm:"cache_ttl_seconds"`
+	// ContentHash identifies what the public dashboard serves, derived from its config and the JSON of its dashboard.
+	// It's only set by GetPublicDashboard, so the serving layer can use it as the ETag of conditional requests.
+	ContentHash string `json:"-" xorm:"-"`
+	// EnabledFrom and EnabledTo limit when the public dashboard is served. Both are either times of day (HH:MM),
+	// evaluated every day in the time zone of the public dashboard, or RFC 3339 timestamps. Empty means it's always
+	// served.
+	EnabledFrom string `json:"enabledFrom" xorm:"enabled_from"`
+	EnabledTo   string `json:"enabledTo" xorm:"enabled_to"`
+	// AllowedDatasourceUids are the uids of the data sources the queries of the public dashboard may run against.
+	// If none are set, the queries may run against any data source the dashboard queries when they're run.
+	AllowedDatasourceUids []string `json:"allowedDatasourceUids" xorm:"allowed_datasource_uids"`
+}
+
+// IsDatasourceAllowed reports whether queries of the public dashboard may run against the data source.
+// It's always true if none are allowed, as only the queries of the dashboard's panels are run, so the data
+// source is one the dashboard queries.
+func (pd PublicDashboard) IsDatasourceAllowed(uid string) bool {
+	if len(pd.AllowedDatasourceUids) == 0 {
+		return true
+	}
+
+	for _, allowed := range pd.AllowedDatasourceUids {
+		if allowed == uid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateEnabledWindow verifies EnabledFrom and EnabledTo are both empty, or form a valid window
+func (pd PublicDashboard) ValidateEnabledWindow() error {
+	if pd.EnabledFrom == "" && pd.EnabledTo == "" {
+		return nil
+	}
+
+	_, _, _, err := pd.enabledWindow()
+	return err
+}
+
+// IsEnabledAt reports whether the public dashboard is served at t according to EnabledFrom and EnabledTo. Windows
+// include their start but not their end. Public dashboards with an invalid window are never served.
+func (pd PublicDashboard) IsEnabledAt(t time.Time) bool {
+	if pd.EnabledFrom == "" && pd.EnabledTo == "" {
+		return true
+	}
+
+	from, to, loc, err := pd.enabledWindow()
+	if err != nil {
+		return false
+	}
+
+	if loc == nil {
+		return !t.Before(from) && t.Before(to)
+	}
+
+	// compare the time of day only, on the date the window is parsed with
+	local := t.In(loc)
+	timeOfDay := time.Date(from.Year(), from.Month(), from.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+	return !timeOfDay.Before(from) && timeOfDay.Before(to)
+}
+
+// enabledWindow parses EnabledFrom and EnabledTo. For daily windows it also returns the time zone they're
+// evaluated in, it's nil for windows of absolute timestamps.
+func (pd PublicDashboard) enabledWindow() (from time.Time, to time.Time, loc *time.Location, err error) {
+	const timeOfDayLayout = "15:04"
+
+	from, fromErr := time.Parse(timeOfDayLayout, pd.EnabledFrom)
+	to, toErr := time.Parse(timeOfDayLayout, pd.EnabledTo)
+	if fromErr == nil && toErr == nil {
+		switch pd.Timezone {
+		case PublicDashboardTimezoneUTC:
+			loc = time.UTC
+		case PublicDashboardTimezoneBrowser, "", "Local":
+			// the time zone of the viewer isn't known when the public dashboard is served
+			return time.Time{}, time.Time{}, nil, ErrPublicDashboardInvalidEnabledWindow
+		default:
+			if loc, err = time.LoadLocation(pd.Timezone); err != nil {
+				return time.Time{}, time.Time{}, nil, ErrPublicDashboardInvalidEnabledWindow
+			}
+		}
+	} else {
+		from, fromErr = time.Parse(time.RFC3339, pd.EnabledFrom)
+		to, toErr = time.Parse(time.RFC3339, pd.EnabledTo)
+		if fromErr != nil || toErr != nil {
+			return time.Time{}, time.Time{}, nil, ErrPublicDashboardInvalidEnabledWindow
+		}
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, nil, ErrPublicDashboardInvalidEnabledWindow
+	}
+
+	return from, to, loc, nil
+}
+
+// IsPasswordProtected reports whether viewers have to enter a password to view the public dashboard
+func (pd PublicDashboard) IsPasswordProtected() bool {
+	return pd.PasswordHash != ""
+}
+
+// VerifyPassword checks the password a viewer entered against the one protecting the public dashboard. It
+// fails with ErrPublicDashboardPasswordRequired if no password was entered, and with
+// ErrPublicDashboardWrongPassword if it doesn't match. Any password is accepted if the public dashboard isn't
+// password protected.
+func (pd PublicDashboard) VerifyPassword(password string) error {
+	if !pd.IsPasswordProtected() {
+		return nil
+	}
+	if password == "" {
+		return ErrPublicDashboardPasswordRequired
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(pd.PasswordHash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPublicDashboardWrongPassword
+		}
+		return err
+	}
+
+	return nil
+}
+
+// IsViewableBy reports whether the viewer may view the public dashboard. Anyone with the link may view public
+// dashboards shared publicly, while authenticated ones are only viewable by users signed in to their org.
+// viewer is nil or anonymous for viewers who aren't signed in.
+func (pd PublicDashboard) IsViewableBy(viewer *SignedInUser) bool {
+	if pd.ShareType != PublicDashboardShareTypeAuthenticated {
+		return true
+	}
+
+	return viewer != nil && !viewer.IsAnonymous && viewer.UserId != 0 && viewer.OrgId == pd.OrgId
 }
 
 func (pd PublicDashboard) TableName() string {
 	return "dashboard_public_config"
 }
 
+// PublicDashboardTimeSettings are the time settings of a public dashboard, stored as JSON in
+// PublicDashboard.TimeSettings. MinTime and MaxTime optionally bound the time range viewers can
+// request. All of them are relative or absolute time expressions.
+type PublicDashboardTimeSettings struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	MinTime string `json:"minTime,omitempty"`
+	MaxTime string `json:"maxTime,omitempty"`
+}
+
+// HasBounds reports whether the time range viewers can request is bounded.
+func (ts PublicDashboardTimeSettings) HasBounds() bool {
+	return ts.MinTime != "" || ts.MaxTime != ""
+}
+
+// ParsePublicDashboardTimeSettings parses the time settings of a public dashboard.
+// Empty time settings, as stored for public dashboards without any, are returned as zero value.
+func ParsePublicDashboardTimeSettings(timeSettings string) (PublicDashboardTimeSettings, error) {
+	var ts PublicDashboardTimeSettings
+	if timeSettings == "" {
+		return ts, nil
+	}
+
+	err := json.Unmarshal([]byte(timeSettings), &ts)
+	return ts, err
+}
+
+// PublicDashboardExport is the portable representation of a public dashboard config, used to move it between
+// Grafana instances. It doesn't contain the access token or any IDs specific to the instance it was exported from.
+type PublicDashboardExport struct {
+	DashboardUid       string   `json:"dashboardUid"`
+	IsPublic           bool     `json:"isPublic"`
+	TimeSettings       string   `json:"timeSettings"`
+	ShareType          string   `json:"shareType"`
+	Theme              string   `json:"theme"`
+	Timezone           string   `json:"timezone"`
+	RefreshInterval    string   `json:"refreshInterval"`
+	AnnotationsEnabled bool     `json:"annotationsEnabled"`
+	AllowedVariables   []string `json:"allowedVariables"`
+	HiddenPanelIds     []int64  `json:"hiddenPanelIds"`
+	AllowedOrigins     []string `json:"allowedOrigins"`
+	// MaxQueryDurationSeconds is only exported if the public dashboard sets its own limit, otherwise
+	// the limit configured where it's imported applies.
+	MaxQueryDurationSeconds int64             `json:"maxQueryDurationSeconds,omitempty"`
+	VariableOverrides       map[string]string `json:"variableOverrides"`
+	RateLimitPerMinute      int               `json:"rateLimitPerMinute"`
+	CacheTTLSeconds         int               `json:"cacheTtlSeconds"`
+	EnabledFrom             string            `json:"enabledFrom"`
+	EnabledTo               string            `json:"enabledTo"`
+	AllowedDatasourceUids   []string          `json:"allowedDatasourceUids"`
+}
+
+// PublicDashboardQueryOptions are the settings of a public dashboard that apply to running its queries
+type PublicDashboardQueryOptions struct {
+	// MaxDuration is how long the queries may run before they're cancelled, zero means they aren't
+	MaxDuration time.Duration
+	// CacheTTL is how long the query responses may be cached, zero means they may not be
+	CacheTTL time.Duration
+}
+
+// PublicDashboardListItem is a public dashboard along with the details of its dashboard
+type PublicDashboardListItem struct {
+	Uid            string    `json:"uid" xorm:"uid"`
+	Slug           string    `json:"slug" xorm:"slug"`
+	DashboardUid   string    `json:"dashboardUid" xorm:"dashboard_uid"`
+	Title          string    `json:"title" xorm:"title"`
+	IsPublic       bool      `json:"isPublic" xorm:"is_public"`
+	CreatedAt      time.Time `json:"createdAt" xorm:"created_at"`
+	LastAccessedAt time.Time `json:"lastAccessedAt" xorm:"last_accessed_at"`
+}
+
+// OrgPublicDashboardStats are the number of public dashboards of an org, and how many of them are enabled
+type OrgPublicDashboardStats struct {
+	OrgId   int64 `json:"orgId" xorm:"org_id"`
+	Total   int64 `json:"total" xorm:"total"`
+	Enabled int64 `json:"enabled" xorm:"enabled"`
+}
+
+// Disabled returns the number of public dashboards of the org that are disabled
+func (s OrgPublicDashboardStats) Disabled() int64 {
+	return s.Total - s.Enabled
+}
+
 //
 // COMMANDS
 //
@@ -46,3 +545,89 @@ type SavePublicDashboardConfigCommand struct {
 	OrgId                 int64
 	PublicDashboardConfig PublicDashboardConfig
 }
+
+// PublicDashboardPatch changes some settings of a public dashboard, leaving the ones that are nil unchanged
+type PublicDashboardPatch struct {
+	// IsEnabled makes the dashboard of the public dashboard public, or not
+	IsEnabled               *bool              `json:"isEnabled,omitempty"`
+	TimeSettings            *string            `json:"timeSettings,omitempty"`
+	AnnotationsEnabled      *bool              `json:"annotationsEnabled,omitempty"`
+	Theme                   *string            `json:"theme,omitempty"`
+	ShareType               *string            `json:"shareType,omitempty"`
+	Slug                    *string            `json:"slug,omitempty"`
+	Timezone                *string            `json:"timezone,omitempty"`
+	RefreshInterval         *string            `json:"refreshInterval,omitempty"`
+	MaxQueryDurationSeconds *int64             `json:"maxQueryDurationSeconds,omitempty"`
+	RateLimitPerMinute      *int               `json:"rateLimitPerMinute,omitempty"`
+	CacheTTLSeconds         *int               `json:"cacheTtlSeconds,omitempty"`
+	EnabledFrom             *string            `json:"enabledFrom,omitempty"`
+	EnabledTo               *string            `json:"enabledTo,omitempty"`
+	AllowedVariables        *[]string          `json:"allowedVariables,omitempty"`
+	VariableOverrides       *map[string]string `json:"variableOverrides,omitempty"`
+	HiddenPanelIds          *[]int64           `json:"hiddenPanelIds,omitempty"`
+	AllowedOrigins          *[]string          `json:"allowedOrigins,omitempty"`
+	AllowedDatasourceUids   *[]string          `json:"allowedDatasourceUids,omitempty"`
+
+	// UpdatedBy is the user patching the public dashboard
+	UpdatedBy int64 `json:"-"`
+}
+
+// Apply changes the settings of pdc that are set in the patch
+func (p PublicDashboardPatch) Apply(pdc *PublicDashboardConfig) {
+	pd := &pdc.PublicDashboard
+	if p.IsEnabled != nil {
+		pdc.IsPublic = *p.IsEnabled
+	}
+	if p.TimeSettings != nil {
+		pd.TimeSettings = *p.TimeSettings
+	}
+	if p.AnnotationsEnabled != nil {
+		pd.AnnotationsEnabled = *p.AnnotationsEnabled
+	}
+	if p.Theme != nil {
+		pd.Theme = *p.Theme
+	}
+	if p.ShareType != nil {
+		pd.ShareType = *p.ShareType
+	}
+	if p.Slug != nil {
+		pd.Slug = *p.Slug
+	}
+	if p.Timezone != nil {
+		pd.Timezone = *p.Timezone
+	}
+	if p.RefreshInterval != nil {
+		pd.RefreshInterval = *p.RefreshInterval
+	}
+	if p.MaxQueryDurationSeconds != nil {
+		pd.MaxQueryDurationSeconds = *p.MaxQueryDurationSeconds
+	}
+	if p.RateLimitPerMinute != nil {
+		pd.RateLimitPerMinute = *p.RateLimitPerMinute
+	}
+	if p.CacheTTLSeconds != nil {
+		pd.CacheTTLSeconds = *p.CacheTTLSeconds
+	}
+	if p.EnabledFrom != nil {
+		pd.EnabledFrom = *p.EnabledFrom
+	}
+	if p.EnabledTo != nil {
+		pd.EnabledTo = *p.EnabledTo
+	}
+	if p.AllowedVariables != nil {
+		pd.AllowedVariables = *p.AllowedVariables
+	}
+	if p.VariableOverrides != nil {
+		pd.VariableOverrides = *p.VariableOverrides
+	}
+	if p.HiddenPanelIds != nil {
+		pd.HiddenPanelIds = *p.HiddenPanelIds
+	}
+	if p.AllowedOrigins != nil {
+		pd.AllowedOrigins = *p.AllowedOrigins
+	}
+	if p.AllowedDatasourceUids != nil {
+		pd.AllowedDatasourceUids = *p.AllowedDatasourceUids
+	}
+	pd.UpdatedBy = p.UpdatedBy
+}
@@ -60,6 +60,10 @@ func (cp *corePlugin) Decommission() error {
 	return nil
 }
 
+func (cp *corePlugin) Recommission() error {
+	return nil
+}
+
 func (cp *corePlugin) IsDecommissioned() bool {
 	return false
 }
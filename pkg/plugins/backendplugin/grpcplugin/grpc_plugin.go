@@ -116,6 +116,15 @@ func (p *grpcPlugin) Decommission() error {
 	return nil
 }
 
+func (p *grpcPlugin) Recommission() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.decommissioned = false
+
+	return nil
+}
+
 func (p *grpcPlugin) IsDecommissioned() bool {
 	return p.decommissioned
 }
@@ -16,6 +16,7 @@ type Plugin interface {
 	IsManaged() bool
 	Exited() bool
 	Decommission() error
+	Recommission() error
 	IsDecommissioned() bool
 	backend.CollectMetricsHandler
 	backend.CheckHealthHandler
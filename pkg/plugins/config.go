@@ -1,6 +1,8 @@
 package plugins
 
 import (
+	"time"
+
 	"github.com/grafana/grafana-azure-sdk-go/azsettings"
 
 	"github.com/grafana/grafana/pkg/setting"
@@ -14,6 +16,32 @@ type Cfg struct {
 	PluginSettings       setting.PluginSettings
 	PluginsAllowUnsigned []string
 
+	// Hosts plugins may be installed from by URL, see setting.Cfg.PluginURLInstallAllowedHosts
+	PluginURLInstallAllowedHosts     []string
+	PluginURLInstallRequireAllowlist bool
+
+	// Plugin ID patterns that may or may not be installed, see setting.Cfg.PluginInstallAllowList
+	PluginInstallAllowList []string
+	PluginInstallDenyList  []string
+
+	// Local plugin bundle to install plugins from, see setting.Cfg.PluginBundlePath
+	PluginBundlePath              string
+	PluginBundleAllowRepoFallback bool
+
+	// Parallel downloads of dependencies, see setting.Cfg.PluginDependencyConcurrency
+	PluginDependencyConcurrency int
+
+	// Retries of failed plugin repository requests, see setting.Cfg.PluginRepositoryRetryAttempts
+	PluginRepositoryRetryAttempts  int
+	PluginRepositoryRetryBaseDelay time.Duration
+
+	// Headers sent to a private plugin repository, see setting.Cfg.PluginRepositoryPrivateHost
+	PluginRepositoryPrivateHost    string
+	PluginRepositoryPrivateHeaders map[string]string
+
+	// Whether removing data source plugins checks dashboards using them, see setting.Cfg.PluginUninstallCheckDashboards
+	PluginUninstallCheckDashboards bool
+
 	EnterpriseLicensePath string
 
 	// AWS Plugin Auth
@@ -38,6 +66,18 @@ func FromGrafanaCfg(grafanaCfg *setting.Cfg) *Cfg {
 
 	cfg.PluginSettings = grafanaCfg.PluginSettings
 	cfg.PluginsAllowUnsigned = grafanaCfg.PluginsAllowUnsigned
+	cfg.PluginURLInstallAllowedHosts = grafanaCfg.PluginURLInstallAllowedHosts
+	cfg.PluginURLInstallRequireAllowlist = grafanaCfg.PluginURLInstallRequireAllowlist
+	cfg.PluginInstallAllowList = grafanaCfg.PluginInstallAllowList
+	cfg.PluginInstallDenyList = grafanaCfg.PluginInstallDenyList
+	cfg.PluginBundlePath = grafanaCfg.PluginBundlePath
+	cfg.PluginBundleAllowRepoFallback = grafanaCfg.PluginBundleAllowRepoFallback
+	cfg.PluginDependencyConcurrency = grafanaCfg.PluginDependencyConcurrency
+	cfg.PluginRepositoryRetryAttempts = grafanaCfg.PluginRepositoryRetryAttempts
+	cfg.PluginRepositoryRetryBaseDelay = grafanaCfg.PluginRepositoryRetryBaseDelay
+	cfg.PluginRepositoryPrivateHost = grafanaCfg.PluginRepositoryPrivateHost
+	cfg.PluginRepositoryPrivateHeaders = grafanaCfg.PluginRepositoryPrivateHeaders
+	cfg.PluginUninstallCheckDashboards = grafanaCfg.PluginUninstallCheckDashboards
 	cfg.EnterpriseLicensePath = grafanaCfg.EnterpriseLicensePath
 
 	// AWS
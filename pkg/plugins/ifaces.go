@@ -20,12 +20,130 @@ type Store interface {
 type Manager interface {
 	// Add adds a plugin to the store.
 	Add(ctx context.Context, pluginID, version string) error
+	// Update updates an installed plugin to the given version.
+	Update(ctx context.Context, pluginID, version string, opts AddOpts) (*InstallPlan, error)
 	// Remove removes a plugin from the store.
 	Remove(ctx context.Context, pluginID string) error
 }
 
 type UpdateInfo struct {
 	PluginZipURL string
+	// Version is the resolved plugin version, which is the latest supported one if no version was requested.
+	Version string
+	// SHA256 is the checksum the plugin repository publishes for the plugin archive, if any.
+	SHA256 string
+
+	// CurrentVersion is the installed version of the plugin. It's only set by the plugin manager.
+	CurrentVersion string
+	// ReleaseNotes are the release notes of the versions after CurrentVersion up to Version, newest first.
+	// They're only set by the plugin manager.
+	ReleaseNotes []ReleaseNote
+}
+
+// ReleaseNote are the release notes of a plugin version. Notes are empty if none were published.
+type ReleaseNote struct {
+	Version string `json:"version"`
+	Notes   string `json:"notes"`
+}
+
+// PluginUpdate describes a newer version being available for an installed plugin.
+type PluginUpdate struct {
+	PluginID         string `json:"pluginId"`
+	CurrentVersion   string `json:"currentVersion"`
+	AvailableVersion string `json:"availableVersion"`
+}
+
+// AddOpts are the options used when adding a plugin.
+type AddOpts struct {
+	// DryRun resolves what would be installed without changing anything.
+	DryRun bool
+	// FailIfInstalled makes adding a plugin that is already installed fail with a DuplicateError,
+	// instead of updating it. Use Update to upgrade an installed plugin.
+	FailIfInstalled bool
+	// Force installs the requested version even if the plugin is pinned to another version.
+	Force bool
+	// Checksum is the expected SHA256 checksum of plugin archives that aren't released through the
+	// plugin repository, such as git refs. Released versions are always verified against the checksum
+	// published by the repository.
+	Checksum string
+	// RepositoryURL overrides the plugin repository the plugin and its dependencies are resolved from,
+	// such as a private plugin catalog. If empty, the default grafana.com repository is used.
+	RepositoryURL string
+	// AllowDefaultRepositoryFallback resolves plugins that can't be found in RepositoryURL from the
+	// default repository instead.
+	AllowDefaultRepositoryFallback bool
+	// Progress is called as the plugin and its dependencies are installed, such as to render a progress bar.
+	// It isn't called for dry runs.
+	Progress InstallProgressFunc
+	// RollbackOnPostInstallHookFailure removes a newly installed plugin again if its post-install hook fails.
+	// Otherwise the plugin stays installed and the failure is only returned.
+	RollbackOnPostInstallHookFailure bool
+}
+
+// InstallPhase is a phase of installing a plugin, reported to AddOpts.Progress.
+type InstallPhase string
+
+const (
+	InstallPhaseResolving   InstallPhase = "resolving"
+	InstallPhaseDownloading InstallPhase = "downloading"
+	InstallPhaseExtracting  InstallPhase = "extracting"
+	InstallPhaseLoading     InstallPhase = "loading"
+)
+
+// InstallProgress describes the progress of installing a plugin or one of its dependencies.
+type InstallProgress struct {
+	Phase InstallPhase
+	// PluginID is the plugin being added, or one of its dependencies.
+	PluginID string
+	// Dependency is set if PluginID is a dependency of the plugin being added.
+	Dependency bool
+	// BytesDownloaded is the number of bytes of the plugin archive downloaded so far in InstallPhaseDownloading.
+	BytesDownloaded int64
+}
+
+// PostInstallHook runs a one-time setup step for a plugin that was just installed to pluginDir. Plugins declare
+// which hook they need in their plugin.json, hooks themselves are registered with the plugin manager.
+type PostInstallHook func(ctx context.Context, pluginID, pluginDir string) error
+
+// InstallProgressFunc receives the progress of installing a plugin. Dependencies are downloaded concurrently,
+// but calls are never made concurrently.
+type InstallProgressFunc func(progress InstallProgress)
+
+// RemoveOpts are the options used when removing a plugin.
+type RemoveOpts struct {
+	// Force removes the plugin even if other installed plugins depend on it, if it's provisioned, or if dashboards
+	// use data sources of it.
+	Force bool
+}
+
+// DashboardUsageFinder finds the dashboards that use the data sources of a plugin.
+type DashboardUsageFinder interface {
+	// DashboardsUsingDatasourcePlugin returns the dashboards of all orgs with panels, queries, template variables
+	// or annotations that refer to a data source of the data source plugin.
+	DashboardsUsingDatasourcePlugin(ctx context.Context, pluginID string) ([]DashboardUsage, error)
+}
+
+// CompatibilityOpts describe the Grafana installation plugins are checked for compatibility with.
+type CompatibilityOpts struct {
+	// GrafanaVersion is the Grafana version to check against. If empty, the running version is used.
+	GrafanaVersion string
+}
+
+// InstallPlan describes the changes that adding a plugin would make.
+type InstallPlan struct {
+	// Download contains the plugin archives that would be downloaded, including transitive dependencies.
+	Download []PluginArchiveInfo `json:"download"`
+	// Remove contains the IDs of installed plugins that would be removed.
+	Remove []string `json:"remove"`
+	// Load contains the IDs of the plugins that would be loaded.
+	Load []string `json:"load"`
+}
+
+// PluginArchiveInfo describes a resolved plugin archive.
+type PluginArchiveInfo struct {
+	PluginID     string `json:"pluginId"`
+	Version      string `json:"version"`
+	PluginZipURL string `json:"pluginZipUrl"`
 }
 
 // Client is used to communicate with backend plugin implementations.
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 
+	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin/instrumentation"
 )
@@ -95,6 +98,10 @@ func (m *PluginManager) CheckHealth(ctx context.Context, req *backend.CheckHealt
 		resp, innerErr = p.CheckHealth(ctx, req)
 		return
 	})
+	// a cancelled request doesn't tell anything about the plugin
+	if ctx.Err() == nil {
+		m.recordHealth(p.ID, healthStatus(resp, err))
+	}
 
 	if err != nil {
 		if errors.Is(err, backendplugin.ErrMethodNotImplemented) {
@@ -111,6 +118,118 @@ func (m *PluginManager) CheckHealth(ctx context.Context, req *backend.CheckHealt
 	return resp, nil
 }
 
+// checkHealthAllTimeout is how long CheckHealthAll waits for each plugin
+var checkHealthAllTimeout = 10 * time.Second
+
+// CheckHealthAll checks the health of all backend data source plugins concurrently and returns
+// their status by plugin ID. Plugins that don't respond in time are reported with HealthStatusTimeout,
+// without holding back the results of the other plugins.
+func (m *PluginManager) CheckHealthAll(ctx context.Context, orgID int64) map[string]plugins.HealthStatus {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	res := make(map[string]plugins.HealthStatus)
+	for _, p := range m.availablePlugins(ctx) {
+		if !p.Backend || !p.IsDataSource() {
+			continue
+		}
+
+		wg.Add(1)
+		go func(p *plugins.Plugin) {
+			defer wg.Done()
+			status := m.checkHealthWithTimeout(ctx, orgID, p.ID)
+
+			mu.Lock()
+			defer mu.Unlock()
+			res[p.ID] = status
+		}(p)
+	}
+	wg.Wait()
+
+	return res
+}
+
+// checkHealthWithTimeout doesn't wait for the health check to return once the timeout expires, so a
+// plugin that ignores the cancellation can't block the caller.
+func (m *PluginManager) checkHealthWithTimeout(ctx context.Context, orgID int64, pluginID string) plugins.HealthStatus {
+	ctx, cancel := context.WithTimeout(ctx, checkHealthAllTimeout)
+	defer cancel()
+
+	done := make(chan plugins.HealthStatus, 1)
+	go func() {
+		resp, err := m.CheckHealth(ctx, &backend.CheckHealthRequest{
+			PluginContext: backend.PluginContext{OrgID: orgID, PluginID: pluginID},
+		})
+		done <- healthStatus(resp, err)
+	}()
+
+	select {
+	case status := <-done:
+		return status
+	case <-ctx.Done():
+		m.log.Warn("Plugin health check timed out", "pluginId", pluginID)
+		return plugins.HealthStatusTimeout
+	}
+}
+
+func healthStatus(resp *backend.CheckHealthResult, err error) plugins.HealthStatus {
+	if err != nil {
+		if errors.Is(err, backendplugin.ErrMethodNotImplemented) {
+			return plugins.HealthStatusNotImplemented
+		}
+		return plugins.HealthStatusError
+	}
+
+	if resp == nil {
+		return plugins.HealthStatusUnknown
+	}
+
+	switch resp.Status {
+	case backend.HealthStatusOk:
+		return plugins.HealthStatusOK
+	case backend.HealthStatusError:
+		return plugins.HealthStatusError
+	default:
+		return plugins.HealthStatusUnknown
+	}
+}
+
+// recordHealth keeps the outcome of the last health check of the plugin, which is reported by Plugin and Plugins
+func (m *PluginManager) recordHealth(pluginID string, status plugins.HealthStatus) {
+	m.lastHealthMu.Lock()
+	defer m.lastHealthMu.Unlock()
+	if m.lastHealth == nil {
+		m.lastHealth = make(map[string]plugins.HealthStatus)
+	}
+	m.lastHealth[pluginID] = status
+}
+
+func (m *PluginManager) resetHealth(pluginID string) {
+	m.lastHealthMu.Lock()
+	defer m.lastHealthMu.Unlock()
+	delete(m.lastHealth, pluginID)
+}
+
+// pluginHealth returns the last known health of the plugin's backend. A backend process that exited is
+// unhealthy, otherwise the outcome of the last health check is reported, or HealthStatusUnknown if it
+// wasn't checked since it was started.
+func (m *PluginManager) pluginHealth(p *plugins.Plugin) plugins.HealthStatus {
+	if !p.Backend {
+		return plugins.HealthStatusNotApplicable
+	}
+
+	if p.Exited() {
+		return plugins.HealthStatusError
+	}
+
+	m.lastHealthMu.RLock()
+	defer m.lastHealthMu.RUnlock()
+	if status, exists := m.lastHealth[p.ID]; exists {
+		return status
+	}
+
+	return plugins.HealthStatusUnknown
+}
+
 func (m *PluginManager) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
 	plugin, exists := m.plugin(ctx, req.PluginContext.PluginID)
 	if !exists {
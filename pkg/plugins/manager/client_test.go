@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_CheckHealthAll(t *testing.T) {
+	timeout := checkHealthAllTimeout
+	checkHealthAllTimeout = 100 * time.Millisecond
+	t.Cleanup(func() {
+		checkHealthAllTimeout = timeout
+	})
+
+	healthy, healthyClient := createPlugin(t, "healthy-datasource", "1.0.0", plugins.External, true, true)
+	healthyClient.CheckHealthHandlerFunc = func(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+		assert.Equal(t, int64(2), req.PluginContext.OrgID)
+		return &backend.CheckHealthResult{Status: backend.HealthStatusOk}, nil
+	}
+	unhealthy, unhealthyClient := createPlugin(t, "unhealthy-datasource", "1.0.0", plugins.External, true, true)
+	unhealthyClient.CheckHealthHandlerFunc = func(_ context.Context, _ *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+		return &backend.CheckHealthResult{Status: backend.HealthStatusError}, nil
+	}
+	notImplemented, _ := createPlugin(t, "no-health-datasource", "1.0.0", plugins.External, true, true)
+
+	// the slow plugin ignores the cancellation and only returns once the test is done
+	unblock := make(chan struct{})
+	t.Cleanup(func() {
+		close(unblock)
+	})
+	slow, slowClient := createPlugin(t, "slow-datasource", "1.0.0", plugins.External, true, true)
+	slowClient.CheckHealthHandlerFunc = func(_ context.Context, _ *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+		<-unblock
+		return &backend.CheckHealthResult{Status: backend.HealthStatusOk}, nil
+	}
+
+	frontend, _ := createPlugin(t, "frontend-datasource", "1.0.0", plugins.External, true, false)
+	app, _ := createPlugin(t, "backend-app", "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+		p.Type = plugins.App
+	})
+
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginRegistry = &fakePluginRegistry{
+			store: map[string]*plugins.Plugin{
+				healthy.ID:        healthy,
+				unhealthy.ID:      unhealthy,
+				notImplemented.ID: notImplemented,
+				slow.ID:           slow,
+				frontend.ID:       frontend,
+				app.ID:            app,
+			},
+		}
+	})
+
+	res := pm.CheckHealthAll(context.Background(), 2)
+	require.Equal(t, map[string]plugins.HealthStatus{
+		"healthy-datasource":   plugins.HealthStatusOK,
+		"unhealthy-datasource": plugins.HealthStatusError,
+		"no-health-datasource": plugins.HealthStatusNotImplemented,
+		"slow-datasource":      plugins.HealthStatusTimeout,
+	}, res)
+}
@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// IncompatiblePlugin is an installed plugin whose Grafana dependency isn't satisfied by the Grafana version
+type IncompatiblePlugin struct {
+	PluginID          string `json:"pluginId"`
+	Version           string `json:"version"`
+	GrafanaDependency string `json:"grafanaDependency"`
+}
+
+// CheckInstalledCompatibility returns the installed external plugins whose declared Grafana dependency isn't
+// satisfied by the Grafana version, ordered by plugin ID ascending. Incompatible plugins are only reported, they
+// stay loaded. Plugins without a Grafana dependency, or with one that can't be parsed, are considered compatible,
+// as is the legacy grafanaVersion, which predates version ranges.
+func (m *PluginManager) CheckInstalledCompatibility(ctx context.Context, opts plugins.CompatibilityOpts) ([]IncompatiblePlugin, error) {
+	grafanaVersion := opts.GrafanaVersion
+	if grafanaVersion == "" {
+		grafanaVersion = m.cfg.BuildVersion
+	}
+
+	v, err := semver.NewVersion(grafanaVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Grafana version %q: %w", grafanaVersion, err)
+	}
+	// constraints never match pre-releases, but plugins support the pre-releases of the versions they support
+	v, err = semver.NewVersion(fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch()))
+	if err != nil {
+		return nil, err
+	}
+
+	incompatible := make([]IncompatiblePlugin, 0)
+	for _, p := range m.availablePlugins(ctx) {
+		if !p.IsExternalPlugin() {
+			continue
+		}
+
+		dependency := strings.TrimSpace(p.Dependencies.GrafanaDependency)
+		if satisfiesGrafanaDependency(v, dependency) {
+			continue
+		}
+
+		incompatible = append(incompatible, IncompatiblePlugin{
+			PluginID:          p.ID,
+			Version:           p.Info.Version,
+			GrafanaDependency: dependency,
+		})
+	}
+
+	return incompatible, nil
+}
+
+// logIncompatiblePlugins warns about the loaded plugins that don't support the running Grafana version
+func (m *PluginManager) logIncompatiblePlugins(ctx context.Context) {
+	incompatible, err := m.CheckInstalledCompatibility(ctx, plugins.CompatibilityOpts{})
+	if err != nil {
+		m.log.Debug("Could not check plugin compatibility", "err", err)
+		return
+	}
+
+	for _, p := range incompatible {
+		m.log.Warn("Plugin doesn't support this Grafana version", "pluginId", p.PluginID, "version", p.Version,
+			"grafanaDependency", p.GrafanaDependency, "grafanaVersion", m.cfg.BuildVersion)
+	}
+}
+
+// satisfiesGrafanaDependency checks v against the Grafana dependency of a plugin, such as ">=8.0.0".
+// A plain version is treated as the minimum supported version. Dependencies that can't be parsed are ignored.
+func satisfiesGrafanaDependency(v *semver.Version, dependency string) bool {
+	if dependency == "" {
+		return true
+	}
+	if _, err := semver.NewVersion(dependency); err == nil {
+		dependency = ">=" + dependency
+	}
+
+	c, err := semver.NewConstraint(dependency)
+	if err != nil {
+		return true
+	}
+
+	return c.Check(v)
+}
@@ -0,0 +1,77 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_CheckInstalledCompatibility(t *testing.T) {
+	setup := func(t *testing.T, dependencies map[string]string) *PluginManager {
+		t.Helper()
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.BuildVersion = "9.1.0"
+		})
+		for pluginID, dependency := range dependencies {
+			p, _ := createPlugin(t, pluginID, "1.0.0", plugins.External, true, false, func(p *plugins.Plugin) {
+				p.Dependencies.GrafanaDependency = dependency
+			})
+			require.NoError(t, pm.registerAndStart(context.Background(), p))
+		}
+
+		return pm
+	}
+
+	t.Run("Reports plugins that don't support the running version", func(t *testing.T) {
+		pm := setup(t, map[string]string{
+			"a-datasource": ">=10.0.0",
+			"b-datasource": ">=8.0.0",
+			"c-datasource": "<9.0.0",
+			"d-datasource": "8.0.0",
+			"e-datasource": "",
+			"f-datasource": "not a range",
+		})
+		core, _ := createPlugin(t, "core-datasource", "1.0.0", plugins.Core, true, false, func(p *plugins.Plugin) {
+			p.Dependencies.GrafanaDependency = ">=10.0.0"
+		})
+		require.NoError(t, pm.registerAndStart(context.Background(), core))
+
+		incompatible, err := pm.CheckInstalledCompatibility(context.Background(), plugins.CompatibilityOpts{})
+		require.NoError(t, err)
+		require.Equal(t, []IncompatiblePlugin{
+			{PluginID: "a-datasource", Version: "1.0.0", GrafanaDependency: ">=10.0.0"},
+			{PluginID: "c-datasource", Version: "1.0.0", GrafanaDependency: "<9.0.0"},
+		}, incompatible)
+
+		// nothing is unloaded
+		_, exists := pm.Plugin(context.Background(), "a-datasource")
+		require.True(t, exists)
+	})
+
+	t.Run("Checks against the requested version", func(t *testing.T) {
+		pm := setup(t, map[string]string{"a-datasource": ">=10.0.0"})
+
+		incompatible, err := pm.CheckInstalledCompatibility(context.Background(), plugins.CompatibilityOpts{GrafanaVersion: "10.0.0"})
+		require.NoError(t, err)
+		require.NotNil(t, incompatible)
+		require.Empty(t, incompatible)
+	})
+
+	t.Run("Pre-releases support the plugins of their version", func(t *testing.T) {
+		pm := setup(t, map[string]string{"a-datasource": ">=10.0.0"})
+
+		incompatible, err := pm.CheckInstalledCompatibility(context.Background(), plugins.CompatibilityOpts{GrafanaVersion: "10.0.0-beta1"})
+		require.NoError(t, err)
+		require.Empty(t, incompatible)
+	})
+
+	t.Run("Fails for an invalid Grafana version", func(t *testing.T) {
+		pm := setup(t, nil)
+
+		_, err := pm.CheckInstalledCompatibility(context.Background(), plugins.CompatibilityOpts{GrafanaVersion: "main"})
+		require.Error(t, err)
+	})
+}
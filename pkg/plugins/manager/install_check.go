@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager/installer"
+)
+
+// Reasons reported by CanInstall when a plugin can't be installed
+const (
+	InstallReasonCorePlugin         = "core-plugin"
+	InstallReasonAlreadyInstalled   = "already-installed"
+	InstallReasonDecommissioned     = "decommissioned"
+	InstallReasonInvalidVersion     = "invalid-version"
+	InstallReasonPinned             = "pinned"
+	InstallReasonNotFound           = "not-found"
+	InstallReasonUnsupportedVersion = "unsupported-version"
+	InstallReasonURLNotAllowed      = "url-not-allowed"
+	InstallReasonNotAllowed         = "not-allowed"
+)
+
+// CanInstall reports whether AddWithOpts would be able to install the requested version of the plugin, without
+// downloading anything or writing to the plugins directory. Only the metadata of the plugin is fetched from the
+// plugin repository, to resolve a version that supports this Grafana version and system.
+// If the plugin can't be installed, it returns false along with one of the InstallReason codes and an error
+// describing the reason. If the check itself fails, such as when the plugin repository can't be reached, the
+// reason is empty.
+// Signatures are verified when the plugin is loaded, so plugins that fail signature verification are not detected.
+func (m *PluginManager) CanInstall(ctx context.Context, pluginID, version string, opts plugins.AddOpts) (bool, string, error) {
+	if err := m.checkInstallAllowed(pluginID); err != nil {
+		return false, InstallReasonNotAllowed, err
+	}
+
+	gitRef, isGitRef := parseGitRef(version)
+	if isGitRef && gitRef == "" {
+		return false, InstallReasonInvalidVersion, fmt.Errorf("missing git ref in version %q", version)
+	}
+	if version != "" && !isGitRef {
+		if _, err := semver.NewVersion(version); err != nil {
+			return false, InstallReasonInvalidVersion, fmt.Errorf("invalid version %q: %w", version, err)
+		}
+	}
+
+	if plugin, exists := m.plugin(ctx, pluginID); exists {
+		if !plugin.IsExternalPlugin() {
+			return false, InstallReasonCorePlugin, plugins.ErrInstallCorePlugin
+		}
+		if opts.FailIfInstalled {
+			return false, InstallReasonAlreadyInstalled, plugins.DuplicateError{
+				PluginID:          plugin.ID,
+				ExistingPluginDir: plugin.PluginDir,
+			}
+		}
+	} else if decommissioned, exists := m.decommissionedPlugin(ctx, pluginID); exists {
+		if err := m.canRemove(decommissioned); err != nil {
+			return false, InstallReasonDecommissioned, fmt.Errorf("%w: %v", plugins.ErrPluginDecommissioned, err)
+		}
+	}
+
+	if err := m.checkPinned(ctx, pluginID, version, opts); err != nil {
+		if errors.Is(err, plugins.ErrPluginPinned) {
+			return false, InstallReasonPinned, err
+		}
+		return false, "", err
+	}
+
+	var err error
+	if isGitRef {
+		_, err = m.gitRefArchiveURL(ctx, pluginID, gitRef, opts)
+	} else {
+		err = m.withRepository(opts, func(repoURL string) error {
+			_, err := m.updateInfo(ctx, pluginID, version, repoURL)
+			return err
+		})
+	}
+	if err != nil {
+		return false, installCheckReason(err), err
+	}
+
+	return true, "", nil
+}
+
+// installCheckReason returns the reason for plugin resolution errors that are caused by the requested plugin or
+// version, or an empty reason for other errors, such as the plugin repository being unavailable
+func installCheckReason(err error) string {
+	var unsupportedErr installer.ErrVersionUnsupported
+	if errors.As(err, &unsupportedErr) {
+		return InstallReasonUnsupportedVersion
+	}
+
+	var gitRefErr installer.ErrGitRefUnsupported
+	if errors.As(err, &gitRefErr) {
+		return InstallReasonInvalidVersion
+	}
+
+	if isNotFoundInRepository(err) {
+		return InstallReasonNotFound
+	}
+
+	if errors.Is(err, plugins.ErrPluginURLNotAllowed) {
+		return InstallReasonURLNotAllowed
+	}
+
+	return ""
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_CanInstall(t *testing.T) {
+	setup := func(t *testing.T, registered ...*plugins.Plugin) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		store := map[string]*plugins.Plugin{}
+		for _, p := range registered {
+			store[p.ID] = p
+		}
+
+		i := &fakePluginInstaller{latestVersions: map[string]string{testPluginID: "1.2.0"}}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.PluginsPath = t.TempDir()
+			pm.pluginInstaller = i
+			pm.pluginRegistry = &fakePluginRegistry{store: store}
+		})
+
+		return pm, i
+	}
+
+	t.Run("Plugin that can be installed", func(t *testing.T) {
+		pm, i := setup(t)
+
+		ok, reason, err := pm.CanInstall(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Empty(t, reason)
+
+		// only the plugin metadata is resolved, nothing is installed
+		assert.Equal(t, 1, i.updateInfoCount)
+		assert.Equal(t, 0, i.installCount)
+		files, err := ioutil.ReadDir(pm.cfg.PluginsPath)
+		require.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("Invalid version", func(t *testing.T) {
+		pm, i := setup(t)
+
+		ok, reason, err := pm.CanInstall(context.Background(), testPluginID, "latest", plugins.AddOpts{})
+		require.Error(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, InstallReasonInvalidVersion, reason)
+		assert.Equal(t, 0, i.updateInfoCount)
+	})
+
+	t.Run("Core plugin", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.Core, true, true)
+		pm, _ := setup(t, p)
+
+		ok, reason, err := pm.CanInstall(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.ErrorIs(t, err, plugins.ErrInstallCorePlugin)
+		assert.False(t, ok)
+		assert.Equal(t, InstallReasonCorePlugin, reason)
+	})
+
+	t.Run("Already installed plugin", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm, _ := setup(t, p)
+
+		ok, reason, err := pm.CanInstall(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{FailIfInstalled: true})
+		require.ErrorAs(t, err, &plugins.DuplicateError{})
+		assert.False(t, ok)
+		assert.Equal(t, InstallReasonAlreadyInstalled, reason)
+
+		// without FailIfInstalled the plugin would be updated instead
+		ok, _, err = pm.CanInstall(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("Pinned plugin", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm, _ := setup(t, p)
+		err := pm.Pin(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		ok, reason, err := pm.CanInstall(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.ErrorIs(t, err, plugins.ErrPluginPinned)
+		assert.False(t, ok)
+		assert.Equal(t, InstallReasonPinned, reason)
+	})
+
+	t.Run("Git ref archive URL not allowed", func(t *testing.T) {
+		pm, i := setup(t)
+		pm.cfg.PluginURLInstallAllowedHosts = []string{"gitlab.com"}
+
+		ok, reason, err := pm.CanInstall(context.Background(), testPluginID, "gitref:feature-x", plugins.AddOpts{})
+		require.ErrorIs(t, err, plugins.ErrPluginURLNotAllowed)
+		assert.False(t, ok)
+		assert.Equal(t, InstallReasonURLNotAllowed, reason)
+		assert.Equal(t, 0, i.installCount)
+	})
+
+	t.Run("Plugin missing in the plugin repository", func(t *testing.T) {
+		pm, i := setup(t)
+		i.missingInRepos = map[string]bool{grafanaComURL: true}
+
+		ok, reason, err := pm.CanInstall(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.Error(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, InstallReasonNotFound, reason)
+	})
+}
@@ -0,0 +1,39 @@
+package manager
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// checkInstallAllowed verifies the plugin may be installed according to the configured allow and deny lists of
+// plugin ID patterns, such as myorg-*. Denied plugins are never installed, even if they're allowed as well. If the
+// allow list is empty, all plugins that aren't denied may be installed. The installer checks the dependencies of the
+// installed plugins with it as well.
+func (m *PluginManager) checkInstallAllowed(pluginID string) error {
+	if pattern, denied := matchPluginID(pluginID, m.cfg.PluginInstallDenyList); denied {
+		return fmt.Errorf("%w: %s is denied by %q", plugins.ErrPluginNotAllowed, pluginID, pattern)
+	}
+
+	if len(m.cfg.PluginInstallAllowList) == 0 {
+		return nil
+	}
+	if _, allowed := matchPluginID(pluginID, m.cfg.PluginInstallAllowList); !allowed {
+		return fmt.Errorf("%w: %s is not in the allow list", plugins.ErrPluginNotAllowed, pluginID)
+	}
+
+	return nil
+}
+
+// matchPluginID returns the first of the patterns that matches the plugin ID. Patterns use the syntax of
+// path.Match, invalid patterns never match.
+func matchPluginID(pluginID string, patterns []string) (string, bool) {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, pluginID); err == nil && matched {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
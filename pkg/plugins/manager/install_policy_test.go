@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_InstallPolicy(t *testing.T) {
+	setup := func(t *testing.T, allowList, denyList []string) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.PluginsPath = t.TempDir()
+			pm.cfg.PluginInstallAllowList = allowList
+			pm.cfg.PluginInstallDenyList = denyList
+			pm.pluginInstaller = i
+		})
+
+		return pm, i
+	}
+
+	add := func(t *testing.T, pm *PluginManager, pluginID string) error {
+		t.Helper()
+		p, _ := createPlugin(t, pluginID, "1.0.0", plugins.External, true, true)
+		pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		return pm.Add(context.Background(), pluginID, "1.0.0")
+	}
+
+	t.Run("Allow list only installs the allowed plugins", func(t *testing.T) {
+		pm, i := setup(t, []string{"allowed-datasource"}, nil)
+
+		require.NoError(t, add(t, pm, "allowed-datasource"))
+		require.Equal(t, 1, i.installCount)
+
+		err := add(t, pm, "other-datasource")
+		require.ErrorIs(t, err, plugins.ErrPluginNotAllowed)
+		require.Equal(t, 1, i.installCount)
+	})
+
+	t.Run("Deny list installs all but the denied plugins", func(t *testing.T) {
+		pm, i := setup(t, nil, []string{"denied-datasource"})
+
+		err := add(t, pm, "denied-datasource")
+		require.ErrorIs(t, err, plugins.ErrPluginNotAllowed)
+		require.Equal(t, 0, i.installCount)
+
+		require.NoError(t, add(t, pm, "other-datasource"))
+		require.Equal(t, 1, i.installCount)
+	})
+
+	t.Run("Patterns match plugin IDs and deny wins over allow", func(t *testing.T) {
+		pm, i := setup(t, []string{"myorg-*"}, []string{"myorg-*-panel"})
+
+		require.NoError(t, add(t, pm, "myorg-datasource"))
+		require.Equal(t, 1, i.installCount)
+
+		for _, pluginID := range []string{"myorg-clock-panel", "otherorg-datasource", "xmyorg-datasource"} {
+			err := add(t, pm, pluginID)
+			require.ErrorIs(t, err, plugins.ErrPluginNotAllowed, pluginID)
+		}
+		require.Equal(t, 1, i.installCount)
+	})
+
+	t.Run("Installed plugins can't be updated once denied", func(t *testing.T) {
+		pm, i := setup(t, nil, nil)
+		require.NoError(t, add(t, pm, testPluginID))
+
+		pm.cfg.PluginInstallDenyList = []string{testPluginID}
+		_, err := pm.Update(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.ErrorIs(t, err, plugins.ErrPluginNotAllowed)
+		require.Equal(t, 1, i.installCount)
+		require.Equal(t, 0, i.uninstallCount)
+	})
+
+	t.Run("Install check reports denied plugins", func(t *testing.T) {
+		pm, _ := setup(t, nil, []string{"denied-*"})
+
+		ok, reason, err := pm.CanInstall(context.Background(), "denied-datasource", "", plugins.AddOpts{})
+		require.False(t, ok)
+		require.Equal(t, InstallReasonNotAllowed, reason)
+		require.ErrorIs(t, err, plugins.ErrPluginNotAllowed)
+	})
+}
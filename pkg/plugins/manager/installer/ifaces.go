@@ -9,11 +9,21 @@ import (
 // Service is responsible for managing plugins (add / remove) on the file system.
 type Service interface {
 	// Install downloads the requested plugin in the provided file system location.
-	Install(ctx context.Context, pluginID, version, pluginsDir, pluginZipURL, pluginRepoURL string) error
+	// pluginZipChecksum is the expected SHA256 checksum of the archive at pluginZipURL, if any.
+	// progress, if not nil, is called as the plugin and its dependencies are resolved, downloaded and extracted.
+	Install(ctx context.Context, pluginID, version, pluginsDir, pluginZipURL, pluginZipChecksum, pluginRepoURL string, progress plugins.InstallProgressFunc) error
+	// InstallFromFile extracts the requested plugin from a local archive in the provided file system location.
+	InstallFromFile(ctx context.Context, pluginID, archivePath, pluginsDir string) error
+	// Plan resolves the requested plugin and its transitive dependencies without installing them.
+	Plan(ctx context.Context, pluginID, version, pluginZipURL, pluginZipChecksum, pluginRepoURL string) ([]plugins.PluginArchiveInfo, error)
 	// Uninstall removes the requested plugin from the provided file system location.
 	Uninstall(ctx context.Context, pluginDir string) error
 	// GetUpdateInfo provides update information for the requested plugin.
 	GetUpdateInfo(ctx context.Context, pluginID, version, pluginRepoURL string) (plugins.UpdateInfo, error)
+	// GetPluginArchiveByGitRef resolves the source archive of the requested plugin at a git branch, tag or commit.
+	GetPluginArchiveByGitRef(ctx context.Context, pluginID, gitRef, pluginRepoURL string) (plugins.PluginArchiveInfo, error)
+	// GetReleaseNotes provides the release notes of every published version of the requested plugin.
+	GetReleaseNotes(ctx context.Context, pluginID, pluginRepoURL string) ([]plugins.ReleaseNote, error)
 }
 
 type Logger interface {
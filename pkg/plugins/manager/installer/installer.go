@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net"
@@ -20,23 +21,67 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Masterminds/semver"
+
 	"github.com/grafana/grafana/pkg/plugins"
 )
 
 type Installer struct {
-	retryCount int
-
 	httpClient          http.Client
 	httpClientNoTimeout http.Client
 	grafanaVersion      string
 	log                 Logger
+
+	// dependencyConcurrency is the maximum number of plugin archives downloaded in parallel by Install.
+	dependencyConcurrency int
+
+	// bundleDir is a directory of plugin archives that plugins are resolved from instead of the plugin
+	// repository. Plugins missing in it are only resolved from the repository if bundleRepoFallback is set.
+	bundleDir          string
+	bundleRepoFallback bool
+
+	// retryAttempts is how often requests to the plugin repository are attempted if they fail transiently,
+	// waiting retryBaseDelay before the first retry and twice as long before each further one.
+	retryAttempts  int
+	retryBaseDelay time.Duration
+
+	// installAllowed returns an error for plugins that may not be installed, nil allows all plugins.
+	installAllowed func(pluginID string) error
+}
+
+// Opts are the options of an installer created with NewWithOpts.
+type Opts struct {
+	// BundleDir is a directory of plugin archives that plugins and their dependencies are resolved from instead
+	// of the plugin repository, such as for air-gapped installs. Plugins missing in the bundle are resolved from
+	// the plugin repository only if BundleRepoFallback is set. An empty BundleDir disables the bundle.
+	BundleDir          string
+	BundleRepoFallback bool
+	// DependencyConcurrency is how many plugin archives are downloaded in parallel when installing dependencies.
+	// If it's not positive, 4 archives are downloaded in parallel.
+	DependencyConcurrency int
+	// RetryAttempts is how often requests to the plugin repository are attempted if they fail transiently.
+	// If it's not positive, requests are attempted 3 times.
+	RetryAttempts int
+	// RetryBaseDelay is the delay before retrying a failed request, doubled with each further retry.
+	// If it's not positive, the first retry is delayed by a second.
+	RetryBaseDelay time.Duration
+	// PrivateRepo configures the headers, such as Authorization, sent to a private plugin repository.
+	PrivateRepo PrivateRepoOpts
+	// InstallAllowed is consulted before any plugin is downloaded, including every transitive dependency of
+	// the requested plugin, and the plugins it returns an error for aren't installed. If it's nil, all plugins
+	// may be installed.
+	InstallAllowed func(pluginID string) error
 }
 
 const (
 	permissionsDeniedMessage = "could not create %q, permission denied, make sure you have write access to plugin dir"
+
+	defaultDependencyConcurrency = 4
 )
 
 var (
@@ -59,6 +104,16 @@ func (e Response4xxError) Error() string {
 	return fmt.Sprintf("%d", e.StatusCode)
 }
 
+// ResponseStatusError is returned when the plugin repository responds with a status other than 2xx or 4xx
+type ResponseStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e ResponseStatusError) Error() string {
+	return fmt.Sprintf("API returned invalid status: %s", e.Status)
+}
+
 type ErrVersionUnsupported struct {
 	PluginID         string
 	RequestedVersion string
@@ -79,92 +134,711 @@ func (e ErrVersionNotFound) Error() string {
 	return fmt.Sprintf("%s v%s either does not exist or is not supported on your system (%s)", e.PluginID, e.RequestedVersion, e.SystemInfo)
 }
 
+type ErrPluginIDMismatch struct {
+	RequestedPluginID string
+	ArchivePluginID   string
+}
+
+func (e ErrPluginIDMismatch) Error() string {
+	return fmt.Sprintf("archive contains plugin %s but %s was requested", e.ArchivePluginID, e.RequestedPluginID)
+}
+
+type ErrDependencyUnavailable struct {
+	PluginID          string
+	DependencyID      string
+	DependencyVersion string
+}
+
+func (e ErrDependencyUnavailable) Error() string {
+	return fmt.Sprintf("dependency %s v%s of %s is not installed and cannot be resolved offline", e.DependencyID, e.DependencyVersion, e.PluginID)
+}
+
+type ErrNotInBundle struct {
+	PluginID         string
+	RequestedVersion string
+	BundleDir        string
+}
+
+func (e ErrNotInBundle) Error() string {
+	if e.RequestedVersion == "" {
+		return fmt.Sprintf("%s is not available in plugin bundle %s", e.PluginID, e.BundleDir)
+	}
+	return fmt.Sprintf("%s v%s is not available in plugin bundle %s", e.PluginID, e.RequestedVersion, e.BundleDir)
+}
+
+type ErrGitRefUnsupported struct {
+	PluginID      string
+	RepositoryURL string
+}
+
+func (e ErrGitRefUnsupported) Error() string {
+	if e.RepositoryURL == "" {
+		return fmt.Sprintf("%s has no source repository to install a git ref from", e.PluginID)
+	}
+	return fmt.Sprintf("installing git refs of %s is not supported for repository %s", e.PluginID, e.RepositoryURL)
+}
+
 func New(skipTLSVerify bool, grafanaVersion string, logger Logger) Service {
+	return NewWithOpts(skipTLSVerify, grafanaVersion, logger, Opts{})
+}
+
+// NewWithOpts returns an installer configured with opts.
+func NewWithOpts(skipTLSVerify bool, grafanaVersion string, logger Logger, opts Opts) Service {
+	dependencyConcurrency := opts.DependencyConcurrency
+	if dependencyConcurrency <= 0 {
+		dependencyConcurrency = defaultDependencyConcurrency
+	}
+	retryAttempts := opts.RetryAttempts
+	if retryAttempts <= 0 {
+		retryAttempts = defaultRetryAttempts
+	}
+	retryBaseDelay := opts.RetryBaseDelay
+	if retryBaseDelay <= 0 {
+		retryBaseDelay = defaultRetryBaseDelay
+	}
+	if opts.PrivateRepo.isPublicRepo() {
+		logger.Warnf("Not sending private plugin repository headers to the public plugin repository %s", publicRepoHost)
+	}
+
 	return &Installer{
-		httpClient:          makeHttpClient(skipTLSVerify, 10*time.Second),
-		httpClientNoTimeout: makeHttpClient(skipTLSVerify, 0),
-		log:                 logger,
-		grafanaVersion:      grafanaVersion,
+		httpClient:            withPrivateRepoHeaders(makeHttpClient(skipTLSVerify, 10*time.Second), opts.PrivateRepo),
+		httpClientNoTimeout:   withPrivateRepoHeaders(makeHttpClient(skipTLSVerify, 0), opts.PrivateRepo),
+		log:                   logger,
+		grafanaVersion:        grafanaVersion,
+		dependencyConcurrency: dependencyConcurrency,
+		bundleDir:             opts.BundleDir,
+		bundleRepoFallback:    opts.BundleRepoFallback,
+		retryAttempts:         retryAttempts,
+		retryBaseDelay:        retryBaseDelay,
+		installAllowed:        opts.InstallAllowed,
 	}
 }
 
 // Install downloads the plugin code as a zip file from specified URL
 // and then extracts the zip into the provided plugins directory.
-func (i *Installer) Install(ctx context.Context, pluginID, version, pluginsDir, pluginZipURL, pluginRepoURL string) error {
-	var checksum string
-	if pluginZipURL == "" {
-		plugin, err := i.getPluginMetadataFromPluginRepo(pluginID, pluginRepoURL)
-		if err != nil {
+// The plugin and its dependencies are downloaded concurrently, bounded by the installer's dependency
+// concurrency, and their version requirements are verified before anything is extracted.
+// If any plugin fails to extract, or ctx is cancelled while extracting, every plugin extracted by this
+// call is removed again.
+// Archives are verified against the SHA256 checksum published by the plugin repository, or against
+// pluginZipChecksum when installing from a plugin zip URL. An empty pluginZipChecksum skips the verification.
+// If progress is not nil, it's called as each plugin is resolved, downloaded and extracted.
+func (i *Installer) Install(ctx context.Context, pluginID, version, pluginsDir, pluginZipURL, pluginZipChecksum, pluginRepoURL string, progress plugins.InstallProgressFunc) error {
+	concurrency := i.dependencyConcurrency
+	if concurrency < 1 {
+		concurrency = defaultDependencyConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	state := &installState{
+		pluginID:      pluginID,
+		pluginsDir:    pluginsDir,
+		pluginRepoURL: pluginRepoURL,
+		slots:         make(chan struct{}, concurrency),
+		seen:          map[string]struct{}{pluginID: {}},
+		cancel:        cancel,
+		progress:      progress,
+	}
+	defer func() {
+		i.removeArchives(state.archives)
+	}()
+
+	if err := i.download(ctx, pluginID, version, pluginZipURL, pluginZipChecksum, state); err != nil {
+		return err
+	}
+
+	// extract in a deterministic order, independent of which download finished first
+	sort.Slice(state.archives, func(a, b int) bool {
+		return state.archives[a].pluginID < state.archives[b].pluginID
+	})
+
+	// verify dependency requirements before writing anything to the plugins directory
+	if err := checkDependencyConflicts(pluginsDir, state.archives); err != nil {
+		return err
+	}
+
+	var installed []string
+	for _, archive := range state.archives {
+		// stop before extracting the next archive if the install was cancelled in the meantime
+		if err := ctx.Err(); err != nil {
+			i.rollback(context.Background(), pluginsDir, installed)
 			return err
 		}
+		state.report(plugins.InstallPhaseExtracting, archive.pluginID, 0)
+		if err := i.extractFiles(archive.path, archive.pluginID, pluginsDir); err != nil {
+			i.rollback(context.Background(), pluginsDir, installed)
+			return fmt.Errorf("%v: %w", "failed to extract plugin archive", err)
+		}
+		installed = append(installed, filepath.Join(pluginsDir, archive.pluginID))
+
+		i.log.Successf("Downloaded %s v%s zip successfully", archive.pluginID, archive.plugin.Info.Version)
+	}
+
+	return nil
+}
+
+// downloadedArchive is a plugin archive downloaded to a temporary file
+type downloadedArchive struct {
+	pluginID string
+	path     string
+	plugin   InstalledPlugin
+}
+
+// installState is shared between all workers of a single Install call.
+type installState struct {
+	pluginID      string
+	pluginsDir    string
+	pluginRepoURL string
+	// slots bounds the number of concurrent downloads.
+	slots  chan struct{}
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	seen     map[string]struct{}
+	archives []downloadedArchive
+	err      error
+
+	// progressMu serializes the calls to progress, as downloads report their progress concurrently
+	progressMu sync.Mutex
+	progress   plugins.InstallProgressFunc
+}
+
+// report passes the progress of installing pluginID to the progress callback, if any
+func (s *installState) report(phase plugins.InstallPhase, pluginID string, bytesDownloaded int64) {
+	if s.progress == nil {
+		return
+	}
+
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	s.progress(plugins.InstallProgress{
+		Phase:           phase,
+		PluginID:        pluginID,
+		Dependency:      pluginID != s.pluginID,
+		BytesDownloaded: bytesDownloaded,
+	})
+}
+
+// markSeen reports whether pluginID has not been scheduled for installation yet and marks it as scheduled.
+func (s *installState) markSeen(pluginID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.seen[pluginID]; exists {
+		return false
+	}
+	s.seen[pluginID] = struct{}{}
+	return true
+}
+
+func (s *installState) addArchive(archive downloadedArchive) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.archives = append(s.archives, archive)
+}
+
+// fail records the first error and cancels all remaining downloads. Errors reported after the
+// first one, such as those caused by the cancellation itself, are ignored.
+func (s *installState) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+		s.cancel()
+	}
+}
+
+func (s *installState) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// download downloads the plugin archive and, concurrently, the archives of its dependencies.
+func (i *Installer) download(ctx context.Context, pluginID, version, pluginZipURL, pluginZipChecksum string, state *installState) error {
+	if err := i.checkInstallAllowed(pluginID); err != nil {
+		return err
+	}
+
+	res, err := i.downloadPlugin(ctx, pluginID, version, pluginZipURL, pluginZipChecksum, state)
+	if err != nil {
+		return err
+	}
+
+	var deps []PluginDependency
+	for _, dep := range res.Dependencies.Plugins {
+		// dependencies shared by several plugins are only downloaded once
+		if state.markSeen(dep.ID) {
+			deps = append(deps, dep)
+		}
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+
+	i.log.Infof("Fetching %s dependencies...", pluginID)
+
+	// download dependency plugins
+	var wg sync.WaitGroup
+	for _, dep := range deps {
+		wg.Add(1)
+		go func(dep PluginDependency) {
+			defer wg.Done()
+			err := i.download(ctx, dep.ID, dependencyVersion(dep.Version), "", "", state)
+			if err == nil {
+				return
+			}
+			// the dependencies of an optional dependency fail the install through state, like any other
+			if dep.Optional && state.failure() == nil {
+				i.log.Warnf("Skipping optional dependency %s of %s as it could not be downloaded: %v", dep.ID, pluginID, err)
+				return
+			}
+			state.fail(fmt.Errorf("failed to install plugin %s: %w", dep.ID, err))
+		}(dep)
+	}
+	wg.Wait()
+
+	return state.failure()
+}
+
+// checkInstallAllowed returns the error of the installer's install policy for the plugin, if it may not be installed
+func (i *Installer) checkInstallAllowed(pluginID string) error {
+	if i.installAllowed == nil {
+		return nil
+	}
+
+	return i.installAllowed(pluginID)
+}
+
+// downloadPlugin downloads a single plugin archive and reads its plugin.json. The worker slot is
+// released before returning, so that dependencies can be fetched without the parent holding on to a slot.
+func (i *Installer) downloadPlugin(ctx context.Context, pluginID, version, pluginZipURL, pluginZipChecksum string, state *installState) (InstalledPlugin, error) {
+	select {
+	case state.slots <- struct{}{}:
+	case <-ctx.Done():
+		return InstalledPlugin{}, ctx.Err()
+	}
+	defer func() { <-state.slots }()
+
+	if err := ctx.Err(); err != nil {
+		return InstalledPlugin{}, err
+	}
+
+	state.report(plugins.InstallPhaseResolving, pluginID, 0)
+	pluginZipURL, _, checksum, err := i.resolvePluginArchive(ctx, pluginID, version, pluginZipURL, pluginZipChecksum, state.pluginRepoURL)
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+
+	i.log.Debugf("Installing plugin\nfrom: %s\ninto: %s", pluginZipURL, state.pluginsDir)
+
+	state.report(plugins.InstallPhaseDownloading, pluginID, 0)
+	archiveFile, err := i.downloadArchive(ctx, pluginID, pluginZipURL, checksum, func(bytesDownloaded int64) {
+		state.report(plugins.InstallPhaseDownloading, pluginID, bytesDownloaded)
+	})
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+
+	res, err := readPluginJSONFromArchive(archiveFile, pluginID)
+	if err != nil {
+		i.log.Warnf("Could not read plugin.json of %s, its dependencies won't be installed: %v", pluginID, err)
+	}
+	state.addArchive(downloadedArchive{pluginID: pluginID, path: archiveFile, plugin: res})
+
+	return res, nil
+}
+
+func (i *Installer) removeArchives(archives []downloadedArchive) {
+	for _, archive := range archives {
+		if err := os.Remove(archive.path); err != nil {
+			i.log.Warn("Failed to remove temporary file", "file", archive.path, "err", err)
+		}
+	}
+}
+
+// checkDependencyConflicts verifies that the version of every dependency present after installing the
+// archives satisfies the requirements of both the archives and the plugins already installed in pluginsDir.
+func checkDependencyConflicts(pluginsDir string, archives []downloadedArchive) error {
+	versions := map[string]string{}
+	requirements := map[string][]plugins.DependencyRequirement{}
+	addRequirements := func(p InstalledPlugin) {
+		for _, dep := range p.Dependencies.Plugins {
+			requirements[dep.ID] = append(requirements[dep.ID], plugins.DependencyRequirement{PluginID: p.ID, Version: dep.Version})
+		}
+	}
+
+	replaced := map[string]struct{}{}
+	for _, archive := range archives {
+		replaced[archive.pluginID] = struct{}{}
+	}
+
+	entries, err := ioutil.ReadDir(pluginsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, exists := replaced[entry.Name()]; exists {
+			continue
+		}
 
-		v, err := i.selectVersion(&plugin, version)
+		p, err := toPluginDTO(pluginsDir, entry.Name())
 		if err != nil {
-			return err
+			continue
 		}
+		versions[p.ID] = p.Info.Version
+		addRequirements(p)
+	}
+
+	for _, archive := range archives {
+		versions[archive.pluginID] = archive.plugin.Info.Version
+		archive.plugin.ID = archive.pluginID
+		addRequirements(archive.plugin)
+	}
+
+	depIDs := make([]string, 0, len(requirements))
+	for depID := range requirements {
+		depIDs = append(depIDs, depID)
+	}
+	sort.Strings(depIDs)
 
-		if version == "" {
-			version = v.Version
+	for _, depID := range depIDs {
+		version, exists := versions[depID]
+		if !exists {
+			continue
+		}
+		v, err := semver.NewVersion(version)
+		if err != nil {
+			continue
 		}
-		pluginZipURL = fmt.Sprintf("%s/%s/versions/%s/download",
-			pluginRepoURL,
-			pluginID,
-			version,
-		)
 
-		// Plugins which are downloaded just as sourcecode zipball from github do not have checksum
-		if v.Arch != nil {
-			archMeta, exists := v.Arch[osAndArchString()]
-			if !exists {
-				archMeta = v.Arch["any"]
+		for _, req := range requirements[depID] {
+			if !satisfiesRequirement(v, req.Version) {
+				return plugins.ErrDependencyVersionConflict{
+					DependencyID: depID,
+					Version:      version,
+					Requirements: requirements[depID],
+				}
 			}
-			checksum = archMeta.SHA256
 		}
 	}
 
-	i.log.Debugf("Installing plugin\nfrom: %s\ninto: %s", pluginZipURL, pluginsDir)
+	return nil
+}
 
-	// Create temp file for downloading zip file
-	tmpFile, err := ioutil.TempFile("", "*.zip")
+// satisfiesRequirement checks v against a dependency version requirement. A plain version is treated
+// as a caret range, as the installer doesn't distinguish between "1.0.0" and "^1.0.0" either.
+// Requirements that can't be parsed are ignored.
+func satisfiesRequirement(v *semver.Version, requirement string) bool {
+	requirement = strings.TrimSpace(requirement)
+	if requirement == "" {
+		return true
+	}
+	if _, err := semver.NewVersion(requirement); err == nil {
+		requirement = "^" + strings.TrimPrefix(requirement, "v")
+	}
+
+	c, err := semver.NewConstraint(requirement)
+	if err != nil {
+		return true
+	}
+
+	return c.Check(v)
+}
+
+func (i *Installer) rollback(ctx context.Context, pluginsDir string, pluginDirs []string) {
+	for idx := len(pluginDirs) - 1; idx >= 0; idx-- {
+		pluginDir := pluginDirs[idx]
+
+		path, err := filepath.Rel(pluginsDir, pluginDir)
+		if err != nil || path == "." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			i.log.Warnf("Skipping removal of %s as it is outside of the plugins directory", pluginDir)
+			continue
+		}
+
+		if err := i.Uninstall(ctx, pluginDir); err != nil {
+			i.log.Warnf("Failed to remove %s after failed install: %v", pluginDir, err)
+		}
+	}
+}
+
+// InstallFromFile extracts the local plugin archive into the provided plugins directory without
+// contacting the plugin repository. All dependencies must already be installed in the plugins directory
+// in a version that satisfies the requirement of the plugin.
+func (i *Installer) InstallFromFile(ctx context.Context, pluginID, archivePath, pluginsDir string) error {
+	res, err := readPluginJSONFromArchive(archivePath, pluginID)
+	if err != nil {
+		return fmt.Errorf("%v: %w", "failed to read plugin archive", err)
+	}
+
+	if res.ID != pluginID {
+		return ErrPluginIDMismatch{
+			RequestedPluginID: pluginID,
+			ArchivePluginID:   res.ID,
+		}
+	}
+
+	// verify all dependencies can be resolved before writing anything to the plugins directory
+	for _, dep := range res.Dependencies.Plugins {
+		installed, err := toPluginDTO(pluginsDir, dep.ID)
+		if err != nil {
+			if dep.Optional {
+				continue
+			}
+			return ErrDependencyUnavailable{
+				PluginID:          pluginID,
+				DependencyID:      dep.ID,
+				DependencyVersion: normalizeVersion(dep.Version),
+			}
+		}
+
+		// another version can't be fetched offline, so an installed version the plugin can't use is a conflict
+		v, err := semver.NewVersion(installed.Info.Version)
+		if err == nil && !satisfiesRequirement(v, dep.Version) {
+			return plugins.ErrDependencyVersionConflict{
+				DependencyID: dep.ID,
+				Version:      installed.Info.Version,
+				Requirements: []plugins.DependencyRequirement{{PluginID: pluginID, Version: dep.Version}},
+			}
+		}
+	}
+
+	i.log.Debugf("Installing plugin\nfrom: %s\ninto: %s", archivePath, pluginsDir)
+
+	err = i.extractFiles(archivePath, pluginID, pluginsDir)
 	if err != nil {
-		return fmt.Errorf("%v: %w", "failed to create temporary file", err)
+		return fmt.Errorf("%v: %w", "failed to extract plugin archive", err)
+	}
+
+	i.log.Successf("Installed %s v%s from %s successfully", res.ID, res.Info.Version, archivePath)
+
+	return nil
+}
+
+// Plan resolves the plugin archive and all of its transitive dependencies
+// without extracting anything into the plugins directory.
+// Archives are verified the same way as by Install.
+func (i *Installer) Plan(ctx context.Context, pluginID, version, pluginZipURL, pluginZipChecksum, pluginRepoURL string) ([]plugins.PluginArchiveInfo, error) {
+	return i.plan(ctx, pluginID, version, pluginZipURL, pluginZipChecksum, pluginRepoURL, make(map[string]struct{}))
+}
+
+func (i *Installer) plan(ctx context.Context, pluginID, version, pluginZipURL, pluginZipChecksum, pluginRepoURL string, seen map[string]struct{}) ([]plugins.PluginArchiveInfo, error) {
+	if _, exists := seen[pluginID]; exists {
+		return nil, nil
+	}
+	seen[pluginID] = struct{}{}
+
+	if err := i.checkInstallAllowed(pluginID); err != nil {
+		return nil, err
+	}
+
+	pluginZipURL, version, checksum, err := i.resolvePluginArchive(ctx, pluginID, version, pluginZipURL, pluginZipChecksum, pluginRepoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	archiveFile, err := i.downloadArchive(ctx, pluginID, pluginZipURL, checksum, nil)
+	if err != nil {
+		return nil, err
 	}
 	defer func() {
-		if err := os.Remove(tmpFile.Name()); err != nil {
-			i.log.Warn("Failed to remove temporary file", "file", tmpFile.Name(), "err", err)
+		if err := os.Remove(archiveFile); err != nil {
+			i.log.Warn("Failed to remove temporary file", "file", archiveFile, "err", err)
 		}
 	}()
 
-	err = i.DownloadFile(pluginID, tmpFile, pluginZipURL, checksum)
+	res, err := readPluginJSONFromArchive(archiveFile, pluginID)
 	if err != nil {
-		if err := tmpFile.Close(); err != nil {
-			i.log.Warn("Failed to close file", "err", err)
+		return nil, err
+	}
+
+	// prefer the version declared by the plugin itself, as URL based installs don't specify one
+	if res.Info.Version != "" {
+		version = res.Info.Version
+	}
+
+	planned := []plugins.PluginArchiveInfo{{
+		PluginID:     pluginID,
+		Version:      version,
+		PluginZipURL: pluginZipURL,
+	}}
+
+	for _, dep := range res.Dependencies.Plugins {
+		depPlan, err := i.plan(ctx, dep.ID, dependencyVersion(dep.Version), "", "", pluginRepoURL, seen)
+		if err != nil && dep.Optional {
+			i.log.Warnf("Skipping optional dependency %s of %s as it could not be resolved: %v", dep.ID, pluginID, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve plugin %s: %w", dep.ID, err)
 		}
-		return fmt.Errorf("%v: %w", "failed to download plugin archive", err)
+		planned = append(planned, depPlan...)
 	}
-	err = tmpFile.Close()
+
+	return planned, nil
+}
+
+// resolvePluginArchive resolves the download URL, version and expected checksum of the requested plugin.
+// If a plugin zip URL is provided it is returned as is, together with the checksum provided for it.
+// With a plugin bundle, the path of the plugin's archive in the bundle is returned instead of a URL. Bundled
+// archives have no published checksum to verify them against.
+func (i *Installer) resolvePluginArchive(ctx context.Context, pluginID, version, pluginZipURL, pluginZipChecksum, pluginRepoURL string) (string, string, string, error) {
+	if pluginZipURL != "" {
+		return pluginZipURL, version, pluginZipChecksum, nil
+	}
+
+	if i.bundleDir != "" {
+		archivePath, bundledVersion, err := i.findBundledArchive(pluginID, version)
+		if err != nil {
+			return "", "", "", err
+		}
+		if archivePath != "" {
+			i.log.Debugf("Resolved %s v%s from plugin bundle: %s", pluginID, bundledVersion, archivePath)
+			return archivePath, bundledVersion, "", nil
+		}
+		if !i.bundleRepoFallback {
+			return "", "", "", ErrNotInBundle{
+				PluginID:         pluginID,
+				RequestedVersion: version,
+				BundleDir:        i.bundleDir,
+			}
+		}
+		i.log.Infof("%s is not available in plugin bundle, falling back to the plugin repository", pluginID)
+	}
+
+	plugin, err := i.getPluginMetadataFromPluginRepo(ctx, pluginID, pluginRepoURL)
 	if err != nil {
-		return fmt.Errorf("%v: %w", "failed to close tmp file", err)
+		return "", "", "", err
 	}
 
-	err = i.extractFiles(tmpFile.Name(), pluginID, pluginsDir)
+	v, err := i.selectVersion(&plugin, version)
 	if err != nil {
-		return fmt.Errorf("%v: %w", "failed to extract plugin archive", err)
+		return "", "", "", err
 	}
 
-	res, _ := toPluginDTO(pluginsDir, pluginID)
+	// the concrete version, if the latest version or a version range was requested
+	version = v.Version
+	pluginZipURL = fmt.Sprintf("%s/%s/versions/%s/download",
+		pluginRepoURL,
+		pluginID,
+		version,
+	)
 
-	i.log.Successf("Downloaded %s v%s zip successfully", res.ID, res.Info.Version)
+	return pluginZipURL, version, archiveChecksum(v), nil
+}
 
-	// download dependency plugins
-	for _, dep := range res.Dependencies.Plugins {
-		i.log.Infof("Fetching %s dependencies...", res.ID)
-		if err := i.Install(ctx, dep.ID, normalizeVersion(dep.Version), pluginsDir, "", pluginRepoURL); err != nil {
-			return fmt.Errorf("failed to install plugin %s: %w", dep.ID, err)
+// findBundledArchive returns the path and version of the archive of the requested plugin version in the plugin
+// bundle, or the latest bundled version if no version is requested. Archives are matched by the plugin ID and
+// version of their plugin.json, so they can be named freely. The path is empty if there's no matching archive.
+func (i *Installer) findBundledArchive(pluginID, version string) (string, string, error) {
+	entries, err := ioutil.ReadDir(i.bundleDir)
+	if err != nil {
+		return "", "", fmt.Errorf("%v: %w", "failed to read plugin bundle", err)
+	}
+
+	var requested *semver.Constraints
+	if version != "" {
+		if !isSemVerExpr(version) {
+			version = "=" + version
+		}
+		// versions that aren't valid semver can't match any bundled archive
+		if requested, err = semver.NewConstraint(version); err != nil {
+			return "", "", nil
 		}
 	}
 
-	return err
+	var archivePath string
+	var latest *semver.Version
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".zip") {
+			continue
+		}
+
+		path := filepath.Join(i.bundleDir, entry.Name())
+		// archives of other plugins don't contain a plugin.json for pluginID
+		res, err := readPluginJSONFromArchive(path, pluginID)
+		if err != nil || res.ID != pluginID {
+			continue
+		}
+
+		v, err := semver.NewVersion(res.Info.Version)
+		if err != nil {
+			i.log.Warnf("Skipping %s in plugin bundle, invalid version %q of %s", entry.Name(), res.Info.Version, pluginID)
+			continue
+		}
+		if requested != nil && !requested.Check(v) {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			archivePath, latest = path, v
+		}
+	}
+
+	if latest == nil {
+		return "", "", nil
+	}
+
+	return archivePath, latest.Original(), nil
+}
+
+// archiveChecksum returns the SHA256 checksum the plugin repository publishes for the archive of
+// the version matching the current system.
+func archiveChecksum(v *Version) string {
+	// Plugins which are downloaded just as sourcecode zipball from github do not have checksum
+	if v.Arch == nil {
+		return ""
+	}
+
+	archMeta, exists := v.Arch[osAndArchString()]
+	if !exists {
+		archMeta = v.Arch["any"]
+	}
+	return archMeta.SHA256
+}
+
+// downloadArchive downloads the plugin archive into a temporary file and returns its path, retrying downloads
+// that fail transiently. The caller is responsible for removing the file. onProgress, if not nil, receives the
+// number of bytes downloaded so far.
+func (i *Installer) downloadArchive(ctx context.Context, pluginID, pluginZipURL, checksum string, onProgress func(int64)) (string, error) {
+	var archiveFile string
+	err := i.withRetry(ctx, "downloading "+pluginID, func() error {
+		var err error
+		archiveFile, err = i.downloadArchiveOnce(ctx, pluginID, pluginZipURL, checksum, onProgress)
+		return err
+	})
+	return archiveFile, err
+}
+
+func (i *Installer) downloadArchiveOnce(ctx context.Context, pluginID, pluginZipURL, checksum string, onProgress func(int64)) (string, error) {
+	// Create temp file for downloading zip file
+	tmpFile, err := ioutil.TempFile("", "*.zip")
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", "failed to create temporary file", err)
+	}
+
+	err = i.downloadFile(ctx, pluginID, tmpFile, pluginZipURL, checksum, 0, onProgress)
+	if err != nil {
+		if err := tmpFile.Close(); err != nil {
+			i.log.Warn("Failed to close file", "err", err)
+		}
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			i.log.Warn("Failed to remove temporary file", "file", tmpFile.Name(), "err", err)
+		}
+		return "", fmt.Errorf("%v: %w", "failed to download plugin archive", err)
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			i.log.Warn("Failed to remove temporary file", "file", tmpFile.Name(), "err", err)
+		}
+		return "", fmt.Errorf("%v: %w", "failed to close tmp file", err)
+	}
+
+	return tmpFile.Name(), nil
 }
 
 // Uninstall removes the specified plugin from the provided plugin directory.
@@ -186,6 +860,15 @@ func (i *Installer) Uninstall(ctx context.Context, pluginDir string) error {
 }
 
 func (i *Installer) DownloadFile(pluginID string, tmpFile *os.File, url string, checksum string) (err error) {
+	return i.downloadFile(context.Background(), pluginID, tmpFile, url, checksum, 0, nil)
+}
+
+// downloadFile keeps track of the retry count per download, so that concurrent downloads don't share it.
+// Cancelling ctx aborts the download. onProgress, if not nil, receives the number of bytes downloaded so far,
+// which starts over when the download is retried.
+func (i *Installer) downloadFile(ctx context.Context, pluginID string, tmpFile *os.File, url string, checksum string, retryCount int, onProgress func(int64)) (err error) {
+	h := sha256.New()
+
 	// Try handling URL as a local file path first
 	if _, err := os.Stat(url); err == nil {
 		// We can ignore this gosec G304 warning since `url` stems from command line flag "pluginUrl". If the
@@ -200,19 +883,17 @@ func (i *Installer) DownloadFile(pluginID string, tmpFile *os.File, url string,
 				i.log.Warn("Failed to close file", "err", err)
 			}
 		}()
-		_, err = io.Copy(tmpFile, f)
+		_, err = io.Copy(tmpFile, io.TeeReader(withProgress(f, onProgress), h))
 		if err != nil {
 			return fmt.Errorf("%v: %w", "Failed to copy plugin archive", err)
 		}
-		return nil
+		return verifyChecksum(checksum, h)
 	}
 
-	i.retryCount = 0
-
 	defer func() {
 		if r := recover(); r != nil {
-			i.retryCount++
-			if i.retryCount < 3 {
+			retryCount++
+			if retryCount < 3 {
 				i.log.Debug("Failed downloading. Will retry once.")
 				err = tmpFile.Truncate(0)
 				if err != nil {
@@ -222,9 +903,8 @@ func (i *Installer) DownloadFile(pluginID string, tmpFile *os.File, url string,
 				if err != nil {
 					return
 				}
-				err = i.DownloadFile(pluginID, tmpFile, url, checksum)
+				err = i.downloadFile(ctx, pluginID, tmpFile, url, checksum, retryCount, onProgress)
 			} else {
-				i.retryCount = 0
 				failure := fmt.Sprintf("%v", r)
 				if failure == "runtime error: makeslice: len out of range" {
 					err = fmt.Errorf("corrupt HTTP response from source, please try again")
@@ -237,7 +917,7 @@ func (i *Installer) DownloadFile(pluginID string, tmpFile *os.File, url string,
 
 	// Using no timeout here as some plugins can be bigger and smaller timeout would prevent to download a plugin on
 	// slow network. As this is CLI operation hanging is not a big of an issue as user can just abort.
-	bodyReader, err := i.sendRequestWithoutTimeout(url)
+	bodyReader, err := i.sendRequestWithoutTimeout(ctx, url)
 	if err != nil {
 		return err
 	}
@@ -248,22 +928,57 @@ func (i *Installer) DownloadFile(pluginID string, tmpFile *os.File, url string,
 	}()
 
 	w := bufio.NewWriter(tmpFile)
-	h := sha256.New()
-	if _, err = io.Copy(w, io.TeeReader(bodyReader, h)); err != nil {
+	if _, err = io.Copy(w, io.TeeReader(withProgress(bodyReader, onProgress), h)); err != nil {
 		return fmt.Errorf("%v: %w", "failed to compute SHA256 checksum", err)
 	}
 	if err := w.Flush(); err != nil {
 		return fmt.Errorf("failed to write to %q: %w", tmpFile.Name(), err)
 	}
-	if len(checksum) > 0 && checksum != fmt.Sprintf("%x", h.Sum(nil)) {
-		return fmt.Errorf("expected SHA256 checksum does not match the downloaded archive - please contact security@grafana.com")
+	return verifyChecksum(checksum, h)
+}
+
+// progressReader passes the number of bytes read so far to onProgress
+type progressReader struct {
+	r          io.Reader
+	read       int64
+	onProgress func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.onProgress(p.read)
+	}
+	return n, err
+}
+
+// withProgress returns r, reporting the bytes read from it to onProgress unless onProgress is nil
+func withProgress(r io.Reader, onProgress func(int64)) io.Reader {
+	if onProgress == nil {
+		return r
+	}
+	return &progressReader{r: r, onProgress: onProgress}
+}
+
+// verifyChecksum verifies the SHA256 checksum of a downloaded archive, unless no checksum is expected.
+func verifyChecksum(checksum string, h hash.Hash) error {
+	if len(checksum) > 0 && !strings.EqualFold(checksum, fmt.Sprintf("%x", h.Sum(nil))) {
+		return fmt.Errorf("%w: expected SHA256 checksum does not match the downloaded archive - please contact security@grafana.com",
+			plugins.ErrPluginChecksumMismatch)
 	}
 	return nil
 }
 
-func (i *Installer) getPluginMetadataFromPluginRepo(pluginID, pluginRepoURL string) (Plugin, error) {
+// getPluginMetadataFromPluginRepo fetches the metadata of the plugin, retrying requests that fail transiently
+func (i *Installer) getPluginMetadataFromPluginRepo(ctx context.Context, pluginID, pluginRepoURL string) (Plugin, error) {
 	i.log.Debugf("Fetching metadata for plugin \"%s\" from repo %s", pluginID, pluginRepoURL)
-	body, err := i.sendRequestGetBytes(pluginRepoURL, "repo", pluginID)
+	var body []byte
+	err := i.withRetry(ctx, "fetching metadata of "+pluginID, func() error {
+		var err error
+		body, err = i.sendRequestGetBytes(ctx, pluginRepoURL, "repo", pluginID)
+		return err
+	})
 	if err != nil {
 		return Plugin{}, err
 	}
@@ -278,8 +993,8 @@ func (i *Installer) getPluginMetadataFromPluginRepo(pluginID, pluginRepoURL stri
 	return data, nil
 }
 
-func (i *Installer) sendRequestGetBytes(URL string, subPaths ...string) ([]byte, error) {
-	bodyReader, err := i.sendRequest(URL, subPaths...)
+func (i *Installer) sendRequestGetBytes(ctx context.Context, URL string, subPaths ...string) ([]byte, error) {
+	bodyReader, err := i.sendRequest(ctx, URL, subPaths...)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -291,8 +1006,8 @@ func (i *Installer) sendRequestGetBytes(URL string, subPaths ...string) ([]byte,
 	return ioutil.ReadAll(bodyReader)
 }
 
-func (i *Installer) sendRequest(URL string, subPaths ...string) (io.ReadCloser, error) {
-	req, err := i.createRequest(URL, subPaths...)
+func (i *Installer) sendRequest(ctx context.Context, URL string, subPaths ...string) (io.ReadCloser, error) {
+	req, err := i.createRequest(ctx, URL, subPaths...)
 	if err != nil {
 		return nil, err
 	}
@@ -304,8 +1019,8 @@ func (i *Installer) sendRequest(URL string, subPaths ...string) (io.ReadCloser,
 	return i.handleResponse(res)
 }
 
-func (i *Installer) sendRequestWithoutTimeout(URL string, subPaths ...string) (io.ReadCloser, error) {
-	req, err := i.createRequest(URL, subPaths...)
+func (i *Installer) sendRequestWithoutTimeout(ctx context.Context, URL string, subPaths ...string) (io.ReadCloser, error) {
+	req, err := i.createRequest(ctx, URL, subPaths...)
 	if err != nil {
 		return nil, err
 	}
@@ -317,7 +1032,7 @@ func (i *Installer) sendRequestWithoutTimeout(URL string, subPaths ...string) (i
 	return i.handleResponse(res)
 }
 
-func (i *Installer) createRequest(URL string, subPaths ...string) (*http.Request, error) {
+func (i *Installer) createRequest(ctx context.Context, URL string, subPaths ...string) (*http.Request, error) {
 	u, err := url.Parse(URL)
 	if err != nil {
 		return nil, err
@@ -327,7 +1042,7 @@ func (i *Installer) createRequest(URL string, subPaths ...string) (*http.Request
 		u.Path = path.Join(u.Path, v)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -363,7 +1078,7 @@ func (i *Installer) handleResponse(res *http.Response) (io.ReadCloser, error) {
 	}
 
 	if res.StatusCode/100 != 2 {
-		return nil, fmt.Errorf("API returned invalid status: %s", res.Status)
+		return nil, ResponseStatusError{StatusCode: res.StatusCode, Status: res.Status}
 	}
 
 	return res.Body, nil
@@ -391,6 +1106,31 @@ func makeHttpClient(skipTLSVerify bool, timeout time.Duration) http.Client {
 	}
 }
 
+// dependencyVersion returns the version of a plugin dependency to install. Version ranges are kept as they are,
+// to be resolved to the highest version satisfying them, whereas plain versions are installed as is.
+func dependencyVersion(version string) string {
+	trimmed := strings.TrimSpace(version)
+	if isSemVerExpr(trimmed) {
+		return trimmed
+	}
+
+	return normalizeVersion(version)
+}
+
+// isSemVerExpr reports whether version is a semver constraint, such as ^1.2.0 or >=1.0.0, <2.0.0, rather than
+// a plain version
+func isSemVerExpr(version string) bool {
+	if version == "" {
+		return false
+	}
+	if _, err := semver.NewVersion(version); err == nil {
+		return false
+	}
+
+	_, err := semver.NewConstraint(version)
+	return err == nil
+}
+
 func normalizeVersion(version string) string {
 	normalized := strings.ReplaceAll(version, " ", "")
 	if strings.HasPrefix(normalized, "^") || strings.HasPrefix(normalized, "v") {
@@ -401,7 +1141,7 @@ func normalizeVersion(version string) string {
 }
 
 func (i *Installer) GetUpdateInfo(ctx context.Context, pluginID, version, pluginRepoURL string) (plugins.UpdateInfo, error) {
-	plugin, err := i.getPluginMetadataFromPluginRepo(pluginID, pluginRepoURL)
+	plugin, err := i.getPluginMetadataFromPluginRepo(ctx, pluginID, pluginRepoURL)
 	if err != nil {
 		return plugins.UpdateInfo{}, err
 	}
@@ -413,9 +1153,69 @@ func (i *Installer) GetUpdateInfo(ctx context.Context, pluginID, version, plugin
 
 	return plugins.UpdateInfo{
 		PluginZipURL: fmt.Sprintf("%s/%s/versions/%s/download", pluginRepoURL, pluginID, v.Version),
+		Version:      v.Version,
+		SHA256:       archiveChecksum(v),
+	}, nil
+}
+
+// GetPluginArchiveByGitRef resolves the GitHub source archive of the plugin at the requested git ref, using the
+// source repository of the plugin's latest version. The archive is installed like any other plugin archive, but
+// since it isn't a release it has no checksum.
+func (i *Installer) GetPluginArchiveByGitRef(ctx context.Context, pluginID, gitRef, pluginRepoURL string) (plugins.PluginArchiveInfo, error) {
+	plugin, err := i.getPluginMetadataFromPluginRepo(ctx, pluginID, pluginRepoURL)
+	if err != nil {
+		return plugins.PluginArchiveInfo{}, err
+	}
+
+	var repoURL string
+	for _, v := range plugin.Versions {
+		if v.URL != "" {
+			repoURL = v.URL
+			break
+		}
+	}
+
+	zipURL, err := gitRefArchiveURL(repoURL, gitRef)
+	if err != nil {
+		return plugins.PluginArchiveInfo{}, ErrGitRefUnsupported{PluginID: pluginID, RepositoryURL: repoURL}
+	}
+
+	return plugins.PluginArchiveInfo{
+		PluginID:     pluginID,
+		Version:      gitRef,
+		PluginZipURL: zipURL,
 	}, nil
 }
 
+// GetReleaseNotes returns the release notes of every version of the plugin, in the order the plugin repository
+// lists them. Versions without published release notes have empty notes.
+func (i *Installer) GetReleaseNotes(ctx context.Context, pluginID, pluginRepoURL string) ([]plugins.ReleaseNote, error) {
+	plugin, err := i.getPluginMetadataFromPluginRepo(ctx, pluginID, pluginRepoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]plugins.ReleaseNote, 0, len(plugin.Versions))
+	for _, v := range plugin.Versions {
+		notes = append(notes, plugins.ReleaseNote{Version: v.Version, Notes: v.Changelog})
+	}
+
+	return notes, nil
+}
+
+// gitRefArchiveURL returns the URL of the zip archive of a GitHub repository at the provided git ref
+func gitRefArchiveURL(repoURL, gitRef string) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(strings.TrimSuffix(repoURL, "/"), ".git"))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "https" || u.Host != "github.com" || strings.Count(strings.Trim(u.Path, "/"), "/") != 1 {
+		return "", fmt.Errorf("unsupported repository URL %q", repoURL)
+	}
+
+	return fmt.Sprintf("%s/archive/%s.zip", u.String(), url.PathEscape(gitRef)), nil
+}
+
 // selectVersion selects the most appropriate plugin version
 // returns the specified version if supported.
 // returns latest version if no specific version is specified.
@@ -437,6 +1237,9 @@ func (i *Installer) selectVersion(plugin *Plugin, version string) (*Version, err
 	if version == "" {
 		return latestForArch, nil
 	}
+	if isSemVerExpr(version) {
+		return i.selectVersionInRange(plugin, version)
+	}
 	for _, v := range plugin.Versions {
 		if v.Version == version {
 			ver = v
@@ -467,6 +1270,52 @@ func (i *Installer) selectVersion(plugin *Plugin, version string) (*Version, err
 	return &ver, nil
 }
 
+// selectVersionInRange selects the highest plugin version that satisfies the version constraint and supports the
+// current system. It doesn't rely on plugin.Versions being sorted, as versions are compared as semver.
+func (i *Installer) selectVersionInRange(plugin *Plugin, constraint string) (*Version, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected *Version
+	var selectedVersion *semver.Version
+	matched := false
+	for _, v := range plugin.Versions {
+		sv, err := semver.NewVersion(v.Version)
+		if err != nil || !c.Check(sv) {
+			continue
+		}
+		matched = true
+
+		ver := v
+		if !supportsCurrentArch(&ver) {
+			continue
+		}
+		if selectedVersion == nil || sv.GreaterThan(selectedVersion) {
+			selected, selectedVersion = &ver, sv
+		}
+	}
+
+	if selected != nil {
+		i.log.Debugf("Resolved %s %s to v%s", plugin.ID, constraint, selected.Version)
+		return selected, nil
+	}
+	if matched {
+		return nil, ErrVersionUnsupported{
+			PluginID:         plugin.ID,
+			RequestedVersion: constraint,
+			SystemInfo:       i.fullSystemInfoString(),
+		}
+	}
+
+	return nil, ErrVersionNotFound{
+		PluginID:         plugin.ID,
+		RequestedVersion: constraint,
+		SystemInfo:       i.fullSystemInfoString(),
+	}
+}
+
 func (i *Installer) fullSystemInfoString() string {
 	return fmt.Sprintf("Grafana v%s %s", i.grafanaVersion, osAndArchString())
 }
@@ -671,6 +1520,48 @@ func removeGitBuildFromName(filename, pluginID string) string {
 	return reGitBuild.ReplaceAllString(filename, pluginID+"/")
 }
 
+// readPluginJSONFromArchive reads the plugin.json of the requested plugin straight from the archive
+func readPluginJSONFromArchive(archiveFile, pluginID string) (InstalledPlugin, error) {
+	r, err := zip.OpenReader(archiveFile)
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	var pluginJSON *zip.File
+	for _, zf := range r.File {
+		switch removeGitBuildFromName(zf.Name, pluginID) {
+		case pluginID + "/dist/plugin.json":
+			pluginJSON = zf
+		case pluginID + "/plugin.json":
+			if pluginJSON == nil {
+				pluginJSON = zf
+			}
+		}
+	}
+
+	if pluginJSON == nil {
+		return InstalledPlugin{}, errors.New("could not find dist/plugin.json or plugin.json in archive of " + pluginID)
+	}
+
+	f, err := pluginJSON.Open()
+	if err != nil {
+		return InstalledPlugin{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	res := InstalledPlugin{}
+	if err := json.NewDecoder(f).Decode(&res); err != nil {
+		return InstalledPlugin{}, err
+	}
+
+	return res, nil
+}
+
 func toPluginDTO(pluginDir, pluginID string) (InstalledPlugin, error) {
 	distPluginDataPath := filepath.Join(pluginDir, pluginID, "dist", "plugin.json")
 
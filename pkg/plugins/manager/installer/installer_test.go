@@ -1,15 +1,26 @@
 package installer
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
 )
 
 func TestInstall(t *testing.T) {
@@ -25,7 +36,7 @@ func TestInstall(t *testing.T) {
 	pluginID := "test-app"
 
 	i := &Installer{log: &fakeLogger{}}
-	err = i.Install(context.Background(), pluginID, "", testDir, "./testdata/plugin-with-symlinks.zip", "")
+	err = i.Install(context.Background(), pluginID, "", testDir, "./testdata/plugin-with-symlinks.zip", "", "", nil)
 	require.NoError(t, err)
 
 	// verify extracted contents
@@ -42,6 +53,880 @@ func TestInstall(t *testing.T) {
 	require.Equal(t, files[5].Name(), "text.txt")
 }
 
+func TestInstall_RollbackOnFailedDependency(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(repo.Close)
+
+	pluginsDir := t.TempDir()
+	existingPluginDir := filepath.Join(pluginsDir, "existing-app")
+	err := os.Mkdir(existingPluginDir, os.ModePerm)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(existingPluginDir, "plugin.json"), []byte(`{"id": "existing-app"}`), 0600)
+	require.NoError(t, err)
+
+	i := &Installer{log: &fakeLogger{}}
+	err = i.Install(context.Background(), "test-app", "", pluginsDir, "./testdata/plugin-with-dependency.zip", "", repo.URL, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to install plugin test-dep")
+
+	// verify the plugins directory was restored to its prior state
+	files, err := ioutil.ReadDir(pluginsDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, "existing-app", files[0].Name())
+}
+
+func TestInstall_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+		// plugin metadata
+		if parts[0] == "repo" {
+			err := json.NewEncoder(w).Encode(Plugin{ID: parts[1], Versions: []Version{{Version: "1.0.0"}}})
+			require.NoError(t, err)
+			return
+		}
+
+		// cancel the install while the dependency archive is being downloaded
+		cancel()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(repo.Close)
+
+	pluginsDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "test-app.zip")
+	err := ioutil.WriteFile(archive, createPluginArchive(t, "test-app", "1.0.0", requireAll("1.0.0", "test-dep")), 0600)
+	require.NoError(t, err)
+
+	i := &Installer{log: &fakeLogger{}}
+	err = i.Install(ctx, "test-app", "", pluginsDir, archive, "", repo.URL, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// verify nothing was extracted into the plugins directory
+	files, err := ioutil.ReadDir(pluginsDir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestInstall_ConcurrentDependencies(t *testing.T) {
+	deps := []string{"dep-a", "dep-b", "dep-c", "dep-d", "dep-e", "dep-f"}
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		metadata := parts[0] == "repo"
+		pluginID := parts[0]
+		if metadata {
+			pluginID = parts[1]
+		}
+		if pluginID == "dep-broken" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// plugin metadata
+		if metadata {
+			err := json.NewEncoder(w).Encode(Plugin{ID: pluginID, Versions: []Version{{Version: "1.0.0"}}})
+			require.NoError(t, err)
+			return
+		}
+
+		// plugin archive
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		_, err := w.Write(createPluginArchive(t, pluginID, "1.0.0", nil))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	t.Run("Downloads dependencies with bounded concurrency", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		archive := filepath.Join(t.TempDir(), "test-app.zip")
+		err := ioutil.WriteFile(archive, createPluginArchive(t, "test-app", "1.0.0", requireAll("1.0.0", deps...)), 0600)
+		require.NoError(t, err)
+
+		i := &Installer{log: &fakeLogger{}, dependencyConcurrency: 2}
+		err = i.Install(context.Background(), "test-app", "", pluginsDir, archive, "", repo.URL, nil)
+		require.NoError(t, err)
+
+		for _, pluginID := range append([]string{"test-app"}, deps...) {
+			res, err := toPluginDTO(pluginsDir, pluginID)
+			require.NoError(t, err)
+			require.Equal(t, pluginID, res.ID)
+		}
+		require.LessOrEqual(t, maxInFlight, 2)
+		require.Greater(t, maxInFlight, 1)
+	})
+
+	t.Run("Failing dependency rolls back all other dependencies", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		archive := filepath.Join(t.TempDir(), "test-app.zip")
+		err := ioutil.WriteFile(archive, createPluginArchive(t, "test-app", "1.0.0", requireAll("1.0.0", append(deps, "dep-broken")...)), 0600)
+		require.NoError(t, err)
+
+		i := &Installer{log: &fakeLogger{}, dependencyConcurrency: 2}
+		err = i.Install(context.Background(), "test-app", "", pluginsDir, archive, "", repo.URL, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to install plugin dep-broken")
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
+}
+
+func TestNewWithOpts_DependencyConcurrency(t *testing.T) {
+	i := NewWithOpts(false, "9.0.0", &fakeLogger{}, Opts{DependencyConcurrency: 2}).(*Installer)
+	require.Equal(t, 2, i.dependencyConcurrency)
+
+	i = NewWithOpts(false, "9.0.0", &fakeLogger{}, Opts{}).(*Installer)
+	require.Equal(t, defaultDependencyConcurrency, i.dependencyConcurrency)
+}
+
+func TestInstall_Retry(t *testing.T) {
+	// newRepo returns a repository failing the first failures requests of each plugin metadata and archive with status
+	newRepo := func(t *testing.T, failures, status int) (*httptest.Server, map[string]int) {
+		var mu sync.Mutex
+		requests := make(map[string]int)
+		repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests[r.URL.Path]++
+			count := requests[r.URL.Path]
+			mu.Unlock()
+			if count <= failures {
+				w.WriteHeader(status)
+				return
+			}
+
+			parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+			if parts[0] == "repo" {
+				err := json.NewEncoder(w).Encode(Plugin{ID: parts[1], Versions: []Version{{Version: "1.0.0"}}})
+				require.NoError(t, err)
+				return
+			}
+			_, err := w.Write(createPluginArchive(t, parts[0], "1.0.0", nil))
+			require.NoError(t, err)
+		}))
+		t.Cleanup(repo.Close)
+		return repo, requests
+	}
+
+	t.Run("Retries requests failing transiently", func(t *testing.T) {
+		repo, requests := newRepo(t, 2, http.StatusServiceUnavailable)
+		pluginsDir := t.TempDir()
+
+		i := &Installer{log: &fakeLogger{}, retryAttempts: 3, retryBaseDelay: time.Millisecond}
+		err := i.Install(context.Background(), "test-app", "", pluginsDir, "", "", repo.URL, nil)
+		require.NoError(t, err)
+
+		res, err := toPluginDTO(pluginsDir, "test-app")
+		require.NoError(t, err)
+		require.Equal(t, "test-app", res.ID)
+		require.Equal(t, map[string]int{
+			"/repo/test-app":                    3,
+			"/test-app/versions/1.0.0/download": 3,
+		}, requests)
+	})
+
+	t.Run("Gives up after the last attempt", func(t *testing.T) {
+		repo, requests := newRepo(t, 3, http.StatusBadGateway)
+
+		i := &Installer{log: &fakeLogger{}, retryAttempts: 3, retryBaseDelay: time.Millisecond}
+		err := i.Install(context.Background(), "test-app", "", t.TempDir(), "", "", repo.URL, nil)
+		var statusErr ResponseStatusError
+		require.ErrorAs(t, err, &statusErr)
+		require.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+		require.Equal(t, map[string]int{"/repo/test-app": 3}, requests)
+	})
+
+	t.Run("Doesn't retry plugins missing in the repository", func(t *testing.T) {
+		repo, requests := newRepo(t, 1, http.StatusNotFound)
+
+		i := &Installer{log: &fakeLogger{}, retryAttempts: 3, retryBaseDelay: time.Millisecond}
+		err := i.Install(context.Background(), "test-app", "", t.TempDir(), "", "", repo.URL, nil)
+		var clientErr Response4xxError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, map[string]int{"/repo/test-app": 1}, requests)
+	})
+
+	t.Run("Stops retrying when cancelled", func(t *testing.T) {
+		repo, requests := newRepo(t, 1, http.StatusServiceUnavailable)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		i := &Installer{log: &fakeLogger{}, retryAttempts: 3, retryBaseDelay: time.Minute}
+		err := i.Install(ctx, "test-app", "", t.TempDir(), "", "", repo.URL, nil)
+		require.Error(t, err)
+		require.Equal(t, map[string]int{"/repo/test-app": 1}, requests)
+	})
+}
+
+func TestIsTransientError(t *testing.T) {
+	require.True(t, IsTransientError(ResponseStatusError{StatusCode: http.StatusServiceUnavailable}))
+	require.True(t, IsTransientError(Response4xxError{StatusCode: http.StatusTooManyRequests}))
+	require.True(t, IsTransientError(fmt.Errorf("failed to download plugin archive: %w", context.DeadlineExceeded)))
+	require.False(t, IsTransientError(Response4xxError{StatusCode: http.StatusNotFound}))
+	require.False(t, IsTransientError(ErrVersionUnsupported{PluginID: "test-app"}))
+	require.False(t, IsTransientError(plugins.ErrPluginChecksumMismatch))
+	require.False(t, IsTransientError(context.Canceled))
+}
+
+func TestInstall_Progress(t *testing.T) {
+	depArchive := createPluginArchive(t, "dep-a", "1.0.0", nil)
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// plugin metadata
+		if strings.Trim(r.URL.Path, "/") == "repo/dep-a" {
+			err := json.NewEncoder(w).Encode(Plugin{ID: "dep-a", Versions: []Version{{Version: "1.0.0"}}})
+			require.NoError(t, err)
+			return
+		}
+
+		_, err := w.Write(depArchive)
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	pluginArchive := createPluginArchive(t, "test-app", "1.0.0", requireAll("1.0.0", "dep-a"))
+	archive := filepath.Join(t.TempDir(), "test-app.zip")
+	err := ioutil.WriteFile(archive, pluginArchive, 0600)
+	require.NoError(t, err)
+
+	var events []plugins.InstallProgress
+	i := &Installer{log: &fakeLogger{}}
+	err = i.Install(context.Background(), "test-app", "", t.TempDir(), archive, "", repo.URL, func(progress plugins.InstallProgress) {
+		events = append(events, progress)
+	})
+	require.NoError(t, err)
+
+	// downloads report their progress any number of times, depending on how the archives are read
+	var phases []plugins.InstallProgress
+	downloaded := map[string]int64{}
+	for _, event := range events {
+		if event.Phase == plugins.InstallPhaseDownloading {
+			downloaded[event.PluginID] = event.BytesDownloaded
+		}
+		event.BytesDownloaded = 0
+		if len(phases) == 0 || phases[len(phases)-1] != event {
+			phases = append(phases, event)
+		}
+	}
+
+	require.Equal(t, []plugins.InstallProgress{
+		{Phase: plugins.InstallPhaseResolving, PluginID: "test-app"},
+		{Phase: plugins.InstallPhaseDownloading, PluginID: "test-app"},
+		{Phase: plugins.InstallPhaseResolving, PluginID: "dep-a", Dependency: true},
+		{Phase: plugins.InstallPhaseDownloading, PluginID: "dep-a", Dependency: true},
+		{Phase: plugins.InstallPhaseExtracting, PluginID: "dep-a", Dependency: true},
+		{Phase: plugins.InstallPhaseExtracting, PluginID: "test-app"},
+	}, phases)
+	require.Equal(t, map[string]int64{
+		"test-app": int64(len(pluginArchive)),
+		"dep-a":    int64(len(depArchive)),
+	}, downloaded)
+}
+
+func TestInstall_DependencyVersionConflict(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		metadata := parts[0] == "repo"
+		if metadata {
+			parts = parts[1:]
+		}
+		if parts[0] != "shared-dep" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// plugin metadata
+		if metadata {
+			err := json.NewEncoder(w).Encode(Plugin{ID: "shared-dep", Versions: []Version{{Version: "2.0.0"}}})
+			require.NoError(t, err)
+			return
+		}
+
+		_, err := w.Write(createPluginArchive(t, "shared-dep", "2.0.0", nil))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	installPlugin := func(t *testing.T, pluginsDir, pluginID, version string, deps map[string]string) {
+		t.Helper()
+		err := os.MkdirAll(filepath.Join(pluginsDir, pluginID), os.ModePerm)
+		require.NoError(t, err)
+		pluginJSON := InstalledPlugin{ID: pluginID, Info: PluginInfo{Version: version}}
+		for dep, depVersion := range deps {
+			pluginJSON.Dependencies.Plugins = append(pluginJSON.Dependencies.Plugins, PluginDependency{ID: dep, Version: depVersion})
+		}
+		data, err := json.Marshal(pluginJSON)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(pluginsDir, pluginID, "plugin.json"), data, 0600)
+		require.NoError(t, err)
+	}
+
+	t.Run("Fails before extracting when requirements of a shared dependency conflict", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		installPlugin(t, pluginsDir, "plugin-a", "1.0.0", map[string]string{"shared-dep": "^1.0.0"})
+		installPlugin(t, pluginsDir, "shared-dep", "1.0.0", nil)
+
+		archive := filepath.Join(t.TempDir(), "plugin-b.zip")
+		err := ioutil.WriteFile(archive, createPluginArchive(t, "plugin-b", "1.0.0", map[string]string{"shared-dep": "^2.0.0"}), 0600)
+		require.NoError(t, err)
+
+		i := &Installer{log: &fakeLogger{}}
+		err = i.Install(context.Background(), "plugin-b", "", pluginsDir, archive, "", repo.URL, nil)
+		require.Equal(t, plugins.ErrDependencyVersionConflict{
+			DependencyID: "shared-dep",
+			Version:      "2.0.0",
+			Requirements: []plugins.DependencyRequirement{
+				{PluginID: "plugin-a", Version: "^1.0.0"},
+				{PluginID: "plugin-b", Version: "^2.0.0"},
+			},
+		}, err)
+		require.EqualError(t, err, "conflicting requirements for shared-dep v2.0.0: plugin-a requires ^1.0.0, plugin-b requires ^2.0.0")
+
+		// nothing was written to the plugins directory
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		res, err := toPluginDTO(pluginsDir, "shared-dep")
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0", res.Info.Version)
+	})
+
+	t.Run("Installs when requirements of a shared dependency are compatible", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		installPlugin(t, pluginsDir, "plugin-a", "1.0.0", map[string]string{"shared-dep": ">=1.0.0"})
+		installPlugin(t, pluginsDir, "shared-dep", "1.0.0", nil)
+
+		archive := filepath.Join(t.TempDir(), "plugin-b.zip")
+		err := ioutil.WriteFile(archive, createPluginArchive(t, "plugin-b", "1.0.0", map[string]string{"shared-dep": "2.0.0"}), 0600)
+		require.NoError(t, err)
+
+		i := &Installer{log: &fakeLogger{}}
+		err = i.Install(context.Background(), "plugin-b", "", pluginsDir, archive, "", repo.URL, nil)
+		require.NoError(t, err)
+
+		res, err := toPluginDTO(pluginsDir, "shared-dep")
+		require.NoError(t, err)
+		require.Equal(t, "2.0.0", res.Info.Version)
+	})
+}
+
+func TestInstall_DependencyVersionRange(t *testing.T) {
+	var downloaded []string
+	var mu sync.Mutex
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if parts[0] == "repo" {
+			err := json.NewEncoder(w).Encode(Plugin{ID: "test-dep", Versions: []Version{
+				{Version: "2.0.0"}, {Version: "1.4.1"}, {Version: "1.2.0"}, {Version: "1.1.0"},
+			}})
+			require.NoError(t, err)
+			return
+		}
+
+		// /test-dep/versions/<version>/download
+		mu.Lock()
+		downloaded = append(downloaded, parts[2])
+		mu.Unlock()
+		_, err := w.Write(createPluginArchive(t, "test-dep", parts[2], nil))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	pluginsDir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "test-app.zip")
+	err := ioutil.WriteFile(archive, createPluginArchive(t, "test-app", "1.0.0", requireAll("^1.2.0", "test-dep")), 0600)
+	require.NoError(t, err)
+
+	i := &Installer{log: &fakeLogger{}}
+	err = i.Install(context.Background(), "test-app", "", pluginsDir, archive, "", repo.URL, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.4.1"}, downloaded)
+
+	res, err := toPluginDTO(pluginsDir, "test-dep")
+	require.NoError(t, err)
+	require.Equal(t, "1.4.1", res.Info.Version)
+
+	t.Run("Plans the highest version satisfying the range", func(t *testing.T) {
+		planned, err := i.Plan(context.Background(), "test-app", "", archive, "", repo.URL)
+		require.NoError(t, err)
+		require.Len(t, planned, 2)
+		require.Equal(t, "test-dep", planned[1].PluginID)
+		require.Equal(t, "1.4.1", planned[1].Version)
+	})
+}
+
+func TestInstall_OptionalDependency(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		metadata := parts[0] == "repo"
+		if metadata {
+			parts = parts[1:]
+		}
+		// the optional dependency isn't published
+		if parts[0] != "required-dep" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if metadata {
+			err := json.NewEncoder(w).Encode(Plugin{ID: "required-dep", Versions: []Version{{Version: "1.0.0"}}})
+			require.NoError(t, err)
+			return
+		}
+
+		_, err := w.Write(createPluginArchive(t, "required-dep", "1.0.0", nil))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	archive := filepath.Join(t.TempDir(), "test-app.zip")
+	err := ioutil.WriteFile(archive, createPluginArchiveWithDependencies(t, "test-app", "1.0.0", []PluginDependency{
+		{ID: "required-dep", Version: "1.0.0"},
+		{ID: "optional-dep", Version: "1.0.0", Optional: true},
+	}), 0600)
+	require.NoError(t, err)
+
+	i := &Installer{log: &fakeLogger{}}
+
+	t.Run("Installs the plugin without the optional dependency that can't be downloaded", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		err := i.Install(context.Background(), "test-app", "", pluginsDir, archive, "", repo.URL, nil)
+		require.NoError(t, err)
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		require.Equal(t, "required-dep", files[0].Name())
+		require.Equal(t, "test-app", files[1].Name())
+	})
+
+	t.Run("Plans the plugin without the optional dependency that can't be resolved", func(t *testing.T) {
+		planned, err := i.Plan(context.Background(), "test-app", "", archive, "", repo.URL)
+		require.NoError(t, err)
+		require.Len(t, planned, 2)
+		require.Equal(t, "test-app", planned[0].PluginID)
+		require.Equal(t, "required-dep", planned[1].PluginID)
+	})
+
+	t.Run("Fails if a required dependency can't be downloaded", func(t *testing.T) {
+		archive := filepath.Join(t.TempDir(), "test-app.zip")
+		err := ioutil.WriteFile(archive, createPluginArchiveWithDependencies(t, "test-app", "1.0.0", []PluginDependency{
+			{ID: "optional-dep", Version: "1.0.0"},
+		}), 0600)
+		require.NoError(t, err)
+
+		err = i.Install(context.Background(), "test-app", "", t.TempDir(), archive, "", repo.URL, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to install plugin optional-dep")
+	})
+}
+
+func TestInstall_InstallPolicy(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		metadata := parts[0] == "repo"
+		if metadata {
+			parts = parts[1:]
+		}
+		mu.Lock()
+		requested = append(requested, parts[0])
+		mu.Unlock()
+
+		if metadata {
+			err := json.NewEncoder(w).Encode(Plugin{ID: parts[0], Versions: []Version{{Version: "1.0.0"}}})
+			require.NoError(t, err)
+			return
+		}
+
+		_, err := w.Write(createPluginArchive(t, parts[0], "1.0.0", nil))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	writeArchive := func(t *testing.T, deps []PluginDependency) string {
+		archive := filepath.Join(t.TempDir(), "test-app.zip")
+		err := ioutil.WriteFile(archive, createPluginArchiveWithDependencies(t, "test-app", "1.0.0", deps), 0600)
+		require.NoError(t, err)
+		return archive
+	}
+
+	i := &Installer{log: &fakeLogger{}, installAllowed: func(pluginID string) error {
+		if pluginID == "denied-dep" {
+			return fmt.Errorf("%w: %s is denied", plugins.ErrPluginNotAllowed, pluginID)
+		}
+		return nil
+	}}
+
+	t.Run("Fails before downloading a dependency of an allowed plugin that isn't allowed", func(t *testing.T) {
+		mu.Lock()
+		requested = nil
+		mu.Unlock()
+		archive := writeArchive(t, []PluginDependency{
+			{ID: "allowed-dep", Version: "1.0.0"},
+			{ID: "denied-dep", Version: "1.0.0"},
+		})
+
+		pluginsDir := t.TempDir()
+		err := i.Install(context.Background(), "test-app", "", pluginsDir, archive, "", repo.URL, nil)
+		require.ErrorIs(t, err, plugins.ErrPluginNotAllowed)
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotContains(t, requested, "denied-dep")
+	})
+
+	t.Run("Doesn't plan a dependency that isn't allowed", func(t *testing.T) {
+		archive := writeArchive(t, []PluginDependency{{ID: "denied-dep", Version: "1.0.0"}})
+
+		_, err := i.Plan(context.Background(), "test-app", "", archive, "", repo.URL)
+		require.ErrorIs(t, err, plugins.ErrPluginNotAllowed)
+	})
+
+	t.Run("Installs the plugin without an optional dependency that isn't allowed", func(t *testing.T) {
+		archive := writeArchive(t, []PluginDependency{{ID: "denied-dep", Version: "1.0.0", Optional: true}})
+
+		pluginsDir := t.TempDir()
+		err := i.Install(context.Background(), "test-app", "", pluginsDir, archive, "", repo.URL, nil)
+		require.NoError(t, err)
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		require.Equal(t, "test-app", files[0].Name())
+	})
+}
+
+func TestInstall_Checksum(t *testing.T) {
+	archive := createPluginArchive(t, "test-app", "1.0.0", nil)
+	checksum := fmt.Sprintf("%x", sha256.Sum256(archive))
+
+	newRepo := func(t *testing.T, publishedChecksum string) *httptest.Server {
+		repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// plugin metadata
+			if strings.Trim(r.URL.Path, "/") == "repo/test-app" {
+				err := json.NewEncoder(w).Encode(Plugin{ID: "test-app", Versions: []Version{{
+					Version: "1.0.0",
+					Arch:    map[string]ArchMeta{"any": {SHA256: publishedChecksum}},
+				}}})
+				require.NoError(t, err)
+				return
+			}
+
+			// plugin archive
+			_, err := w.Write(archive)
+			require.NoError(t, err)
+		}))
+		t.Cleanup(repo.Close)
+		return repo
+	}
+
+	t.Run("Installs plugin with checksum matching the one published by the repository", func(t *testing.T) {
+		repo := newRepo(t, checksum)
+		pluginsDir := t.TempDir()
+
+		i := &Installer{log: &fakeLogger{}}
+		err := i.Install(context.Background(), "test-app", "", pluginsDir, "", "", repo.URL, nil)
+		require.NoError(t, err)
+
+		_, err = toPluginDTO(pluginsDir, "test-app")
+		require.NoError(t, err)
+	})
+
+	t.Run("Fails to install plugin with checksum not matching the one published by the repository", func(t *testing.T) {
+		repo := newRepo(t, strings.Repeat("0", 64))
+		pluginsDir := t.TempDir()
+
+		i := &Installer{log: &fakeLogger{}}
+		err := i.Install(context.Background(), "test-app", "", pluginsDir, "", "", repo.URL, nil)
+		require.ErrorIs(t, err, plugins.ErrPluginChecksumMismatch)
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
+
+	t.Run("Verifies archives installed from a plugin zip URL against the provided checksum", func(t *testing.T) {
+		repo := newRepo(t, "")
+
+		i := &Installer{log: &fakeLogger{}}
+		err := i.Install(context.Background(), "test-app", "", t.TempDir(), repo.URL+"/test-app.zip", checksum, repo.URL, nil)
+		require.NoError(t, err)
+
+		pluginsDir := t.TempDir()
+		err = i.Install(context.Background(), "test-app", "", pluginsDir, repo.URL+"/test-app.zip", strings.Repeat("0", 64), repo.URL, nil)
+		require.ErrorIs(t, err, plugins.ErrPluginChecksumMismatch)
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
+
+	t.Run("Verifies local archives against the provided checksum", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "test-app.zip")
+		err := ioutil.WriteFile(archivePath, archive, 0600)
+		require.NoError(t, err)
+
+		i := &Installer{log: &fakeLogger{}}
+		_, err = i.Plan(context.Background(), "test-app", "", archivePath, checksum, "")
+		require.NoError(t, err)
+
+		_, err = i.Plan(context.Background(), "test-app", "", archivePath, strings.Repeat("0", 64), "")
+		require.ErrorIs(t, err, plugins.ErrPluginChecksumMismatch)
+	})
+}
+
+func TestInstall_Bundle(t *testing.T) {
+	bundleDir := t.TempDir()
+	writeArchive := func(t *testing.T, name string, data []byte) {
+		t.Helper()
+		err := ioutil.WriteFile(filepath.Join(bundleDir, name), data, 0600)
+		require.NoError(t, err)
+	}
+	writeArchive(t, "test-app.zip", createPluginArchive(t, "test-app", "1.0.0", map[string]string{"dep-a": "^1.0.0"}))
+	writeArchive(t, "dep-a-1.0.0.zip", createPluginArchive(t, "dep-a", "1.0.0", nil))
+	writeArchive(t, "dep-a-2.0.0.zip", createPluginArchive(t, "dep-a", "2.0.0", nil))
+	writeArchive(t, "missing-dep-app.zip", createPluginArchive(t, "missing-dep-app", "1.0.0", map[string]string{"dep-b": "1.2.0"}))
+	writeArchive(t, "README.md", []byte("not a plugin"))
+
+	var repoRequests []string
+	var mu sync.Mutex
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		repoRequests = append(repoRequests, r.URL.Path)
+		mu.Unlock()
+
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		// plugin metadata
+		if parts[0] == "repo" {
+			err := json.NewEncoder(w).Encode(Plugin{ID: parts[1], Versions: []Version{{Version: "1.2.0"}}})
+			require.NoError(t, err)
+			return
+		}
+
+		_, err := w.Write(createPluginArchive(t, parts[0], "1.2.0", nil))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	t.Run("Installs plugin and dependencies from the bundle without contacting the repository", func(t *testing.T) {
+		repoRequests = nil
+		pluginsDir := t.TempDir()
+
+		i := &Installer{log: &fakeLogger{}, bundleDir: bundleDir}
+		err := i.Install(context.Background(), "test-app", "", pluginsDir, "", "", repo.URL, nil)
+		require.NoError(t, err)
+		require.Empty(t, repoRequests)
+
+		res, err := toPluginDTO(pluginsDir, "test-app")
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0", res.Info.Version)
+		res, err = toPluginDTO(pluginsDir, "dep-a")
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0", res.Info.Version)
+	})
+
+	t.Run("Installs latest bundled version if no version is requested", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+
+		i := &Installer{log: &fakeLogger{}, bundleDir: bundleDir}
+		err := i.Install(context.Background(), "dep-a", "", pluginsDir, "", "", repo.URL, nil)
+		require.NoError(t, err)
+
+		res, err := toPluginDTO(pluginsDir, "dep-a")
+		require.NoError(t, err)
+		require.Equal(t, "2.0.0", res.Info.Version)
+	})
+
+	t.Run("Plans archives from the bundle", func(t *testing.T) {
+		i := &Installer{log: &fakeLogger{}, bundleDir: bundleDir}
+		archives, err := i.Plan(context.Background(), "test-app", "1.0.0", "", "", repo.URL)
+		require.NoError(t, err)
+		require.Equal(t, []plugins.PluginArchiveInfo{
+			{PluginID: "test-app", Version: "1.0.0", PluginZipURL: filepath.Join(bundleDir, "test-app.zip")},
+			{PluginID: "dep-a", Version: "1.0.0", PluginZipURL: filepath.Join(bundleDir, "dep-a-1.0.0.zip")},
+		}, archives)
+	})
+
+	t.Run("Names the dependency missing in the bundle", func(t *testing.T) {
+		repoRequests = nil
+		pluginsDir := t.TempDir()
+
+		i := &Installer{log: &fakeLogger{}, bundleDir: bundleDir}
+		err := i.Install(context.Background(), "missing-dep-app", "", pluginsDir, "", "", repo.URL, nil)
+		var bundleErr ErrNotInBundle
+		require.ErrorAs(t, err, &bundleErr)
+		require.Equal(t, ErrNotInBundle{PluginID: "dep-b", RequestedVersion: "1.2.0", BundleDir: bundleDir}, bundleErr)
+		require.Contains(t, err.Error(), "dep-b v1.2.0 is not available in plugin bundle")
+		require.Empty(t, repoRequests)
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
+
+	t.Run("Fails if the requested version isn't bundled", func(t *testing.T) {
+		i := &Installer{log: &fakeLogger{}, bundleDir: bundleDir}
+		err := i.Install(context.Background(), "dep-a", "3.0.0", t.TempDir(), "", "", repo.URL, nil)
+		require.Equal(t, ErrNotInBundle{PluginID: "dep-a", RequestedVersion: "3.0.0", BundleDir: bundleDir}, err)
+	})
+
+	t.Run("Falls back to the repository for plugins missing in the bundle if allowed", func(t *testing.T) {
+		repoRequests = nil
+		pluginsDir := t.TempDir()
+
+		i := &Installer{log: &fakeLogger{}, bundleDir: bundleDir, bundleRepoFallback: true}
+		err := i.Install(context.Background(), "missing-dep-app", "", pluginsDir, "", "", repo.URL, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"/repo/dep-b", "/dep-b/versions/1.2.0/download"}, repoRequests)
+
+		res, err := toPluginDTO(pluginsDir, "dep-b")
+		require.NoError(t, err)
+		require.Equal(t, "1.2.0", res.Info.Version)
+	})
+}
+
+// createPluginArchive returns a zip archive containing a plugin.json for pluginID
+// that depends on the given plugins, mapped to their required version.
+func createPluginArchive(t *testing.T, pluginID, version string, dependencies map[string]string) []byte {
+	t.Helper()
+
+	var deps []PluginDependency
+	for dep, depVersion := range dependencies {
+		deps = append(deps, PluginDependency{ID: dep, Version: depVersion})
+	}
+	return createPluginArchiveWithDependencies(t, pluginID, version, deps)
+}
+
+func createPluginArchiveWithDependencies(t *testing.T, pluginID, version string, dependencies []PluginDependency) []byte {
+	t.Helper()
+
+	pluginJSON := InstalledPlugin{ID: pluginID, Info: PluginInfo{Version: version}}
+	pluginJSON.Dependencies.Plugins = dependencies
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(pluginID + "/plugin.json")
+	require.NoError(t, err)
+	err = json.NewEncoder(f).Encode(pluginJSON)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+// requireAll maps every plugin ID to the same required version
+func requireAll(version string, pluginIDs ...string) map[string]string {
+	deps := map[string]string{}
+	for _, pluginID := range pluginIDs {
+		deps[pluginID] = version
+	}
+	return deps
+}
+
+func TestInstallFromFile(t *testing.T) {
+	i := &Installer{log: &fakeLogger{}}
+
+	t.Run("Installs plugin from local archive", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		err := i.InstallFromFile(context.Background(), "test-app", "./testdata/plugin-with-symlinks.zip", pluginsDir)
+		require.NoError(t, err)
+
+		res, err := toPluginDTO(pluginsDir, "test-app")
+		require.NoError(t, err)
+		require.Equal(t, "2.0.0", res.Info.Version)
+	})
+
+	t.Run("Rejects archive containing a different plugin", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		err := i.InstallFromFile(context.Background(), "other-app", "./testdata/plugin-with-symlinks.zip", pluginsDir)
+		require.Equal(t, ErrPluginIDMismatch{RequestedPluginID: "other-app", ArchivePluginID: "test-app"}, err)
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
+
+	t.Run("Names the dependency that can't be resolved offline", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		err := i.InstallFromFile(context.Background(), "test-app", "./testdata/plugin-with-dependency.zip", pluginsDir)
+		require.Equal(t, ErrDependencyUnavailable{PluginID: "test-app", DependencyID: "test-dep", DependencyVersion: "1.0.0"}, err)
+		require.EqualError(t, err, "dependency test-dep v1.0.0 of test-app is not installed and cannot be resolved offline")
+
+		files, err := ioutil.ReadDir(pluginsDir)
+		require.NoError(t, err)
+		require.Empty(t, files)
+	})
+
+	t.Run("Installs plugin when dependencies are already installed", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		err := os.MkdirAll(filepath.Join(pluginsDir, "test-dep"), os.ModePerm)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(pluginsDir, "test-dep", "plugin.json"), []byte(`{"id": "test-dep", "info": {"version": "1.2.0"}}`), 0600)
+		require.NoError(t, err)
+
+		err = i.InstallFromFile(context.Background(), "test-app", "./testdata/plugin-with-dependency.zip", pluginsDir)
+		require.NoError(t, err)
+
+		res, err := toPluginDTO(pluginsDir, "test-app")
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0", res.Info.Version)
+	})
+
+	t.Run("Rejects dependency installed in an incompatible version", func(t *testing.T) {
+		pluginsDir := t.TempDir()
+		err := os.MkdirAll(filepath.Join(pluginsDir, "test-dep"), os.ModePerm)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(pluginsDir, "test-dep", "plugin.json"), []byte(`{"id": "test-dep", "info": {"version": "2.0.0"}}`), 0600)
+		require.NoError(t, err)
+
+		err = i.InstallFromFile(context.Background(), "test-app", "./testdata/plugin-with-dependency.zip", pluginsDir)
+		require.Equal(t, plugins.ErrDependencyVersionConflict{
+			DependencyID: "test-dep",
+			Version:      "2.0.0",
+			Requirements: []plugins.DependencyRequirement{{PluginID: "test-app", Version: "1.0.0"}},
+		}, err)
+
+		_, err = toPluginDTO(pluginsDir, "test-app")
+		require.Error(t, err)
+	})
+}
+
+func TestPlan(t *testing.T) {
+	i := &Installer{log: &fakeLogger{}}
+	archives, err := i.Plan(context.Background(), "test-app", "", "./testdata/plugin-with-symlinks.zip", "", "")
+	require.NoError(t, err)
+	require.Equal(t, []plugins.PluginArchiveInfo{
+		{PluginID: "test-app", Version: "2.0.0", PluginZipURL: "./testdata/plugin-with-symlinks.zip"},
+	}, archives)
+}
+
 func TestUninstall(t *testing.T) {
 	i := &Installer{log: &fakeLogger{}}
 
@@ -221,6 +1106,138 @@ func TestSelectVersion(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "1.0.0", ver.Version)
 	})
+
+	t.Run("Should return highest version satisfying requested range", func(t *testing.T) {
+		plugin := createPlugin(
+			versionArg{version: "1.2.0"},
+			versionArg{version: "2.0.0"},
+			versionArg{version: "1.10.0"},
+			versionArg{version: "1.1.0"},
+		)
+		ver, err := i.selectVersion(plugin, "^1.2.0")
+		require.NoError(t, err)
+		require.Equal(t, "1.10.0", ver.Version)
+
+		ver, err = i.selectVersion(plugin, ">=1.0.0, <1.5.0")
+		require.NoError(t, err)
+		require.Equal(t, "1.2.0", ver.Version)
+	})
+
+	t.Run("Should skip versions in requested range that don't support current arch", func(t *testing.T) {
+		ver, err := i.selectVersion(createPlugin(
+			versionArg{version: "1.3.0", arch: []string{"non-existent"}},
+			versionArg{version: "1.2.0"},
+		), "^1.2.0")
+		require.NoError(t, err)
+		require.Equal(t, "1.2.0", ver.Version)
+
+		_, err = i.selectVersion(createPlugin(versionArg{version: "1.3.0", arch: []string{"non-existent"}}), "^1.2.0")
+		require.ErrorAs(t, err, &ErrVersionUnsupported{})
+	})
+
+	t.Run("Should return error when no version satisfies requested range", func(t *testing.T) {
+		_, err := i.selectVersion(createPlugin(versionArg{version: "2.0.0"}, versionArg{version: "1.1.0"}), "^1.2.0")
+		require.ErrorAs(t, err, &ErrVersionNotFound{})
+	})
+}
+
+func TestIsSemVerExpr(t *testing.T) {
+	for version, expected := range map[string]bool{
+		"":                false,
+		"1.2.0":           false,
+		"v1.2.0":          false,
+		"^1.2.0":          true,
+		"~1.2.0":          true,
+		">=1.0.0, <2.0.0": true,
+		"1.x":             true,
+		"not a version":   false,
+	} {
+		require.Equal(t, expected, isSemVerExpr(version), version)
+	}
+}
+
+func TestGetPluginArchiveByGitRef(t *testing.T) {
+	setup := func(t *testing.T, repoURL string) string {
+		t.Helper()
+		repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := json.NewEncoder(w).Encode(Plugin{ID: "test-app", Versions: []Version{
+				{Version: "1.1.0"},
+				{Version: "1.0.0", URL: repoURL},
+			}})
+			require.NoError(t, err)
+		}))
+		t.Cleanup(repo.Close)
+
+		return repo.URL
+	}
+
+	t.Run("Resolves the GitHub archive of the git ref", func(t *testing.T) {
+		pluginRepoURL := setup(t, "https://github.com/grafana/test-app.git")
+
+		i := &Installer{log: &fakeLogger{}}
+		archive, err := i.GetPluginArchiveByGitRef(context.Background(), "test-app", "feature/x", pluginRepoURL)
+		require.NoError(t, err)
+		require.Equal(t, plugins.PluginArchiveInfo{
+			PluginID:     "test-app",
+			Version:      "feature/x",
+			PluginZipURL: "https://github.com/grafana/test-app/archive/feature%2Fx.zip",
+		}, archive)
+	})
+
+	t.Run("Fails for plugins without a GitHub repository", func(t *testing.T) {
+		pluginRepoURL := setup(t, "https://gitlab.com/grafana/test-app")
+
+		i := &Installer{log: &fakeLogger{}}
+		_, err := i.GetPluginArchiveByGitRef(context.Background(), "test-app", "main", pluginRepoURL)
+		require.ErrorIs(t, err, ErrGitRefUnsupported{PluginID: "test-app", RepositoryURL: "https://gitlab.com/grafana/test-app"})
+	})
+}
+
+func TestGetReleaseNotes(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/repo/test-app", r.URL.Path)
+		err := json.NewEncoder(w).Encode(Plugin{ID: "test-app", Versions: []Version{
+			{Version: "1.1.0", Changelog: "Fixes a crash"},
+			{Version: "1.0.0"},
+		}})
+		require.NoError(t, err)
+	}))
+	t.Cleanup(repo.Close)
+
+	i := &Installer{log: &fakeLogger{}}
+	notes, err := i.GetReleaseNotes(context.Background(), "test-app", repo.URL)
+	require.NoError(t, err)
+	require.Equal(t, []plugins.ReleaseNote{
+		{Version: "1.1.0", Notes: "Fixes a crash"},
+		{Version: "1.0.0", Notes: ""},
+	}, notes)
+}
+
+func TestGitRefArchiveURL(t *testing.T) {
+	tcs := []struct {
+		repoURL string
+		gitRef  string
+		expURL  string
+		expErr  bool
+	}{
+		{repoURL: "https://github.com/grafana/test-app", gitRef: "main", expURL: "https://github.com/grafana/test-app/archive/main.zip"},
+		{repoURL: "https://github.com/grafana/test-app/", gitRef: "v1.0.0", expURL: "https://github.com/grafana/test-app/archive/v1.0.0.zip"},
+		{repoURL: "https://github.com/grafana/test-app.git", gitRef: "a1b2c3d", expURL: "https://github.com/grafana/test-app/archive/a1b2c3d.zip"},
+		{repoURL: "", gitRef: "main", expErr: true},
+		{repoURL: "http://github.com/grafana/test-app", gitRef: "main", expErr: true},
+		{repoURL: "https://gitlab.com/grafana/test-app", gitRef: "main", expErr: true},
+		{repoURL: "https://github.com/grafana", gitRef: "main", expErr: true},
+	}
+
+	for _, tc := range tcs {
+		url, err := gitRefArchiveURL(tc.repoURL, tc.gitRef)
+		if tc.expErr {
+			require.Error(t, err, tc.repoURL)
+			continue
+		}
+		require.NoError(t, err, tc.repoURL)
+		require.Equal(t, tc.expURL, url)
+	}
 }
 
 func TestRemoveGitBuildFromName(t *testing.T) {
@@ -356,3 +1373,90 @@ func (f *fakeLogger) Warn(_ ...interface{})               {}
 func (f *fakeLogger) Warnf(_ string, _ ...interface{})    {}
 func (f *fakeLogger) Error(_ ...interface{})              {}
 func (f *fakeLogger) Errorf(_ string, _ ...interface{})   {}
+
+func TestPrivateRepoHeaders(t *testing.T) {
+	// newRepo returns a repository serving test-app, recording the Authorization header of each request by path.
+	// Archives are redirected to redirectURL, if set.
+	newRepo := func(t *testing.T, redirectURL string) (*httptest.Server, map[string]string) {
+		var mu sync.Mutex
+		auth := make(map[string]string)
+		repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			auth[r.URL.Path] = r.Header.Get("Authorization")
+			mu.Unlock()
+
+			if r.URL.Path == "/repo/test-app" {
+				err := json.NewEncoder(w).Encode(Plugin{ID: "test-app", Versions: []Version{{Version: "1.0.0"}}})
+				require.NoError(t, err)
+				return
+			}
+			if redirectURL != "" {
+				http.Redirect(w, r, redirectURL, http.StatusFound)
+				return
+			}
+			_, err := w.Write(createPluginArchive(t, "test-app", "1.0.0", nil))
+			require.NoError(t, err)
+		}))
+		t.Cleanup(repo.Close)
+		return repo, auth
+	}
+
+	newInstaller := func(t *testing.T, host string) *Installer {
+		t.Helper()
+		return NewWithOpts(false, "9.0.0", &fakeLogger{}, Opts{
+			RetryBaseDelay: time.Millisecond,
+			PrivateRepo: PrivateRepoOpts{
+				Host:    host,
+				Headers: map[string]string{"Authorization": "Bearer secret"},
+			},
+		}).(*Installer)
+	}
+
+	t.Run("Sends the headers to the private repository", func(t *testing.T) {
+		private, auth := newRepo(t, "")
+		i := newInstaller(t, private.Listener.Addr().String())
+
+		err := i.Install(context.Background(), "test-app", "", t.TempDir(), "", "", private.URL, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"/repo/test-app":                    "Bearer secret",
+			"/test-app/versions/1.0.0/download": "Bearer secret",
+		}, auth)
+	})
+
+	t.Run("Doesn't send the headers to other repositories", func(t *testing.T) {
+		private, _ := newRepo(t, "")
+		public, auth := newRepo(t, "")
+		i := newInstaller(t, private.Listener.Addr().String())
+
+		err := i.Install(context.Background(), "test-app", "", t.TempDir(), "", "", public.URL, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"/repo/test-app":                    "",
+			"/test-app/versions/1.0.0/download": "",
+		}, auth)
+	})
+
+	t.Run("Doesn't send the headers to hosts the private repository redirects to", func(t *testing.T) {
+		public, publicAuth := newRepo(t, "")
+		private, privateAuth := newRepo(t, public.URL+"/test-app.zip")
+		i := newInstaller(t, private.Listener.Addr().String())
+
+		err := i.Install(context.Background(), "test-app", "", t.TempDir(), "", "", private.URL, nil)
+		require.NoError(t, err)
+		require.Equal(t, "Bearer secret", privateAuth["/test-app/versions/1.0.0/download"])
+		require.Equal(t, map[string]string{"/test-app.zip": ""}, publicAuth)
+	})
+
+	t.Run("Never sends the headers to the public plugin repository", func(t *testing.T) {
+		i := newInstaller(t, "Grafana.com")
+		require.IsType(t, &http.Transport{}, i.httpClient.Transport)
+		require.IsType(t, &http.Transport{}, i.httpClientNoTimeout.Transport)
+	})
+
+	t.Run("Doesn't format the header values", func(t *testing.T) {
+		opts := PrivateRepoOpts{Host: "plugins.example.com", Headers: map[string]string{"Authorization": "Bearer secret"}}
+		require.NotContains(t, fmt.Sprintf("%v", opts), "secret")
+		require.NotContains(t, fmt.Sprintf("%+v", opts), "secret")
+	})
+}
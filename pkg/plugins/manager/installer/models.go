@@ -18,6 +18,8 @@ type PluginDependency struct {
 	Type    string `json:"type"`
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// Optional dependencies are skipped if they can't be downloaded, instead of failing the install
+	Optional bool `json:"optional"`
 }
 
 type PluginInfo struct {
@@ -36,6 +38,8 @@ type Version struct {
 	URL     string              `json:"url"`
 	Version string              `json:"version"`
 	Arch    map[string]ArchMeta `json:"arch"`
+	// Changelog are the release notes published for the version, if any
+	Changelog string `json:"changelog"`
 }
 
 type ArchMeta struct {
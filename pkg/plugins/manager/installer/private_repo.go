@@ -0,0 +1,79 @@
+package installer
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// publicRepoHost is the host of the public plugin repository, which is never sent the headers of a private one
+const publicRepoHost = "grafana.com"
+
+// PrivateRepoOpts configure the headers, such as Authorization, sent with requests to a private plugin
+// repository. They're only sent to its host, neither to the public plugin repository nor to hosts the
+// private one redirects to.
+type PrivateRepoOpts struct {
+	// Host is the host of the private plugin repository, including the port if it's not the default one
+	// of the scheme, such as plugins.example.com:8443
+	Host    string
+	Headers map[string]string
+}
+
+// String doesn't include the values of the headers, as they commonly hold credentials
+func (o PrivateRepoOpts) String() string {
+	names := make([]string, 0, len(o.Headers))
+	for name := range o.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("{Host:%s Headers:%v}", o.Host, names)
+}
+
+// privateRepoTransport adds the headers of a private plugin repository to the requests to its host. They're
+// added to each request as it's sent rather than when it's created, so the requests of redirects to other
+// hosts don't carry them.
+type privateRepoTransport struct {
+	host    string
+	headers http.Header
+	next    http.RoundTripper
+}
+
+func (t *privateRepoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.EqualFold(req.URL.Host, t.host) {
+		return t.next.RoundTrip(req)
+	}
+
+	// round trippers must not modify the request they're passed
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.next.RoundTrip(req)
+}
+
+// isPublicRepo reports whether the private plugin repository is configured to be the public one
+func (o PrivateRepoOpts) isPublicRepo() bool {
+	return strings.EqualFold(o.Host, publicRepoHost)
+}
+
+// withPrivateRepoHeaders returns client sending the headers of the private plugin repository configured with
+// opts. The client is returned as is if none is configured.
+func withPrivateRepoHeaders(client http.Client, opts PrivateRepoOpts) http.Client {
+	if opts.Host == "" || len(opts.Headers) == 0 || opts.isPublicRepo() {
+		return client
+	}
+
+	headers := make(http.Header, len(opts.Headers))
+	for name, value := range opts.Headers {
+		headers.Set(name, value)
+	}
+
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &privateRepoTransport{host: opts.Host, headers: headers, next: next}
+
+	return client
+}
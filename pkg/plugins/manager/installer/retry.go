@@ -0,0 +1,60 @@
+package installer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = time.Second
+)
+
+// IsTransientError reports whether the plugin repository failed in a way that's worth retrying, such as
+// server errors, rate limiting and network failures. Plugins that don't exist or don't support this system
+// are not transient failures.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr ResponseStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var clientErr Response4xxError
+	if errors.As(err, &clientErr) {
+		return clientErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry calls fn until it succeeds, fails with an error that isn't transient or the installer's retry
+// attempts are used up. The delay between attempts starts at the installer's base delay and doubles with each
+// attempt. Cancelling ctx stops retrying, and the last error of fn is returned.
+func (i *Installer) withRetry(ctx context.Context, description string, fn func() error) error {
+	delay := i.retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= i.retryAttempts || !IsTransientError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		i.log.Warnf("Failed %s (attempt %d of %d), retrying in %s: %v", description, attempt, i.retryAttempts, delay, err)
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		delay *= 2
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gosimple/slug"
 
@@ -40,7 +42,9 @@ type Loader struct {
 	signatureValidator signature.Validator
 	log                log.Logger
 
-	errs map[string]*plugins.SignatureError
+	// errsMu guards errs, as plugin paths may be loaded concurrently
+	errsMu sync.RWMutex
+	errs   map[string]*plugins.SignatureError
 }
 
 func ProvideService(cfg *setting.Cfg, license models.Licensing, authorizer plugins.PluginLoaderAuthorizer,
@@ -110,6 +114,15 @@ func (l *Loader) loadPlugins(ctx context.Context, class plugins.Class, pluginJSO
 		plugin.SignatureOrg = sig.SigningOrg
 		plugin.SignedFiles = sig.Files
 
+		if !plugin.IsCorePlugin() {
+			installedAt, size, err := diskUsage(plugin.PluginDir)
+			if err != nil {
+				l.log.Warn("Could not calculate plugin disk usage", "pluginID", plugin.ID, "err", err)
+			}
+			plugin.InstalledAt = installedAt
+			plugin.SizeBytes = size
+		}
+
 		loadedPlugins[plugin.PluginDir] = plugin
 	}
 
@@ -140,13 +153,17 @@ func (l *Loader) loadPlugins(ctx context.Context, class plugins.Class, pluginJSO
 			l.log.Warn("Skipping loading plugin due to problem with signature",
 				"pluginID", plugin.ID, "status", signingError.SignatureStatus)
 			plugin.SignatureError = signingError
+			l.errsMu.Lock()
 			l.errs[plugin.ID] = signingError
+			l.errsMu.Unlock()
 			// skip plugin so it will not be loaded any further
 			continue
 		}
 
 		// clear plugin error if a pre-existing error has since been resolved
+		l.errsMu.Lock()
 		delete(l.errs, plugin.ID)
+		l.errsMu.Unlock()
 
 		// verify module.js exists for SystemJS to load
 		if !plugin.IsRenderer() && !plugin.IsCorePlugin() {
@@ -232,6 +249,31 @@ func (l *Loader) readPluginJSON(pluginJSONPath string) (plugins.JSONData, error)
 	return plugin, nil
 }
 
+// diskUsage returns when the plugin was installed, based on the modification time of its plugin.json,
+// and the total size of the files in the plugin directory. Symlinks are not followed.
+func diskUsage(pluginDir string) (time.Time, int64, error) {
+	fi, err := os.Stat(filepath.Join(pluginDir, "plugin.json"))
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	var size int64
+	err = filepath.Walk(pluginDir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return fi.ModTime(), size, nil
+}
+
 func createPluginBase(pluginJSON plugins.JSONData, class plugins.Class, pluginDir string, logger log.Logger) *plugins.Plugin {
 	plugin := &plugins.Plugin{
 		JSONData:  pluginJSON,
@@ -328,6 +370,9 @@ func evalRelativePluginURLPath(pathStr, baseURL string, pluginType plugins.Type)
 }
 
 func (l *Loader) PluginErrors() []*plugins.Error {
+	l.errsMu.RLock()
+	defer l.errsMu.RUnlock()
+
 	errs := make([]*plugins.Error, 0)
 	for _, err := range l.errs {
 		errs = append(errs, &plugins.Error{
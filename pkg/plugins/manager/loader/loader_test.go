@@ -3,9 +3,11 @@ package loader
 import (
 	"context"
 	"errors"
+	"os"
 	"path/filepath"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/infra/log/logtest"
 
@@ -25,7 +27,8 @@ import (
 	"github.com/grafana/grafana/pkg/setting"
 )
 
-var compareOpts = cmpopts.IgnoreFields(plugins.Plugin{}, "client", "log")
+// InstalledAt and SizeBytes depend on the checkout, they're verified in TestLoader_DiskUsage
+var compareOpts = cmpopts.IgnoreFields(plugins.Plugin{}, "client", "log", "decommissioned", "InstalledAt", "SizeBytes")
 
 func TestLoader_Load(t *testing.T) {
 	corePluginDir, err := filepath.Abs("./../../../../public")
@@ -621,6 +624,49 @@ func TestLoader_Signature_RootURL(t *testing.T) {
 	})
 }
 
+func TestLoader_DiskUsage(t *testing.T) {
+	t.Run("Sets installed at and size of non-core plugins", func(t *testing.T) {
+		parentDir, err := filepath.Abs("../")
+		require.NoError(t, err)
+
+		l := newLoader(&plugins.Cfg{PluginsPath: filepath.Join(parentDir, "testdata")})
+		got, err := l.Load(context.Background(), plugins.Bundled, []string{"../testdata/valid-v2-signature"}, map[string]struct{}{})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+
+		pluginJSON, err := os.Stat("../testdata/valid-v2-signature/plugin/plugin.json")
+		require.NoError(t, err)
+		manifest, err := os.Stat("../testdata/valid-v2-signature/plugin/MANIFEST.txt")
+		require.NoError(t, err)
+
+		require.Equal(t, pluginJSON.ModTime(), got[0].InstalledAt)
+		require.Equal(t, pluginJSON.Size()+manifest.Size(), got[0].SizeBytes)
+		require.Equal(t, got[0].InstalledAt, got[0].ToDTO().InstalledAt)
+		require.Equal(t, got[0].SizeBytes, got[0].ToDTO().SizeBytes)
+	})
+
+	t.Run("Counts nested files but not symlinks", func(t *testing.T) {
+		pluginDir := t.TempDir()
+		installedAt := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+		err := os.WriteFile(filepath.Join(pluginDir, "plugin.json"), []byte(`{"id": "test"}`), 0600)
+		require.NoError(t, err)
+		err = os.Chtimes(filepath.Join(pluginDir, "plugin.json"), installedAt, installedAt)
+		require.NoError(t, err)
+		err = os.Mkdir(filepath.Join(pluginDir, "dist"), 0750)
+		require.NoError(t, err)
+		err = os.WriteFile(filepath.Join(pluginDir, "dist", "module.js"), []byte("12345"), 0600)
+		require.NoError(t, err)
+		err = os.Symlink(filepath.Join(pluginDir, "dist", "module.js"), filepath.Join(pluginDir, "module.js"))
+		require.NoError(t, err)
+
+		gotInstalledAt, size, err := diskUsage(pluginDir)
+		require.NoError(t, err)
+		require.True(t, installedAt.Equal(gotInstalledAt))
+		require.Equal(t, int64(len(`{"id": "test"}`)+5), size)
+	})
+}
+
 func TestLoader_Load_DuplicatePlugins(t *testing.T) {
 	t.Run("Load duplicate plugin folders", func(t *testing.T) {
 		pluginDir, err := filepath.Abs("../testdata/test-app")
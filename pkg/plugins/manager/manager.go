@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
@@ -18,6 +20,8 @@ import (
 
 const (
 	grafanaComURL = "https://grafana.com/api/plugins"
+	// defaultLoadConcurrency is how many plugin paths are scanned, or plugins started, at a time
+	defaultLoadConcurrency = 8
 )
 
 var _ plugins.Client = (*PluginManager)(nil)
@@ -33,20 +37,49 @@ type PluginManager struct {
 	pluginLoader    loader.Service
 	pluginsMu       sync.RWMutex
 	pluginSources   []PluginSource
-	log             log.Logger
+	updateInfoCache *localcache.CacheService
+	pinnedMu        sync.Mutex
+	loadErrorsMu    sync.RWMutex
+	// loadErrors holds why plugins failed to load, keyed like plugins.LoadError
+	loadErrors map[string]error
+	// bus publishes plugin lifecycle events, it's nil if events aren't wired
+	bus bus.Bus
+	log log.Logger
+	// loadConcurrency is how many plugin paths are scanned, or plugins started, at a time when loading plugins
+	loadConcurrency int
+	// postInstallHooks are the hooks plugins can declare in their plugin.json, keyed by name
+	postInstallHooks   map[string]plugins.PostInstallHook
+	postInstallHooksMu sync.RWMutex
+	// lastHealth is the outcome of the last health check of each backend plugin since it was started, keyed by
+	// plugin ID
+	lastHealth   map[string]plugins.HealthStatus
+	lastHealthMu sync.RWMutex
+	// watchers are the channels of the subscribers to plugin changes, see Watch
+	watchers   map[chan plugins.PluginChangeEvent]struct{}
+	watchersMu sync.Mutex
+	// dashboardUsageFinder finds the dashboards using data source plugins that are removed, it's nil if dashboards
+	// aren't wired
+	dashboardUsageFinder plugins.DashboardUsageFinder
 }
 
 type PluginSource struct {
 	Class plugins.Class
 	Paths []string
+
+	// Provisioned sources are configured for specific plugins, which are managed outside of Grafana
+	Provisioned bool
 }
 
-func ProvideService(grafanaCfg *setting.Cfg, pluginRegistry registry.Service, pluginLoader loader.Service) (*PluginManager, error) {
+func ProvideService(grafanaCfg *setting.Cfg, pluginRegistry registry.Service, pluginLoader loader.Service, bus bus.Bus,
+	dashboardUsageFinder plugins.DashboardUsageFinder) (*PluginManager, error) {
 	pm := New(plugins.FromGrafanaCfg(grafanaCfg), pluginRegistry, []PluginSource{
 		{Class: plugins.Core, Paths: corePluginPaths(grafanaCfg)},
 		{Class: plugins.Bundled, Paths: []string{grafanaCfg.BundledPluginsPath}},
-		{Class: plugins.External, Paths: append([]string{grafanaCfg.PluginsPath}, pluginSettingPaths(grafanaCfg)...)},
+		{Class: plugins.External, Paths: []string{grafanaCfg.PluginsPath}},
+		{Class: plugins.External, Paths: pluginSettingPaths(grafanaCfg), Provisioned: true},
 	}, pluginLoader)
+	pm.bus = bus
+	pm.dashboardUsageFinder = dashboardUsageFinder
 	if err := pm.Init(); err != nil {
 		return nil, err
 	}
@@ -54,23 +87,42 @@ func ProvideService(grafanaCfg *setting.Cfg, pluginRegistry registry.Service, pl
 }
 
 func New(cfg *plugins.Cfg, pluginRegistry registry.Service, pluginSources []PluginSource, pluginLoader loader.Service) *PluginManager {
-	return &PluginManager{
+	pm := &PluginManager{
 		cfg:             cfg,
 		pluginLoader:    pluginLoader,
 		pluginSources:   pluginSources,
 		pluginRegistry:  pluginRegistry,
 		log:             log.New("plugin.manager"),
-		pluginInstaller: installer.New(false, cfg.BuildVersion, newInstallerLogger("plugin.installer", true)),
+		updateInfoCache: newUpdateInfoCache(),
+		loadErrors:      make(map[string]error),
+		loadConcurrency: defaultLoadConcurrency,
 	}
+	// the dependencies of a plugin are installed by the installer, so it has to enforce the install policy as well
+	pm.pluginInstaller = installer.NewWithOpts(false, cfg.BuildVersion, newInstallerLogger("plugin.installer", true), installer.Opts{
+		BundleDir:             cfg.PluginBundlePath,
+		BundleRepoFallback:    cfg.PluginBundleAllowRepoFallback,
+		DependencyConcurrency: cfg.PluginDependencyConcurrency,
+		RetryAttempts:         cfg.PluginRepositoryRetryAttempts,
+		RetryBaseDelay:        cfg.PluginRepositoryRetryBaseDelay,
+		PrivateRepo: installer.PrivateRepoOpts{
+			Host:    cfg.PluginRepositoryPrivateHost,
+			Headers: cfg.PluginRepositoryPrivateHeaders,
+		},
+		InstallAllowed: pm.checkInstallAllowed,
+	})
+
+	return pm
 }
 
+// Init loads the plugins of all plugin sources. Plugins that fail to load don't keep Grafana from starting,
+// they're logged and can be inspected with LoadErrors. Plugins that don't support the running Grafana version
+// are loaded anyway, but logged.
 func (m *PluginManager) Init() error {
 	for _, ps := range m.pluginSources {
-		err := m.loadPlugins(context.Background(), ps.Class, ps.Paths...)
-		if err != nil {
-			return err
-		}
+		// failures are logged and recorded by loadPlugins
+		_ = m.loadPlugins(context.Background(), ps.Class, ps.Paths...)
 	}
+	m.logIncompatiblePlugins(context.Background())
 
 	return nil
 }
@@ -81,33 +133,198 @@ func (m *PluginManager) Run(ctx context.Context) error {
 	return ctx.Err()
 }
 
+// loadPlugins loads and starts the plugins found in paths. Each path is loaded on its own, so that a path
+// that fails to load doesn't keep the plugins of the other paths from loading. If any path or plugin fails,
+// a plugins.LoadError naming each of them is returned once the others are loaded.
+// Paths are scanned and plugins are started concurrently, up to loadConcurrency at a time. A plugin found in
+// more than one path is loaded from the first one, and is only started once the plugins it depends on are.
+// Nothing more is registered once ctx is cancelled, whereas started plugins are not bound to ctx as they
+// outlive the calling request.
 func (m *PluginManager) loadPlugins(ctx context.Context, class plugins.Class, paths ...string) error {
-	if len(paths) == 0 {
-		return nil
+	loadErr := plugins.LoadError{Errors: make(map[string]error)}
+
+	registered := m.registeredPlugins(ctx)
+	results := make([]pathLoadResult, len(paths))
+	m.runConcurrently(len(paths), func(i int) {
+		// an empty path is loaded as no paths at all, like the loader did before paths were loaded on their own
+		pluginPaths := []string{paths[i]}
+		if paths[i] == "" {
+			pluginPaths = nil
+		}
+
+		results[i].plugins, results[i].err = m.pluginLoader.Load(ctx, class, pluginPaths, registered)
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
 	}
 
-	var pluginPaths []string
-	for _, p := range paths {
-		if p != "" {
-			pluginPaths = append(pluginPaths, p)
+	var loadedPlugins []*plugins.Plugin
+	loaded := make(map[string]struct{})
+	for i, path := range paths {
+		if err := results[i].err; err != nil {
+			m.log.Error("Could not load plugins", "path", path, "err", err)
+			loadErr.Errors[path] = err
+		}
+		m.setLoadError(path, results[i].err)
+
+		for _, p := range results[i].plugins {
+			if _, exists := loaded[p.ID]; exists {
+				m.log.Warn("Skipping plugin loading as it's a duplicate", "pluginId", p.ID, "path", path)
+				continue
+			}
+			loaded[p.ID] = struct{}{}
+			p.Provisioned = m.isProvisioned(p.PluginDir)
+			loadedPlugins = append(loadedPlugins, p)
 		}
 	}
 
-	loadedPlugins, err := m.pluginLoader.Load(ctx, class, pluginPaths, m.registeredPlugins(ctx))
+	startErrs, err := m.registerAndStartInOrder(ctx, loadedPlugins)
 	if err != nil {
-		m.log.Error("Could not load plugins", "paths", pluginPaths, "err", err)
 		return err
 	}
-
 	for _, p := range loadedPlugins {
-		if err := m.registerAndStart(context.Background(), p); err != nil {
+		err := startErrs[p.ID]
+		if err != nil {
 			m.log.Error("Could not start plugin", "pluginId", p.ID, "err", err)
+			loadErr.Errors[p.ID] = err
 		}
+		m.setLoadError(p.ID, err)
 	}
 
+	if len(loadErr.Errors) > 0 {
+		return loadErr
+	}
 	return nil
 }
 
+// pathLoadResult is what the loader returned for a plugin path
+type pathLoadResult struct {
+	plugins []*plugins.Plugin
+	err     error
+}
+
+// registerAndStartInOrder registers and starts the plugins concurrently. Plugins that depend on other plugins
+// in ps are started after them, one wave of plugins whose dependencies are started at a time. Plugins that
+// depend on each other are started anyway once nothing else is left, like they were before dependencies were
+// taken into account. It returns why plugins failed to start keyed by plugin ID, or ctx's error if ctx is
+// cancelled before all waves are started.
+func (m *PluginManager) registerAndStartInOrder(ctx context.Context, ps []*plugins.Plugin) (map[string]error, error) {
+	pending := make(map[string]struct{}, len(ps))
+	for _, p := range ps {
+		pending[p.ID] = struct{}{}
+	}
+
+	errs := make(map[string]error)
+	var errsMu sync.Mutex
+	for len(pending) > 0 {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		var wave []*plugins.Plugin
+		for _, p := range ps {
+			if _, isPending := pending[p.ID]; isPending && !dependsOnAny(p, pending) {
+				wave = append(wave, p)
+			}
+		}
+		if len(wave) == 0 {
+			for _, p := range ps {
+				if _, isPending := pending[p.ID]; isPending {
+					m.log.Warn("Starting plugin before its dependencies as they depend on each other", "pluginId", p.ID)
+					wave = append(wave, p)
+				}
+			}
+		}
+		for _, p := range wave {
+			delete(pending, p.ID)
+		}
+
+		m.runConcurrently(len(wave), func(i int) {
+			if err := m.registerAndStart(context.Background(), wave[i]); err != nil {
+				errsMu.Lock()
+				errs[wave[i].ID] = err
+				errsMu.Unlock()
+			}
+		})
+	}
+
+	return errs, nil
+}
+
+// dependsOnAny reports whether the plugin depends on any of the plugins
+func dependsOnAny(p *plugins.Plugin, pluginIDs map[string]struct{}) bool {
+	for _, dep := range p.Dependencies.Plugins {
+		if _, exists := pluginIDs[dep.ID]; exists && dep.ID != p.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// runConcurrently calls fn for 0 to n-1, up to loadConcurrency calls at a time, and waits for all of them to return
+func (m *PluginManager) runConcurrently(n int, fn func(i int)) {
+	limit := m.loadConcurrency
+	if limit < 1 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// isProvisioned reports whether the plugin directory belongs to a provisioned plugin source
+func (m *PluginManager) isProvisioned(pluginDir string) bool {
+	for _, ps := range m.pluginSources {
+		if !ps.Provisioned {
+			continue
+		}
+		for _, path := range ps.Paths {
+			if path != "" && isWithinDir(path, pluginDir) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// setLoadError records why the plugin or path failed to load, or clears it if err is nil
+func (m *PluginManager) setLoadError(key string, err error) {
+	m.loadErrorsMu.Lock()
+	defer m.loadErrorsMu.Unlock()
+
+	if err == nil {
+		delete(m.loadErrors, key)
+		return
+	}
+	m.loadErrors[key] = err
+}
+
+// LoadErrors returns why plugins failed to load, keyed by the ID of the plugin that failed to start, or by
+// the path whose plugins failed to load. Errors are cleared once the plugin or path loads successfully.
+func (m *PluginManager) LoadErrors(_ context.Context) map[string]error {
+	m.loadErrorsMu.RLock()
+	defer m.loadErrorsMu.RUnlock()
+
+	loadErrors := make(map[string]error, len(m.loadErrors))
+	for key, err := range m.loadErrors {
+		loadErrors[key] = err
+	}
+	return loadErrors
+}
+
 func (m *PluginManager) Renderer() *plugins.Plugin {
 	for _, p := range m.availablePlugins(context.TODO()) {
 		if p.IsRenderer() {
@@ -189,12 +406,109 @@ func (m *PluginManager) start(ctx context.Context, p *plugins.Plugin) error {
 	if err := startPluginAndRestartKilledProcesses(ctx, p); err != nil {
 		return err
 	}
+	// health checks of the previous process don't tell anything about the new one
+	m.resetHealth(p.ID)
 
 	p.Logger().Debug("Successfully started backend plugin process")
 
 	return nil
 }
 
+// Restart stops and starts the backend process of the plugin without reloading it from disk.
+// The process is restarted in place, so the watcher that restarts killed processes keeps covering it.
+func (m *PluginManager) Restart(ctx context.Context, pluginID string) error {
+	p, exists := m.plugin(ctx, pluginID)
+	if !exists {
+		return plugins.ErrPluginNotInstalled
+	}
+
+	// core plugins run inside Grafana and unmanaged plugins are started by someone else
+	if !p.Backend || !p.IsManaged() || p.IsCorePlugin() {
+		return plugins.ErrPluginNoBackendProcess
+	}
+
+	m.pluginsMu.Lock()
+	defer m.pluginsMu.Unlock()
+
+	m.log.Info("Restarting plugin process", "pluginId", p.ID)
+	if err := p.Stop(ctx); err != nil {
+		return err
+	}
+
+	if err := p.Start(ctx); err != nil {
+		return err
+	}
+
+	p.Logger().Debug("Successfully restarted backend plugin process")
+
+	return nil
+}
+
+// Decommission takes the plugin out of service without removing it from disk. Its backend process is stopped
+// and the plugin is no longer returned by Plugin or Plugins, until it's brought back with Recommission.
+// Decommissioning a plugin that is already decommissioned is a no-op.
+func (m *PluginManager) Decommission(ctx context.Context, pluginID string) error {
+	p, exists := m.pluginRegistry.Plugin(ctx, pluginID)
+	if !exists {
+		return plugins.ErrPluginNotInstalled
+	}
+
+	if p.IsCorePlugin() {
+		return plugins.ErrDecommissionCorePlugin
+	}
+
+	m.pluginsMu.Lock()
+	defer m.pluginsMu.Unlock()
+
+	if p.IsDecommissioned() {
+		return nil
+	}
+
+	m.log.Info("Decommissioning plugin", "pluginId", p.ID)
+	// decommission first, so the killed process isn't restarted once stopped
+	if err := p.Decommission(); err != nil {
+		return err
+	}
+	m.notifyWatchers(plugins.PluginChangeEvent{
+		Type:      plugins.PluginChangeDecommissioned,
+		PluginID:  p.ID,
+		Version:   p.Info.Version,
+		Timestamp: time.Now(),
+	})
+
+	return p.Stop(ctx)
+}
+
+// Recommission puts a plugin taken out of service by Decommission back into service and starts its backend
+// process again. Recommissioning a plugin that isn't decommissioned is a no-op.
+func (m *PluginManager) Recommission(ctx context.Context, pluginID string) error {
+	p, exists := m.pluginRegistry.Plugin(ctx, pluginID)
+	if !exists {
+		return plugins.ErrPluginNotInstalled
+	}
+
+	m.pluginsMu.Lock()
+	defer m.pluginsMu.Unlock()
+
+	if !p.IsDecommissioned() {
+		return nil
+	}
+
+	m.log.Info("Recommissioning plugin", "pluginId", p.ID)
+	if err := p.Recommission(); err != nil {
+		return err
+	}
+	m.notifyWatchers(plugins.PluginChangeEvent{
+		Type:      plugins.PluginChangeRecommissioned,
+		PluginID:  p.ID,
+		Version:   p.Info.Version,
+		Timestamp: time.Now(),
+	})
+
+	// the process outlives the request, like the processes started when plugins are loaded
+	return m.start(context.Background(), p)
+}
+
 func startPluginAndRestartKilledProcesses(ctx context.Context, p *plugins.Plugin) error {
 	if err := p.Start(ctx); err != nil {
 		return err
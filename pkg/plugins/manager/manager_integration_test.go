@@ -94,7 +94,7 @@ func TestPluginManager_int_init(t *testing.T) {
 
 	pmCfg := plugins.FromGrafanaCfg(cfg)
 	pm, err := ProvideService(cfg, registry.NewInMemory(), loader.New(pmCfg, license, signature.NewUnsignedAuthorizer(pmCfg),
-		provider.ProvideService(coreRegistry)))
+		provider.ProvideService(coreRegistry)), nil, nil)
 	require.NoError(t, err)
 
 	ctx := context.Background()
@@ -2,7 +2,13 @@ package manager
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -13,9 +19,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
+	"github.com/grafana/grafana/pkg/plugins/manager/installer"
 	"github.com/grafana/grafana/pkg/plugins/manager/registry"
 )
 
@@ -38,6 +47,64 @@ func TestPluginManager_Init(t *testing.T) {
 	})
 }
 
+func TestPluginManager_LoadErrors(t *testing.T) {
+	errBroken := errors.New("plugin.json is invalid")
+
+	t.Run("Broken plugin path doesn't keep the other paths from loading", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "", plugins.External, false, false)
+		loader := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p},
+			mockedPathErrs:      map[string]error{"broken/path": errBroken},
+		}
+		pm := New(&plugins.Cfg{}, newFakePluginRegistry(), []PluginSource{
+			{Class: plugins.External, Paths: []string{"broken/path", "good/path"}},
+		}, loader)
+
+		err := pm.Init()
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"broken/path", "good/path"}, loader.loadedPaths)
+
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+		require.Equal(t, map[string]error{"broken/path": errBroken}, pm.LoadErrors(context.Background()))
+	})
+
+	t.Run("Load error names each failing path", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "", plugins.External, false, false)
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginLoader = &fakeLoader{
+				mockedLoadedPlugins: []*plugins.Plugin{p},
+				mockedPathErrs:      map[string]error{"broken/path": errBroken},
+			}
+		})
+
+		err := pm.loadPlugins(context.Background(), plugins.External, "good/path", "broken/path")
+		var loadErr plugins.LoadError
+		require.True(t, errors.As(err, &loadErr))
+		require.Equal(t, map[string]error{"broken/path": errBroken}, loadErr.Errors)
+		require.Contains(t, err.Error(), "broken/path: plugin.json is invalid")
+
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+	})
+
+	t.Run("Load error is cleared once the path loads", func(t *testing.T) {
+		loader := &fakeLoader{mockedPathErrs: map[string]error{"test/path": errBroken}}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginLoader = loader
+		})
+
+		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
+		require.Error(t, err)
+		require.Len(t, pm.LoadErrors(context.Background()), 1)
+
+		loader.mockedPathErrs = nil
+		err = pm.loadPlugins(context.Background(), plugins.External, "test/path")
+		require.NoError(t, err)
+		require.Empty(t, pm.LoadErrors(context.Background()))
+	})
+}
+
 func TestPluginManager_loadPlugins(t *testing.T) {
 	t.Run("Managed backend plugin", func(t *testing.T) {
 		p, pc := createPlugin(t, testPluginID, "", plugins.External, true, true)
@@ -59,7 +126,7 @@ func TestPluginManager_loadPlugins(t *testing.T) {
 
 		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
 		assert.True(t, exists)
-		assert.Equal(t, p.ToDTO(), testPlugin)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
 		assert.Len(t, pm.Plugins(context.Background()), 1)
 
 		verifyNoPluginErrors(t, pm)
@@ -85,7 +152,7 @@ func TestPluginManager_loadPlugins(t *testing.T) {
 
 		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
 		assert.True(t, exists)
-		assert.Equal(t, p.ToDTO(), testPlugin)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
 		assert.Len(t, pm.Plugins(context.Background()), 1)
 
 		verifyNoPluginErrors(t, pm)
@@ -94,210 +161,1825 @@ func TestPluginManager_loadPlugins(t *testing.T) {
 	t.Run("Managed non-backend plugin", func(t *testing.T) {
 		p, pc := createPlugin(t, testPluginID, "", plugins.External, false, true)
 
-		loader := &fakeLoader{
-			mockedLoadedPlugins: []*plugins.Plugin{p},
-		}
+		loader := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p},
+		}
+
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginLoader = loader
+		})
+		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, pc.startCount)
+		assert.Equal(t, 0, pc.stopCount)
+		assert.False(t, pc.exited)
+		assert.False(t, pc.decommissioned)
+
+		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
+		assert.Len(t, pm.Plugins(context.Background()), 1)
+
+		verifyNoPluginErrors(t, pm)
+	})
+
+	t.Run("Unmanaged non-backend plugin", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "", plugins.External, false, false)
+
+		loader := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p},
+		}
+
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginLoader = loader
+		})
+		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, pc.startCount)
+		assert.Equal(t, 0, pc.stopCount)
+		assert.False(t, pc.exited)
+		assert.False(t, pc.decommissioned)
+
+		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
+		assert.Len(t, pm.Plugins(context.Background()), 1)
+
+		verifyNoPluginErrors(t, pm)
+	})
+}
+
+func TestPluginManager_loadPlugins_Concurrently(t *testing.T) {
+	setup := func(t *testing.T, ps ...*plugins.Plugin) *PluginManager {
+		t.Helper()
+		return createManager(t, func(pm *PluginManager) {
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: ps}
+			pm.loadConcurrency = 4
+		})
+	}
+
+	plugin := func(t *testing.T, recorder *startRecorder, pluginID string, dependencies ...string) *plugins.Plugin {
+		t.Helper()
+		p, pc := createPlugin(t, pluginID, "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			for _, id := range dependencies {
+				p.Dependencies.Plugins = append(p.Dependencies.Plugins, plugins.Dependency{ID: id})
+			}
+		})
+		p.RegisterClient(&recordingPluginClient{fakePluginClient: pc, recorder: recorder})
+		return p
+	}
+
+	t.Run("Plugins are started after the plugins they depend on", func(t *testing.T) {
+		recorder := &startRecorder{}
+		pm := setup(t,
+			plugin(t, recorder, "c-app", "b-datasource"),
+			plugin(t, recorder, "b-datasource", "a-datasource"),
+			plugin(t, recorder, "independent-panel"),
+			plugin(t, recorder, "a-datasource"),
+			// dependencies that aren't loaded along with the plugin don't hold it back
+			plugin(t, recorder, "d-app", "installed-datasource"),
+		)
+
+		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a-datasource", "b-datasource", "c-app", "d-app", "independent-panel"}, recorder.started)
+
+		var chain []string
+		for _, pluginID := range recorder.started {
+			if pluginID == "a-datasource" || pluginID == "b-datasource" || pluginID == "c-app" {
+				chain = append(chain, pluginID)
+			}
+		}
+		require.Equal(t, []string{"a-datasource", "b-datasource", "c-app"}, chain)
+	})
+
+	t.Run("Plugins that depend on each other are started anyway", func(t *testing.T) {
+		recorder := &startRecorder{}
+		pm := setup(t, plugin(t, recorder, "a-app", "b-app"), plugin(t, recorder, "b-app", "a-app"))
+
+		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"a-app", "b-app"}, recorder.started)
+	})
+
+	t.Run("Plugins found in more than one path are loaded once", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := setup(t, p)
+
+		err := pm.loadPlugins(context.Background(), plugins.External, "path1", "path2", "path3")
+		require.NoError(t, err)
+		require.Equal(t, 1, pc.startCount)
+		require.Empty(t, pm.LoadErrors(context.Background()))
+	})
+}
+
+func BenchmarkPluginManager_loadPlugins(b *testing.B) {
+	const pluginCount = 100
+
+	for _, concurrency := range []int{1, defaultLoadConcurrency} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				ps := make([]*plugins.Plugin, 0, pluginCount)
+				for i := 0; i < pluginCount; i++ {
+					p, pc := createPlugin(b, fmt.Sprintf("plugin-%d", i), "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+						// every tenth plugin depends on the one before it
+						if i%10 == 9 {
+							p.Dependencies.Plugins = []plugins.Dependency{{ID: fmt.Sprintf("plugin-%d", i-1)}}
+						}
+					})
+					// starting a backend plugin process takes a while
+					p.RegisterClient(&slowPluginClient{fakePluginClient: pc, startDuration: time.Millisecond})
+					ps = append(ps, p)
+				}
+				pm := createManager(b, func(pm *PluginManager) {
+					pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: ps}
+					pm.loadConcurrency = concurrency
+				})
+				b.StartTimer()
+
+				if err := pm.loadPlugins(context.Background(), plugins.External, "test/path"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestPluginManager_Installer(t *testing.T) {
+	t.Run("Install", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+
+		l := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p},
+		}
+
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = l
+		})
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, i.installCount)
+		assert.Equal(t, 0, i.uninstallCount)
+
+		verifyNoPluginErrors(t, pm)
+
+		assert.Len(t, pm.Routes(), 1)
+		assert.Equal(t, p.ID, pm.Routes()[0].PluginID)
+		assert.Equal(t, p.PluginDir, pm.Routes()[0].Directory)
+
+		assert.Equal(t, 1, pc.startCount)
+		assert.Equal(t, 0, pc.stopCount)
+		assert.False(t, pc.exited)
+		assert.False(t, pc.decommissioned)
+
+		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
+		assert.Len(t, pm.Plugins(context.Background()), 1)
+
+		t.Run("Won't install if already installed", func(t *testing.T) {
+			err := pm.Add(context.Background(), testPluginID, "1.0.0")
+			require.Equal(t, plugins.DuplicateError{
+				PluginID:          p.ID,
+				ExistingPluginDir: p.PluginDir,
+			}, err)
+		})
+
+		t.Run("Won't update if already installed and FailIfInstalled is set", func(t *testing.T) {
+			_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{FailIfInstalled: true})
+			require.Equal(t, plugins.DuplicateError{
+				PluginID:          p.ID,
+				ExistingPluginDir: p.PluginDir,
+			}, err)
+			assert.Equal(t, 1, i.installCount)
+			assert.Equal(t, 0, i.uninstallCount)
+		})
+
+		t.Run("Update", func(t *testing.T) {
+			p, pc := createPlugin(t, testPluginID, "1.2.0", plugins.External, true, true)
+
+			l := &fakeLoader{
+				mockedLoadedPlugins: []*plugins.Plugin{p},
+			}
+			pm.pluginLoader = l
+
+			err = pm.Add(context.Background(), testPluginID, "1.2.0")
+			assert.NoError(t, err)
+
+			assert.Equal(t, 2, i.installCount)
+			assert.Equal(t, 1, i.uninstallCount)
+
+			assert.Equal(t, 1, pc.startCount)
+			assert.Equal(t, 0, pc.stopCount)
+			assert.False(t, pc.exited)
+			assert.False(t, pc.decommissioned)
+
+			testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+			assert.True(t, exists)
+			assert.Equal(t, pm.toDTO(p), testPlugin)
+			assert.Len(t, pm.Plugins(context.Background()), 1)
+		})
+
+		t.Run("Uninstall", func(t *testing.T) {
+			err := pm.Remove(context.Background(), p.ID)
+			require.NoError(t, err)
+
+			assert.Equal(t, 2, i.installCount)
+			assert.Equal(t, 2, i.uninstallCount)
+
+			p, exists := pm.Plugin(context.Background(), p.ID)
+			assert.False(t, exists)
+			assert.Equal(t, plugins.PluginDTO{}, p)
+			assert.Len(t, pm.Routes(), 0)
+
+			t.Run("Won't uninstall if not installed", func(t *testing.T) {
+				err := pm.Remove(context.Background(), p.ID)
+				require.Equal(t, plugins.ErrPluginNotInstalled, err)
+			})
+		})
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+		})
+
+		t.Run("Won't update if not installed", func(t *testing.T) {
+			_, err := pm.Update(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{})
+			require.Equal(t, plugins.ErrPluginNotInstalled, err)
+			assert.Equal(t, 0, i.installCount)
+		})
+
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		t.Run("Won't update to the installed version", func(t *testing.T) {
+			_, err := pm.Update(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{})
+			require.Equal(t, plugins.DuplicateError{
+				PluginID:          p.ID,
+				ExistingPluginDir: p.PluginDir,
+			}, err)
+		})
+
+		t.Run("Updates installed plugin", func(t *testing.T) {
+			updated, pc := createPlugin(t, testPluginID, "1.2.0", plugins.External, true, true)
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{updated}}
+
+			plan, err := pm.Update(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+			require.NoError(t, err)
+			require.Nil(t, plan)
+
+			assert.Equal(t, 2, i.installCount)
+			assert.Equal(t, 1, i.uninstallCount)
+			assert.Equal(t, 1, pc.startCount)
+
+			testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+			assert.True(t, exists)
+			assert.Equal(t, "1.2.0", testPlugin.Info.Version)
+		})
+	})
+
+	t.Run("Install from file", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+
+		l := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p},
+		}
+
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = l
+		})
+
+		err := pm.AddFromFile(context.Background(), testPluginID, "/tmp/test-plugin.zip")
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, i.installCount)
+		assert.Equal(t, 1, i.installFromFileCount)
+		assert.Equal(t, 1, pc.startCount)
+
+		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
+
+		t.Run("Won't install if already installed", func(t *testing.T) {
+			err := pm.AddFromFile(context.Background(), testPluginID, "/tmp/test-plugin.zip")
+			require.Equal(t, plugins.DuplicateError{
+				PluginID:          p.ID,
+				ExistingPluginDir: p.PluginDir,
+			}, err)
+			assert.Equal(t, 1, i.installFromFileCount)
+		})
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		dep, _ := createPlugin(t, "test-dep", "1.0.0", plugins.External, true, true)
+
+		l := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p, dep},
+		}
+
+		i := &fakePluginInstaller{
+			plannedArchives: []plugins.PluginArchiveInfo{
+				{PluginID: testPluginID, Version: "1.2.0", PluginZipURL: "https://grafana.com/test-plugin.zip"},
+				{PluginID: "test-dep", Version: "1.0.0", PluginZipURL: "https://grafana.com/test-dep.zip"},
+				{PluginID: "test-new-dep", Version: "2.0.0", PluginZipURL: "https://grafana.com/test-new-dep.zip"},
+			},
+		}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = l
+		})
+
+		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
+		require.NoError(t, err)
+
+		plan, err := pm.AddWithOpts(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, &plugins.InstallPlan{
+			Download: i.plannedArchives,
+			Remove:   []string{testPluginID},
+			Load:     []string{testPluginID, "test-new-dep"},
+		}, plan)
+
+		assert.Equal(t, 0, i.installCount)
+		assert.Equal(t, 0, i.uninstallCount)
+		assert.Equal(t, 1, pc.startCount)
+		assert.Equal(t, 0, pc.stopCount)
+		assert.False(t, pc.decommissioned)
+
+		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+		assert.Equal(t, "1.0.0", testPlugin.Info.Version)
+	})
+
+	t.Run("Can't update core plugin", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "", plugins.Core, true, true)
+
+		loader := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p},
+		}
+
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginLoader = loader
+		})
+		err := pm.loadPlugins(context.Background(), plugins.Core, "test/path")
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, pc.startCount)
+		assert.Equal(t, 0, pc.stopCount)
+		assert.False(t, pc.exited)
+		assert.False(t, pc.decommissioned)
+
+		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
+		assert.Len(t, pm.Plugins(context.Background()), 1)
+
+		verifyNoPluginErrors(t, pm)
+
+		err = pm.Add(context.Background(), testPluginID, "")
+		assert.Equal(t, plugins.ErrInstallCorePlugin, err)
+
+		t.Run("Can't uninstall core plugin", func(t *testing.T) {
+			err := pm.Remove(context.Background(), p.ID)
+			require.Equal(t, plugins.ErrUninstallCorePlugin, err)
+		})
+	})
+
+	t.Run("Can't update bundled plugin", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "", plugins.Bundled, true, true)
+
+		loader := &fakeLoader{
+			mockedLoadedPlugins: []*plugins.Plugin{p},
+		}
+
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginLoader = loader
+		})
+		err := pm.loadPlugins(context.Background(), plugins.Bundled, "test/path")
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, pc.startCount)
+		assert.Equal(t, 0, pc.stopCount)
+		assert.False(t, pc.exited)
+		assert.False(t, pc.decommissioned)
+
+		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+		assert.Equal(t, pm.toDTO(p), testPlugin)
+		assert.Len(t, pm.Plugins(context.Background()), 1)
+
+		verifyNoPluginErrors(t, pm)
+
+		err = pm.Add(context.Background(), testPluginID, "")
+		assert.Equal(t, plugins.ErrInstallCorePlugin, err)
+
+		t.Run("Can't uninstall bundled plugin", func(t *testing.T) {
+			err := pm.Remove(context.Background(), p.ID)
+			require.Equal(t, plugins.ErrUninstallCorePlugin, err)
+		})
+	})
+}
+
+func TestPluginManager_GitRef(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		i := &fakePluginInstaller{}
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		})
+
+		return pm, i
+	}
+
+	t.Run("Installs the source archive of the git ref", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Add(context.Background(), testPluginID, "gitref:feature-x")
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://github.com/grafana/test-plugin/archive/feature-x.zip"}, i.installedZipURLs)
+		require.Equal(t, 0, i.updateInfoCount)
+	})
+
+	t.Run("Updates an installed plugin to the git ref", func(t *testing.T) {
+		pm, i := setup(t)
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		_, err = pm.Update(context.Background(), testPluginID, "gitref:feature-x", plugins.AddOpts{})
+		require.NoError(t, err)
+		require.Equal(t, 2, i.installCount)
+		require.Equal(t, 1, i.uninstallCount)
+		require.Equal(t, "https://github.com/grafana/test-plugin/archive/feature-x.zip", i.installedZipURLs[1])
+		require.Equal(t, 0, i.updateInfoCount)
+	})
+
+	t.Run("Rejects an empty git ref", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Add(context.Background(), testPluginID, "gitref:")
+		require.Error(t, err)
+		require.Equal(t, 0, i.installCount)
+	})
+
+	t.Run("Rejects git refs of core plugins", func(t *testing.T) {
+		pm, i := setup(t)
+		p, _ := createPlugin(t, testPluginID, "", plugins.Core, true, true)
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
+
+		err = pm.Add(context.Background(), testPluginID, "gitref:feature-x")
+		require.ErrorIs(t, err, plugins.ErrInstallCorePlugin)
+		require.Equal(t, 0, i.installCount)
+	})
+}
+
+func TestPluginManager_URLInstallAllowlist(t *testing.T) {
+	const gitRefArchiveURL = "https://github.com/grafana/test-plugin/archive/feature-x.zip"
+
+	tcs := map[string]struct {
+		allowedHosts     []string
+		requireAllowlist bool
+		allowed          bool
+	}{
+		"no allowlist":                       {allowed: true},
+		"no allowlist when required":         {requireAllowlist: true, allowed: false},
+		"allowed host":                       {allowedHosts: []string{"gitlab.com", "github.com"}, allowed: true},
+		"disallowed host":                    {allowedHosts: []string{"gitlab.com"}, allowed: false},
+		"wildcard doesn't match parent host": {allowedHosts: []string{"*.github.com"}, allowed: false},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			i := &fakePluginInstaller{}
+			p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+			pm := createManager(t, func(pm *PluginManager) {
+				pm.cfg.PluginURLInstallAllowedHosts = tc.allowedHosts
+				pm.cfg.PluginURLInstallRequireAllowlist = tc.requireAllowlist
+				pm.pluginInstaller = i
+				pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+			})
+
+			err := pm.Add(context.Background(), testPluginID, "gitref:feature-x")
+			if tc.allowed {
+				require.NoError(t, err)
+				require.Equal(t, []string{gitRefArchiveURL}, i.installedZipURLs)
+			} else {
+				require.ErrorIs(t, err, plugins.ErrPluginURLNotAllowed)
+				require.Equal(t, 0, i.installCount)
+			}
+		})
+	}
+
+	t.Run("Released versions aren't checked", func(t *testing.T) {
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.PluginURLInstallRequireAllowlist = true
+			pm.pluginInstaller = i
+		})
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, 1, i.installCount)
+	})
+}
+
+func TestPluginManager_checkURLInstallAllowed(t *testing.T) {
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.cfg.PluginURLInstallAllowedHosts = []string{"github.com", "*.example.com"}
+	})
+
+	for archiveURL, allowed := range map[string]bool{
+		"https://github.com/grafana/plugin/archive/main.zip":          true,
+		"https://GitHub.com:443/grafana/plugin/archive/main.zip":      true,
+		"https://codeload.example.com/plugin.zip":                     true,
+		"https://a.b.example.com/plugin.zip":                          true,
+		"https://example.com/plugin.zip":                              false,
+		"https://notexample.com/plugin.zip":                           false,
+		"https://github.com.evil.com/grafana/plugin/archive/main.zip": false,
+		"https://evil.com/github.com/plugin.zip":                      false,
+		"not a url":                                                   false,
+	} {
+		err := pm.checkURLInstallAllowed(archiveURL)
+		if allowed {
+			require.NoError(t, err, archiveURL)
+		} else {
+			require.ErrorIs(t, err, plugins.ErrPluginURLNotAllowed, archiveURL)
+		}
+	}
+}
+
+func TestPluginManager_Add_Decommissioned(t *testing.T) {
+	setup := func(t *testing.T, class plugins.Class) (*PluginManager, *fakePluginInstaller, *plugins.Plugin, *fakePluginClient) {
+		t.Helper()
+		stale, staleClient := createPlugin(t, testPluginID, "1.0.0", class, true, true, func(p *plugins.Plugin) {
+			err := p.Decommission()
+			require.NoError(t, err)
+		})
+		reinstalled, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{reinstalled}}
+			pm.pluginRegistry = &fakePluginRegistry{store: map[string]*plugins.Plugin{stale.ID: stale}}
+		})
+
+		return pm, i, reinstalled, staleClient
+	}
+
+	t.Run("Reinstalling a decommissioned plugin replaces its registration", func(t *testing.T) {
+		pm, i, reinstalled, staleClient := setup(t, plugins.External)
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, 1, i.installCount)
+		require.Equal(t, 1, staleClient.stopCount)
+
+		registered := pm.pluginRegistry.Plugins(context.Background())
+		require.Len(t, registered, 1)
+		require.Same(t, reinstalled, registered[0])
+		require.False(t, registered[0].IsDecommissioned())
+
+		p, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+		require.Equal(t, "1.0.0", p.Info.Version)
+	})
+
+	t.Run("Dry run plans to remove the decommissioned plugin", func(t *testing.T) {
+		pm, i, _, staleClient := setup(t, plugins.External)
+		i.plannedArchives = []plugins.PluginArchiveInfo{{PluginID: testPluginID, Version: "1.0.0"}}
+
+		plan, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{testPluginID}, plan.Remove)
+		require.Equal(t, []string{testPluginID}, plan.Load)
+		require.Equal(t, 0, staleClient.stopCount)
+		require.Len(t, pm.pluginRegistry.Plugins(context.Background()), 1)
+	})
+
+	t.Run("Decommissioned core plugins can't be reinstalled", func(t *testing.T) {
+		pm, i, _, _ := setup(t, plugins.Core)
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.ErrorIs(t, err, plugins.ErrPluginDecommissioned)
+		require.Equal(t, 0, i.installCount)
+	})
+}
+
+func TestPluginManager_Add_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+	i := &fakePluginInstaller{cancelOnInstall: cancel}
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginInstaller = i
+		pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+	})
+
+	err := pm.Add(ctx, testPluginID, "1.0.0")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, i.installCount)
+
+	// verify the installed plugin was neither registered nor started, and its files were removed again
+	require.Empty(t, pm.pluginRegistry.Plugins(context.Background()))
+	require.Equal(t, 0, pc.startCount)
+	require.Equal(t, []string{filepath.Join(pm.cfg.PluginsPath, testPluginID)}, i.uninstalledDirs)
+}
+
+func TestPluginManager_Add_PostInstallHook(t *testing.T) {
+	type hookCall struct {
+		pluginID  string
+		pluginDir string
+	}
+
+	setup := func(t *testing.T, hookErr error) (*PluginManager, *fakePluginInstaller, *[]hookCall) {
+		t.Helper()
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = "/plugins/test-plugin"
+			p.PostInstallHook = "seed-config"
+		})
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.PluginsPath = "/plugins"
+			pm.pluginInstaller = i
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		})
+
+		var calls []hookCall
+		pm.RegisterPostInstallHook("seed-config", func(_ context.Context, pluginID, pluginDir string) error {
+			calls = append(calls, hookCall{pluginID: pluginID, pluginDir: pluginDir})
+			return hookErr
+		})
+
+		return pm, i, &calls
+	}
+
+	t.Run("Runs the hook of a newly installed plugin", func(t *testing.T) {
+		pm, _, calls := setup(t, nil)
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, []hookCall{{pluginID: testPluginID, pluginDir: "/plugins/test-plugin"}}, *calls)
+
+		// the hook is a one-time setup step, updates don't run it again
+		_, err = pm.Update(context.Background(), testPluginID, "2.0.0", plugins.AddOpts{})
+		require.NoError(t, err)
+		require.Len(t, *calls, 1)
+	})
+
+	t.Run("Reports a failing hook and keeps the plugin", func(t *testing.T) {
+		hookErr := errors.New("seeding failed")
+		pm, i, calls := setup(t, hookErr)
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.ErrorIs(t, err, hookErr)
+		var postInstallErr plugins.PostInstallHookError
+		require.ErrorAs(t, err, &postInstallErr)
+		require.False(t, postInstallErr.RolledBack)
+		require.Len(t, *calls, 1)
+
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+		require.Empty(t, i.uninstalledDirs)
+	})
+
+	t.Run("Rolls back the install when the hook fails, if requested", func(t *testing.T) {
+		hookErr := errors.New("seeding failed")
+		pm, i, calls := setup(t, hookErr)
+
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{RollbackOnPostInstallHookFailure: true})
+		require.ErrorIs(t, err, hookErr)
+		var postInstallErr plugins.PostInstallHookError
+		require.ErrorAs(t, err, &postInstallErr)
+		require.True(t, postInstallErr.RolledBack)
+		require.Len(t, *calls, 1)
+
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.False(t, exists)
+		require.Equal(t, []string{"/plugins/test-plugin"}, i.uninstalledDirs)
+	})
+
+	t.Run("Fails for hooks that aren't registered", func(t *testing.T) {
+		pm, _, calls := setup(t, nil)
+		pm.postInstallHooks = nil
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.ErrorIs(t, err, plugins.ErrPostInstallHookNotFound)
+		require.Empty(t, *calls)
+	})
+}
+
+func TestPluginManager_Checksum(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		i := &fakePluginInstaller{checksums: map[string]string{testPluginID: "published-checksum"}}
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		})
+
+		return pm, i
+	}
+
+	t.Run("Updates are verified against the checksum published by the repository", func(t *testing.T) {
+		pm, i := setup(t)
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		_, err = pm.Update(context.Background(), testPluginID, "2.0.0", plugins.AddOpts{Checksum: "provided-checksum"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"", "published-checksum"}, i.installedChecksums)
+	})
+
+	t.Run("Git refs are verified against the provided checksum", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "gitref:feature-x", plugins.AddOpts{Checksum: "provided-checksum"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"provided-checksum"}, i.installedChecksums)
+	})
+}
+
+func TestPluginManager_RepositoryURL(t *testing.T) {
+	const privateRepoURL = "https://plugins.example.com/api/plugins"
+
+	setup := func(t *testing.T, missingInRepos ...string) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		i := &fakePluginInstaller{missingInRepos: map[string]bool{}}
+		for _, repoURL := range missingInRepos {
+			i.missingInRepos[repoURL] = true
+		}
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		})
+
+		return pm, i
+	}
+
+	t.Run("Installs from the default repository", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, []string{grafanaComURL}, i.repoURLs)
+	})
+
+	t.Run("Installs from the custom repository", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{RepositoryURL: privateRepoURL})
+		require.NoError(t, err)
+		require.Equal(t, []string{privateRepoURL}, i.repoURLs)
+	})
+
+	t.Run("Updates and plans from the custom repository", func(t *testing.T) {
+		pm, i := setup(t)
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		opts := plugins.AddOpts{RepositoryURL: privateRepoURL}
+		_, err = pm.Update(context.Background(), testPluginID, "2.0.0", opts)
+		require.NoError(t, err)
+
+		opts.DryRun = true
+		_, err = pm.AddWithOpts(context.Background(), "other-plugin", "1.0.0", opts)
+		require.NoError(t, err)
+		require.Equal(t, []string{grafanaComURL, privateRepoURL, privateRepoURL, privateRepoURL}, i.repoURLs)
+	})
+
+	t.Run("Doesn't fall back to the default repository unless allowed", func(t *testing.T) {
+		pm, i := setup(t, privateRepoURL)
+
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{RepositoryURL: privateRepoURL})
+		var clientErr installer.Response4xxError
+		require.ErrorAs(t, err, &clientErr)
+		require.Equal(t, []string{privateRepoURL}, i.repoURLs)
+		require.Equal(t, 0, i.installCount)
+	})
+
+	t.Run("Falls back to the default repository if allowed", func(t *testing.T) {
+		pm, i := setup(t, privateRepoURL)
+
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{
+			RepositoryURL:                  privateRepoURL,
+			AllowDefaultRepositoryFallback: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{privateRepoURL, grafanaComURL}, i.repoURLs)
+		require.Equal(t, 1, i.installCount)
+	})
+
+	t.Run("Rejects invalid repository URLs", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{RepositoryURL: "plugins.example.com"})
+		require.Error(t, err)
+		require.Empty(t, i.repoURLs)
+	})
+}
+
+func TestPluginManager_Add_Progress(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		i := &fakePluginInstaller{}
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		})
+
+		return pm, i
+	}
+
+	t.Run("Reports the progress of the installer and loading the plugin", func(t *testing.T) {
+		pm, _ := setup(t)
+
+		var events []plugins.InstallProgress
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{
+			Progress: func(progress plugins.InstallProgress) {
+				events = append(events, progress)
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []plugins.InstallProgress{
+			{Phase: plugins.InstallPhaseDownloading, PluginID: testPluginID},
+			{Phase: plugins.InstallPhaseLoading, PluginID: testPluginID},
+		}, events)
+	})
+
+	t.Run("Installs without a progress callback", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, 1, i.installCount)
+	})
+
+	t.Run("Doesn't report progress for dry runs", func(t *testing.T) {
+		pm, _ := setup(t)
+
+		var events []plugins.InstallProgress
+		_, err := pm.AddWithOpts(context.Background(), testPluginID, "1.0.0", plugins.AddOpts{
+			DryRun: true,
+			Progress: func(progress plugins.InstallProgress) {
+				events = append(events, progress)
+			},
+		})
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+}
+
+func TestPluginManager_Add_RepositoryErrors(t *testing.T) {
+	tcs := map[string]struct {
+		repoErr  error
+		expected error
+	}{
+		"plugin not found": {
+			repoErr:  installer.Response4xxError{StatusCode: http.StatusNotFound, Message: "Plugin not found"},
+			expected: plugins.ErrPluginNotFoundInRepo,
+		},
+		"version not found": {
+			repoErr:  installer.ErrVersionNotFound{PluginID: testPluginID, RequestedVersion: "1.0.0"},
+			expected: plugins.ErrPluginNotFoundInRepo,
+		},
+		"version not supported": {
+			repoErr:  installer.ErrVersionUnsupported{PluginID: testPluginID, RequestedVersion: "1.0.0"},
+			expected: plugins.ErrPluginIncompatible,
+		},
+		"network failure": {
+			repoErr:  &url.Error{Op: "Get", URL: grafanaComURL, Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}},
+			expected: plugins.ErrRepoUnavailable,
+		},
+		"timeout": {
+			repoErr:  fmt.Errorf("failed to download plugin archive: %w", context.DeadlineExceeded),
+			expected: plugins.ErrRepoUnavailable,
+		},
+		"server error": {
+			repoErr:  installer.ResponseStatusError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"},
+			expected: plugins.ErrRepoUnavailable,
+		},
+		"rate limited": {
+			repoErr:  installer.Response4xxError{StatusCode: http.StatusTooManyRequests},
+			expected: plugins.ErrRepoUnavailable,
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			i := &fakePluginInstaller{repoErrs: map[string]error{grafanaComURL: tc.repoErr}}
+			pm := createManager(t, func(pm *PluginManager) {
+				pm.pluginInstaller = i
+			})
+
+			err := pm.Add(context.Background(), testPluginID, "1.0.0")
+			require.ErrorIs(t, err, tc.expected)
+			// the error of the repository is preserved
+			require.ErrorIs(t, err, tc.repoErr)
+			require.Equal(t, 0, i.installCount)
+		})
+	}
+
+	t.Run("Other errors aren't classified", func(t *testing.T) {
+		repoErr := errors.New("archive is corrupt")
+		i := &fakePluginInstaller{repoErrs: map[string]error{grafanaComURL: repoErr}}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+		})
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.Equal(t, repoErr, err)
+	})
+
+	t.Run("Cancelled requests aren't classified", func(t *testing.T) {
+		repoErr := &url.Error{Op: "Get", URL: grafanaComURL, Err: context.Canceled}
+		i := &fakePluginInstaller{repoErrs: map[string]error{grafanaComURL: repoErr}}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+		})
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, plugins.ErrRepoUnavailable)
+	})
+}
+
+func TestPluginManager_Remove_Provisioned(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		pluginsPath := "plugins"
+		provisioned, _ := createPlugin(t, "provisioned-app", "1.0.0", plugins.External, true, false, func(p *plugins.Plugin) {
+			p.PluginDir = filepath.Join(pluginsPath, "provisioned-app")
+		})
+		manual, _ := createPlugin(t, "manual-app", "1.0.0", plugins.External, true, false, func(p *plugins.Plugin) {
+			p.PluginDir = filepath.Join(pluginsPath, "manual-app")
+		})
+
+		i := &fakePluginInstaller{}
+		pm := New(&plugins.Cfg{PluginsPath: pluginsPath}, newFakePluginRegistry(), []PluginSource{
+			{Class: plugins.External, Paths: []string{pluginsPath}},
+			{Class: plugins.External, Paths: []string{provisioned.PluginDir}, Provisioned: true},
+		}, &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{provisioned, manual}})
+		pm.pluginInstaller = i
+		require.NoError(t, pm.Init())
+
+		return pm, i
+	}
+
+	t.Run("Marks plugins of provisioned sources as provisioned", func(t *testing.T) {
+		pm, _ := setup(t)
+
+		p, exists := pm.Plugin(context.Background(), "provisioned-app")
+		require.True(t, exists)
+		require.True(t, p.Provisioned)
+		p, exists = pm.Plugin(context.Background(), "manual-app")
+		require.True(t, exists)
+		require.False(t, p.Provisioned)
+	})
+
+	t.Run("Won't remove provisioned plugin", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Remove(context.Background(), "provisioned-app")
+		require.ErrorIs(t, err, plugins.ErrPluginManagedExternally)
+		err = pm.RemoveWithDependents(context.Background(), "provisioned-app")
+		require.ErrorIs(t, err, plugins.ErrPluginManagedExternally)
+		assert.Equal(t, 0, i.uninstallCount)
+	})
+
+	t.Run("Removes provisioned plugin when forced", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.RemoveWithOpts(context.Background(), "provisioned-app", plugins.RemoveOpts{Force: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join("plugins", "provisioned-app")}, i.uninstalledDirs)
+	})
+
+	t.Run("Removes manually installed plugin", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Remove(context.Background(), "manual-app")
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join("plugins", "manual-app")}, i.uninstalledDirs)
+	})
+}
+
+func TestPluginManager_RemoveVersion(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		p, _ := createPlugin(t, testPluginID, "1.2.0", plugins.External, true, false, func(p *plugins.Plugin) {
+			p.PluginDir = testPluginID
+		})
+
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+		})
+		require.NoError(t, pm.registerAndStart(context.Background(), p))
+
+		return pm, i
+	}
+
+	t.Run("Removes plugin installed in the expected version", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.RemoveVersion(context.Background(), testPluginID, "1.2.0")
+		require.NoError(t, err)
+		assert.Equal(t, []string{testPluginID}, i.uninstalledDirs)
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.False(t, exists)
+	})
+
+	t.Run("Won't remove plugin installed in another version", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.RemoveVersion(context.Background(), testPluginID, "1.0.0")
+		require.ErrorIs(t, err, plugins.ErrPluginVersionMismatch)
+		require.EqualError(t, err, "plugin is installed in a different version: test-plugin is installed in version 1.2.0, expected 1.0.0")
+		assert.Equal(t, 0, i.uninstallCount)
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+	})
+
+	t.Run("Won't remove plugin outside of the plugins directory", func(t *testing.T) {
+		pm, i := setup(t)
+		pm.cfg.PluginsPath = "plugins"
+
+		err := pm.RemoveVersion(context.Background(), testPluginID, "1.2.0")
+		require.ErrorIs(t, err, plugins.ErrUninstallOutsideOfPluginDir)
+		assert.Equal(t, 0, i.uninstallCount)
+	})
+
+	t.Run("Won't remove plugin that isn't installed", func(t *testing.T) {
+		pm, _ := setup(t)
+
+		err := pm.RemoveVersion(context.Background(), "unknown-datasource", "1.2.0")
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
+	})
+}
+
+func TestPluginManager_Remove_Dependents(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		dependsOn := func(pluginIDs ...string) func(*plugins.Plugin) {
+			return func(p *plugins.Plugin) {
+				p.PluginDir = p.ID
+				for _, id := range pluginIDs {
+					p.Dependencies.Plugins = append(p.Dependencies.Plugins, plugins.Dependency{ID: id})
+				}
+			}
+		}
+		base, _ := createPlugin(t, "base-datasource", "1.0.0", plugins.External, true, true, dependsOn())
+		panel, _ := createPlugin(t, "test-panel", "1.0.0", plugins.External, true, true, dependsOn("base-datasource"))
+		app, _ := createPlugin(t, "test-app", "1.0.0", plugins.External, true, true, dependsOn("test-panel", "base-datasource"))
+		other, _ := createPlugin(t, "other-app", "1.0.0", plugins.External, true, true, dependsOn())
+
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginRegistry = &fakePluginRegistry{
+				store: map[string]*plugins.Plugin{
+					base.ID:  base,
+					panel.ID: panel,
+					app.ID:   app,
+					other.ID: other,
+				},
+			}
+		})
+
+		return pm, i
+	}
+
+	t.Run("Won't remove plugin other plugins depend on", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Remove(context.Background(), "base-datasource")
+		require.Equal(t, plugins.ErrPluginHasDependents{
+			PluginID:   "base-datasource",
+			Dependents: []string{"test-app", "test-panel"},
+		}, err)
+		require.EqualError(t, err, "plugin base-datasource is required by test-app, test-panel")
+		assert.Equal(t, 0, i.uninstallCount)
+	})
+
+	t.Run("Removes plugin other plugins depend on when forced", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.RemoveWithOpts(context.Background(), "base-datasource", plugins.RemoveOpts{Force: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"base-datasource"}, i.uninstalledDirs)
+	})
+
+	t.Run("Removes plugin without dependents", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Remove(context.Background(), "test-app")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"test-app"}, i.uninstalledDirs)
+	})
+
+	t.Run("Removes dependents before the plugins they depend on", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.RemoveWithDependents(context.Background(), "base-datasource")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"test-app", "test-panel", "base-datasource"}, i.uninstalledDirs)
+
+		_, exists := pm.Plugin(context.Background(), "other-app")
+		assert.True(t, exists)
+		_, exists = pm.Plugin(context.Background(), "base-datasource")
+		assert.False(t, exists)
+	})
+}
+
+func TestPluginManager_Remove_InUseByDashboards(t *testing.T) {
+	usages := []plugins.DashboardUsage{
+		{OrgID: 1, UID: "logs", Title: "Logs"},
+		{OrgID: 2, UID: "errors", Title: "Errors"},
+	}
+
+	setup := func(t *testing.T, checkDashboards bool, usages []plugins.DashboardUsage) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		ds, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = p.ID
+		})
+		app, _ := createPlugin(t, "test-app", "1.0.0", plugins.External, true, false, func(p *plugins.Plugin) {
+			p.Type = plugins.App
+			p.PluginDir = p.ID
+		})
+
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.PluginUninstallCheckDashboards = checkDashboards
+			pm.pluginInstaller = i
+			pm.dashboardUsageFinder = &fakeDashboardUsageFinder{usages: map[string][]plugins.DashboardUsage{
+				testPluginID: usages,
+				"test-app":   usages,
+			}}
+		})
+		require.NoError(t, pm.registerAndStart(context.Background(), ds))
+		require.NoError(t, pm.registerAndStart(context.Background(), app))
+
+		return pm, i
+	}
+
+	t.Run("Won't remove data source plugin dashboards use", func(t *testing.T) {
+		pm, i := setup(t, true, usages)
+
+		err := pm.Remove(context.Background(), testPluginID)
+		require.Equal(t, plugins.ErrPluginInUseByDashboards{PluginID: testPluginID, Dashboards: usages}, err)
+		require.EqualError(t, err, "data sources of plugin test-plugin are used by dashboards Logs (logs), Errors (errors)")
+		err = pm.RemoveWithDependents(context.Background(), testPluginID)
+		var inUseErr plugins.ErrPluginInUseByDashboards
+		require.ErrorAs(t, err, &inUseErr)
+		assert.Equal(t, 0, i.uninstallCount)
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		assert.True(t, exists)
+	})
+
+	t.Run("Removes data source plugin dashboards use when forced", func(t *testing.T) {
+		pm, i := setup(t, true, usages)
+
+		err := pm.RemoveWithOpts(context.Background(), testPluginID, plugins.RemoveOpts{Force: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{testPluginID}, i.uninstalledDirs)
+	})
+
+	t.Run("Removes data source plugin no dashboard uses", func(t *testing.T) {
+		pm, i := setup(t, true, nil)
+
+		err := pm.Remove(context.Background(), testPluginID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{testPluginID}, i.uninstalledDirs)
+	})
+
+	t.Run("Only checks data source plugins", func(t *testing.T) {
+		pm, i := setup(t, true, usages)
+
+		err := pm.Remove(context.Background(), "test-app")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"test-app"}, i.uninstalledDirs)
+	})
+
+	t.Run("Doesn't check dashboards unless configured", func(t *testing.T) {
+		pm, i := setup(t, false, usages)
+
+		err := pm.Remove(context.Background(), testPluginID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{testPluginID}, i.uninstalledDirs)
+	})
+
+	t.Run("Won't remove data source plugin if dashboards can't be checked", func(t *testing.T) {
+		pm, i := setup(t, true, usages)
+		pm.dashboardUsageFinder = &fakeDashboardUsageFinder{err: errors.New("database is locked")}
+
+		err := pm.Remove(context.Background(), testPluginID)
+		require.EqualError(t, err, "failed to check which dashboards use test-plugin: database is locked")
+		assert.Equal(t, 0, i.uninstallCount)
+	})
+}
+
+func TestPluginManager_Dependencies(t *testing.T) {
+	dependsOn := func(pluginIDs ...string) func(*plugins.Plugin) {
+		return func(p *plugins.Plugin) {
+			for _, id := range pluginIDs {
+				p.Dependencies.Plugins = append(p.Dependencies.Plugins, plugins.Dependency{ID: id})
+			}
+		}
+	}
+	base, _ := createPlugin(t, "base-datasource", "1.0.0", plugins.External, true, true)
+	panel, _ := createPlugin(t, "test-panel", "1.0.0", plugins.External, true, true, dependsOn("base-datasource"))
+	app, _ := createPlugin(t, "test-app", "1.0.0", plugins.External, true, true, dependsOn("test-panel", "base-datasource"))
+	other, _ := createPlugin(t, "other-app", "1.0.0", plugins.External, true, true)
+
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginRegistry = &fakePluginRegistry{
+			store: map[string]*plugins.Plugin{
+				base.ID:  base,
+				panel.ID: panel,
+				app.ID:   app,
+				other.ID: other,
+			},
+		}
+	})
+
+	tcs := []struct {
+		pluginID           string
+		expectedRequires   []string
+		expectedRequiredBy []string
+	}{
+		{pluginID: "base-datasource", expectedRequires: []string{}, expectedRequiredBy: []string{"test-app", "test-panel"}},
+		{pluginID: "test-panel", expectedRequires: []string{"base-datasource"}, expectedRequiredBy: []string{"test-app"}},
+		{pluginID: "test-app", expectedRequires: []string{"base-datasource", "test-panel"}, expectedRequiredBy: []string{}},
+		{pluginID: "other-app", expectedRequires: []string{}, expectedRequiredBy: []string{}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.pluginID, func(t *testing.T) {
+			requires, requiredBy, err := pm.Dependencies(context.Background(), tc.pluginID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRequires, requires)
+			assert.Equal(t, tc.expectedRequiredBy, requiredBy)
+		})
+	}
+
+	t.Run("Unknown plugin", func(t *testing.T) {
+		_, _, err := pm.Dependencies(context.Background(), "unknown-app")
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
+	})
+}
+
+func TestPluginManager_Events(t *testing.T) {
+	setup := func(t *testing.T, loaded *plugins.Plugin) (*PluginManager, *fakeBus) {
+		t.Helper()
+		b := &fakeBus{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = &fakePluginInstaller{}
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{loaded}}
+			pm.bus = b
+		})
+
+		return pm, b
+	}
+
+	t.Run("Publishes installed event", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm, b := setup(t, p)
+
+		err := pm.Add(context.Background(), testPluginID, "")
+		require.NoError(t, err)
+
+		require.Len(t, b.published, 1)
+		installed, ok := b.published[0].(*events.PluginInstalledEvent)
+		require.True(t, ok)
+		assert.Equal(t, testPluginID, installed.PluginID)
+		assert.Equal(t, "1.0.0", installed.Version)
+		assert.False(t, installed.IsUpdate)
+		assert.False(t, installed.Timestamp.IsZero())
+	})
+
+	t.Run("Publishes uninstalled and installed events on update", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm, b := setup(t, p)
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		updated, _ := createPlugin(t, testPluginID, "1.2.0", plugins.External, true, true)
+		pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{updated}}
+		_, err = pm.Update(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.NoError(t, err)
+
+		require.Len(t, b.published, 3)
+		uninstalled, ok := b.published[1].(*events.PluginUninstalledEvent)
+		require.True(t, ok)
+		assert.Equal(t, testPluginID, uninstalled.PluginID)
+		assert.Equal(t, "1.0.0", uninstalled.Version)
+		assert.True(t, uninstalled.IsUpdate)
+
+		installed, ok := b.published[2].(*events.PluginInstalledEvent)
+		require.True(t, ok)
+		assert.Equal(t, "1.2.0", installed.Version)
+		assert.True(t, installed.IsUpdate)
+	})
+
+	t.Run("Publishes uninstalled event", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm, b := setup(t, p)
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		err = pm.Remove(context.Background(), testPluginID)
+		require.NoError(t, err)
+
+		require.Len(t, b.published, 2)
+		uninstalled, ok := b.published[1].(*events.PluginUninstalledEvent)
+		require.True(t, ok)
+		assert.Equal(t, testPluginID, uninstalled.PluginID)
+		assert.Equal(t, "1.0.0", uninstalled.Version)
+		assert.False(t, uninstalled.IsUpdate)
+	})
+
+	t.Run("Doesn't publish events for failed changes", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.Core, true, true)
+		pm, b := setup(t, p)
+		pm.pluginRegistry = &fakePluginRegistry{store: map[string]*plugins.Plugin{p.ID: p}}
+
+		err := pm.Remove(context.Background(), testPluginID)
+		require.Equal(t, plugins.ErrUninstallCorePlugin, err)
+		_, err = pm.Update(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.Equal(t, plugins.ErrInstallCorePlugin, err)
+
+		require.Empty(t, b.published)
+	})
+
+	t.Run("Works without a bus", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm, _ := setup(t, p)
+		pm.bus = nil
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		err = pm.Remove(context.Background(), testPluginID)
+		require.NoError(t, err)
+	})
+}
+
+func TestPluginManager_Search(t *testing.T) {
+	withInfo := func(name, author string, pluginType plugins.Type) func(*plugins.Plugin) {
+		return func(p *plugins.Plugin) {
+			p.Name = name
+			p.Info.Author.Name = author
+			p.Type = pluginType
+		}
+	}
+	clock, _ := createPlugin(t, "grafana-clock-panel", "", plugins.External, true, false, withInfo("Clock", "Grafana Labs", plugins.Panel))
+	worldmap, _ := createPlugin(t, "grafana-worldmap-panel", "", plugins.External, true, false, withInfo("Worldmap Panel", "Grafana Labs", plugins.Panel))
+	clockDS, _ := createPlugin(t, "clock", "", plugins.External, true, true, withInfo("Time", "Clock Inc", plugins.DataSource))
+	redis, _ := createPlugin(t, "redis-datasource", "", plugins.External, true, true, withInfo("Redis", "Redis Ltd", plugins.DataSource))
+
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginRegistry = &fakePluginRegistry{
+			store: map[string]*plugins.Plugin{
+				clock.ID:    clock,
+				worldmap.ID: worldmap,
+				clockDS.ID:  clockDS,
+				redis.ID:    redis,
+			},
+		}
+	})
+
+	ids := func(res []plugins.PluginDTO) []string {
+		var ids []string
+		for _, p := range res {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	t.Run("Orders exact ID matches first", func(t *testing.T) {
+		res := pm.Search(context.Background(), "Clock")
+		require.Equal(t, []string{"clock", "grafana-clock-panel"}, ids(res))
+	})
+
+	t.Run("Matches name and author", func(t *testing.T) {
+		require.Equal(t, []string{"grafana-worldmap-panel"}, ids(pm.Search(context.Background(), "worldMAP panel")))
+		require.Equal(t, []string{"grafana-clock-panel", "grafana-worldmap-panel"}, ids(pm.Search(context.Background(), "labs")))
+	})
+
+	t.Run("Ranks ID matches before name and author matches", func(t *testing.T) {
+		res := pm.Search(context.Background(), "redis")
+		require.Equal(t, []string{"redis-datasource"}, ids(res))
+
+		res = pm.Search(context.Background(), "grafana")
+		require.Equal(t, []string{"grafana-clock-panel", "grafana-worldmap-panel"}, ids(res))
+	})
+
+	t.Run("Filters by type", func(t *testing.T) {
+		res := pm.Search(context.Background(), "clock", plugins.Panel)
+		require.Equal(t, []string{"grafana-clock-panel"}, ids(res))
+	})
+
+	t.Run("No matches returns empty slice", func(t *testing.T) {
+		res := pm.Search(context.Background(), "influx")
+		require.NotNil(t, res)
+		require.Empty(t, res)
+	})
+}
+
+func TestPluginManager_FilteredPlugins(t *testing.T) {
+	unsigned, _ := createPlugin(t, "test-unsigned", "", plugins.External, true, true, func(p *plugins.Plugin) {
+		p.Signature = plugins.SignatureUnsigned
+	})
+	valid, _ := createPlugin(t, "test-valid", "", plugins.External, true, true, func(p *plugins.Plugin) {
+		p.Signature = plugins.SignatureValid
+	})
+	validPanel, _ := createPlugin(t, "test-valid-panel", "", plugins.External, true, false, func(p *plugins.Plugin) {
+		p.Type = plugins.Panel
+		p.Signature = plugins.SignatureValid
+	})
+
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginRegistry = &fakePluginRegistry{
+			store: map[string]*plugins.Plugin{
+				unsigned.ID:   unsigned,
+				valid.ID:      valid,
+				validPanel.ID: validPanel,
+			},
+		}
+	})
+
+	t.Run("Filter by signature", func(t *testing.T) {
+		res := pm.FilteredPlugins(context.Background(), plugins.FilterBySignature(plugins.SignatureUnsigned))
+		require.Len(t, res, 1)
+		require.Equal(t, unsigned.ID, res[0].ID)
+	})
+
+	t.Run("Filter by signature and type", func(t *testing.T) {
+		res := pm.FilteredPlugins(context.Background(),
+			plugins.FilterBySignature(plugins.SignatureValid),
+			plugins.FilterByType(plugins.Panel),
+		)
+		require.Len(t, res, 1)
+		require.Equal(t, validPanel.ID, res[0].ID)
+	})
+
+	t.Run("No matches returns empty slice", func(t *testing.T) {
+		res := pm.FilteredPlugins(context.Background(), plugins.FilterBySignature(plugins.SignatureModified))
+		require.NotNil(t, res)
+		require.Empty(t, res)
+	})
 
-		pm := createManager(t, func(pm *PluginManager) {
-			pm.pluginLoader = loader
+	t.Run("Type filter is unchanged", func(t *testing.T) {
+		require.Len(t, pm.Plugins(context.Background()), 3)
+		require.Len(t, pm.Plugins(context.Background(), plugins.DataSource), 2)
+	})
+}
+
+func TestPluginManager_ListByAuthor(t *testing.T) {
+	byAuthor := func(id, author string) *plugins.Plugin {
+		p, _ := createPlugin(t, id, "", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.Info.Author.Name = author
 		})
-		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
-		require.NoError(t, err)
+		return p
+	}
+	grafanaDatasource := byAuthor("test-grafana-datasource", "Grafana Labs")
+	grafanaPanel := byAuthor("test-grafana-panel", "grafana labs")
+	other := byAuthor("test-other", "Grafana Labs Fan Club")
 
-		assert.Equal(t, 0, pc.startCount)
-		assert.Equal(t, 0, pc.stopCount)
-		assert.False(t, pc.exited)
-		assert.False(t, pc.decommissioned)
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginRegistry = &fakePluginRegistry{
+			store: map[string]*plugins.Plugin{
+				grafanaDatasource.ID: grafanaDatasource,
+				grafanaPanel.ID:      grafanaPanel,
+				other.ID:             other,
+			},
+		}
+	})
 
-		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
-		assert.True(t, exists)
-		assert.Equal(t, p.ToDTO(), testPlugin)
-		assert.Len(t, pm.Plugins(context.Background()), 1)
+	t.Run("Matches the author ignoring case", func(t *testing.T) {
+		res := pm.ListByAuthor(context.Background(), "GRAFANA LABS")
+		require.Len(t, res, 2)
+		require.Equal(t, grafanaDatasource.ID, res[0].ID)
+		require.Equal(t, grafanaPanel.ID, res[1].ID)
+	})
 
-		verifyNoPluginErrors(t, pm)
+	t.Run("No matches returns empty slice", func(t *testing.T) {
+		res := pm.ListByAuthor(context.Background(), "Unknown Vendor")
+		require.NotNil(t, res)
+		require.Empty(t, res)
 	})
+}
 
-	t.Run("Unmanaged non-backend plugin", func(t *testing.T) {
-		p, pc := createPlugin(t, testPluginID, "", plugins.External, false, false)
+func TestPluginManager_Plugins_Order(t *testing.T) {
+	store := map[string]*plugins.Plugin{}
+	for _, id := range []string{"test-c", "test-a", "test-e", "test-b", "test-d"} {
+		p, _ := createPlugin(t, id, "", plugins.External, true, true)
+		store[id] = p
+	}
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginRegistry = &fakePluginRegistry{store: store}
+	})
 
-		loader := &fakeLoader{
-			mockedLoadedPlugins: []*plugins.Plugin{p},
+	ids := func(dtos []plugins.PluginDTO) []string {
+		res := make([]string, 0, len(dtos))
+		for _, dto := range dtos {
+			res = append(res, dto.ID)
 		}
+		return res
+	}
 
-		pm := createManager(t, func(pm *PluginManager) {
-			pm.pluginLoader = loader
-		})
-		err := pm.loadPlugins(context.Background(), plugins.External, "test/path")
+	expected := []string{"test-a", "test-b", "test-c", "test-d", "test-e"}
+	for i := 0; i < 10; i++ {
+		require.Equal(t, expected, ids(pm.Plugins(context.Background())))
+	}
+}
+
+func TestPluginManager_InstalledVersions(t *testing.T) {
+	external, _ := createPlugin(t, "test-datasource", "1.2.3", plugins.External, true, true)
+	externalPanel, _ := createPlugin(t, "test-panel", "2.0.0", plugins.External, true, false)
+	bundled, _ := createPlugin(t, "test-bundled", "1.0.0", plugins.Bundled, true, true)
+	core, _ := createPlugin(t, "test-core", "", plugins.Core, true, false)
+	decommissioned, _ := createPlugin(t, "test-decommissioned", "3.0.0", plugins.External, true, false, func(p *plugins.Plugin) {
+		err := p.Decommission()
 		require.NoError(t, err)
+	})
 
-		assert.Equal(t, 0, pc.startCount)
-		assert.Equal(t, 0, pc.stopCount)
-		assert.False(t, pc.exited)
-		assert.False(t, pc.decommissioned)
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginRegistry = &fakePluginRegistry{
+			store: map[string]*plugins.Plugin{
+				external.ID:       external,
+				externalPanel.ID:  externalPanel,
+				bundled.ID:        bundled,
+				core.ID:           core,
+				decommissioned.ID: decommissioned,
+			},
+		}
+	})
 
-		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
-		assert.True(t, exists)
-		assert.Equal(t, p.ToDTO(), testPlugin)
-		assert.Len(t, pm.Plugins(context.Background()), 1)
+	require.Equal(t, map[string]string{
+		"test-datasource": "1.2.3",
+		"test-panel":      "2.0.0",
+	}, pm.InstalledVersions(context.Background()))
 
-		verifyNoPluginErrors(t, pm)
+	t.Run("Returns empty map if no external plugins are installed", func(t *testing.T) {
+		pm := createManager(t)
+		res := pm.InstalledVersions(context.Background())
+		require.NotNil(t, res)
+		require.Empty(t, res)
 	})
 }
 
-func TestPluginManager_Installer(t *testing.T) {
-	t.Run("Install", func(t *testing.T) {
+func TestPluginManager_Restart(t *testing.T) {
+	t.Run("Restarts the backend process of the plugin", func(t *testing.T) {
 		p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := createManager(t)
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
+		require.Equal(t, 1, pc.startCount)
 
-		l := &fakeLoader{
-			mockedLoadedPlugins: []*plugins.Plugin{p},
-		}
+		err = pm.Restart(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.Equal(t, 1, pc.stopCount)
+		require.Equal(t, 2, pc.startCount)
+		require.False(t, pc.Exited())
+		require.False(t, pc.IsDecommissioned())
 
-		i := &fakePluginInstaller{}
-		pm := createManager(t, func(pm *PluginManager) {
-			pm.pluginInstaller = i
-			pm.pluginLoader = l
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+	})
+
+	t.Run("Returns ErrPluginNotInstalled for unknown plugins", func(t *testing.T) {
+		pm := createManager(t)
+		err := pm.Restart(context.Background(), testPluginID)
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
+	})
+
+	tcs := map[string]struct {
+		class   plugins.Class
+		managed bool
+		backend bool
+	}{
+		"frontend plugin":  {class: plugins.External, managed: true, backend: false},
+		"unmanaged plugin": {class: plugins.External, managed: false, backend: true},
+		"core plugin":      {class: plugins.Core, managed: true, backend: true},
+	}
+	for name, tc := range tcs {
+		t.Run("Returns ErrPluginNoBackendProcess for "+name, func(t *testing.T) {
+			p, pc := createPlugin(t, testPluginID, "1.0.0", tc.class, tc.managed, tc.backend)
+			pm := createManager(t)
+			err := pm.registerAndStart(context.Background(), p)
+			require.NoError(t, err)
+
+			err = pm.Restart(context.Background(), testPluginID)
+			require.ErrorIs(t, err, plugins.ErrPluginNoBackendProcess)
+			require.Equal(t, 0, pc.stopCount)
 		})
+	}
+}
 
-		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+func TestPluginManager_Decommission(t *testing.T) {
+	t.Run("Decommissions and recommissions the plugin", func(t *testing.T) {
+		pluginDir := t.TempDir()
+		err := os.WriteFile(filepath.Join(pluginDir, "plugin.json"), []byte("{}"), 0600)
 		require.NoError(t, err)
 
-		assert.Equal(t, 1, i.installCount)
-		assert.Equal(t, 0, i.uninstallCount)
+		p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = pluginDir
+		})
+		pm := createManager(t)
+		err = pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
 
-		verifyNoPluginErrors(t, pm)
+		err = pm.Decommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.True(t, pc.IsDecommissioned())
+		require.True(t, pc.Exited())
+		require.Equal(t, 1, pc.stopCount)
 
-		assert.Len(t, pm.Routes(), 1)
-		assert.Equal(t, p.ID, pm.Routes()[0].PluginID)
-		assert.Equal(t, p.PluginDir, pm.Routes()[0].Directory)
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.False(t, exists)
+		require.Empty(t, pm.Plugins(context.Background()))
+		require.FileExists(t, filepath.Join(pluginDir, "plugin.json"))
 
-		assert.Equal(t, 1, pc.startCount)
-		assert.Equal(t, 0, pc.stopCount)
-		assert.False(t, pc.exited)
-		assert.False(t, pc.decommissioned)
+		// decommissioning again is a no-op
+		err = pm.Decommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.Equal(t, 1, pc.stopCount)
 
-		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
-		assert.True(t, exists)
-		assert.Equal(t, p.ToDTO(), testPlugin)
-		assert.Len(t, pm.Plugins(context.Background()), 1)
+		err = pm.Recommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.False(t, pc.IsDecommissioned())
+		require.False(t, pc.Exited())
+		require.Equal(t, 2, pc.startCount)
 
-		t.Run("Won't install if already installed", func(t *testing.T) {
-			err := pm.Add(context.Background(), testPluginID, "1.0.0")
-			require.Equal(t, plugins.DuplicateError{
-				PluginID:          p.ID,
-				ExistingPluginDir: p.PluginDir,
-			}, err)
-		})
+		_, exists = pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
 
-		t.Run("Update", func(t *testing.T) {
-			p, pc := createPlugin(t, testPluginID, "1.2.0", plugins.External, true, true)
+		// recommissioning a plugin in service is a no-op
+		err = pm.Recommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.Equal(t, 2, pc.startCount)
+	})
 
-			l := &fakeLoader{
-				mockedLoadedPlugins: []*plugins.Plugin{p},
-			}
-			pm.pluginLoader = l
+	t.Run("Decommissions frontend plugins", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, false)
+		p.RegisterClient(nil)
+		pm := createManager(t)
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
 
-			err = pm.Add(context.Background(), testPluginID, "1.2.0")
-			assert.NoError(t, err)
+		err = pm.Decommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.False(t, exists)
 
-			assert.Equal(t, 2, i.installCount)
-			assert.Equal(t, 1, i.uninstallCount)
+		err = pm.Recommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		_, exists = pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+	})
 
-			assert.Equal(t, 1, pc.startCount)
-			assert.Equal(t, 0, pc.stopCount)
-			assert.False(t, pc.exited)
-			assert.False(t, pc.decommissioned)
+	t.Run("Returns ErrDecommissionCorePlugin for core plugins", func(t *testing.T) {
+		p, pc := createPlugin(t, testPluginID, "", plugins.Core, true, true)
+		pm := createManager(t)
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
 
-			testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
-			assert.True(t, exists)
-			assert.Equal(t, p.ToDTO(), testPlugin)
-			assert.Len(t, pm.Plugins(context.Background()), 1)
-		})
+		err = pm.Decommission(context.Background(), testPluginID)
+		require.ErrorIs(t, err, plugins.ErrDecommissionCorePlugin)
+		require.False(t, pc.IsDecommissioned())
+	})
 
-		t.Run("Uninstall", func(t *testing.T) {
-			err := pm.Remove(context.Background(), p.ID)
-			require.NoError(t, err)
+	t.Run("Returns ErrPluginNotInstalled for unknown plugins", func(t *testing.T) {
+		pm := createManager(t)
+		err := pm.Decommission(context.Background(), testPluginID)
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
 
-			assert.Equal(t, 2, i.installCount)
-			assert.Equal(t, 2, i.uninstallCount)
+		err = pm.Recommission(context.Background(), testPluginID)
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
+	})
+}
 
-			p, exists := pm.Plugin(context.Background(), p.ID)
-			assert.False(t, exists)
-			assert.Equal(t, plugins.PluginDTO{}, p)
-			assert.Len(t, pm.Routes(), 0)
+func TestPluginManager_DecommissionedPlugins(t *testing.T) {
+	t.Run("Lists only decommissioned plugins", func(t *testing.T) {
+		decommissioned, _ := createPlugin(t, "test-decommissioned", "1.0.0", plugins.External, true, true)
+		inService, _ := createPlugin(t, "test-in-service", "1.0.0", plugins.External, true, true)
+		pm := createManager(t)
+		for _, p := range []*plugins.Plugin{decommissioned, inService} {
+			err := pm.registerAndStart(context.Background(), p)
+			require.NoError(t, err)
+		}
 
-			t.Run("Won't uninstall if not installed", func(t *testing.T) {
-				err := pm.Remove(context.Background(), p.ID)
-				require.Equal(t, plugins.ErrPluginNotInstalled, err)
-			})
-		})
-	})
+		err := pm.Decommission(context.Background(), decommissioned.ID)
+		require.NoError(t, err)
 
-	t.Run("Can't update core plugin", func(t *testing.T) {
-		p, pc := createPlugin(t, testPluginID, "", plugins.Core, true, true)
+		res := pm.DecommissionedPlugins(context.Background())
+		require.Len(t, res, 1)
+		require.Equal(t, decommissioned.ID, res[0].ID)
 
-		loader := &fakeLoader{
-			mockedLoadedPlugins: []*plugins.Plugin{p},
+		for _, p := range pm.Plugins(context.Background()) {
+			require.NotEqual(t, decommissioned.ID, p.ID)
 		}
 
-		pm := createManager(t, func(pm *PluginManager) {
-			pm.pluginLoader = loader
-		})
-		err := pm.loadPlugins(context.Background(), plugins.Core, "test/path")
+		err = pm.Recommission(context.Background(), decommissioned.ID)
 		require.NoError(t, err)
+		require.Empty(t, pm.DecommissionedPlugins(context.Background()))
+	})
 
-		assert.Equal(t, 0, pc.startCount)
-		assert.Equal(t, 0, pc.stopCount)
-		assert.False(t, pc.exited)
-		assert.False(t, pc.decommissioned)
+	t.Run("No decommissioned plugins returns empty slice", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := createManager(t)
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
 
-		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
-		assert.True(t, exists)
-		assert.Equal(t, p.ToDTO(), testPlugin)
-		assert.Len(t, pm.Plugins(context.Background()), 1)
+		res := pm.DecommissionedPlugins(context.Background())
+		require.NotNil(t, res)
+		require.Empty(t, res)
+	})
+}
 
-		verifyNoPluginErrors(t, pm)
+func TestPluginManager_PluginHealth(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginClient) {
+		t.Helper()
+		p, pc := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		pm := createManager(t)
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
 
-		err = pm.Add(context.Background(), testPluginID, "")
-		assert.Equal(t, plugins.ErrInstallCorePlugin, err)
+		return pm, pc
+	}
 
-		t.Run("Can't uninstall core plugin", func(t *testing.T) {
-			err := pm.Remove(context.Background(), p.ID)
-			require.Equal(t, plugins.ErrUninstallCorePlugin, err)
+	health := func(t *testing.T, pm *PluginManager) plugins.HealthStatus {
+		t.Helper()
+		p, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+		return p.Health
+	}
+
+	checkHealth := func(pm *PluginManager) {
+		_, _ = pm.CheckHealth(context.Background(), &backend.CheckHealthRequest{
+			PluginContext: backend.PluginContext{PluginID: testPluginID},
 		})
-	})
+	}
 
-	t.Run("Can't update bundled plugin", func(t *testing.T) {
-		p, pc := createPlugin(t, testPluginID, "", plugins.Bundled, true, true)
+	t.Run("Reports unknown until the plugin is checked", func(t *testing.T) {
+		pm, _ := setup(t)
+		require.Equal(t, plugins.HealthStatusUnknown, health(t, pm))
+	})
 
-		loader := &fakeLoader{
-			mockedLoadedPlugins: []*plugins.Plugin{p},
+	t.Run("Reports the outcome of the last health check", func(t *testing.T) {
+		pm, pc := setup(t)
+		status := backend.HealthStatusError
+		pc.CheckHealthHandlerFunc = func(_ context.Context, _ *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+			return &backend.CheckHealthResult{Status: status}, nil
 		}
 
-		pm := createManager(t, func(pm *PluginManager) {
-			pm.pluginLoader = loader
-		})
-		err := pm.loadPlugins(context.Background(), plugins.Bundled, "test/path")
-		require.NoError(t, err)
+		checkHealth(pm)
+		require.Equal(t, plugins.HealthStatusError, health(t, pm))
+		res := pm.Plugins(context.Background())
+		require.Len(t, res, 1)
+		require.Equal(t, plugins.HealthStatusError, res[0].Health)
 
-		assert.Equal(t, 1, pc.startCount)
-		assert.Equal(t, 0, pc.stopCount)
-		assert.False(t, pc.exited)
-		assert.False(t, pc.decommissioned)
+		status = backend.HealthStatusOk
+		checkHealth(pm)
+		require.Equal(t, plugins.HealthStatusOK, health(t, pm))
+	})
 
-		testPlugin, exists := pm.Plugin(context.Background(), testPluginID)
-		assert.True(t, exists)
-		assert.Equal(t, p.ToDTO(), testPlugin)
-		assert.Len(t, pm.Plugins(context.Background()), 1)
+	t.Run("Reports plugins whose process exited as unhealthy", func(t *testing.T) {
+		pm, pc := setup(t)
+		pc.CheckHealthHandlerFunc = func(_ context.Context, _ *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+			return &backend.CheckHealthResult{Status: backend.HealthStatusOk}, nil
+		}
+		checkHealth(pm)
 
-		verifyNoPluginErrors(t, pm)
+		pc.kill()
+		require.Equal(t, plugins.HealthStatusError, health(t, pm))
+	})
 
-		err = pm.Add(context.Background(), testPluginID, "")
-		assert.Equal(t, plugins.ErrInstallCorePlugin, err)
+	t.Run("Reports plugins without a backend as not applicable", func(t *testing.T) {
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.Core, true, false)
+		pm := createManager(t)
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
 
-		t.Run("Can't uninstall bundled plugin", func(t *testing.T) {
-			err := pm.Remove(context.Background(), p.ID)
-			require.Equal(t, plugins.ErrUninstallCorePlugin, err)
-		})
+		require.Equal(t, plugins.HealthStatusNotApplicable, health(t, pm))
 	})
 }
 
@@ -521,7 +2203,7 @@ func TestPluginManager_lifecycle_unmanaged(t *testing.T) {
 	})
 }
 
-func createManager(t *testing.T, cbs ...func(*PluginManager)) *PluginManager {
+func createManager(t testing.TB, cbs ...func(*PluginManager)) *PluginManager {
 	t.Helper()
 
 	pm := New(&plugins.Cfg{}, newFakePluginRegistry(), nil, &fakeLoader{})
@@ -533,7 +2215,7 @@ func createManager(t *testing.T, cbs ...func(*PluginManager)) *PluginManager {
 	return pm
 }
 
-func createPlugin(t *testing.T, pluginID, version string, class plugins.Class, managed, backend bool, cbs ...func(*plugins.Plugin)) (*plugins.Plugin, *fakePluginClient) {
+func createPlugin(t testing.TB, pluginID, version string, class plugins.Class, managed, backend bool, cbs ...func(*plugins.Plugin)) (*plugins.Plugin, *fakePluginClient) {
 	t.Helper()
 
 	p := &plugins.Plugin{
@@ -601,33 +2283,123 @@ func verifyNoPluginErrors(t *testing.T, pm *PluginManager) {
 }
 
 type fakePluginInstaller struct {
-	installCount   int
-	uninstallCount int
+	installCount         int
+	installFromFileCount int
+	uninstallCount       int
+	updateInfoCount      int
+
+	plannedArchives    []plugins.PluginArchiveInfo
+	uninstalledDirs    []string
+	installedZipURLs   []string
+	installedChecksums []string
+	// latestVersions are the versions returned as latest by GetUpdateInfo, keyed by plugin ID
+	latestVersions map[string]string
+	// checksums are the archive checksums returned by GetUpdateInfo, keyed by plugin ID
+	checksums map[string]string
+	// releaseNotes are the release notes returned by GetReleaseNotes, keyed by plugin ID
+	releaseNotes map[string][]plugins.ReleaseNote
+	// repoURLs are the plugin repositories requested, in order
+	repoURLs []string
+	// missingInRepos are the plugin repositories which respond to every request with a 404
+	missingInRepos map[string]bool
+	// repoErrs are the errors plugin repositories fail every request with
+	repoErrs map[string]error
+	// cancelOnInstall is called by Install, simulating a request cancelled while the plugin is installed
+	cancelOnInstall context.CancelFunc
+}
+
+// requestRepo records the requested plugin repository and fails if plugins are missing in it
+func (f *fakePluginInstaller) requestRepo(repoURL string) error {
+	f.repoURLs = append(f.repoURLs, repoURL)
+	if f.missingInRepos[repoURL] {
+		return installer.Response4xxError{StatusCode: http.StatusNotFound, Message: "Plugin not found"}
+	}
+	return f.repoErrs[repoURL]
 }
 
-func (f *fakePluginInstaller) Install(_ context.Context, _, _, _, _, _ string) error {
+func (f *fakePluginInstaller) Install(_ context.Context, pluginID, _, _, pluginZipURL, pluginZipChecksum, repoURL string, progress plugins.InstallProgressFunc) error {
+	if err := f.requestRepo(repoURL); err != nil {
+		return err
+	}
+	if progress != nil {
+		progress(plugins.InstallProgress{Phase: plugins.InstallPhaseDownloading, PluginID: pluginID})
+	}
 	f.installCount++
+	f.installedZipURLs = append(f.installedZipURLs, pluginZipURL)
+	f.installedChecksums = append(f.installedChecksums, pluginZipChecksum)
+	if f.cancelOnInstall != nil {
+		f.cancelOnInstall()
+	}
 	return nil
 }
 
-func (f *fakePluginInstaller) Uninstall(_ context.Context, _ string) error {
+func (f *fakePluginInstaller) InstallFromFile(_ context.Context, _, _, _ string) error {
+	f.installFromFileCount++
+	return nil
+}
+
+func (f *fakePluginInstaller) Plan(_ context.Context, _, _, _, _, repoURL string) ([]plugins.PluginArchiveInfo, error) {
+	if err := f.requestRepo(repoURL); err != nil {
+		return nil, err
+	}
+	return f.plannedArchives, nil
+}
+
+func (f *fakePluginInstaller) Uninstall(_ context.Context, pluginDir string) error {
 	f.uninstallCount++
+	f.uninstalledDirs = append(f.uninstalledDirs, pluginDir)
 	return nil
 }
 
-func (f *fakePluginInstaller) GetUpdateInfo(_ context.Context, _, _, _ string) (plugins.UpdateInfo, error) {
-	return plugins.UpdateInfo{}, nil
+func (f *fakePluginInstaller) GetUpdateInfo(_ context.Context, pluginID, version, repoURL string) (plugins.UpdateInfo, error) {
+	if err := f.requestRepo(repoURL); err != nil {
+		return plugins.UpdateInfo{}, err
+	}
+	f.updateInfoCount++
+	if version == "" {
+		version = f.latestVersions[pluginID]
+	}
+	return plugins.UpdateInfo{Version: version, SHA256: f.checksums[pluginID]}, nil
+}
+
+func (f *fakePluginInstaller) GetReleaseNotes(_ context.Context, pluginID, repoURL string) ([]plugins.ReleaseNote, error) {
+	if err := f.requestRepo(repoURL); err != nil {
+		return nil, err
+	}
+	return f.releaseNotes[pluginID], nil
+}
+
+func (f *fakePluginInstaller) GetPluginArchiveByGitRef(_ context.Context, pluginID, gitRef, repoURL string) (plugins.PluginArchiveInfo, error) {
+	if err := f.requestRepo(repoURL); err != nil {
+		return plugins.PluginArchiveInfo{}, err
+	}
+	return plugins.PluginArchiveInfo{
+		PluginID:     pluginID,
+		Version:      gitRef,
+		PluginZipURL: fmt.Sprintf("https://github.com/grafana/%s/archive/%s.zip", pluginID, gitRef),
+	}, nil
 }
 
 type fakeLoader struct {
 	mockedLoadedPlugins []*plugins.Plugin
+	// mockedPathErrs fails loading the paths they're keyed by
+	mockedPathErrs map[string]error
 
 	loadedPaths []string
+	mu          sync.Mutex
 }
 
 func (l *fakeLoader) Load(_ context.Context, _ plugins.Class, paths []string, _ map[string]struct{}) ([]*plugins.Plugin, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	l.loadedPaths = append(l.loadedPaths, paths...)
 
+	for _, path := range paths {
+		if err, exists := l.mockedPathErrs[path]; exists {
+			return nil, err
+		}
+	}
+
 	return l.mockedLoadedPlugins, nil
 }
 
@@ -691,6 +2463,15 @@ func (pc *fakePluginClient) Decommission() error {
 	return nil
 }
 
+func (pc *fakePluginClient) Recommission() error {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	pc.decommissioned = false
+
+	return nil
+}
+
 func (pc *fakePluginClient) IsDecommissioned() bool {
 	pc.mutex.RLock()
 	defer pc.mutex.RUnlock()
@@ -759,6 +2540,7 @@ func (s *fakeSender) Send(crr *backend.CallResourceResponse) error {
 
 type fakePluginRegistry struct {
 	store map[string]*plugins.Plugin
+	mu    sync.RWMutex
 }
 
 func newFakePluginRegistry() *fakePluginRegistry {
@@ -768,11 +2550,15 @@ func newFakePluginRegistry() *fakePluginRegistry {
 }
 
 func (f *fakePluginRegistry) Plugin(_ context.Context, id string) (*plugins.Plugin, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	p, exists := f.store[id]
 	return p, exists
 }
 
 func (f *fakePluginRegistry) Plugins(_ context.Context) []*plugins.Plugin {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	var res []*plugins.Plugin
 
 	for _, p := range f.store {
@@ -783,11 +2569,65 @@ func (f *fakePluginRegistry) Plugins(_ context.Context) []*plugins.Plugin {
 }
 
 func (f *fakePluginRegistry) Add(_ context.Context, p *plugins.Plugin) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.store[p.ID] = p
 	return nil
 }
 
 func (f *fakePluginRegistry) Remove(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	delete(f.store, id)
 	return nil
 }
+
+// startRecorder records the order plugins are started in
+type startRecorder struct {
+	started []string
+	mu      sync.Mutex
+}
+
+// recordingPluginClient records when the plugin is started
+type recordingPluginClient struct {
+	*fakePluginClient
+	recorder *startRecorder
+}
+
+func (pc *recordingPluginClient) Start(ctx context.Context) error {
+	pc.recorder.mu.Lock()
+	pc.recorder.started = append(pc.recorder.started, pc.pluginID)
+	pc.recorder.mu.Unlock()
+	return pc.fakePluginClient.Start(ctx)
+}
+
+// slowPluginClient takes startDuration to start
+type slowPluginClient struct {
+	*fakePluginClient
+	startDuration time.Duration
+}
+
+func (pc *slowPluginClient) Start(ctx context.Context) error {
+	time.Sleep(pc.startDuration)
+	return pc.fakePluginClient.Start(ctx)
+}
+
+type fakeBus struct {
+	published []bus.Msg
+}
+
+func (b *fakeBus) Publish(_ context.Context, msg bus.Msg) error {
+	b.published = append(b.published, msg)
+	return nil
+}
+
+func (b *fakeBus) AddEventListener(_ bus.HandlerFunc) {}
+
+type fakeDashboardUsageFinder struct {
+	usages map[string][]plugins.DashboardUsage
+	err    error
+}
+
+func (f *fakeDashboardUsageFinder) DashboardsUsingDatasourcePlugin(_ context.Context, pluginID string) ([]plugins.DashboardUsage, error) {
+	return f.usages[pluginID], f.err
+}
@@ -0,0 +1,176 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// PluginSpec declares a plugin that should be installed. An empty version accepts any installed version, and
+// installs the latest one if the plugin is missing.
+type PluginSpec struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// ApplyManifestOpts are the options for applying a plugin manifest
+type ApplyManifestOpts struct {
+	// Prune removes the installed plugins that aren't in the manifest. Provisioned plugins are left alone,
+	// as they're managed outside of Grafana.
+	Prune bool
+	// RepositoryURL overrides the plugin repository the plugins are installed from
+	RepositoryURL string
+}
+
+// ManifestAction is what applying a manifest did to a plugin
+type ManifestAction string
+
+const (
+	ManifestActionInstalled ManifestAction = "installed"
+	ManifestActionUpdated   ManifestAction = "updated"
+	ManifestActionRemoved   ManifestAction = "removed"
+	ManifestActionUnchanged ManifestAction = "unchanged"
+)
+
+// ManifestPluginResult is the action taken for a plugin when applying a manifest
+type ManifestPluginResult struct {
+	PluginID string         `json:"pluginId"`
+	Action   ManifestAction `json:"action"`
+	// Version is the version the plugin is installed in, or was installed in before it was removed
+	Version string `json:"version"`
+	// PreviousVersion is the version an updated plugin was installed in before
+	PreviousVersion string `json:"previousVersion,omitempty"`
+}
+
+// ApplyResult lists the actions taken when applying a manifest, in the order they were taken
+type ApplyResult struct {
+	Plugins []ManifestPluginResult `json:"plugins"`
+}
+
+// ApplyManifest converges the installed plugins to the manifest. Missing plugins are installed with Add, and
+// plugins installed in a version that doesn't match the spec are updated with Update. A spec version matches
+// if it's equal to the installed version, or a range the installed version is within. When opts.Prune is set,
+// the external plugins that aren't in the manifest are removed with Remove, dependents first.
+// Applying the same manifest again doesn't change anything. It stops at the first failing plugin and returns the
+// actions taken until then, so applying the manifest again picks up where it stopped.
+func (m *PluginManager) ApplyManifest(ctx context.Context, manifest []PluginSpec, opts ApplyManifestOpts) (ApplyResult, error) {
+	result := ApplyResult{Plugins: []ManifestPluginResult{}}
+
+	specs := make(map[string]struct{}, len(manifest))
+	for _, spec := range manifest {
+		if spec.ID == "" {
+			return result, fmt.Errorf("invalid plugin manifest: plugin without ID")
+		}
+		if _, exists := specs[spec.ID]; exists {
+			return result, fmt.Errorf("invalid plugin manifest: plugin %s is declared more than once", spec.ID)
+		}
+		specs[spec.ID] = struct{}{}
+	}
+
+	addOpts := plugins.AddOpts{RepositoryURL: opts.RepositoryURL}
+	for _, spec := range manifest {
+		installed, exists := m.plugin(ctx, spec.ID)
+		switch {
+		case !exists:
+			if _, err := m.AddWithOpts(ctx, spec.ID, spec.Version, addOpts); err != nil {
+				return result, fmt.Errorf("failed to install plugin %s: %w", spec.ID, err)
+			}
+			result.Plugins = append(result.Plugins, ManifestPluginResult{
+				PluginID: spec.ID,
+				Action:   ManifestActionInstalled,
+				Version:  m.installedVersion(ctx, spec.ID, spec.Version),
+			})
+		case matchesSpecVersion(installed.Info.Version, spec.Version):
+			result.Plugins = append(result.Plugins, ManifestPluginResult{
+				PluginID: spec.ID,
+				Action:   ManifestActionUnchanged,
+				Version:  installed.Info.Version,
+			})
+		default:
+			previousVersion := installed.Info.Version
+			if _, err := m.Update(ctx, spec.ID, spec.Version, addOpts); err != nil {
+				return result, fmt.Errorf("failed to update plugin %s: %w", spec.ID, err)
+			}
+			result.Plugins = append(result.Plugins, ManifestPluginResult{
+				PluginID:        spec.ID,
+				Action:          ManifestActionUpdated,
+				Version:         m.installedVersion(ctx, spec.ID, spec.Version),
+				PreviousVersion: previousVersion,
+			})
+		}
+	}
+
+	if !opts.Prune {
+		return result, nil
+	}
+
+	var prune []*plugins.Plugin
+	for _, p := range m.availablePlugins(ctx) {
+		// nested plugins are removed along with their parent
+		if !p.IsExternalPlugin() || p.Provisioned || p.Parent != nil {
+			continue
+		}
+		if _, exists := specs[p.ID]; !exists {
+			prune = append(prune, p)
+		}
+	}
+
+	// plugins can only be removed once their dependents are, so every pass removes the ones nothing depends on anymore
+	for len(prune) > 0 {
+		var remaining []*plugins.Plugin
+		for _, p := range prune {
+			if len(m.dependents(ctx, p.ID)) > 0 {
+				remaining = append(remaining, p)
+				continue
+			}
+			if err := m.Remove(ctx, p.ID); err != nil {
+				return result, fmt.Errorf("failed to remove plugin %s: %w", p.ID, err)
+			}
+			result.Plugins = append(result.Plugins, ManifestPluginResult{
+				PluginID: p.ID,
+				Action:   ManifestActionRemoved,
+				Version:  p.Info.Version,
+			})
+		}
+
+		if len(remaining) == len(prune) {
+			// the plugins left are depended on by plugins of the manifest
+			p := remaining[0]
+			return result, fmt.Errorf("failed to remove plugin %s: %w", p.ID, plugins.ErrPluginHasDependents{
+				PluginID:   p.ID,
+				Dependents: m.dependents(ctx, p.ID),
+			})
+		}
+		prune = remaining
+	}
+
+	return result, nil
+}
+
+// installedVersion returns the version the plugin was installed in, falling back to the requested version
+func (m *PluginManager) installedVersion(ctx context.Context, pluginID, version string) string {
+	if p, exists := m.plugin(ctx, pluginID); exists && p.Info.Version != "" {
+		return p.Info.Version
+	}
+	return version
+}
+
+// matchesSpecVersion reports whether the installed version satisfies the version of a plugin spec
+func matchesSpecVersion(installed, version string) bool {
+	if version == "" || version == installed {
+		return true
+	}
+
+	v, err := semver.NewVersion(installed)
+	if err != nil {
+		return false
+	}
+	c, err := semver.NewConstraint(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
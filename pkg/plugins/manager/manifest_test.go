@@ -0,0 +1,196 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_ApplyManifest(t *testing.T) {
+	setup := func(t *testing.T, installed ...*plugins.Plugin) (*PluginManager, *manifestInstaller) {
+		t.Helper()
+		l := &fakeLoader{}
+		i := &manifestInstaller{fakePluginInstaller: &fakePluginInstaller{}, loader: l, releases: map[string]*plugins.Plugin{}}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginLoader = l
+		})
+		for _, p := range installed {
+			require.NoError(t, pm.registerAndStart(context.Background(), p))
+		}
+
+		return pm, i
+	}
+
+	plugin := func(t *testing.T, pluginID, version string, dependencies ...string) *plugins.Plugin {
+		t.Helper()
+		p, _ := createPlugin(t, pluginID, version, plugins.External, true, false, func(p *plugins.Plugin) {
+			p.PluginDir = pluginID
+			for _, id := range dependencies {
+				p.Dependencies.Plugins = append(p.Dependencies.Plugins, plugins.Dependency{ID: id})
+			}
+		})
+		return p
+	}
+
+	t.Run("Installs missing plugins", func(t *testing.T) {
+		pm, i := setup(t)
+		i.release(plugin(t, "a-app", "1.0.0"), "1.0.0")
+		i.release(plugin(t, "b-panel", "2.1.0"), "")
+
+		result, err := pm.ApplyManifest(context.Background(), []PluginSpec{
+			{ID: "a-app", Version: "1.0.0"},
+			{ID: "b-panel"},
+		}, ApplyManifestOpts{})
+		require.NoError(t, err)
+		require.Equal(t, []ManifestPluginResult{
+			{PluginID: "a-app", Action: ManifestActionInstalled, Version: "1.0.0"},
+			{PluginID: "b-panel", Action: ManifestActionInstalled, Version: "2.1.0"},
+		}, result.Plugins)
+		require.Equal(t, 2, i.installCount)
+		require.Equal(t, map[string]string{"a-app": "1.0.0", "b-panel": "2.1.0"}, pm.InstalledVersions(context.Background()))
+	})
+
+	t.Run("Updates plugins whose version doesn't match", func(t *testing.T) {
+		pm, i := setup(t, plugin(t, "a-app", "1.0.0"), plugin(t, "b-panel", "1.0.0"))
+		i.release(plugin(t, "a-app", "1.2.0"), "1.2.0")
+		i.release(plugin(t, "b-panel", "2.0.0"), "^2.0.0")
+
+		result, err := pm.ApplyManifest(context.Background(), []PluginSpec{
+			{ID: "a-app", Version: "1.2.0"},
+			{ID: "b-panel", Version: "^2.0.0"},
+		}, ApplyManifestOpts{})
+		require.NoError(t, err)
+		require.Equal(t, []ManifestPluginResult{
+			{PluginID: "a-app", Action: ManifestActionUpdated, Version: "1.2.0", PreviousVersion: "1.0.0"},
+			{PluginID: "b-panel", Action: ManifestActionUpdated, Version: "2.0.0", PreviousVersion: "1.0.0"},
+		}, result.Plugins)
+		require.Equal(t, 2, i.uninstallCount)
+		require.Equal(t, map[string]string{"a-app": "1.2.0", "b-panel": "2.0.0"}, pm.InstalledVersions(context.Background()))
+	})
+
+	t.Run("Removes plugins that aren't in the manifest when pruning, dependents first", func(t *testing.T) {
+		pm, i := setup(t,
+			plugin(t, "a-app", "1.0.0"),
+			plugin(t, "base-datasource", "1.0.0"),
+			plugin(t, "c-app", "1.0.0", "base-datasource"),
+		)
+
+		result, err := pm.ApplyManifest(context.Background(), []PluginSpec{{ID: "a-app", Version: "1.0.0"}}, ApplyManifestOpts{Prune: true})
+		require.NoError(t, err)
+		require.Equal(t, []ManifestPluginResult{
+			{PluginID: "a-app", Action: ManifestActionUnchanged, Version: "1.0.0"},
+			{PluginID: "c-app", Action: ManifestActionRemoved, Version: "1.0.0"},
+			{PluginID: "base-datasource", Action: ManifestActionRemoved, Version: "1.0.0"},
+		}, result.Plugins)
+		require.Equal(t, []string{"c-app", "base-datasource"}, i.uninstalledDirs)
+		require.Equal(t, map[string]string{"a-app": "1.0.0"}, pm.InstalledVersions(context.Background()))
+	})
+
+	t.Run("Keeps plugins that aren't in the manifest without pruning", func(t *testing.T) {
+		pm, i := setup(t, plugin(t, "a-app", "1.0.0"), plugin(t, "b-panel", "1.0.0"))
+
+		result, err := pm.ApplyManifest(context.Background(), []PluginSpec{{ID: "a-app"}}, ApplyManifestOpts{})
+		require.NoError(t, err)
+		require.Equal(t, []ManifestPluginResult{
+			{PluginID: "a-app", Action: ManifestActionUnchanged, Version: "1.0.0"},
+		}, result.Plugins)
+		require.Equal(t, 0, i.uninstallCount)
+	})
+
+	t.Run("Won't prune provisioned plugins or plugins the manifest depends on", func(t *testing.T) {
+		provisioned := plugin(t, "provisioned-app", "1.0.0")
+		provisioned.Provisioned = true
+		pm, i := setup(t, plugin(t, "a-app", "1.0.0", "base-datasource"), plugin(t, "base-datasource", "1.0.0"), provisioned)
+
+		result, err := pm.ApplyManifest(context.Background(), []PluginSpec{{ID: "a-app"}}, ApplyManifestOpts{Prune: true})
+		var dependentsErr plugins.ErrPluginHasDependents
+		require.ErrorAs(t, err, &dependentsErr)
+		require.Equal(t, plugins.ErrPluginHasDependents{PluginID: "base-datasource", Dependents: []string{"a-app"}}, dependentsErr)
+		require.Len(t, result.Plugins, 1)
+		require.Equal(t, 0, i.uninstallCount)
+	})
+
+	t.Run("Converges to the manifest", func(t *testing.T) {
+		pm, i := setup(t, plugin(t, "a-app", "1.0.0"), plugin(t, "stale-panel", "1.0.0"))
+		i.release(plugin(t, "a-app", "1.2.0"), "1.2.0")
+		i.release(plugin(t, "b-panel", "2.0.0"), "2.0.0")
+		manifest := []PluginSpec{
+			{ID: "a-app", Version: "1.2.0"},
+			{ID: "b-panel", Version: "2.0.0"},
+		}
+
+		_, err := pm.ApplyManifest(context.Background(), manifest, ApplyManifestOpts{Prune: true})
+		require.NoError(t, err)
+		installCount, uninstallCount := i.installCount, i.uninstallCount
+
+		result, err := pm.ApplyManifest(context.Background(), manifest, ApplyManifestOpts{Prune: true})
+		require.NoError(t, err)
+		require.Equal(t, []ManifestPluginResult{
+			{PluginID: "a-app", Action: ManifestActionUnchanged, Version: "1.2.0"},
+			{PluginID: "b-panel", Action: ManifestActionUnchanged, Version: "2.0.0"},
+		}, result.Plugins)
+		require.Equal(t, installCount, i.installCount)
+		require.Equal(t, uninstallCount, i.uninstallCount)
+	})
+
+	t.Run("Stops at the first failing plugin", func(t *testing.T) {
+		pm, i := setup(t)
+		i.release(plugin(t, "a-app", "1.0.0"), "1.0.0")
+
+		result, err := pm.ApplyManifest(context.Background(), []PluginSpec{
+			{ID: "a-app", Version: "1.0.0"},
+			{ID: "unreleased-app", Version: "1.0.0"},
+			{ID: "b-panel", Version: "1.0.0"},
+		}, ApplyManifestOpts{})
+		require.EqualError(t, err, "failed to install plugin unreleased-app: unreleased-app 1.0.0 isn't released")
+		require.Equal(t, []ManifestPluginResult{
+			{PluginID: "a-app", Action: ManifestActionInstalled, Version: "1.0.0"},
+		}, result.Plugins)
+	})
+
+	t.Run("Rejects invalid manifests before changing anything", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.ApplyManifest(context.Background(), []PluginSpec{{ID: "a-app"}, {ID: "a-app", Version: "1.0.0"}}, ApplyManifestOpts{})
+		require.EqualError(t, err, "invalid plugin manifest: plugin a-app is declared more than once")
+		_, err = pm.ApplyManifest(context.Background(), []PluginSpec{{Version: "1.0.0"}}, ApplyManifestOpts{})
+		require.Error(t, err)
+		require.Equal(t, 0, i.installCount)
+	})
+}
+
+func TestMatchesSpecVersion(t *testing.T) {
+	require.True(t, matchesSpecVersion("1.0.0", ""))
+	require.True(t, matchesSpecVersion("1.0.0", "1.0.0"))
+	require.True(t, matchesSpecVersion("1.4.1", "^1.2.0"))
+	require.False(t, matchesSpecVersion("2.0.0", "^1.2.0"))
+	require.False(t, matchesSpecVersion("1.0.1", "1.0.0"))
+	require.False(t, matchesSpecVersion("1.0.0", "gitref:main"))
+}
+
+// manifestInstaller loads the release of a plugin it installs
+type manifestInstaller struct {
+	*fakePluginInstaller
+	loader *fakeLoader
+	// releases are the plugins installed for a plugin ID and requested version
+	releases map[string]*plugins.Plugin
+}
+
+func (i *manifestInstaller) release(p *plugins.Plugin, version string) {
+	i.releases[p.ID+"@"+version] = p
+}
+
+func (i *manifestInstaller) Install(ctx context.Context, pluginID, version, pluginsDir, pluginZipURL, pluginZipChecksum, repoURL string, progress plugins.InstallProgressFunc) error {
+	p, exists := i.releases[pluginID+"@"+version]
+	if !exists {
+		return fmt.Errorf("%s %s isn't released", pluginID, version)
+	}
+	i.loader.mockedLoadedPlugins = []*plugins.Plugin{p}
+
+	return i.fakePluginInstaller.Install(ctx, pluginID, version, pluginsDir, pluginZipURL, pluginZipChecksum, repoURL, progress)
+}
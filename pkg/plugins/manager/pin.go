@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// pinnedPluginsFile is stored in the plugins directory so pins survive restarts
+const pinnedPluginsFile = ".pinned-plugins.json"
+
+// Pin pins the plugin to the provided version, which keeps Add and Update from installing any
+// other version unless forced. An empty version pins the currently installed version.
+func (m *PluginManager) Pin(ctx context.Context, pluginID, version string) error {
+	if version == "" {
+		plugin, exists := m.plugin(ctx, pluginID)
+		if !exists {
+			return plugins.ErrPluginNotInstalled
+		}
+		version = plugin.Info.Version
+	}
+
+	m.pinnedMu.Lock()
+	defer m.pinnedMu.Unlock()
+
+	pinned, err := m.readPinned()
+	if err != nil {
+		return err
+	}
+	pinned[pluginID] = version
+
+	return m.writePinned(pinned)
+}
+
+// Unpin removes the pin of the plugin, if any.
+func (m *PluginManager) Unpin(_ context.Context, pluginID string) error {
+	m.pinnedMu.Lock()
+	defer m.pinnedMu.Unlock()
+
+	pinned, err := m.readPinned()
+	if err != nil {
+		return err
+	}
+	if _, exists := pinned[pluginID]; !exists {
+		return nil
+	}
+	delete(pinned, pluginID)
+
+	return m.writePinned(pinned)
+}
+
+// PinnedVersion returns the version the plugin is pinned to.
+func (m *PluginManager) PinnedVersion(_ context.Context, pluginID string) (string, bool, error) {
+	m.pinnedMu.Lock()
+	defer m.pinnedMu.Unlock()
+
+	pinned, err := m.readPinned()
+	if err != nil {
+		return "", false, err
+	}
+	version, exists := pinned[pluginID]
+
+	return version, exists, nil
+}
+
+// checkPinned returns plugins.ErrPluginPinned if the plugin is pinned to a different version than the
+// requested one. An empty version, which means latest, never matches a pin.
+func (m *PluginManager) checkPinned(ctx context.Context, pluginID, version string, opts plugins.AddOpts) error {
+	if opts.Force {
+		return nil
+	}
+
+	pinnedVersion, pinned, err := m.PinnedVersion(ctx, pluginID)
+	if err != nil {
+		return err
+	}
+	if pinned && pinnedVersion != version {
+		return plugins.ErrPluginPinned
+	}
+
+	return nil
+}
+
+func (m *PluginManager) pinnedPluginsPath() string {
+	return filepath.Join(m.cfg.PluginsPath, pinnedPluginsFile)
+}
+
+func (m *PluginManager) readPinned() (map[string]string, error) {
+	pinned := make(map[string]string)
+
+	// We can ignore the gosec G304 warning since the path is built from the configured plugins path
+	// nolint:gosec
+	b, err := os.ReadFile(m.pinnedPluginsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return pinned, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &pinned); err != nil {
+		return nil, err
+	}
+
+	return pinned, nil
+}
+
+func (m *PluginManager) writePinned(pinned map[string]string) error {
+	b, err := json.Marshal(pinned)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(m.pinnedPluginsPath(), b, 0600)
+}
@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_Pin(t *testing.T) {
+	pluginsPath := t.TempDir()
+
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		i := &fakePluginInstaller{}
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = filepath.Join(pluginsPath, testPluginID)
+		})
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.PluginsPath = pluginsPath
+			pm.pluginInstaller = i
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		})
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, 1, i.installCount)
+
+		return pm, i
+	}
+
+	pm, i := setup(t)
+
+	err := pm.Pin(context.Background(), testPluginID, "")
+	require.NoError(t, err)
+
+	version, pinned, err := pm.PinnedVersion(context.Background(), testPluginID)
+	require.NoError(t, err)
+	require.True(t, pinned)
+	require.Equal(t, "1.0.0", version)
+
+	t.Run("Pinned plugin is not updated to latest", func(t *testing.T) {
+		err := pm.Add(context.Background(), testPluginID, "")
+		require.ErrorIs(t, err, plugins.ErrPluginPinned)
+
+		_, err = pm.Update(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{})
+		require.ErrorIs(t, err, plugins.ErrPluginPinned)
+
+		require.Equal(t, 1, i.installCount)
+		require.Equal(t, 0, i.uninstallCount)
+		p, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+		assert.Equal(t, "1.0.0", p.Info.Version)
+	})
+
+	t.Run("Pin survives a restart", func(t *testing.T) {
+		restarted, restartedInstaller := setup(t)
+
+		err := restarted.Add(context.Background(), testPluginID, "")
+		require.ErrorIs(t, err, plugins.ErrPluginPinned)
+		require.Equal(t, 1, restartedInstaller.installCount)
+	})
+
+	t.Run("Pinned plugin is updated when forced", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.Update(context.Background(), testPluginID, "1.2.0", plugins.AddOpts{Force: true})
+		require.NoError(t, err)
+		require.Equal(t, 2, i.installCount)
+	})
+
+	t.Run("Unpinned plugin is updated", func(t *testing.T) {
+		pm, i := setup(t)
+
+		err := pm.Unpin(context.Background(), testPluginID)
+		require.NoError(t, err)
+
+		_, pinned, err := pm.PinnedVersion(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.False(t, pinned)
+
+		err = pm.Add(context.Background(), testPluginID, "")
+		require.NoError(t, err)
+		require.Equal(t, 2, i.installCount)
+	})
+
+	t.Run("Pinning a plugin that isn't installed requires a version", func(t *testing.T) {
+		err := pm.Pin(context.Background(), "not-installed", "")
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
+	})
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// RegisterPostInstallHook registers a hook that plugins can run after they're installed, by naming it in the
+// postInstallHook field of their plugin.json. Registering a hook with a name that is already taken replaces it.
+func (m *PluginManager) RegisterPostInstallHook(name string, hook plugins.PostInstallHook) {
+	m.postInstallHooksMu.Lock()
+	defer m.postInstallHooksMu.Unlock()
+	if m.postInstallHooks == nil {
+		m.postInstallHooks = make(map[string]plugins.PostInstallHook)
+	}
+	m.postInstallHooks[name] = hook
+}
+
+// runPostInstallHook runs the post-install hook the newly installed plugin declares, if any. A failing hook is
+// returned as a plugins.PostInstallHookError. The plugin is removed again if opts.RollbackOnPostInstallHookFailure
+// is set, which is reported by rolledBack.
+func (m *PluginManager) runPostInstallHook(ctx context.Context, pluginID string, opts plugins.AddOpts) (rolledBack bool, err error) {
+	p, exists := m.plugin(ctx, pluginID)
+	if !exists || p.PostInstallHook == "" {
+		return false, nil
+	}
+
+	m.postInstallHooksMu.RLock()
+	hook, exists := m.postInstallHooks[p.PostInstallHook]
+	m.postInstallHooksMu.RUnlock()
+
+	if !exists {
+		err = plugins.ErrPostInstallHookNotFound
+	} else {
+		m.log.Info("Running post-install hook", "pluginId", p.ID, "hook", p.PostInstallHook)
+		err = hook(ctx, p.ID, p.PluginDir)
+	}
+	if err == nil {
+		return false, nil
+	}
+
+	m.log.Error("Post-install hook failed", "pluginId", p.ID, "hook", p.PostInstallHook, "err", err)
+	hookErr := plugins.PostInstallHookError{PluginID: p.ID, Hook: p.PostInstallHook, Err: err}
+	if !opts.RollbackOnPostInstallHookFailure {
+		return false, hookErr
+	}
+
+	if err := m.rollbackInstall(ctx, p); err != nil {
+		m.log.Error("Failed to remove plugin after its post-install hook failed", "pluginId", p.ID, "err", err)
+		return false, hookErr
+	}
+	hookErr.RolledBack = true
+
+	return true, hookErr
+}
+
+// rollbackInstall removes a plugin that was just installed. Unlike remove, it doesn't publish an uninstalled
+// event, as the installed event wasn't published either.
+func (m *PluginManager) rollbackInstall(ctx context.Context, p *plugins.Plugin) error {
+	if err := m.unregisterAndStop(ctx, p); err != nil {
+		return err
+	}
+
+	if err := m.pluginInstaller.Uninstall(ctx, p.PluginDir); err != nil {
+		return err
+	}
+	m.invalidateUpdateInfo(p.ID)
+
+	return nil
+}
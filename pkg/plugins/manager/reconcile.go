@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/events"
+)
+
+// ReconcileReport describes how the plugin registry drifted from the plugins directory
+type ReconcileReport struct {
+	// Unregistered are the IDs of the plugins that were unregistered because their directory no longer exists
+	Unregistered []string `json:"unregistered"`
+	// Untracked are the directories in the plugins directory that don't contain a registered plugin.
+	// They're only reported, not loaded.
+	Untracked []string `json:"untracked"`
+}
+
+// Reconcile compares the registered external plugins against the plugins directory. Plugins whose directory was
+// removed from disk without uninstalling them are unregistered, and directories without a registered plugin, such
+// as plugins that were copied in by hand or failed to load, are reported. Both are sorted.
+func (m *PluginManager) Reconcile(ctx context.Context) (ReconcileReport, error) {
+	report := ReconcileReport{Unregistered: []string{}, Untracked: []string{}}
+	if m.cfg.PluginsPath == "" {
+		return report, nil
+	}
+
+	var pluginDirs []string
+	for _, p := range m.pluginRegistry.Plugins(ctx) {
+		if !p.IsExternalPlugin() || !isWithinDir(m.cfg.PluginsPath, p.PluginDir) {
+			continue
+		}
+
+		_, err := os.Stat(p.PluginDir)
+		if err == nil {
+			pluginDirs = append(pluginDirs, p.PluginDir)
+			continue
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return report, err
+		}
+
+		m.log.Warn("Unregistering plugin whose directory was removed", "pluginId", p.ID, "path", p.PluginDir)
+		if err := m.unregisterAndStop(ctx, p); err != nil {
+			return report, err
+		}
+		m.invalidateUpdateInfo(p.ID)
+		m.setLoadError(p.ID, nil)
+		m.publish(ctx, &events.PluginUninstalledEvent{
+			Timestamp: time.Now(),
+			PluginID:  p.ID,
+			Version:   p.Info.Version,
+		})
+		report.Unregistered = append(report.Unregistered, p.ID)
+	}
+
+	entries, err := os.ReadDir(m.cfg.PluginsPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return report, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		// nested plugins, such as the data sources of an app, are registered from within the directory
+		dir := filepath.Join(m.cfg.PluginsPath, entry.Name())
+		tracked := false
+		for _, pluginDir := range pluginDirs {
+			if isWithinDir(dir, pluginDir) {
+				tracked = true
+				break
+			}
+		}
+		if !tracked {
+			report.Untracked = append(report.Untracked, dir)
+		}
+	}
+
+	sort.Strings(report.Unregistered)
+	sort.Strings(report.Untracked)
+
+	return report, nil
+}
+
+// isWithinDir reports whether path is dir or inside of it
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_Reconcile(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, string, *fakeBus) {
+		t.Helper()
+		pluginsPath := t.TempDir()
+		b := &fakeBus{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.PluginsPath = pluginsPath
+			pm.bus = b
+		})
+
+		return pm, pluginsPath, b
+	}
+
+	register := func(t *testing.T, pm *PluginManager, pluginID, pluginDir string) *fakePluginClient {
+		t.Helper()
+		p, pc := createPlugin(t, pluginID, "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = pluginDir
+		})
+		err := pm.registerAndStart(context.Background(), p)
+		require.NoError(t, err)
+
+		return pc
+	}
+
+	t.Run("Unregisters plugins whose directory is missing", func(t *testing.T) {
+		pm, pluginsPath, b := setup(t)
+		installedDir := filepath.Join(pluginsPath, "installed-app")
+		require.NoError(t, os.MkdirAll(installedDir, 0750))
+		register(t, pm, "installed-app", installedDir)
+		pc := register(t, pm, testPluginID, filepath.Join(pluginsPath, testPluginID))
+
+		report, err := pm.Reconcile(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{testPluginID}, report.Unregistered)
+		require.Empty(t, report.Untracked)
+
+		_, exists := pm.pluginRegistry.Plugin(context.Background(), testPluginID)
+		require.False(t, exists)
+		require.Equal(t, 1, pc.stopCount)
+		_, exists = pm.Plugin(context.Background(), "installed-app")
+		require.True(t, exists)
+
+		require.Len(t, b.published, 1)
+		uninstalled, ok := b.published[0].(*events.PluginUninstalledEvent)
+		require.True(t, ok)
+		require.Equal(t, testPluginID, uninstalled.PluginID)
+	})
+
+	t.Run("Reports directories without a registered plugin", func(t *testing.T) {
+		pm, pluginsPath, _ := setup(t)
+		appDir := filepath.Join(pluginsPath, "test-app")
+		require.NoError(t, os.MkdirAll(filepath.Join(appDir, "datasource"), 0750))
+		register(t, pm, "test-app", appDir)
+		// nested plugins don't make their parent directory untracked
+		register(t, pm, "test-app-datasource", filepath.Join(appDir, "datasource"))
+
+		strayDir := filepath.Join(pluginsPath, "stray-panel")
+		require.NoError(t, os.MkdirAll(strayDir, 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(strayDir, "plugin.json"), []byte("{}"), 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(pluginsPath, "README.md"), []byte("readme"), 0600))
+
+		report, err := pm.Reconcile(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, report.Unregistered)
+		require.Equal(t, []string{strayDir}, report.Untracked)
+
+		// untracked plugins are only reported, not loaded
+		_, exists := pm.Plugin(context.Background(), "stray-panel")
+		require.False(t, exists)
+		require.DirExists(t, strayDir)
+	})
+
+	t.Run("Ignores plugins outside of the plugins directory", func(t *testing.T) {
+		pm, _, _ := setup(t)
+		register(t, pm, testPluginID, filepath.Join(t.TempDir(), "missing"))
+		p, _ := createPlugin(t, "core-datasource", "1.0.0", plugins.Core, true, false)
+		require.NoError(t, pm.registerAndStart(context.Background(), p))
+
+		report, err := pm.Reconcile(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, report.Unregistered)
+		require.Empty(t, report.Untracked)
+		_, exists := pm.Plugin(context.Background(), testPluginID)
+		require.True(t, exists)
+	})
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager/installer"
+)
+
+// withRepository calls fn with the plugin repository to resolve plugins from, which is the one of
+// opts.RepositoryURL or the default one. If a plugin can't be found in a custom repository, fn is
+// retried with the default repository, but only when opts.AllowDefaultRepositoryFallback is set.
+// Errors of the repository are returned as plugins.RepositoryError.
+func (m *PluginManager) withRepository(opts plugins.AddOpts, fn func(repoURL string) error) error {
+	if opts.RepositoryURL == "" {
+		return classifyRepositoryError(fn(grafanaComURL))
+	}
+
+	if err := validateRepositoryURL(opts.RepositoryURL); err != nil {
+		return err
+	}
+
+	err := fn(opts.RepositoryURL)
+	if err == nil || !opts.AllowDefaultRepositoryFallback || !isNotFoundInRepository(err) {
+		return classifyRepositoryError(err)
+	}
+
+	m.log.Warn("Plugin not found in custom plugin repository, falling back to the default repository",
+		"repository", opts.RepositoryURL, "err", err)
+	return classifyRepositoryError(fn(grafanaComURL))
+}
+
+// classifyRepositoryError wraps errors of the plugin repository in a plugins.RepositoryError, so callers can
+// tell plugins that don't exist or don't support this system apart from failures worth retrying.
+// Other errors, such as a cancelled request, are returned as they are.
+func classifyRepositoryError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	if isNotFoundInRepository(err) {
+		return plugins.RepositoryError{Kind: plugins.ErrPluginNotFoundInRepo, Err: err}
+	}
+
+	var unsupportedErr installer.ErrVersionUnsupported
+	if errors.As(err, &unsupportedErr) {
+		return plugins.RepositoryError{Kind: plugins.ErrPluginIncompatible, Err: err}
+	}
+
+	if installer.IsTransientError(err) {
+		return plugins.RepositoryError{Kind: plugins.ErrRepoUnavailable, Err: err}
+	}
+
+	return err
+}
+
+// validateRepositoryURL verifies the URL of a custom plugin repository is an absolute HTTP(S) URL
+func validateRepositoryURL(repoURL string) error {
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid plugin repository URL %q", repoURL)
+	}
+
+	return nil
+}
+
+// checkURLInstallAllowed verifies plugins may be installed from the archive URL, according to the configured
+// allowlist of hosts. Without an allowlist, all URLs are allowed unless the allowlist is required.
+func (m *PluginManager) checkURLInstallAllowed(archiveURL string) error {
+	allowedHosts := m.cfg.PluginURLInstallAllowedHosts
+	if len(allowedHosts) == 0 {
+		if m.cfg.PluginURLInstallRequireAllowlist {
+			return fmt.Errorf("%w: no hosts are allowed", plugins.ErrPluginURLNotAllowed)
+		}
+		return nil
+	}
+
+	u, err := url.Parse(archiveURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("%w: invalid URL %q", plugins.ErrPluginURLNotAllowed, archiveURL)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedHosts {
+		if matchesHost(host, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: host %s is not allowed", plugins.ErrPluginURLNotAllowed, host)
+}
+
+// matchesHost reports whether the host matches the allowed host, where *.example.com matches any
+// subdomain of example.com, but not example.com itself
+func matchesHost(host, allowed string) bool {
+	if strings.HasPrefix(allowed, "*.") {
+		return strings.HasSuffix(host, allowed[1:])
+	}
+
+	return host == allowed
+}
+
+// isNotFoundInRepository reports whether the error is caused by the requested plugin or version
+// not being available in the plugin repository
+func isNotFoundInRepository(err error) bool {
+	var versionNotFoundErr installer.ErrVersionNotFound
+	if errors.As(err, &versionNotFoundErr) {
+		return true
+	}
+
+	var clientErr installer.Response4xxError
+	return errors.As(err, &clientErr) && clientErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"context"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager/signature"
+)
+
+// VerifySignatures re-verifies the signatures of installed plugins against the current keychain
+// and reports their status. If no plugin IDs are provided, all external plugins are verified.
+// Plugins are left untouched, so the loaded signature state stays as is until they're reloaded.
+func (m *PluginManager) VerifySignatures(ctx context.Context, pluginIDs ...string) ([]plugins.SignatureResult, error) {
+	var toVerify []*plugins.Plugin
+	if len(pluginIDs) == 0 {
+		for _, p := range m.availablePlugins(ctx) {
+			if p.IsExternalPlugin() {
+				toVerify = append(toVerify, p)
+			}
+		}
+		sort.Slice(toVerify, func(i, j int) bool {
+			return toVerify[i].ID < toVerify[j].ID
+		})
+	} else {
+		for _, pluginID := range pluginIDs {
+			p, exists := m.plugin(ctx, pluginID)
+			if !exists {
+				return nil, plugins.ErrPluginNotInstalled
+			}
+			toVerify = append(toVerify, p)
+		}
+	}
+
+	res := make([]plugins.SignatureResult, 0, len(toVerify))
+	for _, p := range toVerify {
+		res = append(res, m.verifySignature(p))
+	}
+
+	return res, nil
+}
+
+func (m *PluginManager) verifySignature(p *plugins.Plugin) plugins.SignatureResult {
+	res := plugins.SignatureResult{
+		PluginID:       p.ID,
+		PreviousStatus: p.Signature,
+	}
+
+	// nested plugins are signed as part of their root plugin
+	root := p
+	for root.Parent != nil && !root.IsCorePlugin() {
+		root = root.Parent
+	}
+
+	sig, err := signature.Calculate(m.log, root)
+	if err != nil {
+		m.log.Warn("Could not verify plugin signature", "pluginID", p.ID, "err", err)
+		res.Status = plugins.SignatureInvalid
+		res.Error = err.Error()
+		return res
+	}
+
+	res.Status = sig.Status
+	res.Type = sig.Type
+	res.SigningOrg = sig.SigningOrg
+	if res.Changed() {
+		m.log.Info("Plugin signature status changed", "pluginID", p.ID, "previous", res.PreviousStatus, "status", res.Status)
+	}
+
+	return res
+}
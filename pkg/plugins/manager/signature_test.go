@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_VerifySignatures(t *testing.T) {
+	setup := func(t *testing.T) *PluginManager {
+		t.Helper()
+		signed, _ := createPlugin(t, "test", "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = filepath.Join("testdata", "valid-v2-signature", "plugin")
+			p.Signature = plugins.SignatureUnsigned
+		})
+		modified, _ := createPlugin(t, "test-app", "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = t.TempDir()
+			p.Signature = plugins.SignatureValid
+			p.SignatureType = plugins.GrafanaSignature
+			p.SignatureOrg = "Grafana Labs"
+		})
+		bundled, _ := createPlugin(t, "test-panel", "1.0.0", plugins.Bundled, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = t.TempDir()
+			p.Signature = plugins.SignatureValid
+		})
+
+		return createManager(t, func(pm *PluginManager) {
+			pm.pluginRegistry = &fakePluginRegistry{
+				store: map[string]*plugins.Plugin{
+					signed.ID:   signed,
+					modified.ID: modified,
+					bundled.ID:  bundled,
+				},
+			}
+		})
+	}
+
+	t.Run("Verifies all external plugins if no plugin IDs are provided", func(t *testing.T) {
+		pm := setup(t)
+
+		res, err := pm.VerifySignatures(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []plugins.SignatureResult{
+			{
+				PluginID:       "test",
+				PreviousStatus: plugins.SignatureUnsigned,
+				Status:         plugins.SignatureValid,
+				Type:           plugins.GrafanaSignature,
+				SigningOrg:     "Grafana Labs",
+			},
+			{
+				PluginID:       "test-app",
+				PreviousStatus: plugins.SignatureValid,
+				Status:         plugins.SignatureUnsigned,
+			},
+		}, res)
+		assert.True(t, res[0].Changed())
+		assert.True(t, res[1].Changed())
+
+		// plugins keep the signature they were loaded with
+		p, exists := pm.plugin(context.Background(), "test")
+		require.True(t, exists)
+		assert.Equal(t, plugins.SignatureUnsigned, p.Signature)
+		p, exists = pm.plugin(context.Background(), "test-app")
+		require.True(t, exists)
+		assert.Equal(t, plugins.SignatureValid, p.Signature)
+		assert.Equal(t, "Grafana Labs", p.SignatureOrg)
+	})
+
+	t.Run("Verifies only the requested plugins", func(t *testing.T) {
+		pm := setup(t)
+
+		res, err := pm.VerifySignatures(context.Background(), "test-panel")
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, "test-panel", res[0].PluginID)
+		assert.Equal(t, plugins.SignatureUnsigned, res[0].Status)
+	})
+
+	t.Run("Returns error if a requested plugin is not installed", func(t *testing.T) {
+		pm := setup(t)
+
+		res, err := pm.VerifySignatures(context.Background(), "test", "unknown")
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
+		require.Nil(t, res)
+	})
+}
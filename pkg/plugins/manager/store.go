@@ -2,9 +2,14 @@ package manager
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/events"
 	"github.com/grafana/grafana/pkg/plugins"
 )
 
@@ -14,29 +19,114 @@ func (m *PluginManager) Plugin(ctx context.Context, pluginID string) (plugins.Pl
 		return plugins.PluginDTO{}, false
 	}
 
-	return p.ToDTO(), true
+	return m.toDTO(p), true
 }
 
+// Plugins returns the plugins of the requested types, ordered by plugin ID ascending.
 func (m *PluginManager) Plugins(ctx context.Context, pluginTypes ...plugins.Type) []plugins.PluginDTO {
-	// if no types passed, assume all
-	if len(pluginTypes) == 0 {
-		pluginTypes = plugins.PluginTypes
+	return m.FilteredPlugins(ctx, plugins.FilterByType(pluginTypes...))
+}
+
+// FilteredPlugins returns the plugins matching all of the provided filters, ordered by plugin ID ascending.
+func (m *PluginManager) FilteredPlugins(ctx context.Context, filters ...plugins.PluginFilter) []plugins.PluginDTO {
+	pluginsList := make([]plugins.PluginDTO, 0)
+	for _, p := range m.availablePlugins(ctx) {
+		dto := m.toDTO(p)
+		if matchesFilters(dto, filters) {
+			pluginsList = append(pluginsList, dto)
+		}
 	}
+	return pluginsList
+}
+
+// ListByAuthor returns the plugins by the provided author or organization, ignoring case, ordered by plugin ID
+// ascending. It returns an empty slice if no plugin matches.
+func (m *PluginManager) ListByAuthor(ctx context.Context, author string) []plugins.PluginDTO {
+	return m.FilteredPlugins(ctx, plugins.FilterByAuthor(author))
+}
+
+// Search returns the plugins of the requested types whose ID, name or author contains the query, ignoring case.
+// Plugins whose ID equals the query come first, followed by matches on the ID, the name and the author. Plugins
+// matching equally well are ordered by plugin ID ascending.
+func (m *PluginManager) Search(ctx context.Context, query string, pluginTypes ...plugins.Type) []plugins.PluginDTO {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	ranks := make(map[string]int)
+	res := m.FilteredPlugins(ctx, plugins.FilterByType(pluginTypes...), func(p plugins.PluginDTO) bool {
+		rank, matches := searchRank(p, query)
+		ranks[p.ID] = rank
+		return matches
+	})
+	sort.SliceStable(res, func(i, j int) bool {
+		return ranks[res[i].ID] < ranks[res[j].ID]
+	})
+
+	return res
+}
 
-	var requestedTypes = make(map[plugins.Type]struct{})
-	for _, pt := range pluginTypes {
-		requestedTypes[pt] = struct{}{}
+// searchRank reports whether the plugin matches the lowercase query, and how well. Lower ranks are better matches.
+func searchRank(p plugins.PluginDTO, query string) (int, bool) {
+	id := strings.ToLower(p.ID)
+	switch {
+	case id == query:
+		return 0, true
+	case strings.Contains(id, query):
+		return 1, true
+	case strings.Contains(strings.ToLower(p.Name), query):
+		return 2, true
+	case strings.Contains(strings.ToLower(p.Info.Author.Name), query):
+		return 3, true
+	default:
+		return 0, false
 	}
+}
 
-	pluginsList := make([]plugins.PluginDTO, 0)
+// InstalledVersions returns the installed version of every external plugin, keyed by plugin ID.
+func (m *PluginManager) InstalledVersions(ctx context.Context) map[string]string {
+	versions := make(map[string]string)
 	for _, p := range m.availablePlugins(ctx) {
-		if _, exists := requestedTypes[p.Type]; exists {
-			pluginsList = append(pluginsList, p.ToDTO())
+		if p.IsExternalPlugin() {
+			versions[p.ID] = p.Info.Version
 		}
 	}
+
+	return versions
+}
+
+// DecommissionedPlugins returns the plugins that were taken out of service by Decommission, but are still
+// installed, ordered by plugin ID ascending. It returns an empty slice if no plugin is decommissioned.
+func (m *PluginManager) DecommissionedPlugins(ctx context.Context) []plugins.PluginDTO {
+	pluginsList := make([]plugins.PluginDTO, 0)
+	for _, p := range m.pluginRegistry.Plugins(ctx) {
+		if p.IsDecommissioned() {
+			pluginsList = append(pluginsList, m.toDTO(p))
+		}
+	}
+
+	// the registry doesn't keep any order
+	sort.SliceStable(pluginsList, func(i, j int) bool {
+		return pluginsList[i].ID < pluginsList[j].ID
+	})
+
 	return pluginsList
 }
 
+// toDTO returns the DTO of the plugin along with its health, which only the plugin manager keeps track of
+func (m *PluginManager) toDTO(p *plugins.Plugin) plugins.PluginDTO {
+	dto := p.ToDTO()
+	dto.Health = m.pluginHealth(p)
+	return dto
+}
+
+func matchesFilters(p plugins.PluginDTO, filters []plugins.PluginFilter) bool {
+	for _, filter := range filters {
+		if !filter(p) {
+			return false
+		}
+	}
+	return true
+}
+
 // plugin finds a plugin with `pluginID` from the registry that is not decommissioned
 func (m *PluginManager) plugin(ctx context.Context, pluginID string) (*plugins.Plugin, bool) {
 	p, exists := m.pluginRegistry.Plugin(ctx, pluginID)
@@ -47,7 +137,7 @@ func (m *PluginManager) plugin(ctx context.Context, pluginID string) (*plugins.P
 	return p, true
 }
 
-// availablePlugins returns all non-decommissioned plugins from the registry
+// availablePlugins returns all non-decommissioned plugins from the registry, ordered by plugin ID ascending
 func (m *PluginManager) availablePlugins(ctx context.Context) []*plugins.Plugin {
 	var res []*plugins.Plugin
 	for _, p := range m.pluginRegistry.Plugins(ctx) {
@@ -55,6 +145,12 @@ func (m *PluginManager) availablePlugins(ctx context.Context) []*plugins.Plugin
 			res = append(res, p)
 		}
 	}
+
+	// the registry doesn't keep any order
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+
 	return res
 }
 
@@ -69,54 +165,474 @@ func (m *PluginManager) registeredPlugins(ctx context.Context) map[string]struct
 }
 
 func (m *PluginManager) Add(ctx context.Context, pluginID, version string) error {
-	var pluginZipURL string
+	_, err := m.AddWithOpts(ctx, pluginID, version, plugins.AddOpts{})
+	return err
+}
 
-	if plugin, exists := m.plugin(ctx, pluginID); exists {
-		if !plugin.IsExternalPlugin() {
-			return plugins.ErrInstallCorePlugin
-		}
+// AddWithOpts adds a plugin using the provided options. When opts.DryRun is set, the plugin and its
+// dependencies are only resolved and the returned plan describes what would be changed. Otherwise,
+// the returned plan is nil.
+// If the plugin is already installed it is updated to the requested version, unless opts.FailIfInstalled is set.
+// Pinned plugins are only installed in their pinned version, unless opts.Force is set.
+// A plugin that was decommissioned, but not removed, is reinstalled in place of its stale registration.
+// Once a newly installed plugin is loaded, the post-install hook it declares is run, see RegisterPostInstallHook.
+func (m *PluginManager) AddWithOpts(ctx context.Context, pluginID, version string, opts plugins.AddOpts) (*plugins.InstallPlan, error) {
+	if err := m.checkInstallAllowed(pluginID); err != nil {
+		return nil, err
+	}
 
-		if plugin.Info.Version == version {
-			return plugins.DuplicateError{
+	if plugin, exists := m.plugin(ctx, pluginID); exists {
+		if opts.FailIfInstalled && plugin.IsExternalPlugin() {
+			return nil, plugins.DuplicateError{
 				PluginID:          plugin.ID,
 				ExistingPluginDir: plugin.PluginDir,
 			}
 		}
 
-		// get plugin update information to confirm if upgrading is possible
-		updateInfo, err := m.pluginInstaller.GetUpdateInfo(ctx, pluginID, version, grafanaComURL)
+		return m.update(ctx, plugin, version, opts)
+	}
+
+	if err := m.checkPinned(ctx, pluginID, version, opts); err != nil {
+		return nil, err
+	}
+
+	plan := newInstallPlan(opts)
+	if decommissioned, exists := m.decommissionedPlugin(ctx, pluginID); exists {
+		if err := m.canRemove(decommissioned); err != nil {
+			return nil, fmt.Errorf("%w: %v", plugins.ErrPluginDecommissioned, err)
+		}
+
+		if opts.DryRun {
+			plan.Remove = append(plan.Remove, pluginID)
+		} else if err := m.unregisterDecommissioned(ctx, decommissioned); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.install(ctx, pluginID, version, "", "", opts, plan, false)
+}
+
+// decommissionedPlugin finds a plugin with `pluginID` from the registry that is decommissioned
+func (m *PluginManager) decommissionedPlugin(ctx context.Context, pluginID string) (*plugins.Plugin, bool) {
+	p, exists := m.pluginRegistry.Plugin(ctx, pluginID)
+	if !exists || !p.IsDecommissioned() {
+		return nil, false
+	}
+
+	return p, true
+}
+
+// unregisterDecommissioned removes the registration left behind by a plugin that was decommissioned, but
+// not removed, such as when stopping its process failed. Otherwise the loader would skip the reinstalled
+// plugin as already registered, and keep serving the stale one.
+func (m *PluginManager) unregisterDecommissioned(ctx context.Context, p *plugins.Plugin) error {
+	m.log.Info("Unregistering decommissioned plugin before reinstalling it", "pluginId", p.ID)
+	if err := m.unregisterAndStop(ctx, p); err != nil {
+		return fmt.Errorf("%w: %v", plugins.ErrPluginDecommissioned, err)
+	}
+
+	return nil
+}
+
+// Update updates an installed plugin to the requested version. It fails with
+// plugins.ErrPluginNotInstalled if the plugin is not installed.
+func (m *PluginManager) Update(ctx context.Context, pluginID, version string, opts plugins.AddOpts) (*plugins.InstallPlan, error) {
+	if err := m.checkInstallAllowed(pluginID); err != nil {
+		return nil, err
+	}
+
+	plugin, exists := m.plugin(ctx, pluginID)
+	if !exists {
+		return nil, plugins.ErrPluginNotInstalled
+	}
+
+	return m.update(ctx, plugin, version, opts)
+}
+
+// update removes the existing installation of the plugin and installs the requested version instead
+func (m *PluginManager) update(ctx context.Context, plugin *plugins.Plugin, version string, opts plugins.AddOpts) (*plugins.InstallPlan, error) {
+	if !plugin.IsExternalPlugin() {
+		return nil, plugins.ErrInstallCorePlugin
+	}
+
+	if err := m.checkPinned(ctx, plugin.ID, version, opts); err != nil {
+		return nil, err
+	}
+
+	if plugin.Info.Version == version {
+		return nil, plugins.DuplicateError{
+			PluginID:          plugin.ID,
+			ExistingPluginDir: plugin.PluginDir,
+		}
+	}
+
+	// get plugin update information to confirm if upgrading is possible
+	var pluginZipURL, checksum string
+	if gitRef, isGitRef := parseGitRef(version); isGitRef {
+		zipURL, err := m.gitRefArchiveURL(ctx, plugin.ID, gitRef, opts)
+		if err != nil {
+			return nil, err
+		}
+		pluginZipURL, checksum = zipURL, opts.Checksum
+	} else {
+		err := m.withRepository(opts, func(repoURL string) error {
+			updateInfo, err := m.updateInfo(ctx, plugin.ID, version, repoURL)
+			if err != nil {
+				return err
+			}
+			pluginZipURL, checksum = updateInfo.PluginZipURL, updateInfo.SHA256
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan := newInstallPlan(opts)
+	if opts.DryRun {
+		plan.Remove = append(plan.Remove, plugin.ID)
+	} else {
+		// remove existing installation of plugin, plugins depending on it will use the updated version
+		err := m.remove(ctx, plugin, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m.install(ctx, plugin.ID, version, pluginZipURL, checksum, opts, plan, true)
+}
+
+// newInstallPlan returns an empty plan for dry runs, otherwise nil
+func newInstallPlan(opts plugins.AddOpts) *plugins.InstallPlan {
+	if !opts.DryRun {
+		return nil
+	}
+
+	return &plugins.InstallPlan{
+		Download: []plugins.PluginArchiveInfo{},
+		Remove:   []string{},
+		Load:     []string{},
+	}
+}
+
+// gitRefVersionPrefix marks versions that refer to a branch, tag or commit of the plugin's source
+// repository instead of a released version, for example gitref:feature-x.
+const gitRefVersionPrefix = "gitref:"
+
+// parseGitRef returns the git ref of a version using the gitRefVersionPrefix
+func parseGitRef(version string) (string, bool) {
+	if !strings.HasPrefix(version, gitRefVersionPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(version, gitRefVersionPrefix), true
+}
+
+// gitRefArchiveURL resolves the URL of the plugin's source archive at the git ref
+func (m *PluginManager) gitRefArchiveURL(ctx context.Context, pluginID, gitRef string, opts plugins.AddOpts) (string, error) {
+	if gitRef == "" {
+		return "", fmt.Errorf("missing git ref in version %q", gitRefVersionPrefix)
+	}
+
+	var zipURL string
+	err := m.withRepository(opts, func(repoURL string) error {
+		archive, err := m.pluginInstaller.GetPluginArchiveByGitRef(ctx, pluginID, gitRef, repoURL)
 		if err != nil {
 			return err
 		}
+		zipURL = archive.PluginZipURL
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
 
-		pluginZipURL = updateInfo.PluginZipURL
+	// the archive is downloaded from wherever the plugin's source is hosted, not the plugin repository
+	if err := m.checkURLInstallAllowed(zipURL); err != nil {
+		return "", err
+	}
+
+	return zipURL, nil
+}
 
-		// remove existing installation of plugin
-		err = m.Remove(ctx, plugin.ID)
+// install downloads and loads the plugin, or only fills in the plan when opts.DryRun is set.
+// The archive at pluginZipURL is verified against checksum, archives resolved from the plugin
+// repository against the checksum published by the repository.
+func (m *PluginManager) install(ctx context.Context, pluginID, version, pluginZipURL, checksum string, opts plugins.AddOpts, plan *plugins.InstallPlan, isUpdate bool) (*plugins.InstallPlan, error) {
+	if gitRef, isGitRef := parseGitRef(version); isGitRef && pluginZipURL == "" {
+		zipURL, err := m.gitRefArchiveURL(ctx, pluginID, gitRef, opts)
 		if err != nil {
+			return nil, err
+		}
+		pluginZipURL, checksum = zipURL, opts.Checksum
+	}
+
+	if opts.DryRun {
+		var archives []plugins.PluginArchiveInfo
+		err := m.withRepository(opts, func(repoURL string) error {
+			var err error
+			archives, err = m.pluginInstaller.Plan(ctx, pluginID, version, pluginZipURL, checksum, repoURL)
 			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		registered := m.registeredPlugins(ctx)
+		for _, archive := range archives {
+			plan.Download = append(plan.Download, archive)
+			// already registered plugins are skipped by the loader, unless they are removed first
+			if _, exists := registered[archive.PluginID]; !exists || archive.PluginID == pluginID {
+				plan.Load = append(plan.Load, archive.PluginID)
+			}
+		}
+
+		return plan, nil
+	}
+
+	err := m.withRepository(opts, func(repoURL string) error {
+		return m.pluginInstaller.Install(ctx, pluginID, version, m.cfg.PluginsPath, pluginZipURL, checksum, repoURL, opts.Progress)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.invalidateUpdateInfo(pluginID)
+
+	if opts.Progress != nil {
+		opts.Progress(plugins.InstallProgress{Phase: plugins.InstallPhaseLoading, PluginID: pluginID})
+	}
+	err = m.loadPlugins(ctx, plugins.External, m.cfg.PluginsPath)
+	if err != nil {
+		m.removeCancelledInstall(ctx, pluginID)
+		return nil, err
+	}
+
+	// the hook is a one-time setup step, so updates don't run it again
+	var hookErr error
+	if !isUpdate {
+		var rolledBack bool
+		if rolledBack, hookErr = m.runPostInstallHook(ctx, pluginID, opts); rolledBack {
+			return nil, hookErr
 		}
 	}
+	m.publishInstalled(ctx, pluginID, version, isUpdate)
+
+	return nil, hookErr
+}
+
+// AddFromFile installs a plugin from a local zip archive without contacting the plugin repository.
+// All of the plugin's dependencies must already be installed.
+func (m *PluginManager) AddFromFile(ctx context.Context, pluginID, zipPath string) error {
+	if err := m.checkInstallAllowed(pluginID); err != nil {
+		return err
+	}
+
+	if plugin, exists := m.plugin(ctx, pluginID); exists {
+		if !plugin.IsExternalPlugin() {
+			return plugins.ErrInstallCorePlugin
+		}
 
-	err := m.pluginInstaller.Install(ctx, pluginID, version, m.cfg.PluginsPath, pluginZipURL, grafanaComURL)
+		return plugins.DuplicateError{
+			PluginID:          plugin.ID,
+			ExistingPluginDir: plugin.PluginDir,
+		}
+	}
+
+	err := m.pluginInstaller.InstallFromFile(ctx, pluginID, zipPath, m.cfg.PluginsPath)
 	if err != nil {
 		return err
 	}
+	m.invalidateUpdateInfo(pluginID)
 
-	err = m.loadPlugins(context.Background(), plugins.External, m.cfg.PluginsPath)
+	err = m.loadPlugins(ctx, plugins.External, m.cfg.PluginsPath)
 	if err != nil {
+		m.removeCancelledInstall(ctx, pluginID)
 		return err
 	}
+	m.publishInstalled(ctx, pluginID, "", false)
 
 	return nil
 }
 
+// removeCancelledInstall removes the files of a plugin that was installed but not loaded because ctx was
+// cancelled, so that a cancelled install doesn't leave a plugin behind that is only picked up on restart.
+func (m *PluginManager) removeCancelledInstall(ctx context.Context, pluginID string) {
+	if ctx.Err() == nil {
+		return
+	}
+
+	pluginDir := filepath.Join(m.cfg.PluginsPath, pluginID)
+	if err := m.pluginInstaller.Uninstall(context.Background(), pluginDir); err != nil {
+		m.log.Warn("Failed to remove plugin after cancelled install", "pluginId", pluginID, "err", err)
+	}
+}
+
 func (m *PluginManager) Remove(ctx context.Context, pluginID string) error {
+	return m.RemoveWithOpts(ctx, pluginID, plugins.RemoveOpts{})
+}
+
+// RemoveWithOpts removes a plugin. Unless opts.Force is set, it fails with plugins.ErrPluginHasDependents
+// if other installed plugins depend on the plugin, and with plugins.ErrPluginManagedExternally if the plugin
+// is provisioned, as it would be loaded again on restart. If configured, it also fails with
+// plugins.ErrPluginInUseByDashboards if dashboards use data sources of the plugin.
+func (m *PluginManager) RemoveWithOpts(ctx context.Context, pluginID string, opts plugins.RemoveOpts) error {
 	plugin, exists := m.plugin(ctx, pluginID)
 	if !exists {
 		return plugins.ErrPluginNotInstalled
 	}
 
+	return m.removeWithOpts(ctx, plugin, opts)
+}
+
+// RemoveVersion removes a plugin like Remove, but only if it's installed in expectedVersion. Otherwise it fails
+// with plugins.ErrPluginVersionMismatch, so that a plugin another actor updated in the meantime isn't removed.
+func (m *PluginManager) RemoveVersion(ctx context.Context, pluginID, expectedVersion string) error {
+	plugin, exists := m.plugin(ctx, pluginID)
+	if !exists {
+		return plugins.ErrPluginNotInstalled
+	}
+
+	if plugin.Info.Version != expectedVersion {
+		return fmt.Errorf("%w: %s is installed in version %s, expected %s", plugins.ErrPluginVersionMismatch,
+			pluginID, plugin.Info.Version, expectedVersion)
+	}
+
+	// the checked plugin is removed, even if the plugin was replaced in the registry since
+	return m.removeWithOpts(ctx, plugin, plugins.RemoveOpts{})
+}
+
+// removeWithOpts checks whether the plugin may be removed according to opts and removes it
+func (m *PluginManager) removeWithOpts(ctx context.Context, plugin *plugins.Plugin, opts plugins.RemoveOpts) error {
+	if err := m.canRemove(plugin); err != nil {
+		return err
+	}
+
+	if !opts.Force {
+		if plugin.Provisioned {
+			return plugins.ErrPluginManagedExternally
+		}
+		if dependents := m.dependents(ctx, plugin.ID); len(dependents) > 0 {
+			return plugins.ErrPluginHasDependents{PluginID: plugin.ID, Dependents: dependents}
+		}
+		if err := m.checkDashboardUsage(ctx, plugin); err != nil {
+			return err
+		}
+	}
+
+	return m.remove(ctx, plugin, false)
+}
+
+// checkDashboardUsage fails with plugins.ErrPluginInUseByDashboards if the plugin is a data source plugin that
+// dashboards use data sources of. Dashboards are only checked if configured.
+func (m *PluginManager) checkDashboardUsage(ctx context.Context, plugin *plugins.Plugin) error {
+	if !m.cfg.PluginUninstallCheckDashboards || !plugin.IsDataSource() || m.dashboardUsageFinder == nil {
+		return nil
+	}
+
+	dashboards, err := m.dashboardUsageFinder.DashboardsUsingDatasourcePlugin(ctx, plugin.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check which dashboards use %s: %w", plugin.ID, err)
+	}
+	if len(dashboards) > 0 {
+		return plugins.ErrPluginInUseByDashboards{PluginID: plugin.ID, Dashboards: dashboards}
+	}
+
+	return nil
+}
+
+// RemoveWithDependents removes a plugin along with every installed plugin that depends on it, directly
+// or transitively. Dependents are removed before the plugins they depend on. It fails with
+// plugins.ErrPluginManagedExternally without removing anything if any of them is provisioned, and with
+// plugins.ErrPluginInUseByDashboards if dashboards use data sources of any of them.
+func (m *PluginManager) RemoveWithDependents(ctx context.Context, pluginID string) error {
+	plugin, exists := m.plugin(ctx, pluginID)
+	if !exists {
+		return plugins.ErrPluginNotInstalled
+	}
+
+	var order []*plugins.Plugin
+	visited := map[string]struct{}{}
+	var visit func(p *plugins.Plugin)
+	visit = func(p *plugins.Plugin) {
+		if _, seen := visited[p.ID]; seen {
+			return
+		}
+		visited[p.ID] = struct{}{}
+		for _, dependentID := range m.dependents(ctx, p.ID) {
+			if dependent, exists := m.plugin(ctx, dependentID); exists {
+				visit(dependent)
+			}
+		}
+		order = append(order, p)
+	}
+	visit(plugin)
+
+	// verify all plugins can be removed before removing any of them
+	for _, p := range order {
+		if err := m.canRemove(p); err != nil {
+			return err
+		}
+		if p.Provisioned {
+			return plugins.ErrPluginManagedExternally
+		}
+		if err := m.checkDashboardUsage(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range order {
+		if err := m.remove(ctx, p, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Dependencies returns the IDs of the plugins the installed plugin declares a dependency on, and the IDs
+// of the installed plugins that declare a dependency on it, both sorted.
+func (m *PluginManager) Dependencies(ctx context.Context, pluginID string) ([]string, []string, error) {
+	plugin, exists := m.plugin(ctx, pluginID)
+	if !exists {
+		return nil, nil, plugins.ErrPluginNotInstalled
+	}
+
+	requires := make([]string, 0, len(plugin.Dependencies.Plugins))
+	seen := map[string]struct{}{}
+	for _, dep := range plugin.Dependencies.Plugins {
+		if _, exists := seen[dep.ID]; exists {
+			continue
+		}
+		seen[dep.ID] = struct{}{}
+		requires = append(requires, dep.ID)
+	}
+	sort.Strings(requires)
+
+	requiredBy := m.dependents(ctx, pluginID)
+	if requiredBy == nil {
+		requiredBy = []string{}
+	}
+
+	return requires, requiredBy, nil
+}
+
+// dependents returns the IDs of the installed plugins that declare a dependency on pluginID
+func (m *PluginManager) dependents(ctx context.Context, pluginID string) []string {
+	var dependents []string
+	for _, p := range m.availablePlugins(ctx) {
+		for _, dep := range p.Dependencies.Plugins {
+			if dep.ID == pluginID {
+				dependents = append(dependents, p.ID)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+
+	return dependents
+}
+
+func (m *PluginManager) canRemove(plugin *plugins.Plugin) error {
 	if !plugin.IsExternalPlugin() {
 		return plugins.ErrUninstallCorePlugin
 	}
@@ -127,9 +643,62 @@ func (m *PluginManager) Remove(ctx context.Context, pluginID string) error {
 		return plugins.ErrUninstallOutsideOfPluginDir
 	}
 
+	return nil
+}
+
+// remove stops and uninstalls the plugin. isUpdate is reported in the published event, so listeners
+// can tell a plugin being replaced by another version apart from one being removed.
+func (m *PluginManager) remove(ctx context.Context, plugin *plugins.Plugin, isUpdate bool) error {
+	if err := m.canRemove(plugin); err != nil {
+		return err
+	}
+
 	if err := m.unregisterAndStop(ctx, plugin); err != nil {
 		return err
 	}
 
-	return m.pluginInstaller.Uninstall(ctx, plugin.PluginDir)
+	if err := m.pluginInstaller.Uninstall(ctx, plugin.PluginDir); err != nil {
+		return err
+	}
+	m.invalidateUpdateInfo(plugin.ID)
+
+	m.publish(ctx, &events.PluginUninstalledEvent{
+		Timestamp: time.Now(),
+		PluginID:  plugin.ID,
+		Version:   plugin.Info.Version,
+		IsUpdate:  isUpdate,
+	})
+
+	return nil
+}
+
+// publishInstalled publishes a PluginInstalledEvent with the version that was loaded, which can
+// differ from the requested one when no specific version was requested.
+func (m *PluginManager) publishInstalled(ctx context.Context, pluginID, version string, isUpdate bool) {
+	if p, exists := m.plugin(ctx, pluginID); exists {
+		version = p.Info.Version
+	}
+
+	m.publish(ctx, &events.PluginInstalledEvent{
+		Timestamp: time.Now(),
+		PluginID:  pluginID,
+		Version:   version,
+		IsUpdate:  isUpdate,
+	})
+}
+
+// publish publishes a plugin lifecycle event if a bus is wired, and sends it to the subscribers of Watch.
+// Failing to publish doesn't fail the change that was already made.
+func (m *PluginManager) publish(ctx context.Context, msg bus.Msg) {
+	if event, ok := changeEvent(msg); ok {
+		m.notifyWatchers(event)
+	}
+
+	if m.bus == nil {
+		return
+	}
+
+	if err := m.bus.Publish(ctx, msg); err != nil {
+		m.log.Error("Failed to publish plugin event", "event", msg, "err", err)
+	}
 }
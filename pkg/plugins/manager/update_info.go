@@ -0,0 +1,192 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// updateInfoCacheTTL is how long plugin update information fetched from the plugin repository is reused
+const updateInfoCacheTTL = 5 * time.Minute
+
+func newUpdateInfoCache() *localcache.CacheService {
+	return localcache.New(updateInfoCacheTTL, 2*updateInfoCacheTTL)
+}
+
+// DisableUpdateInfoCache makes the manager query the plugin repository for every update, which is
+// useful for tests.
+func (m *PluginManager) DisableUpdateInfoCache() {
+	m.updateInfoCache = nil
+}
+
+// updateInfo returns the update information of the requested plugin version from the plugin repository.
+// Results are cached per plugin, version, Grafana version and repository since the compatible archive
+// depends on all of them.
+func (m *PluginManager) updateInfo(ctx context.Context, pluginID, version, repoURL string) (plugins.UpdateInfo, error) {
+	if m.updateInfoCache == nil {
+		return m.pluginInstaller.GetUpdateInfo(ctx, pluginID, version, repoURL)
+	}
+
+	key := updateInfoCacheKey(pluginID, version, m.cfg.BuildVersion, repoURL)
+	if cached, found := m.updateInfoCache.Get(key); found {
+		return cached.(plugins.UpdateInfo), nil
+	}
+
+	updateInfo, err := m.pluginInstaller.GetUpdateInfo(ctx, pluginID, version, repoURL)
+	if err != nil {
+		return plugins.UpdateInfo{}, err
+	}
+	m.updateInfoCache.SetDefault(key, updateInfo)
+
+	return updateInfo, nil
+}
+
+// ListUpdatable returns the external plugins for which the plugin repository has a newer version that
+// supports this system, sorted by plugin ID. Plugins that can't be resolved in the repository, such as
+// private plugins, or whose versions aren't semver are skipped.
+func (m *PluginManager) ListUpdatable(ctx context.Context) ([]plugins.PluginUpdate, error) {
+	versions := m.InstalledVersions(ctx)
+	pluginIDs := make([]string, 0, len(versions))
+	for pluginID := range versions {
+		pluginIDs = append(pluginIDs, pluginID)
+	}
+	sort.Strings(pluginIDs)
+
+	updates := make([]plugins.PluginUpdate, 0)
+	for _, pluginID := range pluginIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		current, err := semver.NewVersion(versions[pluginID])
+		if err != nil {
+			m.log.Debug("Skipping update check of plugin with invalid version", "pluginId", pluginID, "version", versions[pluginID])
+			continue
+		}
+
+		updateInfo, err := m.updateInfo(ctx, pluginID, "", grafanaComURL)
+		if err != nil {
+			m.log.Warn("Failed to check plugin for updates", "pluginId", pluginID, "err", err)
+			continue
+		}
+
+		latest, err := semver.NewVersion(updateInfo.Version)
+		if err != nil {
+			m.log.Debug("Skipping update check of plugin with invalid latest version", "pluginId", pluginID, "version", updateInfo.Version)
+			continue
+		}
+
+		if latest.GreaterThan(current) {
+			updates = append(updates, plugins.PluginUpdate{
+				PluginID:         pluginID,
+				CurrentVersion:   versions[pluginID],
+				AvailableVersion: updateInfo.Version,
+			})
+		}
+	}
+
+	return updates, nil
+}
+
+// UpdateInfo returns what updating the installed plugin to targetVersion would change, along with the release
+// notes of every version after the installed one up to the one it resolves to. An empty targetVersion resolves
+// to the latest version that supports this system. Plugins that don't publish release notes have empty notes,
+// which isn't an error. If the installed version isn't semver, only the notes of the resolved version are returned.
+func (m *PluginManager) UpdateInfo(ctx context.Context, pluginID, targetVersion string, opts plugins.AddOpts) (plugins.UpdateInfo, error) {
+	plugin, exists := m.plugin(ctx, pluginID)
+	if !exists {
+		return plugins.UpdateInfo{}, plugins.ErrPluginNotInstalled
+	}
+	if !plugin.IsExternalPlugin() {
+		return plugins.UpdateInfo{}, plugins.ErrInstallCorePlugin
+	}
+
+	var updateInfo plugins.UpdateInfo
+	var releaseNotes []plugins.ReleaseNote
+	err := m.withRepository(opts, func(repoURL string) error {
+		var err error
+		updateInfo, err = m.updateInfo(ctx, pluginID, targetVersion, repoURL)
+		if err != nil {
+			return err
+		}
+		releaseNotes, err = m.pluginInstaller.GetReleaseNotes(ctx, pluginID, repoURL)
+		return err
+	})
+	if err != nil {
+		return plugins.UpdateInfo{}, err
+	}
+
+	updateInfo.CurrentVersion = plugin.Info.Version
+	updateInfo.ReleaseNotes = releaseNotesBetween(releaseNotes, plugin.Info.Version, updateInfo.Version)
+
+	return updateInfo, nil
+}
+
+// releaseNotesBetween returns the release notes of the versions after current up to target, newest first
+func releaseNotesBetween(releaseNotes []plugins.ReleaseNote, current, target string) []plugins.ReleaseNote {
+	res := make([]plugins.ReleaseNote, 0)
+	from, fromErr := semver.NewVersion(current)
+	to, toErr := semver.NewVersion(target)
+	if fromErr != nil || toErr != nil {
+		for _, note := range releaseNotes {
+			if note.Version == target {
+				res = append(res, note)
+			}
+		}
+		return res
+	}
+
+	var versions []*semver.Version
+	for _, note := range releaseNotes {
+		v, err := semver.NewVersion(note.Version)
+		if err != nil || !v.GreaterThan(from) || v.GreaterThan(to) {
+			continue
+		}
+		versions = append(versions, v)
+		res = append(res, note)
+	}
+
+	// the plugin repository doesn't guarantee any order
+	sort.Sort(releaseNotesByVersion{notes: res, versions: versions})
+
+	return res
+}
+
+// releaseNotesByVersion sorts release notes by their parsed versions, newest first
+type releaseNotesByVersion struct {
+	notes    []plugins.ReleaseNote
+	versions []*semver.Version
+}
+
+func (r releaseNotesByVersion) Len() int { return len(r.notes) }
+
+func (r releaseNotesByVersion) Less(i, j int) bool { return r.versions[i].GreaterThan(r.versions[j]) }
+
+func (r releaseNotesByVersion) Swap(i, j int) {
+	r.notes[i], r.notes[j] = r.notes[j], r.notes[i]
+	r.versions[i], r.versions[j] = r.versions[j], r.versions[i]
+}
+
+// invalidateUpdateInfo drops the cached update information of all versions of the plugin
+func (m *PluginManager) invalidateUpdateInfo(pluginID string) {
+	if m.updateInfoCache == nil {
+		return
+	}
+
+	prefix := pluginID + "|"
+	for key := range m.updateInfoCache.Items() {
+		if strings.HasPrefix(key, prefix) {
+			m.updateInfoCache.Delete(key)
+		}
+	}
+}
+
+func updateInfoCacheKey(pluginID, version, grafanaVersion, repoURL string) string {
+	return strings.Join([]string{pluginID, version, grafanaVersion, repoURL}, "|")
+}
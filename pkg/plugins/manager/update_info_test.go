@@ -0,0 +1,222 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_UpdateInfoCache(t *testing.T) {
+	setup := func(t *testing.T) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true, func(p *plugins.Plugin) {
+			p.PluginDir = testPluginID
+		})
+
+		i := &fakePluginInstaller{}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.cfg.BuildVersion = "9.0.0"
+			pm.pluginInstaller = i
+			pm.pluginRegistry = &fakePluginRegistry{
+				store: map[string]*plugins.Plugin{p.ID: p},
+			}
+		})
+
+		return pm, i
+	}
+
+	t.Run("Reuses update info of the same plugin version", func(t *testing.T) {
+		pm, i := setup(t)
+
+		for j := 0; j < 3; j++ {
+			_, err := pm.updateInfo(context.Background(), testPluginID, "1.1.0", grafanaComURL)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, i.updateInfoCount)
+
+		_, err := pm.updateInfo(context.Background(), testPluginID, "1.2.0", grafanaComURL)
+		require.NoError(t, err)
+		assert.Equal(t, 2, i.updateInfoCount)
+	})
+
+	t.Run("Update info depends on the Grafana version", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.updateInfo(context.Background(), testPluginID, "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+		pm.cfg.BuildVersion = "9.1.0"
+		_, err = pm.updateInfo(context.Background(), testPluginID, "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+		assert.Equal(t, 2, i.updateInfoCount)
+	})
+
+	t.Run("Removing the plugin invalidates its update info", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.updateInfo(context.Background(), testPluginID, "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+		_, err = pm.updateInfo(context.Background(), "other-plugin", "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+
+		err = pm.Remove(context.Background(), testPluginID)
+		require.NoError(t, err)
+
+		_, err = pm.updateInfo(context.Background(), testPluginID, "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+		_, err = pm.updateInfo(context.Background(), "other-plugin", "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+		assert.Equal(t, 3, i.updateInfoCount)
+	})
+
+	t.Run("Installing the plugin invalidates its update info", func(t *testing.T) {
+		pm, i := setup(t)
+
+		_, err := pm.updateInfo(context.Background(), "other-plugin", "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+
+		err = pm.Add(context.Background(), "other-plugin", "1.1.0")
+		require.NoError(t, err)
+
+		_, err = pm.updateInfo(context.Background(), "other-plugin", "1.1.0", grafanaComURL)
+		require.NoError(t, err)
+		assert.Equal(t, 2, i.updateInfoCount)
+	})
+
+	t.Run("Doesn't cache update info when disabled", func(t *testing.T) {
+		pm, i := setup(t)
+		pm.DisableUpdateInfoCache()
+
+		for j := 0; j < 3; j++ {
+			_, err := pm.updateInfo(context.Background(), testPluginID, "1.1.0", grafanaComURL)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 3, i.updateInfoCount)
+	})
+}
+
+func TestPluginManager_ListUpdatable(t *testing.T) {
+	outdated, _ := createPlugin(t, "outdated-plugin", "1.0.0", plugins.External, true, true)
+	upToDate, _ := createPlugin(t, "up-to-date-plugin", "2.0.0", plugins.External, true, true)
+	private, _ := createPlugin(t, "private-plugin", "1.0.0", plugins.External, true, true)
+	invalid, _ := createPlugin(t, "invalid-version-plugin", "latest", plugins.External, true, true)
+	core, _ := createPlugin(t, "core-plugin", "1.0.0", plugins.Core, true, true)
+
+	i := &fakePluginInstaller{latestVersions: map[string]string{
+		outdated.ID: "1.2.0",
+		upToDate.ID: "2.0.0",
+		invalid.ID:  "1.0.0",
+		core.ID:     "2.0.0",
+	}}
+	pm := createManager(t, func(pm *PluginManager) {
+		pm.pluginInstaller = i
+		pm.pluginRegistry = &fakePluginRegistry{
+			store: map[string]*plugins.Plugin{
+				outdated.ID: outdated,
+				upToDate.ID: upToDate,
+				private.ID:  private,
+				invalid.ID:  invalid,
+				core.ID:     core,
+			},
+		}
+	})
+
+	updates, err := pm.ListUpdatable(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []plugins.PluginUpdate{
+		{PluginID: "outdated-plugin", CurrentVersion: "1.0.0", AvailableVersion: "1.2.0"},
+	}, updates)
+
+	t.Run("Reuses cached update info", func(t *testing.T) {
+		count := i.updateInfoCount
+		_, err := pm.ListUpdatable(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, count, i.updateInfoCount)
+	})
+}
+
+func TestPluginManager_UpdateInfo(t *testing.T) {
+	setup := func(t *testing.T, installedVersion string, releaseNotes []plugins.ReleaseNote) (*PluginManager, *fakePluginInstaller) {
+		t.Helper()
+		p, _ := createPlugin(t, testPluginID, installedVersion, plugins.External, true, true)
+
+		i := &fakePluginInstaller{
+			latestVersions: map[string]string{testPluginID: "1.3.0"},
+			releaseNotes:   map[string][]plugins.ReleaseNote{testPluginID: releaseNotes},
+		}
+		pm := createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = i
+			pm.pluginRegistry = &fakePluginRegistry{
+				store: map[string]*plugins.Plugin{p.ID: p},
+			}
+		})
+
+		return pm, i
+	}
+
+	releaseNotes := []plugins.ReleaseNote{
+		{Version: "1.0.0", Notes: "Initial release"},
+		{Version: "1.3.0", Notes: "Adds annotations"},
+		{Version: "1.1.0", Notes: "Fixes a crash"},
+		{Version: "1.2.0", Notes: ""},
+	}
+
+	t.Run("Returns the release notes since the installed version, newest first", func(t *testing.T) {
+		pm, _ := setup(t, "1.0.0", releaseNotes)
+
+		updateInfo, err := pm.UpdateInfo(context.Background(), testPluginID, "", plugins.AddOpts{})
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", updateInfo.CurrentVersion)
+		assert.Equal(t, "1.3.0", updateInfo.Version)
+		assert.Equal(t, []plugins.ReleaseNote{
+			{Version: "1.3.0", Notes: "Adds annotations"},
+			{Version: "1.2.0", Notes: ""},
+			{Version: "1.1.0", Notes: "Fixes a crash"},
+		}, updateInfo.ReleaseNotes)
+	})
+
+	t.Run("Stops at the target version", func(t *testing.T) {
+		pm, _ := setup(t, "1.0.0", releaseNotes)
+
+		updateInfo, err := pm.UpdateInfo(context.Background(), testPluginID, "1.1.0", plugins.AddOpts{})
+		require.NoError(t, err)
+		assert.Equal(t, "1.1.0", updateInfo.Version)
+		assert.Equal(t, []plugins.ReleaseNote{{Version: "1.1.0", Notes: "Fixes a crash"}}, updateInfo.ReleaseNotes)
+	})
+
+	t.Run("Returns empty notes for plugins without published release notes", func(t *testing.T) {
+		pm, _ := setup(t, "1.0.0", nil)
+
+		updateInfo, err := pm.UpdateInfo(context.Background(), testPluginID, "", plugins.AddOpts{})
+		require.NoError(t, err)
+		assert.Equal(t, "1.3.0", updateInfo.Version)
+		assert.NotNil(t, updateInfo.ReleaseNotes)
+		assert.Empty(t, updateInfo.ReleaseNotes)
+	})
+
+	t.Run("Returns the notes of the target version if the installed version isn't semver", func(t *testing.T) {
+		pm, _ := setup(t, "nightly", releaseNotes)
+
+		updateInfo, err := pm.UpdateInfo(context.Background(), testPluginID, "", plugins.AddOpts{})
+		require.NoError(t, err)
+		assert.Equal(t, []plugins.ReleaseNote{{Version: "1.3.0", Notes: "Adds annotations"}}, updateInfo.ReleaseNotes)
+	})
+
+	t.Run("Uses the requested repository", func(t *testing.T) {
+		pm, i := setup(t, "1.0.0", releaseNotes)
+
+		_, err := pm.UpdateInfo(context.Background(), testPluginID, "", plugins.AddOpts{RepositoryURL: "https://plugins.example.com/api/plugins"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"https://plugins.example.com/api/plugins", "https://plugins.example.com/api/plugins"}, i.repoURLs)
+	})
+
+	t.Run("Fails for plugins that aren't installed", func(t *testing.T) {
+		pm, _ := setup(t, "1.0.0", releaseNotes)
+
+		_, err := pm.UpdateInfo(context.Background(), "unknown-datasource", "", plugins.AddOpts{})
+		require.ErrorIs(t, err, plugins.ErrPluginNotInstalled)
+	})
+}
@@ -0,0 +1,78 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// watchBufferSize is how many changes a subscriber can fall behind before changes are dropped for it
+const watchBufferSize = 64
+
+// Watch returns a channel that receives every plugin that is installed, removed, decommissioned or recommissioned,
+// until ctx is cancelled, after which the channel is closed. Every call subscribes a new channel. Changes are
+// dropped for subscribers that fall more than watchBufferSize changes behind, so a slow subscriber can't hold back
+// the plugin manager.
+func (m *PluginManager) Watch(ctx context.Context) <-chan plugins.PluginChangeEvent {
+	ch := make(chan plugins.PluginChangeEvent, watchBufferSize)
+
+	m.watchersMu.Lock()
+	if m.watchers == nil {
+		m.watchers = make(map[chan plugins.PluginChangeEvent]struct{})
+	}
+	m.watchers[ch] = struct{}{}
+	m.watchersMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		// changes are only sent while holding the lock, so none is sent on the closed channel
+		m.watchersMu.Lock()
+		defer m.watchersMu.Unlock()
+		delete(m.watchers, ch)
+		close(ch)
+	}()
+
+	return ch
+}
+
+// notifyWatchers sends the change to every subscriber of Watch without blocking
+func (m *PluginManager) notifyWatchers(event plugins.PluginChangeEvent) {
+	m.watchersMu.Lock()
+	defer m.watchersMu.Unlock()
+
+	for ch := range m.watchers {
+		select {
+		case ch <- event:
+		default:
+			m.log.Warn("Dropping plugin change for a subscriber that isn't keeping up", "pluginId", event.PluginID,
+				"change", event.Type)
+		}
+	}
+}
+
+// changeEvent returns the change a plugin lifecycle event published on the bus describes
+func changeEvent(msg bus.Msg) (plugins.PluginChangeEvent, bool) {
+	switch e := msg.(type) {
+	case *events.PluginInstalledEvent:
+		return plugins.PluginChangeEvent{
+			Type:      plugins.PluginChangeInstalled,
+			PluginID:  e.PluginID,
+			Version:   e.Version,
+			IsUpdate:  e.IsUpdate,
+			Timestamp: e.Timestamp,
+		}, true
+	case *events.PluginUninstalledEvent:
+		return plugins.PluginChangeEvent{
+			Type:      plugins.PluginChangeRemoved,
+			PluginID:  e.PluginID,
+			Version:   e.Version,
+			IsUpdate:  e.IsUpdate,
+			Timestamp: e.Timestamp,
+		}, true
+	default:
+		return plugins.PluginChangeEvent{}, false
+	}
+}
@@ -0,0 +1,112 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+func TestPluginManager_Watch(t *testing.T) {
+	setup := func(t *testing.T) *PluginManager {
+		t.Helper()
+		p, _ := createPlugin(t, testPluginID, "1.0.0", plugins.External, true, true)
+		return createManager(t, func(pm *PluginManager) {
+			pm.pluginInstaller = &fakePluginInstaller{}
+			pm.pluginLoader = &fakeLoader{mockedLoadedPlugins: []*plugins.Plugin{p}}
+		})
+	}
+
+	receive := func(t *testing.T, ch <-chan plugins.PluginChangeEvent) plugins.PluginChangeEvent {
+		t.Helper()
+		select {
+		case event := <-ch:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no plugin change received")
+			return plugins.PluginChangeEvent{}
+		}
+	}
+
+	t.Run("Installing a plugin notifies every subscriber", func(t *testing.T) {
+		pm := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		first := pm.Watch(ctx)
+		second := pm.Watch(ctx)
+
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		for _, ch := range []<-chan plugins.PluginChangeEvent{first, second} {
+			event := receive(t, ch)
+			require.Equal(t, plugins.PluginChangeInstalled, event.Type)
+			require.Equal(t, testPluginID, event.PluginID)
+			require.Equal(t, "1.0.0", event.Version)
+			require.False(t, event.IsUpdate)
+		}
+	})
+
+	t.Run("Decommissioning and removing a plugin notifies subscribers", func(t *testing.T) {
+		pm := setup(t)
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := pm.Watch(ctx)
+
+		err = pm.Decommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.Equal(t, plugins.PluginChangeDecommissioned, receive(t, ch).Type)
+
+		err = pm.Recommission(context.Background(), testPluginID)
+		require.NoError(t, err)
+		require.Equal(t, plugins.PluginChangeRecommissioned, receive(t, ch).Type)
+
+		err = pm.Remove(context.Background(), testPluginID)
+		require.NoError(t, err)
+		event := receive(t, ch)
+		require.Equal(t, plugins.PluginChangeRemoved, event.Type)
+		require.Equal(t, testPluginID, event.PluginID)
+	})
+
+	t.Run("Cancelling the context closes the channel and unsubscribes", func(t *testing.T) {
+		pm := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := pm.Watch(ctx)
+		other := pm.Watch(context.Background())
+
+		cancel()
+		select {
+		case _, open := <-ch:
+			require.False(t, open)
+		case <-time.After(time.Second):
+			t.Fatal("channel wasn't closed")
+		}
+
+		pm.watchersMu.Lock()
+		require.Len(t, pm.watchers, 1)
+		pm.watchersMu.Unlock()
+
+		// the remaining subscriber still gets notified
+		err := pm.Add(context.Background(), testPluginID, "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, plugins.PluginChangeInstalled, receive(t, other).Type)
+	})
+
+	t.Run("Slow subscribers don't block changes", func(t *testing.T) {
+		pm := setup(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch := pm.Watch(ctx)
+
+		for i := 0; i < watchBufferSize+1; i++ {
+			pm.notifyWatchers(plugins.PluginChangeEvent{Type: plugins.PluginChangeInstalled, PluginID: testPluginID})
+		}
+		require.Len(t, ch, watchBufferSize)
+	})
+}
@@ -3,6 +3,9 @@ package plugins
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/models"
 )
@@ -14,8 +17,21 @@ const (
 var (
 	ErrInstallCorePlugin           = errors.New("cannot install a Core plugin")
 	ErrUninstallCorePlugin         = errors.New("cannot uninstall a Core plugin")
+	ErrDecommissionCorePlugin      = errors.New("cannot decommission a Core plugin")
 	ErrUninstallOutsideOfPluginDir = errors.New("cannot uninstall a plugin outside")
 	ErrPluginNotInstalled          = errors.New("plugin is not installed")
+	ErrPluginPinned                = errors.New("plugin is pinned to a different version")
+	ErrPluginNoBackendProcess      = errors.New("plugin has no backend process managed by Grafana")
+	ErrPluginChecksumMismatch      = errors.New("plugin archive checksum mismatch")
+	ErrPluginDecommissioned        = errors.New("plugin is decommissioned and cannot be reinstalled")
+	ErrPluginNotFoundInRepo        = errors.New("plugin not found in plugin repository")
+	ErrRepoUnavailable             = errors.New("plugin repository is unavailable")
+	ErrPluginIncompatible          = errors.New("plugin is not compatible with this system")
+	ErrPluginURLNotAllowed         = errors.New("installing plugins from this URL is not allowed")
+	ErrPluginNotAllowed            = errors.New("installing this plugin is not allowed")
+	ErrPluginManagedExternally     = errors.New("plugin is provisioned and managed outside of Grafana")
+	ErrPluginVersionMismatch       = errors.New("plugin is installed in a different version")
+	ErrPostInstallHookNotFound     = errors.New("post-install hook is not registered")
 )
 
 type NotFoundError struct {
@@ -26,6 +42,79 @@ func (e NotFoundError) Error() string {
 	return fmt.Sprintf("plugin with ID '%s' not found", e.PluginID)
 }
 
+// DependencyRequirement is a version requirement a plugin declares on one of its dependencies.
+type DependencyRequirement struct {
+	PluginID string
+	Version  string
+}
+
+// ErrDependencyVersionConflict is returned when the version of a dependency doesn't satisfy the
+// requirements of all plugins depending on it.
+type ErrDependencyVersionConflict struct {
+	DependencyID string
+	Version      string
+	Requirements []DependencyRequirement
+}
+
+func (e ErrDependencyVersionConflict) Error() string {
+	reqs := make([]string, 0, len(e.Requirements))
+	for _, r := range e.Requirements {
+		reqs = append(reqs, fmt.Sprintf("%s requires %s", r.PluginID, r.Version))
+	}
+	return fmt.Sprintf("conflicting requirements for %s v%s: %s", e.DependencyID, e.Version, strings.Join(reqs, ", "))
+}
+
+// ErrPluginHasDependents is returned when removing a plugin that other installed plugins depend on.
+type ErrPluginHasDependents struct {
+	PluginID   string
+	Dependents []string
+}
+
+func (e ErrPluginHasDependents) Error() string {
+	return fmt.Sprintf("plugin %s is required by %s", e.PluginID, strings.Join(e.Dependents, ", "))
+}
+
+// ErrPluginInUseByDashboards is returned when removing a data source plugin that dashboards use data sources of
+type ErrPluginInUseByDashboards struct {
+	PluginID   string
+	Dashboards []DashboardUsage
+}
+
+func (e ErrPluginInUseByDashboards) Error() string {
+	dashboards := make([]string, 0, len(e.Dashboards))
+	for _, d := range e.Dashboards {
+		dashboards = append(dashboards, fmt.Sprintf("%s (%s)", d.Title, d.UID))
+	}
+	return fmt.Sprintf("data sources of plugin %s are used by dashboards %s", e.PluginID, strings.Join(dashboards, ", "))
+}
+
+// DashboardUsage is a dashboard that uses a data source of a plugin
+type DashboardUsage struct {
+	OrgID int64  `json:"orgId"`
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+// PostInstallHookError is returned when the post-install hook of a newly installed plugin fails.
+// RolledBack reports whether the plugin was removed again because of it.
+type PostInstallHookError struct {
+	PluginID   string
+	Hook       string
+	RolledBack bool
+	Err        error
+}
+
+func (e PostInstallHookError) Error() string {
+	if e.RolledBack {
+		return fmt.Sprintf("post-install hook %s of plugin %s failed, the plugin was removed: %v", e.Hook, e.PluginID, e.Err)
+	}
+	return fmt.Sprintf("post-install hook %s of plugin %s failed: %v", e.Hook, e.PluginID, e.Err)
+}
+
+func (e PostInstallHookError) Unwrap() error {
+	return e.Err
+}
+
 type DuplicateError struct {
 	PluginID          string
 	ExistingPluginDir string
@@ -41,6 +130,47 @@ func (e DuplicateError) Is(err error) bool {
 	return ok
 }
 
+// RepositoryError is returned when a plugin can't be resolved or downloaded from the plugin repository.
+// It matches its Kind, one of ErrPluginNotFoundInRepo, ErrRepoUnavailable or ErrPluginIncompatible, with
+// errors.Is and unwraps to the error returned by the repository.
+type RepositoryError struct {
+	Kind error
+	Err  error
+}
+
+func (e RepositoryError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e RepositoryError) Is(err error) bool {
+	// nolint:errorlint
+	return err == e.Kind
+}
+
+func (e RepositoryError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError is returned when plugins fail to load or start. Errors holds the reason of each failure,
+// keyed by the ID of the plugin that failed to start, or by the path whose plugins failed to load.
+type LoadError struct {
+	Errors map[string]error
+}
+
+func (e LoadError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	failures := make([]string, 0, len(keys))
+	for _, key := range keys {
+		failures = append(failures, fmt.Sprintf("%s: %v", key, e.Errors[key]))
+	}
+	return fmt.Sprintf("failed to load plugins: %s", strings.Join(failures, "; "))
+}
+
 type SignatureError struct {
 	PluginID        string          `json:"pluginId"`
 	SignatureStatus SignatureStatus `json:"status"`
@@ -189,6 +319,98 @@ type Signature struct {
 	Files      PluginFiles
 }
 
+// HealthStatus is the outcome of checking the health of a plugin.
+type HealthStatus string
+
+const (
+	HealthStatusOK             HealthStatus = "OK"
+	HealthStatusError          HealthStatus = "ERROR"
+	HealthStatusUnknown        HealthStatus = "UNKNOWN"
+	HealthStatusNotImplemented HealthStatus = "NOT_IMPLEMENTED"
+	HealthStatusTimeout        HealthStatus = "TIMEOUT"
+	// HealthStatusNotApplicable is reported for plugins without a backend
+	HealthStatusNotApplicable HealthStatus = "NOT_APPLICABLE"
+)
+
+// PluginChangeType is the kind of change a PluginChangeEvent describes.
+type PluginChangeType string
+
+const (
+	PluginChangeInstalled      PluginChangeType = "installed"
+	PluginChangeRemoved        PluginChangeType = "removed"
+	PluginChangeDecommissioned PluginChangeType = "decommissioned"
+	PluginChangeRecommissioned PluginChangeType = "recommissioned"
+)
+
+// PluginChangeEvent describes a change to the installed plugins, as sent to the subscribers of the plugin manager.
+type PluginChangeEvent struct {
+	Type     PluginChangeType `json:"type"`
+	PluginID string           `json:"pluginId"`
+	Version  string           `json:"version"`
+	// IsUpdate is set for the removal and installation a plugin is updated with.
+	IsUpdate  bool      `json:"isUpdate"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SignatureResult is the outcome of re-verifying the signature of an installed plugin.
+type SignatureResult struct {
+	PluginID string `json:"pluginId"`
+	// PreviousStatus is the signature status the plugin was loaded with.
+	PreviousStatus SignatureStatus `json:"previousStatus"`
+	Status         SignatureStatus `json:"status"`
+	Type           SignatureType   `json:"type,omitempty"`
+	SigningOrg     string          `json:"signingOrg,omitempty"`
+	// Error is set if the signature could not be verified.
+	Error string `json:"error,omitempty"`
+}
+
+// Changed reports whether the signature status differs from the one the plugin was loaded with.
+func (r SignatureResult) Changed() bool {
+	return r.Status != r.PreviousStatus
+}
+
+// PluginFilter reports whether a plugin should be included in a plugin listing.
+type PluginFilter func(p PluginDTO) bool
+
+// FilterByType includes plugins of any of the provided types. If no types are provided, all plugin types are included.
+func FilterByType(pluginTypes ...Type) PluginFilter {
+	// if no types passed, assume all
+	if len(pluginTypes) == 0 {
+		pluginTypes = PluginTypes
+	}
+
+	var requestedTypes = make(map[Type]struct{})
+	for _, pt := range pluginTypes {
+		requestedTypes[pt] = struct{}{}
+	}
+
+	return func(p PluginDTO) bool {
+		_, exists := requestedTypes[p.Type]
+		return exists
+	}
+}
+
+// FilterBySignature includes plugins with any of the provided signature statuses.
+func FilterBySignature(statuses ...SignatureStatus) PluginFilter {
+	var requestedStatuses = make(map[SignatureStatus]struct{})
+	for _, ss := range statuses {
+		requestedStatuses[ss] = struct{}{}
+	}
+
+	return func(p PluginDTO) bool {
+		_, exists := requestedStatuses[p.Signature]
+		return exists
+	}
+}
+
+// FilterByAuthor includes plugins whose author is the provided author, ignoring case.
+func FilterByAuthor(author string) PluginFilter {
+	author = strings.TrimSpace(author)
+	return func(p PluginDTO) bool {
+		return strings.EqualFold(strings.TrimSpace(p.Info.Author.Name), author)
+	}
+}
+
 type PluginMetaDTO struct {
 	JSONData
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -37,10 +38,20 @@ type Plugin struct {
 	Module  string
 	BaseURL string
 
+	// Filesystem fields, left empty for core plugins
+	InstalledAt time.Time
+	SizeBytes   int64
+
+	// Provisioned is set for plugins loaded from paths configured for them, which are managed outside of Grafana
+	Provisioned bool
+
 	Renderer       pluginextensionv2.RendererPlugin
 	SecretsManager secretsmanagerplugin.SecretsManagerPlugin
 	client         backendplugin.Plugin
 	log            log.Logger
+
+	// decommissioned is only used by plugins without a backend client, which keeps track of it otherwise
+	decommissioned bool
 }
 
 type PluginDTO struct {
@@ -65,6 +76,16 @@ type PluginDTO struct {
 	Module  string
 	BaseURL string
 
+	// Filesystem fields, left empty for core plugins
+	InstalledAt time.Time
+	SizeBytes   int64
+
+	// Provisioned is set for plugins loaded from paths configured for them, which are managed outside of Grafana
+	Provisioned bool
+
+	// Health is the last known health of the plugin's backend. It's only set by the plugin manager.
+	Health HealthStatus
+
 	// temporary
 	backend.StreamHandler
 }
@@ -136,6 +157,9 @@ type JSONData struct {
 
 	// Backend (Datasource + Renderer + SecretsManager)
 	Executable string `json:"executable,omitempty"`
+
+	// PostInstallHook names a hook registered with the plugin manager, which is run once after the plugin is installed
+	PostInstallHook string `json:"postInstallHook,omitempty"`
 }
 
 func (d JSONData) DashboardIncludes() []*Includes {
@@ -224,6 +248,15 @@ func (p *Plugin) Decommission() error {
 	if p.client != nil {
 		return p.client.Decommission()
 	}
+	p.decommissioned = true
+	return nil
+}
+
+func (p *Plugin) Recommission() error {
+	if p.client != nil {
+		return p.client.Recommission()
+	}
+	p.decommissioned = false
 	return nil
 }
 
@@ -231,7 +264,7 @@ func (p *Plugin) IsDecommissioned() bool {
 	if p.client != nil {
 		return p.client.IsDecommissioned()
 	}
-	return false
+	return p.decommissioned
 }
 
 func (p *Plugin) Exited() bool {
@@ -333,6 +366,9 @@ func (p *Plugin) ToDTO() PluginDTO {
 		SignatureError:  p.SignatureError,
 		Module:          p.Module,
 		BaseURL:         p.BaseURL,
+		InstalledAt:     p.InstalledAt,
+		SizeBytes:       p.SizeBytes,
+		Provisioned:     p.Provisioned,
 		StreamHandler:   c,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"path"
 	"time"
 
+	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
 	"github.com/grafana/grafana/pkg/services/queryhistory"
@@ -23,7 +24,7 @@ import (
 
 func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockService,
 	shortURLService shorturls.Service, store sqlstore.Store, queryHistoryService queryhistory.Service,
-	dashboardVersionService dashver.Service, dashSnapSvc dashboardsnapshots.Service) *CleanUpService {
+	dashboardVersionService dashver.Service, dashSnapSvc dashboardsnapshots.Service, dashboardStore dashboards.Store) *CleanUpService {
 	s := &CleanUpService{
 		Cfg:                      cfg,
 		ServerLockService:        serverLockService,
@@ -33,6 +34,7 @@ func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockSe
 		log:                      log.New("cleanup"),
 		dashboardVersionService:  dashboardVersionService,
 		dashboardSnapshotService: dashSnapSvc,
+		dashboardStore:           dashboardStore,
 	}
 	return s
 }
@@ -46,6 +48,7 @@ type CleanUpService struct {
 	QueryHistoryService      queryhistory.Service
 	dashboardVersionService  dashver.Service
 	dashboardSnapshotService dashboardsnapshots.Service
+	dashboardStore           dashboards.Store
 }
 
 func (srv *CleanUpService) Run(ctx context.Context) error {
@@ -65,6 +68,8 @@ func (srv *CleanUpService) Run(ctx context.Context) error {
 			srv.expireOldUserInvites(ctx)
 			srv.deleteStaleShortURLs(ctx)
 			srv.deleteStaleQueryHistory(ctx)
+			srv.deleteExpiredPublicDashboards(ctx)
+			srv.deleteOrphanedPublicDashboards(ctx)
 			err := srv.ServerLockService.LockAndExecute(ctx, "delete old login attempts",
 				time.Minute*10, func(context.Context) {
 					srv.deleteOldLoginAttempts(ctx)
@@ -184,6 +189,25 @@ func (srv *CleanUpService) expireOldUserInvites(ctx context.Context) {
 	}
 }
 
+func (srv *CleanUpService) deleteExpiredPublicDashboards(ctx context.Context) {
+	olderThan := time.Now().Add(-models.PublicDashboardRecoveryWindow)
+	deleted, err := srv.dashboardStore.PurgeDeletedPublicDashboards(ctx, olderThan)
+	if err != nil {
+		srv.log.Error("Problem purging deleted public dashboards", "error", err.Error())
+	} else {
+		srv.log.Debug("Purged deleted public dashboards", "rows affected", deleted)
+	}
+}
+
+func (srv *CleanUpService) deleteOrphanedPublicDashboards(ctx context.Context) {
+	deleted, err := srv.dashboardStore.DeleteOrphanedPublicDashboards(ctx)
+	if err != nil {
+		srv.log.Error("Problem deleting orphaned public dashboards", "error", err.Error())
+	} else {
+		srv.log.Debug("Deleted orphaned public dashboards", "rows affected", deleted)
+	}
+}
+
 func (srv *CleanUpService) deleteStaleShortURLs(ctx context.Context) {
 	cmd := models.DeleteShortUrlCommand{
 		OlderThan: time.Now().Add(-time.Hour * 24 * 7),
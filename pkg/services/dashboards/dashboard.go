@@ -2,6 +2,7 @@ package dashboards
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/models"
@@ -10,27 +11,36 @@ import (
 //go:generate mockery --name DashboardService --structname FakeDashboardService --inpackage --filename dashboard_service_mock.go
 // DashboardService is a service for operating on dashboards.
 type DashboardService interface {
-	BuildPublicDashboardMetricRequest(ctx context.Context, publicDashboardUid string, panelId int64) (dtos.MetricRequest, error)
+	BuildPublicDashboardMetricRequest(ctx context.Context, accessToken string, password string, panelId int64, reqDTO dtos.PublicDashboardQueryDTO, viewer *models.SignedInUser) (dtos.MetricRequest, models.PublicDashboardQueryOptions, error)
 	BuildSaveDashboardCommand(ctx context.Context, dto *SaveDashboardDTO, shouldValidateAlerts bool, validateProvisionedDashboard bool) (*models.SaveDashboardCommand, error)
 	DeleteDashboard(ctx context.Context, dashboardId int64, orgId int64) error
+	DeletePublicDashboardConfig(ctx context.Context, orgId int64, userId int64, dashboardUid string) error
 	FindDashboards(ctx context.Context, query *models.FindPersistedDashboardsQuery) ([]DashboardSearchProjection, error)
 	GetDashboard(ctx context.Context, query *models.GetDashboardQuery) error
 	GetDashboardAclInfoList(ctx context.Context, query *models.GetDashboardAclInfoListQuery) error
 	GetDashboards(ctx context.Context, query *models.GetDashboardsQuery) error
 	GetDashboardTags(ctx context.Context, query *models.GetDashboardTagsQuery) error
 	GetDashboardUIDById(ctx context.Context, query *models.GetDashboardRefByIdQuery) error
-	GetPublicDashboard(ctx context.Context, publicDashboardUid string) (*models.Dashboard, error)
+	GetEnabledPublicDashboardByDashboardUid(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboard, error)
+	GetPublicDashboard(ctx context.Context, accessToken string, password string, viewer *models.SignedInUser) (*models.Dashboard, error)
 	GetPublicDashboardConfig(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboardConfig, error)
+	GetPublicDashboardForRendering(ctx context.Context, accessToken string, password string, viewer *models.SignedInUser) (*models.Dashboard, error)
 	HasAdminPermissionInFolders(ctx context.Context, query *models.HasAdminPermissionInFoldersQuery) error
 	HasEditPermissionInFolders(ctx context.Context, query *models.HasEditPermissionInFoldersQuery) error
 	ImportDashboard(ctx context.Context, dto *SaveDashboardDTO) (*models.Dashboard, error)
 	MakeUserAdmin(ctx context.Context, orgID int64, userID, dashboardID int64, setViewAndEditPermissions bool) error
+	ResolvePublicDashboardUsers(ctx context.Context, pdc *models.PublicDashboardConfig) error
 	SaveDashboard(ctx context.Context, dto *SaveDashboardDTO, allowUiUpdate bool) (*models.Dashboard, error)
 	SavePublicDashboardConfig(ctx context.Context, dto *SavePublicDashboardConfigDTO) (*models.PublicDashboardConfig, error)
 	SearchDashboards(ctx context.Context, query *models.FindPersistedDashboardsQuery) error
 	UpdateDashboardACL(ctx context.Context, uid int64, items []*models.DashboardAcl) error
 }
 
+// PublicDashboardAuditLogger records changes to public dashboards in an audit trail.
+type PublicDashboardAuditLogger interface {
+	Log(ctx context.Context, entry PublicDashboardAuditEntry)
+}
+
 // PluginService is a service for operating on plugin dashboards.
 type PluginService interface {
 	GetDashboardsByPluginID(ctx context.Context, query *models.GetDashboardsByPluginIdQuery) error
@@ -54,6 +64,10 @@ type DashboardProvisioningService interface {
 type Store interface {
 	DeleteDashboard(ctx context.Context, cmd *models.DeleteDashboardCommand) error
 	DeleteOrphanedProvisionedDashboards(ctx context.Context, cmd *models.DeleteOrphanedProvisionedDashboardsCommand) error
+	DeleteOrphanedPublicDashboards(ctx context.Context) (int64, error)
+	DeletePublicDashboardConfig(ctx context.Context, orgId int64, uid string) error
+	DisableAllPublicDashboards(ctx context.Context, orgId int64) (int64, error)
+	ExportPublicDashboardConfig(ctx context.Context, orgId int64, uid string) ([]byte, error)
 	FindDashboards(ctx context.Context, query *models.FindPersistedDashboardsQuery) ([]DashboardSearchProjection, error)
 	GetDashboard(ctx context.Context, query *models.GetDashboardQuery) (*models.Dashboard, error)
 	GetDashboardAclInfoList(ctx context.Context, query *models.GetDashboardAclInfoListQuery) error
@@ -62,22 +76,39 @@ type Store interface {
 	// GetDashboardsByPluginID retrieves dashboards identified by plugin.
 	GetDashboardsByPluginID(ctx context.Context, query *models.GetDashboardsByPluginIdQuery) error
 	GetDashboardTags(ctx context.Context, query *models.GetDashboardTagsQuery) error
+	GetEnabledPublicDashboardByDashboardUid(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboard, error)
+	GetOrphanedPublicDashboards(ctx context.Context) ([]models.PublicDashboard, error)
 	GetProvisionedDashboardData(name string) ([]*models.DashboardProvisioning, error)
 	GetProvisionedDataByDashboardID(dashboardID int64) (*models.DashboardProvisioning, error)
 	GetProvisionedDataByDashboardUID(orgID int64, dashboardUID string) (*models.DashboardProvisioning, error)
 	GetPublicDashboardConfig(orgId int64, dashboardUid string) (*models.PublicDashboardConfig, error)
-	GetPublicDashboard(uid string) (*models.PublicDashboard, *models.Dashboard, error)
+	GetPublicDashboardConfigByAccessToken(ctx context.Context, accessToken string) (*models.PublicDashboardConfig, error)
+	GetPublicDashboardConfigs(ctx context.Context, orgId int64, dashboardUids []string) (map[string]*models.PublicDashboard, error)
+	GetPublicDashboard(accessToken string) (*models.PublicDashboard, *models.Dashboard, error)
+	GetPublicDashboardOrgId(ctx context.Context, accessToken string) (int64, error)
+	GetPublicDashboardQuerySignature(ctx context.Context, orgId int64, dashboardUid string) (string, error)
 	HasAdminPermissionInFolders(ctx context.Context, query *models.HasAdminPermissionInFoldersQuery) error
 	HasEditPermissionInFolders(ctx context.Context, query *models.HasEditPermissionInFoldersQuery) error
+	ImportPublicDashboardConfig(ctx context.Context, orgId int64, payload []byte) (*models.PublicDashboardConfig, error)
+	ListPublicDashboards(ctx context.Context, orgId int64) ([]models.PublicDashboardListItem, error)
+	PatchPublicDashboardConfig(ctx context.Context, orgId int64, uid string, patch models.PublicDashboardPatch) (*models.PublicDashboardConfig, error)
+	PublicDashboardStats(ctx context.Context) ([]models.OrgPublicDashboardStats, error)
+	PurgeDeletedPublicDashboards(ctx context.Context, olderThan time.Time) (int64, error)
+	ResolvePublicDashboardUsers(ctx context.Context, pdc *models.PublicDashboardConfig) error
+	RestorePublicDashboardConfig(ctx context.Context, orgId int64, uid string) (*models.PublicDashboardConfig, error)
+	RotatePublicDashboardAccessToken(ctx context.Context, orgId int64, uid string) (string, error)
 	// SaveAlerts saves dashboard alerts.
 	SaveAlerts(ctx context.Context, dashID int64, alerts []*models.Alert) error
 	SaveDashboard(cmd models.SaveDashboardCommand) (*models.Dashboard, error)
 	SaveProvisionedDashboard(cmd models.SaveDashboardCommand, provisioning *models.DashboardProvisioning) (*models.Dashboard, error)
 	SavePublicDashboardConfig(cmd models.SavePublicDashboardConfigCommand) (*models.PublicDashboardConfig, error)
+	SavePublicDashboardConfigBatch(ctx context.Context, cmds []models.SavePublicDashboardConfigCommand) ([]models.PublicDashboard, []error, error)
+	SetPublicDashboardPassword(ctx context.Context, orgId int64, uid string, password string) error
 	UnprovisionDashboard(ctx context.Context, id int64) error
 	UpdateDashboardACL(ctx context.Context, uid int64, items []*models.DashboardAcl) error
 	// ValidateDashboardBeforeSave validates a dashboard before save.
 	ValidateDashboardBeforeSave(dashboard *models.Dashboard, overwrite bool) (bool, error)
+	VerifyPublicDashboardPassword(ctx context.Context, accessToken string, password string) error
 
 	FolderStore
 }
@@ -18,25 +18,32 @@ type FakeDashboardService struct {
 	mock.Mock
 }
 
-// BuildPublicDashboardMetricRequest provides a mock function with given fields: ctx, publicDashboardUid, panelId
-func (_m *FakeDashboardService) BuildPublicDashboardMetricRequest(ctx context.Context, publicDashboardUid string, panelId int64) (dtos.MetricRequest, error) {
-	ret := _m.Called(ctx, publicDashboardUid, panelId)
+// BuildPublicDashboardMetricRequest provides a mock function with given fields: ctx, accessToken, password, panelId, reqDTO, viewer
+func (_m *FakeDashboardService) BuildPublicDashboardMetricRequest(ctx context.Context, accessToken string, password string, panelId int64, reqDTO dtos.PublicDashboardQueryDTO, viewer *models.SignedInUser) (dtos.MetricRequest, models.PublicDashboardQueryOptions, error) {
+	ret := _m.Called(ctx, accessToken, password, panelId, reqDTO, viewer)
 
 	var r0 dtos.MetricRequest
-	if rf, ok := ret.Get(0).(func(context.Context, string, int64) dtos.MetricRequest); ok {
-		r0 = rf(ctx, publicDashboardUid, panelId)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, dtos.PublicDashboardQueryDTO, *models.SignedInUser) dtos.MetricRequest); ok {
+		r0 = rf(ctx, accessToken, password, panelId, reqDTO, viewer)
 	} else {
 		r0 = ret.Get(0).(dtos.MetricRequest)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
-		r1 = rf(ctx, publicDashboardUid, panelId)
+	var r1 models.PublicDashboardQueryOptions
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64, dtos.PublicDashboardQueryDTO, *models.SignedInUser) models.PublicDashboardQueryOptions); ok {
+		r1 = rf(ctx, accessToken, password, panelId, reqDTO, viewer)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(models.PublicDashboardQueryOptions)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, int64, dtos.PublicDashboardQueryDTO, *models.SignedInUser) error); ok {
+		r2 = rf(ctx, accessToken, password, panelId, reqDTO, viewer)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // BuildSaveDashboardCommand provides a mock function with given fields: ctx, dto, shouldValidateAlerts, validateProvisionedDashboard
@@ -76,6 +83,20 @@ func (_m *FakeDashboardService) DeleteDashboard(ctx context.Context, dashboardId
 	return r0
 }
 
+// DeletePublicDashboardConfig provides a mock function with given fields: ctx, orgId, userId, dashboardUid
+func (_m *FakeDashboardService) DeletePublicDashboardConfig(ctx context.Context, orgId int64, userId int64, dashboardUid string) error {
+	ret := _m.Called(ctx, orgId, userId, dashboardUid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string) error); ok {
+		r0 = rf(ctx, orgId, userId, dashboardUid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // FindDashboards provides a mock function with given fields: ctx, query
 func (_m *FakeDashboardService) FindDashboards(ctx context.Context, query *models.FindPersistedDashboardsQuery) ([]DashboardSearchProjection, error) {
 	ret := _m.Called(ctx, query)
@@ -169,13 +190,36 @@ func (_m *FakeDashboardService) GetDashboards(ctx context.Context, query *models
 	return r0
 }
 
-// GetPublicDashboard provides a mock function with given fields: ctx, publicDashboardUid
-func (_m *FakeDashboardService) GetPublicDashboard(ctx context.Context, publicDashboardUid string) (*models.Dashboard, error) {
-	ret := _m.Called(ctx, publicDashboardUid)
+// GetEnabledPublicDashboardByDashboardUid provides a mock function with given fields: ctx, orgId, dashboardUid
+func (_m *FakeDashboardService) GetEnabledPublicDashboardByDashboardUid(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboard, error) {
+	ret := _m.Called(ctx, orgId, dashboardUid)
+
+	var r0 *models.PublicDashboard
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) *models.PublicDashboard); ok {
+		r0 = rf(ctx, orgId, dashboardUid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PublicDashboard)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, orgId, dashboardUid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPublicDashboard provides a mock function with given fields: ctx, accessToken, password, viewer
+func (_m *FakeDashboardService) GetPublicDashboard(ctx context.Context, accessToken string, password string, viewer *models.SignedInUser) (*models.Dashboard, error) {
+	ret := _m.Called(ctx, accessToken, password, viewer)
 
 	var r0 *models.Dashboard
-	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Dashboard); ok {
-		r0 = rf(ctx, publicDashboardUid)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *models.SignedInUser) *models.Dashboard); ok {
+		r0 = rf(ctx, accessToken, password, viewer)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Dashboard)
@@ -183,8 +227,8 @@ func (_m *FakeDashboardService) GetPublicDashboard(ctx context.Context, publicDa
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, publicDashboardUid)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *models.SignedInUser) error); ok {
+		r1 = rf(ctx, accessToken, password, viewer)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -215,6 +259,29 @@ func (_m *FakeDashboardService) GetPublicDashboardConfig(ctx context.Context, or
 	return r0, r1
 }
 
+// GetPublicDashboardForRendering provides a mock function with given fields: ctx, accessToken, password, viewer
+func (_m *FakeDashboardService) GetPublicDashboardForRendering(ctx context.Context, accessToken string, password string, viewer *models.SignedInUser) (*models.Dashboard, error) {
+	ret := _m.Called(ctx, accessToken, password, viewer)
+
+	var r0 *models.Dashboard
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *models.SignedInUser) *models.Dashboard); ok {
+		r0 = rf(ctx, accessToken, password, viewer)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Dashboard)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *models.SignedInUser) error); ok {
+		r1 = rf(ctx, accessToken, password, viewer)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HasAdminPermissionInFolders provides a mock function with given fields: ctx, query
 func (_m *FakeDashboardService) HasAdminPermissionInFolders(ctx context.Context, query *models.HasAdminPermissionInFoldersQuery) error {
 	ret := _m.Called(ctx, query)
@@ -280,6 +347,20 @@ func (_m *FakeDashboardService) MakeUserAdmin(ctx context.Context, orgID int64,
 	return r0
 }
 
+// ResolvePublicDashboardUsers provides a mock function with given fields: ctx, pdc
+func (_m *FakeDashboardService) ResolvePublicDashboardUsers(ctx context.Context, pdc *models.PublicDashboardConfig) error {
+	ret := _m.Called(ctx, pdc)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PublicDashboardConfig) error); ok {
+		r0 = rf(ctx, pdc)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveDashboard provides a mock function with given fields: ctx, dto, allowUiUpdate
 func (_m *FakeDashboardService) SaveDashboard(ctx context.Context, dto *SaveDashboardDTO, allowUiUpdate bool) (*models.Dashboard, error) {
 	ret := _m.Called(ctx, dto, allowUiUpdate)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"xorm.io/xorm"
@@ -11,6 +13,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	dashver "github.com/grafana/grafana/pkg/services/dashboardversion"
@@ -25,13 +28,30 @@ type DashboardStore struct {
 	sqlStore *sqlstore.SQLStore
 	log      log.Logger
 	dialect  migrator.Dialect
+
+	// publicDashboardAccess tracks when the last access of each public dashboard was recorded
+	publicDashboardAccess   map[string]time.Time
+	publicDashboardAccessMu sync.Mutex
+
+	// publicDashboardConfigCache is nil if caching public dashboard configurations is disabled
+	publicDashboardConfigCache *publicDashboardConfigCache
 }
 
 // DashboardStore implements the Store interface
 var _ dashboards.Store = (*DashboardStore)(nil)
 
 func ProvideDashboardStore(sqlStore *sqlstore.SQLStore) *DashboardStore {
-	return &DashboardStore{sqlStore: sqlStore, log: log.New("dashboard-store"), dialect: sqlStore.Dialect}
+	store := &DashboardStore{
+		sqlStore:              sqlStore,
+		log:                   log.New("dashboard-store"),
+		dialect:               sqlStore.Dialect,
+		publicDashboardAccess: make(map[string]time.Time),
+	}
+	if sqlStore.Cfg != nil {
+		store.publicDashboardConfigCache = newPublicDashboardConfigCache(sqlStore.Cfg.PublicDashboardsConfigCacheTTL, sqlStore.Cfg.PublicDashboardsConfigCacheSize)
+	}
+
+	return store
 }
 
 func (d *DashboardStore) ValidateDashboardBeforeSave(dashboard *models.Dashboard, overwrite bool) (bool, error) {
@@ -697,10 +717,70 @@ func (d *DashboardStore) GetDashboardsByPluginID(ctx context.Context, query *mod
 	})
 }
 
+// DashboardsUsingDatasourcePlugin returns the dashboards, across all orgs, referring to a data source provided
+// by the plugin, sorted by org and title. Dashboards are first narrowed down by matching their JSON against the
+// uids and names of the data sources, and only those are parsed.
+func (d *DashboardStore) DashboardsUsingDatasourcePlugin(ctx context.Context, pluginID string) ([]plugins.DashboardUsage, error) {
+	var usages []plugins.DashboardUsage
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var datasources []struct {
+			OrgId int64
+			Uid   string
+			Name  string
+		}
+		if err := sess.Table("data_source").Cols("org_id", "uid", "name").Where("type = ?", pluginID).
+			Find(&datasources); err != nil {
+			return err
+		}
+
+		for _, ds := range datasources {
+			var dashboards []*models.Dashboard
+			if err := sess.Where("org_id = ? AND is_folder = "+d.dialect.BooleanStr(false), ds.OrgId).
+				And("(data "+d.dialect.LikeStr()+" ? OR data "+d.dialect.LikeStr()+" ?)", "%"+ds.Uid+"%", "%"+ds.Name+"%").
+				Cols("id", "org_id", "uid", "title", "data").Find(&dashboards); err != nil {
+				return err
+			}
+
+			for _, dash := range dashboards {
+				if models.DashboardReferencesDatasource(dash.Data, ds.Uid, ds.Name) && !containsUsage(usages, dash) {
+					usages = append(usages, plugins.DashboardUsage{OrgID: dash.OrgId, UID: dash.Uid, Title: dash.Title})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].OrgID != usages[j].OrgID {
+			return usages[i].OrgID < usages[j].OrgID
+		}
+		return usages[i].Title < usages[j].Title
+	})
+	return usages, nil
+}
+
+// containsUsage reports whether the dashboard is already in usages, as it may refer to several data sources of
+// the same plugin
+func containsUsage(usages []plugins.DashboardUsage, dash *models.Dashboard) bool {
+	for _, usage := range usages {
+		if usage.OrgID == dash.OrgId && usage.UID == dash.Uid {
+			return true
+		}
+	}
+	return false
+}
+
 func (d *DashboardStore) DeleteDashboard(ctx context.Context, cmd *models.DeleteDashboardCommand) error {
-	return d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
 		return d.deleteDashboard(cmd, sess)
 	})
+
+	// the public dashboards of the dashboard, or of all dashboards in it when it's a folder, were deleted
+	d.publicDashboardConfigCache.invalidateAll()
+	return err
 }
 
 func (d *DashboardStore) deleteDashboard(cmd *models.DeleteDashboardCommand, sess *sqlstore.DBSession) error {
@@ -712,6 +792,11 @@ func (d *DashboardStore) deleteDashboard(cmd *models.DeleteDashboardCommand, ses
 		return models.ErrDashboardNotFound
 	}
 
+	// runs before the dashboard rows are deleted, since folder children are matched through them
+	if err := deleteDashboardPublicDashboards(sess, &dashboard); err != nil {
+		return err
+	}
+
 	deletes := []string{
 		"DELETE FROM dashboard_tag WHERE dashboard_id = ? ",
 		"DELETE FROM star WHERE dashboard_id = ? ",
@@ -2,28 +2,45 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/tsdb/legacydata"
 	"github.com/grafana/grafana/pkg/util"
 )
 
-// retrieves public dashboard configuration
-func (d *DashboardStore) GetPublicDashboard(uid string) (*models.PublicDashboard, *models.Dashboard, error) {
-	if uid == "" {
+// retrieves public dashboard configuration along with its dashboard, by the access token or the slug the public
+// dashboard is served with. The uid isn't accepted, as it doesn't change when the access token is rotated.
+// Disabled public dashboards return ErrPublicDashboardDisabled, so callers can't serve them by accident.
+func (d *DashboardStore) GetPublicDashboard(accessToken string) (*models.PublicDashboard, *models.Dashboard, error) {
+	if accessToken == "" {
 		return nil, nil, models.ErrPublicDashboardIdentifierNotSet
 	}
 
-	// get public dashboard
-	pdRes := &models.PublicDashboard{Uid: uid}
+	// get public dashboard, slugs never match the access token of another public dashboard
+	pdRes := &models.PublicDashboard{}
 	err := d.sqlStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
-		has, err := sess.Get(pdRes)
+		// soft deleted public dashboards can't be viewed
+		has, err := sess.Where("(access_token = ? OR slug = ?) AND deleted_at IS NULL", accessToken, accessToken).Get(pdRes)
 		if err != nil {
 			return err
 		}
 		if !has {
-			return models.ErrPublicDashboardNotFound
+			return models.PublicDashboardLookupErr{Err: models.ErrPublicDashboardNotFound}
 		}
 		return nil
 	})
@@ -40,7 +57,12 @@ func (d *DashboardStore) GetPublicDashboard(uid string) (*models.PublicDashboard
 			return err
 		}
 		if !has {
-			return models.ErrPublicDashboardNotFound
+			// the public dashboard exists but the dashboard it references was deleted
+			return models.PublicDashboardLookupErr{Uid: pdRes.Uid, Err: models.ErrDashboardNotFound}
+		}
+		// disabled public dashboards must never be served, neither outside of the window they're enabled in
+		if !dashRes.IsPublic || !pdRes.IsEnabledAt(timeNow()) {
+			return models.PublicDashboardLookupErr{Uid: pdRes.Uid, Err: models.ErrPublicDashboardDisabled}
 		}
 		return nil
 	})
@@ -49,7 +71,66 @@ func (d *DashboardStore) GetPublicDashboard(uid string) (*models.PublicDashboard
 		return nil, nil, err
 	}
 
-	return pdRes, dashRes, err
+	d.recordPublicDashboardAccess(pdRes.Uid)
+	d.applyMaxQueryDurationDefault(pdRes)
+
+	pdRes.ContentHash, err = publicDashboardContentHash(pdRes, dashRes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pdRes, dashRes, nil
+}
+
+// publicDashboardContentHash returns the hash of everything the public dashboard serves, which changes whenever
+// its config or its dashboard is saved with different content
+func publicDashboardContentHash(pd *models.PublicDashboard, dashboard *models.Dashboard) (string, error) {
+	config := *pd
+	// accessing the public dashboard doesn't change what it serves
+	config.LastAccessedAt = time.Time{}
+	config.ContentHash = ""
+
+	// maps are encoded with sorted keys, so equal content always encodes the same
+	content, err := json.Marshal(struct {
+		Config       models.PublicDashboard `json:"config"`
+		PasswordHash string                 `json:"passwordHash"`
+		Dashboard    *simplejson.Json       `json:"dashboard"`
+	}{
+		Config:       config,
+		PasswordHash: pd.PasswordHash,
+		Dashboard:    dashboard.Data,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// recordPublicDashboardAccess updates when the public dashboard was last accessed, at most once per
+// PublicDashboardAccessInterval. The update happens in the background and failures are only logged,
+// so it never holds back serving the public dashboard.
+func (d *DashboardStore) recordPublicDashboardAccess(uid string) {
+	now := timeNow()
+
+	d.publicDashboardAccessMu.Lock()
+	if last, ok := d.publicDashboardAccess[uid]; ok && now.Sub(last) < models.PublicDashboardAccessInterval {
+		d.publicDashboardAccessMu.Unlock()
+		return
+	}
+	d.publicDashboardAccess[uid] = now
+	d.publicDashboardAccessMu.Unlock()
+
+	go func() {
+		err := d.sqlStore.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+			_, err := sess.Exec("UPDATE dashboard_public_config SET last_accessed_at = ? WHERE uid = ?", now, uid)
+			return err
+		})
+		if err != nil {
+			d.log.Warn("Failed to record public dashboard access", "uid", uid, "error", err)
+		}
+	}()
 }
 
 // generates a new unique uid to retrieve a public dashboard
@@ -57,7 +138,8 @@ func generateNewPublicDashboardUid(sess *sqlstore.DBSession) (string, error) {
 	for i := 0; i < 3; i++ {
 		uid := util.GenerateShortUID()
 
-		exists, err := sess.Get(&models.PublicDashboard{Uid: uid})
+		// public dashboards are looked up by uid or slug, so the uid must not be taken as a slug either
+		exists, err := sess.Table("dashboard_public_config").Where("uid = ? OR slug = ?", uid, uid).Exist()
 		if err != nil {
 			return "", err
 		}
@@ -70,12 +152,234 @@ func generateNewPublicDashboardUid(sess *sqlstore.DBSession) (string, error) {
 	return "", models.ErrPublicDashboardFailedGenerateUniqueUid
 }
 
+// generateAccessToken returns a cryptographically random access token
+func generateAccessToken() (string, error) {
+	token, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(token.String(), "-", ""), nil
+}
+
+// accessTokenInUse checks if the access token already belongs to a public dashboard other than uid. Public dashboards
+// are served by access token or slug, so a token can't be the slug of another public dashboard either.
+func accessTokenInUse(sess *sqlstore.DBSession, accessToken, uid string) (bool, error) {
+	return sess.Where("(access_token = ? OR slug = ?) AND uid <> ?", accessToken, accessToken, uid).Exist(&models.PublicDashboard{})
+}
+
+// resolveAccessToken sets the access token of the public dashboard being saved. An existing token is kept
+// when the command leaves it empty, otherwise a new one is generated, retrying once on collision.
+func resolveAccessToken(sess *sqlstore.DBSession, pd *models.PublicDashboard, existingToken string) error {
+	if pd.AccessToken == "" && existingToken != "" {
+		pd.AccessToken = existingToken
+		return nil
+	}
+
+	if pd.AccessToken != "" {
+		inUse, err := accessTokenInUse(sess, pd.AccessToken, pd.Uid)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return models.ErrPublicDashboardAccessTokenCollision
+		}
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := generateAccessToken()
+		if err != nil {
+			return err
+		}
+
+		inUse, err := accessTokenInUse(sess, token, pd.Uid)
+		if err != nil {
+			return err
+		}
+
+		if !inUse {
+			pd.AccessToken = token
+			return nil
+		}
+	}
+
+	return models.ErrPublicDashboardAccessTokenCollision
+}
+
+// GetPublicDashboardConfigByAccessToken retrieves the public dashboard configuration the access token belongs to.
+// Like disabled public dashboards, public dashboards outside of their enabled window are returned, and cached, as
+// is. Callers serving them have to check IsPublic and PublicDashboard.IsEnabledAt.
+func (d *DashboardStore) GetPublicDashboardConfigByAccessToken(ctx context.Context, accessToken string) (*models.PublicDashboardConfig, error) {
+	if accessToken == "" {
+		return nil, models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	cacheKey := publicDashboardConfigAccessTokenKey(accessToken)
+	cached, generation, found := d.publicDashboardConfigCache.get(cacheKey)
+	if found {
+		return cached, nil
+	}
+
+	pdRes := &models.PublicDashboard{AccessToken: accessToken}
+	dashRes := &models.Dashboard{}
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Where("deleted_at IS NULL").Get(pdRes)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+
+		has, err = sess.Where("org_id = ? AND uid = ?", pdRes.OrgId, pdRes.DashboardUid).Get(dashRes)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrDashboardNotFound
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	d.applyMaxQueryDurationDefault(pdRes)
+
+	pdc := &models.PublicDashboardConfig{
+		IsPublic:        dashRes.IsPublic,
+		PublicDashboard: *pdRes,
+	}
+	d.publicDashboardConfigCache.set(cacheKey, pdc, generation)
+
+	return pdc, nil
+}
+
+// GetPublicDashboardOrgId returns the org of the public dashboard the access token belongs to. It only reads
+// the indexed access token column, so it's cheap enough to run on every public dashboard request.
+func (d *DashboardStore) GetPublicDashboardOrgId(ctx context.Context, accessToken string) (int64, error) {
+	if accessToken == "" {
+		return 0, models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	var orgId int64
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Table("dashboard_public_config").Cols("org_id").
+			Where("access_token = ? AND deleted_at IS NULL", accessToken).Get(&orgId)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return orgId, nil
+}
+
+// ResolvePublicDashboardUsers sets the login and name of the users that created and last updated the
+// public dashboard. Users that were deleted are returned with an empty login and name.
+func (d *DashboardStore) ResolvePublicDashboardUsers(ctx context.Context, pdc *models.PublicDashboardConfig) error {
+	pd := pdc.PublicDashboard
+	var ids []int64
+	for _, id := range []int64{pd.CreatedBy, pd.UpdatedBy} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var users []models.PublicDashboardUser
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Table("user").Cols("id", "login", "name").In("id", ids).Find(&users)
+	})
+	if err != nil {
+		return err
+	}
+
+	userByID := func(id int64) *models.PublicDashboardUser {
+		if id == 0 {
+			return nil
+		}
+		for _, u := range users {
+			if u.Id == id {
+				u := u
+				return &u
+			}
+		}
+		return &models.PublicDashboardUser{Id: id}
+	}
+
+	pdc.CreatedByUser = userByID(pd.CreatedBy)
+	pdc.UpdatedByUser = userByID(pd.UpdatedBy)
+
+	return nil
+}
+
+// ListPublicDashboards returns the public dashboards of an org along with the title
+// of their dashboards. Public dashboards of deleted dashboards are excluded.
+func (d *DashboardStore) ListPublicDashboards(ctx context.Context, orgId int64) ([]models.PublicDashboardListItem, error) {
+	list := make([]models.PublicDashboardListItem, 0)
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := `SELECT dashboard_public_config.uid, dashboard_public_config.slug, dashboard_public_config.dashboard_uid, dashboard.title, dashboard.is_public, dashboard_public_config.created_at,
+			dashboard_public_config.last_accessed_at
+			FROM dashboard_public_config
+			INNER JOIN dashboard ON dashboard.org_id = dashboard_public_config.org_id AND dashboard.uid = dashboard_public_config.dashboard_uid
+			WHERE dashboard_public_config.org_id = ? AND dashboard_public_config.deleted_at IS NULL
+			ORDER BY dashboard.title ASC`
+		return sess.SQL(rawSQL, orgId).Find(&list)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// PublicDashboardStats returns the number of public dashboards of every org that has any, and how many of them
+// are enabled, ordered by org id. Deleted public dashboards and public dashboards of deleted dashboards are
+// excluded. The stats of all orgs are counted in a single query, so they can be collected periodically.
+func (d *DashboardStore) PublicDashboardStats(ctx context.Context) ([]models.OrgPublicDashboardStats, error) {
+	stats := make([]models.OrgPublicDashboardStats, 0)
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := `SELECT dashboard_public_config.org_id, COUNT(*) AS total,
+			SUM(CASE WHEN dashboard.is_public = ? THEN 1 ELSE 0 END) AS enabled
+			FROM dashboard_public_config
+			INNER JOIN dashboard ON dashboard.org_id = dashboard_public_config.org_id AND dashboard.uid = dashboard_public_config.dashboard_uid
+			WHERE dashboard_public_config.deleted_at IS NULL
+			GROUP BY dashboard_public_config.org_id
+			ORDER BY dashboard_public_config.org_id ASC`
+		return sess.SQL(rawSQL, true).Find(&stats)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 // retrieves public dashboard configuration
 func (d *DashboardStore) GetPublicDashboardConfig(orgId int64, dashboardUid string) (*models.PublicDashboardConfig, error) {
 	if dashboardUid == "" {
 		return nil, models.ErrDashboardIdentifierNotSet
 	}
 
+	cacheKey := publicDashboardConfigDashboardKey(orgId, dashboardUid)
+	cached, generation, found := d.publicDashboardConfigCache.get(cacheKey)
+	if found {
+		return cached, nil
+	}
+
 	// get dashboard and publicDashboard
 	dashRes := &models.Dashboard{OrgId: orgId, Uid: dashboardUid}
 	pdRes := &models.PublicDashboard{OrgId: orgId, DashboardUid: dashboardUid}
@@ -89,12 +393,17 @@ func (d *DashboardStore) GetPublicDashboardConfig(orgId int64, dashboardUid stri
 			return models.ErrDashboardNotFound
 		}
 
-		// publicDashboard
-		_, err = sess.Get(pdRes)
+		// publicDashboard, soft deleted ones are ignored until restored
+		has, err = sess.Where("deleted_at IS NULL").Get(pdRes)
 		if err != nil {
 			return err
 		}
 
+		// public dashboards stored in an older version of the model are upgraded on read
+		if has && pdRes.ModelVersion < models.PublicDashboardModelVersion {
+			return upgradePublicDashboard(sess, pdRes)
+		}
+
 		return nil
 	})
 
@@ -102,56 +411,1288 @@ func (d *DashboardStore) GetPublicDashboardConfig(orgId int64, dashboardUid stri
 		return nil, err
 	}
 
+	// dashboards without a public dashboard get an empty one
+	if pdRes.Uid != "" {
+		d.applyMaxQueryDurationDefault(pdRes)
+	}
+
 	pdc := &models.PublicDashboardConfig{
 		IsPublic:        dashRes.IsPublic,
 		PublicDashboard: *pdRes,
 	}
+	d.publicDashboardConfigCache.set(cacheKey, pdc, generation)
 
 	return pdc, err
 }
 
-// persists public dashboard configuration
-func (d *DashboardStore) SavePublicDashboardConfig(cmd models.SavePublicDashboardConfigCommand) (*models.PublicDashboardConfig, error) {
-	if len(cmd.PublicDashboardConfig.PublicDashboard.DashboardUid) == 0 {
+// GetPublicDashboardConfigs returns the public dashboards of the dashboards in a single query, keyed by dashboard uid.
+// Dashboards without a public dashboard, or with a deleted one, are absent from the map.
+func (d *DashboardStore) GetPublicDashboardConfigs(ctx context.Context, orgId int64, dashboardUids []string) (map[string]*models.PublicDashboard, error) {
+	res := make(map[string]*models.PublicDashboard)
+	if len(dashboardUids) == 0 {
+		return res, nil
+	}
+
+	var pds []*models.PublicDashboard
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		err := sess.Where("org_id = ? AND deleted_at IS NULL", orgId).In("dashboard_uid", dashboardUids).Find(&pds)
+		if err != nil {
+			return err
+		}
+
+		// public dashboards stored in an older version of the model are upgraded on read
+		for _, pd := range pds {
+			if pd.ModelVersion < models.PublicDashboardModelVersion {
+				if err := upgradePublicDashboard(sess, pd); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pd := range pds {
+		d.applyMaxQueryDurationDefault(pd)
+		res[pd.DashboardUid] = pd
+	}
+
+	return res, nil
+}
+
+// GetEnabledPublicDashboardByDashboardUid returns the public dashboard of a dashboard, but only if it's
+// enabled, within its enabled window and not deleted. Otherwise it fails with models.ErrPublicDashboardNotFound.
+func (d *DashboardStore) GetEnabledPublicDashboardByDashboardUid(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboard, error) {
+	if dashboardUid == "" {
 		return nil, models.ErrDashboardIdentifierNotSet
 	}
 
-	err := d.sqlStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
-		// update isPublic on dashboard entry
-		affectedRowCount, err := sess.Table("dashboard").Where("org_id = ? AND uid = ?", cmd.OrgId, cmd.DashboardUid).Update(map[string]interface{}{"is_public": cmd.PublicDashboardConfig.IsPublic})
+	pdRes := &models.PublicDashboard{OrgId: orgId, DashboardUid: dashboardUid}
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Where("deleted_at IS NULL").Get(pdRes)
 		if err != nil {
 			return err
 		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
 
-		if affectedRowCount == 0 {
-			return models.ErrDashboardNotFound
+		// a disabled public dashboard is reported the same way as a missing one
+		count, err := sess.Table("dashboard").Where("org_id = ? AND uid = ? AND is_public = ?", orgId, dashboardUid, true).Count()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return models.ErrPublicDashboardNotFound
+		}
+
+		if pdRes.ModelVersion < models.PublicDashboardModelVersion {
+			return upgradePublicDashboard(sess, pdRes)
 		}
 
-		// update dashboard_public_config
-		// if we have a uid, public dashboard config exists. delete it otherwise generate a uid
-		if cmd.PublicDashboardConfig.PublicDashboard.Uid != "" {
-			if _, err = sess.Exec("DELETE FROM dashboard_public_config WHERE uid=?", cmd.PublicDashboardConfig.PublicDashboard.Uid); err != nil {
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	// outside of its enabled window, a public dashboard is reported the same way as a disabled one
+	if !pdRes.IsEnabledAt(timeNow()) {
+		return nil, models.ErrPublicDashboardNotFound
+	}
+	d.applyMaxQueryDurationDefault(pdRes)
+
+	return pdRes, nil
+}
+
+// persists public dashboard configuration. Configs can't be saved while the feature is disabled, since they couldn't
+// be served, but existing ones can still be read and deleted.
+func (d *DashboardStore) SavePublicDashboardConfig(cmd models.SavePublicDashboardConfigCommand) (*models.PublicDashboardConfig, error) {
+	if !d.sqlStore.Cfg.IsFeatureToggleEnabled(featuremgmt.FlagPublicDashboards) {
+		return nil, models.ErrPublicDashboardsDisabled
+	}
+
+	if len(cmd.PublicDashboardConfig.PublicDashboard.DashboardUid) == 0 {
+		return nil, models.ErrDashboardIdentifierNotSet
+	}
+
+	err := d.sqlStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		return savePublicDashboardConfig(sess, &cmd, d.publicDashboardQuota(), d.unsupportedPanelTypes())
+	})
+	d.publicDashboardConfigCache.invalidate(cmd.OrgId, cmd.PublicDashboardConfig.PublicDashboard.DashboardUid)
+
+	if err != nil {
+		return nil, err
+	}
+	d.applyMaxQueryDurationDefault(&cmd.PublicDashboardConfig.PublicDashboard)
+
+	return &cmd.PublicDashboardConfig, nil
+}
+
+// SavePublicDashboardConfigBatch persists several public dashboard configurations
+// in a single transaction. If any of the commands fails the whole batch is rolled
+// back. The returned error slice is aligned with cmds so callers can report which
+// items failed.
+func (d *DashboardStore) SavePublicDashboardConfigBatch(ctx context.Context, cmds []models.SavePublicDashboardConfigCommand) ([]models.PublicDashboard, []error, error) {
+	if !d.sqlStore.Cfg.IsFeatureToggleEnabled(featuremgmt.FlagPublicDashboards) {
+		return nil, nil, models.ErrPublicDashboardsDisabled
+	}
+
+	itemErrs := make([]error, len(cmds))
+	res := make([]models.PublicDashboard, len(cmds))
+	quota := d.publicDashboardQuota()
+	unsupportedPanels := d.unsupportedPanelTypes()
+
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var firstErr error
+		for i := range cmds {
+			cmd := cmds[i]
+			if len(cmd.PublicDashboardConfig.PublicDashboard.DashboardUid) == 0 {
+				itemErrs[i] = models.ErrDashboardIdentifierNotSet
+			} else if itemErr, err := saveBatchItem(sess, &cmd, quota, unsupportedPanels); err != nil {
 				return err
+			} else if itemErr != nil {
+				itemErrs[i] = itemErr
+			} else {
+				res[i] = cmd.PublicDashboardConfig.PublicDashboard
+				continue
 			}
-		} else {
-			uid, err := generateNewPublicDashboardUid(sess)
-			if err != nil {
-				return fmt.Errorf("failed to generate UID for public dashboard: %w", err)
+
+			if firstErr == nil {
+				firstErr = itemErrs[i]
 			}
-			cmd.PublicDashboardConfig.PublicDashboard.Uid = uid
 		}
 
-		_, err = sess.Insert(&cmd.PublicDashboardConfig.PublicDashboard)
+		// returning an error rolls back every item in the batch
+		return firstErr
+	})
+	for _, cmd := range cmds {
+		d.publicDashboardConfigCache.invalidate(cmd.OrgId, cmd.PublicDashboardConfig.PublicDashboard.DashboardUid)
+	}
+
+	if err != nil {
+		return nil, itemErrs, err
+	}
+	for i := range res {
+		d.applyMaxQueryDurationDefault(&res[i])
+	}
+
+	return res, itemErrs, nil
+}
+
+// batchItemSavepoint is the savepoint each item of a batch of public dashboards is saved in
+const batchItemSavepoint = "public_dashboard_batch_item"
+
+// saveBatchItem saves an item of a batch of public dashboards in a savepoint and returns why the item failed, if
+// it did. A failing item is rolled back to the savepoint, so the items after it are saved and validated on their
+// own. Otherwise a database error, which aborts the whole transaction on Postgres, would be reported for all of
+// them. The second error is only set if the savepoint itself fails.
+func saveBatchItem(sess *sqlstore.DBSession, cmd *models.SavePublicDashboardConfigCommand, quota int64, unsupportedPanels []string) (error, error) {
+	if _, err := sess.Exec("SAVEPOINT " + batchItemSavepoint); err != nil {
+		return nil, err
+	}
+
+	if itemErr := savePublicDashboardConfig(sess, cmd, quota, unsupportedPanels); itemErr != nil {
+		_, err := sess.Exec("ROLLBACK TO SAVEPOINT " + batchItemSavepoint)
+		return itemErr, err
+	}
+
+	_, err := sess.Exec("RELEASE SAVEPOINT " + batchItemSavepoint)
+	return nil, err
+}
+
+// PatchPublicDashboardConfig changes the settings of the public dashboard set in the patch and keeps the others.
+// The patched config is validated like a saved one. Unlike saving, patching doesn't publish the current queries
+// of the dashboard, so they have to be reviewed and saved to be run.
+func (d *DashboardStore) PatchPublicDashboardConfig(ctx context.Context, orgId int64, uid string, patch models.PublicDashboardPatch) (*models.PublicDashboardConfig, error) {
+	if !d.sqlStore.Cfg.IsFeatureToggleEnabled(featuremgmt.FlagPublicDashboards) {
+		return nil, models.ErrPublicDashboardsDisabled
+	}
+	if uid == "" {
+		return nil, models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	existing := &models.PublicDashboard{Uid: uid, OrgId: orgId}
+	var cmd models.SavePublicDashboardConfigCommand
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Where("deleted_at IS NULL").Get(existing)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+		// the patch applies to the current version of the model
+		if existing.ModelVersion < models.PublicDashboardModelVersion {
+			if err := upgradePublicDashboard(sess, existing); err != nil {
+				return err
+			}
+		}
+
+		dashboard := &models.Dashboard{OrgId: orgId, Uid: existing.DashboardUid}
+		has, err = sess.Get(dashboard)
 		if err != nil {
 			return err
 		}
+		if !has {
+			return models.ErrDashboardNotFound
+		}
 
-		return nil
+		cmd = models.SavePublicDashboardConfigCommand{
+			DashboardUid: existing.DashboardUid,
+			OrgId:        orgId,
+			PublicDashboardConfig: models.PublicDashboardConfig{
+				IsPublic:        dashboard.IsPublic,
+				PublicDashboard: *existing,
+			},
+		}
+		patch.Apply(&cmd.PublicDashboardConfig)
+
+		if err := savePublicDashboardConfig(sess, &cmd, d.publicDashboardQuota(), d.unsupportedPanelTypes()); err != nil {
+			return err
+		}
+
+		// saving published the current queries, the ones published before are kept instead
+		cmd.PublicDashboardConfig.PublicDashboard.QuerySignature = existing.QuerySignature
+		_, err = sess.Exec("UPDATE dashboard_public_config SET query_signature = ? WHERE org_id = ? AND uid = ?", existing.QuerySignature, orgId, uid)
+		return err
 	})
+	if existing.DashboardUid != "" {
+		d.publicDashboardConfigCache.invalidate(orgId, existing.DashboardUid)
+	}
 
 	if err != nil {
 		return nil, err
 	}
+	d.applyMaxQueryDurationDefault(&cmd.PublicDashboardConfig.PublicDashboard)
 
 	return &cmd.PublicDashboardConfig, nil
 }
+
+// DeletePublicDashboardConfig soft deletes the public dashboard configuration and marks its
+// dashboard as no longer public, so the public dashboard uid stops resolving. The configuration
+// can be restored with RestorePublicDashboardConfig until it's purged.
+func (d *DashboardStore) DeletePublicDashboardConfig(ctx context.Context, orgId int64, uid string) error {
+	if uid == "" {
+		return models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	pd := &models.PublicDashboard{Uid: uid, OrgId: orgId}
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Where("deleted_at IS NULL").Get(pd)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+
+		if _, err := sess.Exec("UPDATE dashboard_public_config SET deleted_at = ? WHERE org_id = ? AND uid = ?", timeNow(), orgId, uid); err != nil {
+			return err
+		}
+
+		_, err = sess.Table("dashboard").Where("org_id = ? AND uid = ?", orgId, pd.DashboardUid).Update(map[string]interface{}{"is_public": false})
+		return err
+	})
+	if pd.DashboardUid != "" {
+		d.publicDashboardConfigCache.invalidate(orgId, pd.DashboardUid)
+	}
+
+	return err
+}
+
+// DisableAllPublicDashboards makes every public dashboard of the org private in a single statement and returns
+// the number of public dashboards that were disabled. The configurations are kept, so each public dashboard can
+// be enabled again later with its existing settings.
+func (d *DashboardStore) DisableAllPublicDashboards(ctx context.Context, orgId int64) (int64, error) {
+	var affected int64
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		res, err := sess.Exec("UPDATE dashboard SET is_public = ? WHERE org_id = ? AND is_public = ? AND uid IN (SELECT dashboard_uid FROM dashboard_public_config WHERE org_id = ? AND deleted_at IS NULL)",
+			false, orgId, true, orgId)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	d.publicDashboardConfigCache.invalidateAll()
+
+	return affected, err
+}
+
+// deleteDashboardPublicDashboards soft deletes the public dashboard configurations of the dashboard, and of the
+// dashboards in it when it's a folder, using the session of the dashboard deletion. Their access tokens stop
+// resolving right away and the configurations are purged like any other deleted public dashboard.
+func deleteDashboardPublicDashboards(sess *sqlstore.DBSession, dashboard *models.Dashboard) error {
+	_, err := sess.Exec("UPDATE dashboard_public_config SET deleted_at = ? WHERE org_id = ? AND dashboard_uid = ? AND deleted_at IS NULL",
+		timeNow(), dashboard.OrgId, dashboard.Uid)
+	if err != nil || !dashboard.IsFolder {
+		return err
+	}
+
+	_, err = sess.Exec("UPDATE dashboard_public_config SET deleted_at = ? WHERE org_id = ? AND deleted_at IS NULL AND dashboard_uid IN (SELECT uid FROM dashboard WHERE org_id = ? AND folder_id = ?)",
+		timeNow(), dashboard.OrgId, dashboard.OrgId, dashboard.Id)
+	return err
+}
+
+// RestorePublicDashboardConfig restores a soft deleted public dashboard configuration and makes its
+// dashboard public again. A fresh access token is generated, so the previous one stays revoked.
+func (d *DashboardStore) RestorePublicDashboardConfig(ctx context.Context, orgId int64, uid string) (*models.PublicDashboardConfig, error) {
+	if uid == "" {
+		return nil, models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	pd := &models.PublicDashboard{Uid: uid, OrgId: orgId}
+	quota := d.publicDashboardQuota()
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if err := lockPublicDashboardQuota(sess, orgId, quota); err != nil {
+			return err
+		}
+
+		has, err := sess.Where("deleted_at IS NOT NULL").Get(pd)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+
+		affectedRowCount, err := sess.Table("dashboard").Where("org_id = ? AND uid = ?", orgId, pd.DashboardUid).Update(map[string]interface{}{"is_public": true})
+		if err != nil {
+			return err
+		}
+		if affectedRowCount == 0 {
+			return models.ErrDashboardNotFound
+		}
+
+		pd.AccessToken = ""
+		if err := resolveAccessToken(sess, pd, ""); err != nil {
+			return err
+		}
+		pd.DeletedAt = time.Time{}
+
+		_, err = sess.Exec("UPDATE dashboard_public_config SET deleted_at = NULL, access_token = ? WHERE org_id = ? AND uid = ?", pd.AccessToken, orgId, uid)
+		if err != nil {
+			return err
+		}
+
+		return checkPublicDashboardQuota(sess, orgId, quota)
+	})
+	if pd.DashboardUid != "" {
+		d.publicDashboardConfigCache.invalidate(orgId, pd.DashboardUid)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	d.applyMaxQueryDurationDefault(pd)
+
+	return &models.PublicDashboardConfig{
+		IsPublic:        true,
+		PublicDashboard: *pd,
+	}, nil
+}
+
+// RotatePublicDashboardAccessToken replaces the access token of the public dashboard with a freshly
+// generated one and returns it. The token is replaced in a single update, so the previous token stops
+// resolving as soon as the new one is valid.
+func (d *DashboardStore) RotatePublicDashboardAccessToken(ctx context.Context, orgId int64, uid string) (string, error) {
+	if uid == "" {
+		return "", models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	pd := &models.PublicDashboard{Uid: uid, OrgId: orgId}
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Where("deleted_at IS NULL").Get(pd)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+
+		pd.AccessToken = ""
+		if err := resolveAccessToken(sess, pd, ""); err != nil {
+			return err
+		}
+
+		_, err = sess.Exec("UPDATE dashboard_public_config SET access_token = ? WHERE org_id = ? AND uid = ?", pd.AccessToken, orgId, uid)
+		return err
+	})
+	if pd.DashboardUid != "" {
+		d.publicDashboardConfigCache.invalidate(orgId, pd.DashboardUid)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return pd.AccessToken, nil
+}
+
+// SetPublicDashboardPassword protects the public dashboard with a password viewers have to enter. An empty
+// password removes the protection. Only the bcrypt hash of the password is stored.
+func (d *DashboardStore) SetPublicDashboardPassword(ctx context.Context, orgId int64, uid string, password string) error {
+	if uid == "" {
+		return models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	var passwordHash string
+	if password != "" {
+		// bcrypt only uses the first 72 bytes, the rest of a longer password wouldn't be checked
+		if len(password) > 72 {
+			return models.ErrPublicDashboardInvalidPassword
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		passwordHash = string(hash)
+	}
+
+	pd := &models.PublicDashboard{Uid: uid, OrgId: orgId}
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Where("deleted_at IS NULL").Get(pd)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+
+		_, err = sess.Exec("UPDATE dashboard_public_config SET password_hash = ? WHERE org_id = ? AND uid = ?", passwordHash, orgId, uid)
+		return err
+	})
+	if pd.DashboardUid != "" {
+		d.publicDashboardConfigCache.invalidate(orgId, pd.DashboardUid)
+	}
+
+	return err
+}
+
+// VerifyPublicDashboardPassword checks the password a viewer entered for the public dashboard served by the
+// access token or slug, the same way GetPublicDashboard resolves it. It fails with
+// ErrPublicDashboardPasswordRequired if no password was entered and with ErrPublicDashboardWrongPassword if it
+// doesn't match. Public dashboards that aren't password protected don't require a password, so any password is
+// accepted.
+func (d *DashboardStore) VerifyPublicDashboardPassword(ctx context.Context, accessToken string, password string) error {
+	if accessToken == "" {
+		return models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	pd := models.PublicDashboard{}
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Table("dashboard_public_config").Cols("password_hash").
+			Where("(access_token = ? OR slug = ?) AND deleted_at IS NULL", accessToken, accessToken).Get(&pd)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return pd.VerifyPassword(password)
+}
+
+// ExportPublicDashboardConfig returns the public dashboard configuration as portable JSON, which can be
+// imported into another org or Grafana instance with ImportPublicDashboardConfig.
+func (d *DashboardStore) ExportPublicDashboardConfig(ctx context.Context, orgId int64, uid string) ([]byte, error) {
+	if uid == "" {
+		return nil, models.ErrPublicDashboardIdentifierNotSet
+	}
+
+	pd := &models.PublicDashboard{Uid: uid, OrgId: orgId}
+	dash := &models.Dashboard{}
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		has, err := sess.Where("deleted_at IS NULL").Get(pd)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrPublicDashboardNotFound
+		}
+
+		has, err = sess.Where("org_id = ? AND uid = ?", orgId, pd.DashboardUid).Get(dash)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrDashboardNotFound
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(models.PublicDashboardExport{
+		DashboardUid:            pd.DashboardUid,
+		IsPublic:                dash.IsPublic,
+		TimeSettings:            pd.TimeSettings,
+		ShareType:               pd.ShareType,
+		Theme:                   pd.Theme,
+		Timezone:                pd.Timezone,
+		RefreshInterval:         pd.RefreshInterval,
+		AnnotationsEnabled:      pd.AnnotationsEnabled,
+		AllowedVariables:        pd.AllowedVariables,
+		HiddenPanelIds:          pd.HiddenPanelIds,
+		AllowedOrigins:          pd.AllowedOrigins,
+		VariableOverrides:       pd.VariableOverrides,
+		RateLimitPerMinute:      pd.RateLimitPerMinute,
+		CacheTTLSeconds:         pd.CacheTTLSeconds,
+		EnabledFrom:             pd.EnabledFrom,
+		EnabledTo:               pd.EnabledTo,
+		AllowedDatasourceUids:   pd.AllowedDatasourceUids,
+		MaxQueryDurationSeconds: pd.MaxQueryDurationSeconds,
+	})
+}
+
+// ImportPublicDashboardConfig creates a public dashboard configuration from JSON exported with
+// ExportPublicDashboardConfig. The configuration gets a new uid and a freshly generated access token,
+// and replaces any existing configuration of the dashboard. The dashboard must exist in the org.
+func (d *DashboardStore) ImportPublicDashboardConfig(ctx context.Context, orgId int64, payload []byte) (*models.PublicDashboardConfig, error) {
+	var export models.PublicDashboardExport
+	if err := json.Unmarshal(payload, &export); err != nil {
+		return nil, models.ErrPublicDashboardInvalidExport
+	}
+	if export.DashboardUid == "" {
+		return nil, models.ErrDashboardIdentifierNotSet
+	}
+
+	cmd := models.SavePublicDashboardConfigCommand{
+		DashboardUid: export.DashboardUid,
+		OrgId:        orgId,
+		PublicDashboardConfig: models.PublicDashboardConfig{
+			IsPublic: export.IsPublic,
+			PublicDashboard: models.PublicDashboard{
+				DashboardUid:            export.DashboardUid,
+				OrgId:                   orgId,
+				TimeSettings:            export.TimeSettings,
+				ShareType:               export.ShareType,
+				Theme:                   export.Theme,
+				Timezone:                export.Timezone,
+				RefreshInterval:         export.RefreshInterval,
+				AnnotationsEnabled:      export.AnnotationsEnabled,
+				AllowedVariables:        export.AllowedVariables,
+				HiddenPanelIds:          export.HiddenPanelIds,
+				AllowedOrigins:          export.AllowedOrigins,
+				VariableOverrides:       export.VariableOverrides,
+				RateLimitPerMinute:      export.RateLimitPerMinute,
+				CacheTTLSeconds:         export.CacheTTLSeconds,
+				EnabledFrom:             export.EnabledFrom,
+				EnabledTo:               export.EnabledTo,
+				AllowedDatasourceUids:   export.AllowedDatasourceUids,
+				MaxQueryDurationSeconds: export.MaxQueryDurationSeconds,
+			},
+		},
+	}
+
+	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		// the imported configuration is a new one, so it must not take over the uid or token of an existing one
+		if _, err := sess.Exec("DELETE FROM dashboard_public_config WHERE org_id = ? AND dashboard_uid = ?", orgId, export.DashboardUid); err != nil {
+			return err
+		}
+
+		return savePublicDashboardConfig(sess, &cmd, d.publicDashboardQuota(), d.unsupportedPanelTypes())
+	})
+	d.publicDashboardConfigCache.invalidate(orgId, export.DashboardUid)
+
+	if err != nil {
+		return nil, err
+	}
+	d.applyMaxQueryDurationDefault(&cmd.PublicDashboardConfig.PublicDashboard)
+
+	return &cmd.PublicDashboardConfig, nil
+}
+
+// PurgeDeletedPublicDashboards hard deletes public dashboard configurations that were soft deleted
+// before olderThan and returns the number of deleted rows.
+func (d *DashboardStore) PurgeDeletedPublicDashboards(ctx context.Context, olderThan time.Time) (int64, error) {
+	var affected int64
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		res, err := sess.Exec("DELETE FROM dashboard_public_config WHERE deleted_at IS NOT NULL AND deleted_at < ?", olderThan.UTC())
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+
+	return affected, err
+}
+
+// orphanedPublicDashboardFilter matches public dashboard configurations whose dashboard no longer exists
+const orphanedPublicDashboardFilter = "NOT EXISTS (SELECT 1 FROM dashboard WHERE dashboard.org_id = dashboard_public_config.org_id AND dashboard.uid = dashboard_public_config.dashboard_uid)"
+
+// GetOrphanedPublicDashboards returns the public dashboard configurations, including soft deleted
+// ones, that reference a dashboard that no longer exists.
+func (d *DashboardStore) GetOrphanedPublicDashboards(ctx context.Context) ([]models.PublicDashboard, error) {
+	orphans := make([]models.PublicDashboard, 0)
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where(orphanedPublicDashboardFilter).Asc("org_id", "uid").Find(&orphans)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return orphans, nil
+}
+
+// DeleteOrphanedPublicDashboards hard deletes the public dashboard configurations that reference a
+// dashboard that no longer exists and returns the number of deleted rows.
+func (d *DashboardStore) DeleteOrphanedPublicDashboards(ctx context.Context) (int64, error) {
+	var affected int64
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		res, err := sess.Exec("DELETE FROM dashboard_public_config WHERE " + orphanedPublicDashboardFilter)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		return err
+	})
+	d.publicDashboardConfigCache.invalidateAll()
+
+	return affected, err
+}
+
+// getExistingPublicDashboard finds the stored config of the public dashboard being saved, either by its uid
+// or, since a dashboard has at most one public dashboard config, by its dashboard
+func getExistingPublicDashboard(sess *sqlstore.DBSession, pd *models.PublicDashboard) (*models.PublicDashboard, bool, error) {
+	if pd.Uid != "" {
+		existing := &models.PublicDashboard{Uid: pd.Uid}
+		has, err := sess.Get(existing)
+		if err != nil || has {
+			return existing, has, err
+		}
+	}
+
+	existing := &models.PublicDashboard{}
+	has, err := sess.Where("org_id = ? AND dashboard_uid = ?", pd.OrgId, pd.DashboardUid).Get(existing)
+	return existing, has, err
+}
+
+// GetPublicDashboardQuerySignature returns the signature of the current panel queries of the dashboard, which
+// is stored on its public dashboard when it's saved. Comparing them tells whether the queries changed since.
+func (d *DashboardStore) GetPublicDashboardQuerySignature(ctx context.Context, orgId int64, dashboardUid string) (string, error) {
+	if dashboardUid == "" {
+		return "", models.ErrDashboardIdentifierNotSet
+	}
+
+	var signature string
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var err error
+		signature, err = dashboardQuerySignature(sess, orgId, dashboardUid)
+		return err
+	})
+
+	return signature, err
+}
+
+// dashboardQuerySignature returns the signature of the current panel queries of the dashboard
+func dashboardQuerySignature(sess *sqlstore.DBSession, orgId int64, dashboardUid string) (string, error) {
+	dashboard := &models.Dashboard{OrgId: orgId, Uid: dashboardUid}
+	has, err := sess.Get(dashboard)
+	if err != nil {
+		return "", err
+	}
+	if !has {
+		return "", models.ErrDashboardNotFound
+	}
+
+	return models.GetQuerySignatureFromDashboard(dashboard.Data)
+}
+
+// savePublicDashboardConfig updates the dashboard isPublic flag and upserts the
+// public dashboard config using the provided session
+func savePublicDashboardConfig(sess *sqlstore.DBSession, cmd *models.SavePublicDashboardConfigCommand, quota int64, unsupportedPanels []string) error {
+	if cmd.PublicDashboardConfig.IsPublic {
+		if err := lockPublicDashboardQuota(sess, cmd.OrgId, quota); err != nil {
+			return err
+		}
+	}
+
+	if err := validateTimeSettings(cmd.PublicDashboardConfig.PublicDashboard.TimeSettings); err != nil {
+		return err
+	}
+	if err := validateTheme(cmd.PublicDashboardConfig.PublicDashboard.Theme); err != nil {
+		return err
+	}
+	if cmd.PublicDashboardConfig.PublicDashboard.Timezone == "" {
+		cmd.PublicDashboardConfig.PublicDashboard.Timezone = models.PublicDashboardTimezoneBrowser
+	}
+	if err := validateTimezone(cmd.PublicDashboardConfig.PublicDashboard.Timezone); err != nil {
+		return err
+	}
+
+	if cmd.PublicDashboardConfig.PublicDashboard.RefreshInterval == "" {
+		cmd.PublicDashboardConfig.PublicDashboard.RefreshInterval = models.PublicDashboardRefreshOff
+	}
+	if err := validateRefreshInterval(cmd.PublicDashboardConfig.PublicDashboard.RefreshInterval); err != nil {
+		return err
+	}
+	if cmd.PublicDashboardConfig.PublicDashboard.ShareType == "" {
+		cmd.PublicDashboardConfig.PublicDashboard.ShareType = models.PublicDashboardShareTypePublic
+	}
+	if err := validateShareType(cmd.PublicDashboardConfig.PublicDashboard.ShareType); err != nil {
+		return err
+	}
+	if err := validateSlug(cmd.PublicDashboardConfig.PublicDashboard.Slug); err != nil {
+		return err
+	}
+	if err := validateAllowedOrigins(cmd.PublicDashboardConfig.PublicDashboard.AllowedOrigins); err != nil {
+		return err
+	}
+	// zero keeps the configured default, which is applied when the public dashboard is read
+	if cmd.PublicDashboardConfig.PublicDashboard.MaxQueryDurationSeconds < 0 {
+		return models.ErrPublicDashboardInvalidMaxQueryDuration
+	}
+	// zero leaves the public dashboard to the rate limit of its org
+	if cmd.PublicDashboardConfig.PublicDashboard.RateLimitPerMinute < 0 {
+		return models.ErrPublicDashboardInvalidRateLimit
+	}
+	if cmd.PublicDashboardConfig.PublicDashboard.CacheTTLSeconds < 0 {
+		return models.ErrPublicDashboardInvalidCacheTTL
+	}
+	if err := cmd.PublicDashboardConfig.PublicDashboard.ValidateEnabledWindow(); err != nil {
+		return err
+	}
+
+	// update isPublic on dashboard entry
+	affectedRowCount, err := sess.Table("dashboard").Where("org_id = ? AND uid = ?", cmd.OrgId, cmd.DashboardUid).Update(map[string]interface{}{"is_public": cmd.PublicDashboardConfig.IsPublic})
+	if err != nil {
+		return err
+	}
+
+	if affectedRowCount == 0 {
+		return models.ErrDashboardNotFound
+	}
+
+	// the dashboard is loaded once for all the checks of the config against its panels, variables and queries
+	dashboard := &models.Dashboard{OrgId: cmd.OrgId, Uid: cmd.DashboardUid}
+	has, err := sess.Get(dashboard)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return models.ErrDashboardNotFound
+	}
+
+	pd := &cmd.PublicDashboardConfig.PublicDashboard
+	allowedVariables := make(map[string]struct{}, len(pd.AllowedVariables))
+	for _, name := range pd.AllowedVariables {
+		allowedVariables[name] = struct{}{}
+	}
+	if err := validateVariableNames(dashboard, allowedVariables, models.ErrPublicDashboardUnknownVariable); err != nil {
+		return err
+	}
+	// variables may have been removed from the dashboard since the public dashboard was last saved
+	overriddenVariables := make(map[string]struct{}, len(pd.VariableOverrides))
+	for name := range pd.VariableOverrides {
+		overriddenVariables[name] = struct{}{}
+	}
+	if err := validateVariableNames(dashboard, overriddenVariables, models.ErrPublicDashboardInvalidVariable); err != nil {
+		return err
+	}
+
+	// panels may have been removed from the dashboard since the public dashboard was last saved
+	if err := validateHiddenPanels(dashboard, pd.HiddenPanelIds); err != nil {
+		return err
+	}
+
+	if err := validateAllowedDatasources(sess, cmd.OrgId, dashboard, pd.AllowedDatasourceUids); err != nil {
+		return err
+	}
+
+	// disabled public dashboards can still be saved, so they can be prepared before the panels are replaced
+	if cmd.PublicDashboardConfig.IsPublic {
+		if err := validatePanelTypes(dashboard, unsupportedPanels); err != nil {
+			return err
+		}
+	}
+
+	// update dashboard_public_config
+	// if the public dashboard config exists delete it, otherwise generate a uid
+	cmd.PublicDashboardConfig.PublicDashboard.CreatedAt = timestampNow()
+	cmd.PublicDashboardConfig.PublicDashboard.UpdatedAt = cmd.PublicDashboardConfig.PublicDashboard.CreatedAt
+	cmd.PublicDashboardConfig.PublicDashboard.CreatedBy = cmd.PublicDashboardConfig.PublicDashboard.UpdatedBy
+	// the validated config is in the current version of the model, even if the existing one isn't
+	cmd.PublicDashboardConfig.PublicDashboard.ModelVersion = models.PublicDashboardModelVersion
+	existing, has, err := getExistingPublicDashboard(sess, &cmd.PublicDashboardConfig.PublicDashboard)
+	if err != nil {
+		return err
+	}
+	// uids are looked up across orgs, public dashboards of other orgs are reported as missing to not leak them
+	if has && existing.OrgId != cmd.OrgId {
+		return models.ErrPublicDashboardNotFound
+	}
+	var existingToken string
+	if has {
+		// saving a dashboard's public dashboard again updates its existing config
+		cmd.PublicDashboardConfig.PublicDashboard.Uid = existing.Uid
+		if !existing.CreatedAt.IsZero() {
+			cmd.PublicDashboardConfig.PublicDashboard.CreatedAt = existing.CreatedAt
+		}
+		if existing.CreatedBy != 0 {
+			cmd.PublicDashboardConfig.PublicDashboard.CreatedBy = existing.CreatedBy
+		}
+		if cmd.PublicDashboardConfig.PublicDashboard.LastAccessedAt.IsZero() {
+			cmd.PublicDashboardConfig.PublicDashboard.LastAccessedAt = existing.LastAccessedAt
+		}
+		// the password is only changed through SetPublicDashboardPassword
+		if cmd.PublicDashboardConfig.PublicDashboard.PasswordHash == "" {
+			cmd.PublicDashboardConfig.PublicDashboard.PasswordHash = existing.PasswordHash
+		}
+		// a soft deleted public dashboard gets a fresh token, its previous one stays revoked
+		if existing.DeletedAt.IsZero() {
+			existingToken = existing.AccessToken
+		}
+
+		if err := resolveAccessToken(sess, &cmd.PublicDashboardConfig.PublicDashboard, existingToken); err != nil {
+			return err
+		}
+
+		if _, err = sess.Exec("DELETE FROM dashboard_public_config WHERE uid=?", cmd.PublicDashboardConfig.PublicDashboard.Uid); err != nil {
+			return err
+		}
+	} else {
+		if cmd.PublicDashboardConfig.PublicDashboard.Uid == "" {
+			uid, err := generateNewPublicDashboardUid(sess)
+			if err != nil {
+				return fmt.Errorf("failed to generate UID for public dashboard: %w", err)
+			}
+			cmd.PublicDashboardConfig.PublicDashboard.Uid = uid
+		}
+
+		if err := resolveAccessToken(sess, &cmd.PublicDashboardConfig.PublicDashboard, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := checkSlugAvailable(sess, &cmd.PublicDashboardConfig.PublicDashboard); err != nil {
+		return err
+	}
+
+	// saving publishes the current queries of the dashboard
+	signature, err := models.GetQuerySignatureFromDashboard(dashboard.Data)
+	if err != nil {
+		return err
+	}
+	cmd.PublicDashboardConfig.PublicDashboard.QuerySignature = signature
+
+	_, err = sess.Insert(&cmd.PublicDashboardConfig.PublicDashboard)
+	if err != nil {
+		return err
+	}
+
+	if !cmd.PublicDashboardConfig.IsPublic {
+		return nil
+	}
+
+	return checkPublicDashboardQuota(sess, cmd.OrgId, quota)
+}
+
+// unsupportedPanelTypes returns the panel types that keep a dashboard from being made public
+func (d *DashboardStore) unsupportedPanelTypes() []string {
+	if d.sqlStore.Cfg == nil {
+		return nil
+	}
+
+	return d.sqlStore.Cfg.PublicDashboardsUnsupportedPanels
+}
+
+// applyMaxQueryDurationDefault sets the configured query duration limit on a public dashboard that
+// doesn't set its own, which includes all public dashboards stored before the limit existed.
+func (d *DashboardStore) applyMaxQueryDurationDefault(pd *models.PublicDashboard) {
+	if pd.MaxQueryDurationSeconds > 0 {
+		return
+	}
+
+	pd.MaxQueryDurationSeconds = models.DefaultPublicDashboardMaxQueryDurationSeconds
+	if d.sqlStore.Cfg != nil && d.sqlStore.Cfg.PublicDashboardsMaxQueryDurationSeconds > 0 {
+		pd.MaxQueryDurationSeconds = d.sqlStore.Cfg.PublicDashboardsMaxQueryDurationSeconds
+	}
+}
+
+// publicDashboardQuota returns the maximum number of enabled public dashboards per org,
+// or -1 when it's unlimited.
+func (d *DashboardStore) publicDashboardQuota() int64 {
+	cfg := d.sqlStore.Cfg
+	if cfg == nil || !cfg.Quota.Enabled || cfg.Quota.Org == nil {
+		return -1
+	}
+
+	return cfg.Quota.Org.PublicDashboard
+}
+
+// lockPublicDashboardQuota locks the row of the org until the transaction ends, which serializes the
+// writes that count against its public dashboard quota. Counting after the write isn't enough on its
+// own: two transactions publishing at the same time would each only see their own new row, and both
+// would pass. It must run before anything else is read in the transaction, so that on MySQL the
+// snapshot of later reads includes what the transaction it waited on committed. SQLite allows a
+// single writer at a time, and xorm leaves out FOR UPDATE there.
+func lockPublicDashboardQuota(sess *sqlstore.DBSession, orgId int64, quota int64) error {
+	if quota < 0 {
+		return nil
+	}
+
+	var id int64
+	_, err := sess.Table("org").Where("id = ?", orgId).Cols("id").ForUpdate().Get(&id)
+	return err
+}
+
+// checkPublicDashboardQuota returns ErrPublicDashboardQuotaReached when the org has more enabled
+// public dashboards than quota allows. It's meant to run after the write, in the transaction that
+// took lockPublicDashboardQuota, so the error rolls the write back. Disabled and deleted public
+// dashboards don't count.
+func checkPublicDashboardQuota(sess *sqlstore.DBSession, orgId int64, quota int64) error {
+	if quota < 0 {
+		return nil
+	}
+
+	count, err := sess.Table("dashboard_public_config").
+		Join("INNER", "dashboard", "dashboard.org_id = dashboard_public_config.org_id AND dashboard.uid = dashboard_public_config.dashboard_uid").
+		Where("dashboard_public_config.org_id = ? AND dashboard_public_config.deleted_at IS NULL AND dashboard.is_public = ?", orgId, true).
+		Count()
+	if err != nil {
+		return err
+	}
+
+	if count > quota {
+		return models.ErrPublicDashboardQuotaReached
+	}
+
+	return nil
+}
+
+// timeNow returns the current time with the precision the database stores DATETIME columns with
+var timeNow = func() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// timestampNow returns the current time with the precision the created and updated times of public
+// dashboards are stored with on every database
+var timestampNow = func() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// validateVariableNames verifies every name is the name of a template variable of the dashboard, and fails with
+// errUnknown otherwise
+func validateVariableNames(dashboard *models.Dashboard, names map[string]struct{}, errUnknown error) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	variables := make(map[string]struct{})
+	for _, name := range models.GetVariableNamesFromDashboard(dashboard.Data) {
+		variables[name] = struct{}{}
+	}
+	for name := range names {
+		if _, exists := variables[name]; !exists {
+			return errUnknown
+		}
+	}
+
+	return nil
+}
+
+// validateHiddenPanels verifies every hidden panel is a panel of the dashboard
+func validateHiddenPanels(dashboard *models.Dashboard, hidden []int64) error {
+	if len(hidden) == 0 {
+		return nil
+	}
+
+	panels := make(map[int64]struct{})
+	for _, id := range models.GetPanelIdsFromDashboard(dashboard.Data) {
+		panels[id] = struct{}{}
+	}
+	for _, id := range hidden {
+		if _, exists := panels[id]; !exists {
+			return models.ErrPublicDashboardInvalidPanel
+		}
+	}
+
+	return nil
+}
+
+// validateAllowedDatasources verifies every allowed data source is a data source of the org or is queried by the
+// dashboard. An empty allowlist is stored as is, so data sources the dashboard queries later are allowed too.
+func validateAllowedDatasources(sess *sqlstore.DBSession, orgId int64, dashboard *models.Dashboard, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	queried := models.GetDatasourceUidsFromDashboard(dashboard.Data)
+	// data sources the dashboard queries are known even if they aren't stored, like the built-in ones
+	known := make(map[string]struct{}, len(queried))
+	for _, uid := range queried {
+		known[uid] = struct{}{}
+	}
+	var unknown []string
+	for _, uid := range allowed {
+		if _, exists := known[uid]; !exists {
+			known[uid] = struct{}{}
+			unknown = append(unknown, uid)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	count, err := sess.Table("data_source").Where("org_id = ?", orgId).In("uid", unknown).Count()
+	if err != nil {
+		return err
+	}
+	if count != int64(len(unknown)) {
+		return models.ErrPublicDashboardUnknownDatasource
+	}
+
+	return nil
+}
+
+// validatePanelTypes returns a PublicDashboardUnsupportedPanelErr listing the panel types of the dashboard
+// that can't be shown publicly
+func validatePanelTypes(dashboard *models.Dashboard, unsupportedPanels []string) error {
+	if len(unsupportedPanels) == 0 {
+		return nil
+	}
+
+	unsupported := make(map[string]struct{}, len(unsupportedPanels))
+	for _, panelType := range unsupportedPanels {
+		unsupported[panelType] = struct{}{}
+	}
+
+	var found []string
+	for _, panelType := range models.GetPanelTypesFromDashboard(dashboard.Data) {
+		if _, exists := unsupported[panelType]; exists {
+			found = append(found, panelType)
+		}
+	}
+	if len(found) > 0 {
+		return models.PublicDashboardUnsupportedPanelErr{PanelTypes: found}
+	}
+
+	return nil
+}
+
+// validateTheme verifies the theme is either empty, to use the org default, or one of the supported themes
+func validateTheme(theme string) error {
+	switch theme {
+	case "", models.PublicDashboardThemeLight, models.PublicDashboardThemeDark:
+		return nil
+	default:
+		return models.ErrPublicDashboardInvalidTheme
+	}
+}
+
+// publicDashboardSlugPattern matches lowercase words of letters and digits separated by single dashes
+var publicDashboardSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// reservedPublicDashboardSlugs can't be used as slugs, so they stay available for routes next to public dashboards
+var reservedPublicDashboardSlugs = map[string]bool{
+	"api":      true,
+	"new":      true,
+	"edit":     true,
+	"list":     true,
+	"public":   true,
+	"panels":   true,
+	"query":    true,
+	"settings": true,
+}
+
+// validateSlug verifies the optional slug only uses safe characters and isn't a reserved word
+func validateSlug(slug string) error {
+	if slug == "" {
+		return nil
+	}
+
+	if len(slug) > 64 || !publicDashboardSlugPattern.MatchString(slug) || reservedPublicDashboardSlugs[slug] {
+		return models.ErrPublicDashboardInvalidSlug
+	}
+
+	return nil
+}
+
+// checkSlugAvailable verifies no other public dashboard uses the slug, or has it as uid or access token.
+// Public URLs don't carry the org, so slugs must be unique across orgs for lookups to be unambiguous.
+// Deleted public dashboards keep their slug, so it's still theirs if they are restored.
+func checkSlugAvailable(sess *sqlstore.DBSession, pd *models.PublicDashboard) error {
+	if pd.Slug == "" {
+		return nil
+	}
+
+	count, err := sess.Table("dashboard_public_config").
+		Where("uid <> ? AND (slug = ? OR uid = ? OR access_token = ?)", pd.Uid, pd.Slug, pd.Slug, pd.Slug).
+		Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return models.ErrPublicDashboardSlugTaken
+	}
+
+	return nil
+}
+
+// publicDashboardUpgrades upgrade a stored public dashboard from the model version at their index to the next one.
+// Upgrades may only change the columns persisted by upgradePublicDashboard.
+var publicDashboardUpgrades = []func(pd *models.PublicDashboard){
+	// version 1 stores the share type explicitly
+	func(pd *models.PublicDashboard) {
+		if pd.ShareType == "" {
+			pd.ShareType = models.PublicDashboardShareTypePublic
+		}
+	},
+}
+
+// upgradePublicDashboard upgrades a public dashboard stored in an older version of the model
+// to the current version and persists it.
+func upgradePublicDashboard(sess *sqlstore.DBSession, pd *models.PublicDashboard) error {
+	for pd.ModelVersion < models.PublicDashboardModelVersion {
+		publicDashboardUpgrades[pd.ModelVersion](pd)
+		pd.ModelVersion++
+	}
+
+	_, err := sess.Exec("UPDATE dashboard_public_config SET time_settings = ?, share_type = ?, model_version = ? WHERE uid = ?",
+		pd.TimeSettings, pd.ShareType, pd.ModelVersion, pd.Uid)
+	return err
+}
+
+// validateRefreshInterval verifies the refresh interval is either off or a positive duration
+func validateRefreshInterval(refreshInterval string) error {
+	if refreshInterval == models.PublicDashboardRefreshOff {
+		return nil
+	}
+
+	d, err := gtime.ParseDuration(refreshInterval)
+	if err != nil || d <= 0 {
+		return models.ErrPublicDashboardInvalidRefreshInterval
+	}
+
+	return nil
+}
+
+// validateAllowedOrigins verifies every allowed origin is an http or https origin, a scheme and host
+// without any path, query or credentials
+func validateAllowedOrigins(origins []string) error {
+	for _, origin := range origins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return models.ErrPublicDashboardInvalidOrigin
+		}
+		// anything but the scheme and host, even a trailing slash, is lost when the origin is rebuilt
+		if !strings.EqualFold(origin, u.Scheme+"://"+u.Host) {
+			return models.ErrPublicDashboardInvalidOrigin
+		}
+	}
+
+	return nil
+}
+
+// validateTimezone verifies the time zone is either one of the supported time zones or an IANA time zone name
+func validateTimezone(timezone string) error {
+	switch timezone {
+	case models.PublicDashboardTimezoneBrowser, models.PublicDashboardTimezoneUTC:
+		return nil
+	case "", "Local":
+		// both are accepted by time.LoadLocation, but depend on the server rather than naming a time zone
+		return models.ErrPublicDashboardInvalidTimezone
+	}
+
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return models.ErrPublicDashboardInvalidTimezone
+	}
+	return nil
+}
+
+// validateShareType verifies the share type is one of the supported share types
+func validateShareType(shareType string) error {
+	switch shareType {
+	case models.PublicDashboardShareTypePublic, models.PublicDashboardShareTypeAuthenticated:
+		return nil
+	default:
+		return models.ErrPublicDashboardInvalidShareType
+	}
+}
+
+// validateTimeSettings verifies the time settings contain both a from and a to that are valid
+// relative or absolute time expressions. Unset settings, either empty or "{}", are allowed.
+func validateTimeSettings(timeSettings string) error {
+	if timeSettings == "" {
+		return nil
+	}
+
+	var ts map[string]interface{}
+	if err := json.Unmarshal([]byte(timeSettings), &ts); err != nil {
+		return models.ErrPublicDashboardInvalidTimeSettings
+	}
+
+	if len(ts) == 0 {
+		return nil
+	}
+
+	from, ok := ts["from"].(string)
+	if !ok || from == "" {
+		return models.ErrPublicDashboardInvalidTimeSettings
+	}
+	to, ok := ts["to"].(string)
+	if !ok || to == "" {
+		return models.ErrPublicDashboardInvalidTimeSettings
+	}
+
+	timeRange := legacydata.NewDataTimeRange(from, to)
+	if _, err := timeRange.ParseFrom(); err != nil {
+		return models.ErrPublicDashboardInvalidTimeSettings
+	}
+	if _, err := timeRange.ParseTo(); err != nil {
+		return models.ErrPublicDashboardInvalidTimeSettings
+	}
+
+	bounds, err := models.ParsePublicDashboardTimeSettings(timeSettings)
+	if err != nil {
+		return models.ErrPublicDashboardInvalidTimeBounds
+	}
+
+	return validateTimeBounds(bounds)
+}
+
+// validateTimeBounds verifies the optional minTime and maxTime are valid time expressions,
+// and that minTime is before maxTime when both are set.
+func validateTimeBounds(ts models.PublicDashboardTimeSettings) error {
+	now := timeNow()
+
+	var minTime, maxTime time.Time
+	if ts.MinTime != "" {
+		t, err := legacydata.DataTimeRange{From: ts.MinTime, Now: now}.ParseFrom()
+		if err != nil {
+			return models.ErrPublicDashboardInvalidTimeBounds
+		}
+		minTime = t
+	}
+	if ts.MaxTime != "" {
+		t, err := legacydata.DataTimeRange{To: ts.MaxTime, Now: now}.ParseTo()
+		if err != nil {
+			return models.ErrPublicDashboardInvalidTimeBounds
+		}
+		maxTime = t
+	}
+
+	if !minTime.IsZero() && !maxTime.IsZero() && !minTime.Before(maxTime) {
+		return models.ErrPublicDashboardInvalidTimeBounds
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// publicDashboardConfigCache caches public dashboard configurations looked up by their dashboard and by their
+// access token, so that busy public dashboards don't hit the database on every request. Writes to public
+// dashboards invalidate the affected entries once they're committed. The cache is local to the instance, so
+// changes made by other instances are only picked up when entries expire.
+type publicDashboardConfigCache struct {
+	cache   *localcache.CacheService
+	maxSize int
+
+	// generation is bumped on every invalidation, so that lookups which read the database before an
+	// invalidation can't cache what they read afterwards
+	mu         sync.Mutex
+	generation uint64
+}
+
+// newPublicDashboardConfigCache returns a cache holding at most maxSize configurations for ttl, or nil,
+// which disables caching, if either of them isn't positive
+func newPublicDashboardConfigCache(ttl time.Duration, maxSize int) *publicDashboardConfigCache {
+	if ttl <= 0 || maxSize <= 0 {
+		return nil
+	}
+
+	return &publicDashboardConfigCache{
+		cache:   localcache.New(ttl, 2*ttl),
+		maxSize: maxSize,
+	}
+}
+
+func publicDashboardConfigDashboardKey(orgId int64, dashboardUid string) string {
+	return fmt.Sprintf("dashboard|%d|%s", orgId, dashboardUid)
+}
+
+func publicDashboardConfigAccessTokenKey(accessToken string) string {
+	return "token|" + accessToken
+}
+
+// get returns a copy of the cached configuration. The returned generation must be passed to set when
+// caching the configuration read from the database instead.
+func (c *publicDashboardConfigCache) get(key string) (*models.PublicDashboardConfig, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	cached, found := c.cache.Get(key)
+	if !found {
+		return nil, generation, false
+	}
+
+	pdc := cached.(models.PublicDashboardConfig)
+	return &pdc, generation, true
+}
+
+// set caches the configuration, unless the cache was invalidated since generation was returned by get or
+// the cache is full
+func (c *publicDashboardConfigCache) set(key string, pdc *models.PublicDashboardConfig, generation uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+
+	if c.cache.ItemCount() >= c.maxSize {
+		c.cache.DeleteExpired()
+		if c.cache.ItemCount() >= c.maxSize {
+			return
+		}
+	}
+
+	c.cache.SetDefault(key, *pdc)
+}
+
+// invalidate drops the cached configuration of the dashboard, both by dashboard and by access token
+func (c *publicDashboardConfigCache) invalidate(orgId int64, dashboardUid string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+
+	c.cache.Delete(publicDashboardConfigDashboardKey(orgId, dashboardUid))
+	for key, item := range c.cache.Items() {
+		pdc, ok := item.Object.(models.PublicDashboardConfig)
+		if ok && pdc.PublicDashboard.OrgId == orgId && pdc.PublicDashboard.DashboardUid == dashboardUid {
+			c.cache.Delete(key)
+		}
+	}
+}
+
+// invalidateAll drops every cached configuration, for writes that affect many public dashboards
+func (c *publicDashboardConfigCache) invalidateAll() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.cache.Flush()
+}
+
+// DisablePublicDashboardConfigCache makes the store read public dashboard configurations from the database
+// on every lookup, which is useful for tests.
+func (d *DashboardStore) DisablePublicDashboardConfigCache() {
+	d.publicDashboardConfigCache = nil
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationPublicDashboardConfigCache(t *testing.T) {
+	var sqlStore *sqlstore.SQLStore
+	var dashboardStore *DashboardStore
+	var savedDashboard *models.Dashboard
+
+	setup := func() {
+		sqlStore = sqlstore.InitTestDB(t, sqlstore.InitTestDBOpt{FeatureFlags: []string{featuremgmt.FlagPublicDashboards}})
+		dashboardStore = ProvideDashboardStore(sqlStore)
+		dashboardStore.publicDashboardConfigCache = newPublicDashboardConfigCache(time.Minute, 10)
+		savedDashboard = insertTestDashboard(t, dashboardStore, "testDashie", 1, 0, true)
+	}
+
+	save := func(t *testing.T, isPublic bool, theme string) *models.PublicDashboardConfig {
+		t.Helper()
+		pdc, err := dashboardStore.SavePublicDashboardConfig(models.SavePublicDashboardConfigCommand{
+			DashboardUid: savedDashboard.Uid,
+			OrgId:        savedDashboard.OrgId,
+			PublicDashboardConfig: models.PublicDashboardConfig{
+				IsPublic: isPublic,
+				PublicDashboard: models.PublicDashboard{
+					DashboardUid: savedDashboard.Uid,
+					OrgId:        savedDashboard.OrgId,
+					Theme:        theme,
+				},
+			},
+		})
+		require.NoError(t, err)
+		return pdc
+	}
+
+	// setThemeInDatabase changes the public dashboard behind the store's back, so only lookups that miss
+	// the cache see the change
+	setThemeInDatabase := func(t *testing.T, uid, theme string) {
+		t.Helper()
+		err := sqlStore.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+			_, err := sess.Exec("UPDATE dashboard_public_config SET theme = ? WHERE uid = ?", theme, uid)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("reads the database on a miss and serves hits from the cache", func(t *testing.T) {
+		setup()
+		saved := save(t, true, "dark")
+
+		pdc, err := dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+		assert.Equal(t, "dark", pdc.PublicDashboard.Theme)
+		pdc, err = dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "dark", pdc.PublicDashboard.Theme)
+
+		setThemeInDatabase(t, saved.PublicDashboard.Uid, "light")
+
+		pdc, err = dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+		assert.Equal(t, "dark", pdc.PublicDashboard.Theme)
+		pdc, err = dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "dark", pdc.PublicDashboard.Theme)
+	})
+
+	t.Run("hits return copies of the cached config", func(t *testing.T) {
+		setup()
+		save(t, true, "dark")
+
+		pdc, err := dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+		pdc.PublicDashboard.Theme = "light"
+
+		pdc, err = dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+		assert.Equal(t, "dark", pdc.PublicDashboard.Theme)
+	})
+
+	t.Run("invalidates lookups by dashboard and access token on update", func(t *testing.T) {
+		setup()
+		saved := save(t, true, "dark")
+
+		_, err := dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+		_, err = dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+
+		save(t, false, "light")
+
+		pdc, err := dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+		assert.False(t, pdc.IsPublic)
+		assert.Equal(t, "light", pdc.PublicDashboard.Theme)
+		pdc, err = dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+		assert.False(t, pdc.IsPublic)
+		assert.Equal(t, "light", pdc.PublicDashboard.Theme)
+	})
+
+	t.Run("disabled state doesn't linger after disabling all public dashboards", func(t *testing.T) {
+		setup()
+		saved := save(t, true, "dark")
+
+		pdc, err := dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+		require.True(t, pdc.IsPublic)
+
+		_, err = dashboardStore.DisableAllPublicDashboards(context.Background(), savedDashboard.OrgId)
+		require.NoError(t, err)
+
+		pdc, err = dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+		assert.False(t, pdc.IsPublic)
+	})
+
+	t.Run("rotated access tokens stop resolving", func(t *testing.T) {
+		setup()
+		saved := save(t, true, "dark")
+
+		_, err := dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+
+		_, err = dashboardStore.RotatePublicDashboardAccessToken(context.Background(), savedDashboard.OrgId, saved.PublicDashboard.Uid)
+		require.NoError(t, err)
+
+		_, err = dashboardStore.GetPublicDashboardConfigByAccessToken(context.Background(), saved.PublicDashboard.AccessToken)
+		require.True(t, errors.Is(err, models.ErrPublicDashboardNotFound))
+	})
+
+	t.Run("reads the database on every lookup when disabled", func(t *testing.T) {
+		setup()
+		dashboardStore.DisablePublicDashboardConfigCache()
+		saved := save(t, true, "dark")
+
+		_, err := dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+
+		setThemeInDatabase(t, saved.PublicDashboard.Uid, "light")
+
+		pdc, err := dashboardStore.GetPublicDashboardConfig(savedDashboard.OrgId, savedDashboard.Uid)
+		require.NoError(t, err)
+		assert.Equal(t, "light", pdc.PublicDashboard.Theme)
+	})
+}
+
+func TestPublicDashboardConfigCache(t *testing.T) {
+	pdc := &models.PublicDashboardConfig{
+		IsPublic:        true,
+		PublicDashboard: models.PublicDashboard{OrgId: 1, DashboardUid: "dash-uid", AccessToken: "token"},
+	}
+
+	t.Run("is disabled without a TTL or size", func(t *testing.T) {
+		assert.Nil(t, newPublicDashboardConfigCache(0, 10))
+		assert.Nil(t, newPublicDashboardConfigCache(time.Minute, 0))
+	})
+
+	t.Run("doesn't cache lookups that raced with an invalidation", func(t *testing.T) {
+		c := newPublicDashboardConfigCache(time.Minute, 10)
+		key := publicDashboardConfigDashboardKey(1, "dash-uid")
+
+		_, generation, found := c.get(key)
+		require.False(t, found)
+		c.invalidate(1, "dash-uid")
+		c.set(key, pdc, generation)
+
+		_, _, found = c.get(key)
+		assert.False(t, found)
+	})
+
+	t.Run("invalidates only the affected dashboard", func(t *testing.T) {
+		c := newPublicDashboardConfigCache(time.Minute, 10)
+		other := &models.PublicDashboardConfig{PublicDashboard: models.PublicDashboard{OrgId: 1, DashboardUid: "other-uid", AccessToken: "other-token"}}
+		for key, value := range map[string]*models.PublicDashboardConfig{
+			publicDashboardConfigDashboardKey(1, "dash-uid"):   pdc,
+			publicDashboardConfigAccessTokenKey("token"):       pdc,
+			publicDashboardConfigDashboardKey(1, "other-uid"):  other,
+			publicDashboardConfigAccessTokenKey("other-token"): other,
+		} {
+			_, generation, _ := c.get(key)
+			c.set(key, value, generation)
+		}
+
+		c.invalidate(1, "dash-uid")
+
+		_, _, found := c.get(publicDashboardConfigDashboardKey(1, "dash-uid"))
+		assert.False(t, found)
+		_, _, found = c.get(publicDashboardConfigAccessTokenKey("token"))
+		assert.False(t, found)
+		_, _, found = c.get(publicDashboardConfigDashboardKey(1, "other-uid"))
+		assert.True(t, found)
+		_, _, found = c.get(publicDashboardConfigAccessTokenKey("other-token"))
+		assert.True(t, found)
+	})
+
+	t.Run("doesn't grow past its size", func(t *testing.T) {
+		c := newPublicDashboardConfigCache(time.Minute, 1)
+
+		_, generation, _ := c.get("first")
+		c.set("first", pdc, generation)
+		_, generation, _ = c.get("second")
+		c.set("second", pdc, generation)
+
+		_, _, found := c.get("first")
+		assert.True(t, found)
+		_, _, found = c.get("second")
+		assert.False(t, found)
+	})
+}
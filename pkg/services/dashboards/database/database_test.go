@@ -13,6 +13,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/searchstore"
@@ -500,6 +501,62 @@ func TestIntegrationDashboardDataAccessGivenPluginWithImportedDashboards(t *test
 	require.Equal(t, len(query.Result), 2)
 }
 
+func TestIntegrationDashboardsUsingDatasourcePlugin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	sqlStore := sqlstore.InitTestDB(t)
+	dashboardStore := ProvideDashboardStore(sqlStore)
+
+	for _, cmd := range []*models.AddDataSourceCommand{
+		{OrgId: 1, Name: "Loki", Type: "loki", Access: models.DS_ACCESS_PROXY, Uid: "loki-uid"},
+		{OrgId: 2, Name: "Loki", Type: "loki", Access: models.DS_ACCESS_PROXY, Uid: "other-loki-uid"},
+		{OrgId: 1, Name: "Prometheus", Type: "prometheus", Access: models.DS_ACCESS_PROXY, Uid: "prom-uid"},
+	} {
+		err := sqlStore.AddDataSource(context.Background(), cmd)
+		require.NoError(t, err)
+	}
+
+	save := func(title string, orgID int64, panels ...interface{}) *models.Dashboard {
+		dash, err := dashboardStore.SaveDashboard(models.SaveDashboardCommand{
+			OrgId: orgID,
+			Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"id":     nil,
+				"title":  title,
+				"panels": panels,
+			}),
+		})
+		require.NoError(t, err)
+		return dash
+	}
+	panel := func(datasource interface{}) map[string]interface{} {
+		return map[string]interface{}{"id": 1, "datasource": datasource, "targets": []interface{}{}}
+	}
+
+	byUID := save("Logs", 1, panel(map[string]interface{}{"uid": "loki-uid"}))
+	byName := save("Legacy logs", 1, panel("Loki"))
+	otherOrg := save("Logs", 2, panel(map[string]interface{}{"uid": "other-loki-uid"}))
+	save("Metrics", 1, panel(map[string]interface{}{"uid": "prom-uid"}))
+	// mentions the data source without referring to it
+	save("Loki", 1, panel(map[string]interface{}{"uid": "prom-uid"}))
+
+	t.Run("returns dashboards referring to data sources of the plugin in all orgs", func(t *testing.T) {
+		usages, err := dashboardStore.DashboardsUsingDatasourcePlugin(context.Background(), "loki")
+		require.NoError(t, err)
+		require.Equal(t, []plugins.DashboardUsage{
+			{OrgID: 1, UID: byName.Uid, Title: "Legacy logs"},
+			{OrgID: 1, UID: byUID.Uid, Title: "Logs"},
+			{OrgID: 2, UID: otherOrg.Uid, Title: "Logs"},
+		}, usages)
+	})
+
+	t.Run("returns nothing if no dashboard uses the plugin", func(t *testing.T) {
+		usages, err := dashboardStore.DashboardsUsingDatasourcePlugin(context.Background(), "graphite")
+		require.NoError(t, err)
+		require.Empty(t, usages)
+	})
+}
+
 func TestIntegrationDashboard_SortingOptions(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
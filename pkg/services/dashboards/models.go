@@ -18,9 +18,32 @@ type SaveDashboardDTO struct {
 type SavePublicDashboardConfigDTO struct {
 	DashboardUid          string
 	OrgId                 int64
+	UserId                int64
 	PublicDashboardConfig *models.PublicDashboardConfig
 }
 
+// PublicDashboardAuditAction is a change made to a public dashboard that is recorded in the audit log.
+type PublicDashboardAuditAction string
+
+const (
+	PublicDashboardCreated  PublicDashboardAuditAction = "created"
+	PublicDashboardUpdated  PublicDashboardAuditAction = "updated"
+	PublicDashboardEnabled  PublicDashboardAuditAction = "enabled"
+	PublicDashboardDisabled PublicDashboardAuditAction = "disabled"
+	PublicDashboardDeleted  PublicDashboardAuditAction = "deleted"
+)
+
+// PublicDashboardAuditEntry describes a single change to a public dashboard, including whether the
+// dashboard was public before and after the change.
+type PublicDashboardAuditEntry struct {
+	ActorUserId  int64
+	OrgId        int64
+	DashboardUid string
+	Action       PublicDashboardAuditAction
+	WasEnabled   bool
+	IsEnabled    bool
+}
+
 type DashboardSearchProjection struct {
 	ID          int64  `xorm:"id"`
 	UID         string `xorm:"uid"`
@@ -3,15 +3,21 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"strconv"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/tsdb/legacydata"
 )
 
-// Gets public dashboard via generated Uid
-func (dr *DashboardServiceImpl) GetPublicDashboard(ctx context.Context, dashboardUid string) (*models.Dashboard, error) {
-	pdc, d, err := dr.dashboardStore.GetPublicDashboard(dashboardUid)
+// Gets public dashboard via its access token or slug. viewer is the user viewing it, which is anonymous if they aren't signed in.
+// password is the password the viewer entered, which is only required if the public dashboard is password protected.
+func (dr *DashboardServiceImpl) GetPublicDashboard(ctx context.Context, accessToken string, password string, viewer *models.SignedInUser) (*models.Dashboard, error) {
+	pdc, d, err := dr.dashboardStore.GetPublicDashboard(accessToken)
 
 	if err != nil {
 		return nil, err
@@ -22,7 +28,16 @@ func (dr *DashboardServiceImpl) GetPublicDashboard(ctx context.Context, dashboar
 	}
 
 	if !d.IsPublic {
-		return nil, models.ErrPublicDashboardNotFound
+		return nil, models.ErrPublicDashboardDisabled
+	}
+
+	if !pdc.IsViewableBy(viewer) {
+		return nil, models.ErrPublicDashboardAuthRequired
+	}
+
+	// viewers are prompted for the password until they enter the right one
+	if err := pdc.VerifyPassword(password); err != nil {
+		return nil, err
 	}
 
 	// Replace dashboard time range with pubdash time range
@@ -36,9 +51,174 @@ func (dr *DashboardServiceImpl) GetPublicDashboard(ctx context.Context, dashboar
 		d.Data.Set("time", pdcTimeSettings)
 	}
 
+	// Strip annotation queries unless they were enabled for the public dashboard
+	if !pdc.AnnotationsEnabled && d.Data != nil {
+		if annotations, ok := d.Data.CheckGet("annotations"); ok {
+			annotations.Set("list", []interface{}{})
+		}
+	}
+
+	if d.Data != nil {
+		lockVariables(d.Data, pdc.AllowedVariables, pdc.VariableOverrides)
+		removeHiddenPanels(d.Data, pdc.HiddenPanelIds)
+		overrideRefresh(d.Data, pdc.RefreshInterval)
+	}
+
+	return d, nil
+}
+
+// GetPublicDashboardForRendering gets the public dashboard the way it's served to its viewers. On top of the
+// public config's hide rules applied by GetPublicDashboard, the fields that are only meant for users of the
+// instance are stripped: the internal ids, links into the instance, alert rules and data source references.
+// The queries are run by panel on the server, so viewers don't need to know which data sources they hit.
+func (dr *DashboardServiceImpl) GetPublicDashboardForRendering(ctx context.Context, accessToken string, password string, viewer *models.SignedInUser) (*models.Dashboard, error) {
+	d, err := dr.GetPublicDashboard(ctx, accessToken, password, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Id = 0
+	d.FolderId = 0
+	d.CreatedBy = 0
+	d.UpdatedBy = 0
+	d.HasAcl = false
+
+	if d.Data != nil {
+		sanitizeDashboardData(d.Data)
+	}
+
 	return d, nil
 }
 
+// sanitizeDashboardData strips the internal-only fields from the dashboard JSON, including the ones of the
+// panels collapsed into rows.
+func sanitizeDashboardData(data *simplejson.Json) {
+	data.Del("id")
+	data.Del("links")
+
+	var sanitize func(panels []interface{}) []interface{}
+	sanitize = func(panels []interface{}) []interface{} {
+		for i := range panels {
+			panel := simplejson.NewFromAny(panels[i])
+			panel.Del("alert")
+			panel.Del("links")
+			panel.Del("libraryPanel")
+			panel.Del("datasource")
+			// data links point into the instance just like panel links
+			panel.GetPath("fieldConfig", "defaults").Del("links")
+
+			if targets, ok := panel.CheckGet("targets"); ok {
+				queries := targets.MustArray()
+				for j := range queries {
+					query := simplejson.NewFromAny(queries[j])
+					query.Del("datasource")
+					queries[j] = query.Interface()
+				}
+				panel.Set("targets", queries)
+			}
+
+			if nested, ok := panel.CheckGet("panels"); ok {
+				panel.Set("panels", sanitize(nested.MustArray()))
+			}
+			panels[i] = panel.Interface()
+		}
+		return panels
+	}
+
+	if panels, ok := data.CheckGet("panels"); ok {
+		data.Set("panels", sanitize(panels.MustArray()))
+	}
+}
+
+// lockVariables hides the template variables that aren't allowed and locks them to their saved value, or to
+// their override if there is one. Allowed variables start out with their override.
+// The queries of locked variables are removed, so the data source queries behind them aren't exposed.
+func lockVariables(data *simplejson.Json, allowed []string, overrides map[string]string) {
+	allowedNames := make(map[string]struct{}, len(allowed))
+	for _, name := range allowed {
+		allowedNames[name] = struct{}{}
+	}
+
+	variables := data.GetPath("templating", "list").MustArray()
+	for i := range variables {
+		variable := simplejson.NewFromAny(variables[i])
+		name := variable.Get("name").MustString()
+		if value, ok := overrides[name]; ok {
+			variable.Set("current", map[string]interface{}{"text": value, "value": value, "selected": true})
+		}
+
+		if _, ok := allowedNames[name]; ok {
+			variables[i] = variable.Interface()
+			continue
+		}
+
+		// 2 hides the variable along with its label
+		variable.Set("hide", 2)
+		variable.Set("refresh", 0)
+		variable.Del("query")
+		variable.Del("definition")
+		if current, ok := variable.CheckGet("current"); ok {
+			variable.Set("options", []interface{}{current.Interface()})
+		} else {
+			variable.Set("options", []interface{}{})
+		}
+		variables[i] = variable.Interface()
+	}
+
+	if len(variables) > 0 {
+		data.SetPath([]string{"templating", "list"}, variables)
+	}
+}
+
+// overrideRefresh replaces the auto-refresh of the dashboard with the refresh interval of the public dashboard.
+// Public dashboards saved before refresh intervals keep the refresh of their dashboard.
+func overrideRefresh(data *simplejson.Json, refreshInterval string) {
+	switch refreshInterval {
+	case "":
+		return
+	case models.PublicDashboardRefreshOff:
+		// dashboards without a refresh don't auto-refresh
+		data.Set("refresh", "")
+	default:
+		data.Set("refresh", refreshInterval)
+	}
+}
+
+// removeHiddenPanels removes the hidden panels from the dashboard, including the ones in collapsed rows.
+// Hiding a row hides the panels collapsed into it along with it.
+func removeHiddenPanels(data *simplejson.Json, hidden []int64) {
+	if len(hidden) == 0 {
+		return
+	}
+
+	hiddenIds := make(map[int64]struct{}, len(hidden))
+	for _, id := range hidden {
+		hiddenIds[id] = struct{}{}
+	}
+
+	var filter func(panels []interface{}) []interface{}
+	filter = func(panels []interface{}) []interface{} {
+		visible := make([]interface{}, 0, len(panels))
+		for _, panelObj := range panels {
+			panel := simplejson.NewFromAny(panelObj)
+			if id, err := panel.Get("id").Int64(); err == nil {
+				if _, ok := hiddenIds[id]; ok {
+					continue
+				}
+			}
+			if nested, ok := panel.CheckGet("panels"); ok {
+				panel.Set("panels", filter(nested.MustArray()))
+			}
+			visible = append(visible, panel.Interface())
+		}
+		return visible
+	}
+
+	if panels, ok := data.CheckGet("panels"); ok {
+		data.Set("panels", filter(panels.MustArray()))
+	}
+}
+
 // GetPublicDashboardConfig is a helper method to retrieve the public dashboard configuration for a given dashboard from the database
 func (dr *DashboardServiceImpl) GetPublicDashboardConfig(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboardConfig, error) {
 	pdc, err := dr.dashboardStore.GetPublicDashboardConfig(orgId, dashboardUid)
@@ -49,6 +229,18 @@ func (dr *DashboardServiceImpl) GetPublicDashboardConfig(ctx context.Context, or
 	return pdc, nil
 }
 
+// GetEnabledPublicDashboardByDashboardUid returns the public dashboard of a dashboard if it's enabled and within its
+// enabled window, and fails with models.ErrPublicDashboardNotFound otherwise. Unlike GetPublicDashboardConfig, it never returns an empty config.
+func (dr *DashboardServiceImpl) GetEnabledPublicDashboardByDashboardUid(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboard, error) {
+	return dr.dashboardStore.GetEnabledPublicDashboardByDashboardUid(ctx, orgId, dashboardUid)
+}
+
+// ResolvePublicDashboardUsers sets the users that created and last updated the public dashboard config.
+// It's kept separate from GetPublicDashboardConfig, so that only callers who need the users pay for the lookup.
+func (dr *DashboardServiceImpl) ResolvePublicDashboardUsers(ctx context.Context, pdc *models.PublicDashboardConfig) error {
+	return dr.dashboardStore.ResolvePublicDashboardUsers(ctx, pdc)
+}
+
 // SavePublicDashboardConfig is a helper method to persist the sharing config
 // to the database. It handles validations for sharing config and persistence
 func (dr *DashboardServiceImpl) SavePublicDashboardConfig(ctx context.Context, dto *dashboards.SavePublicDashboardConfigDTO) (*models.PublicDashboardConfig, error) {
@@ -61,43 +253,229 @@ func (dr *DashboardServiceImpl) SavePublicDashboardConfig(ctx context.Context, d
 	// Eventually we want this to propagate to array of public dashboards
 	cmd.PublicDashboardConfig.PublicDashboard.OrgId = dto.OrgId
 	cmd.PublicDashboardConfig.PublicDashboard.DashboardUid = dto.DashboardUid
+	cmd.PublicDashboardConfig.PublicDashboard.UpdatedBy = dto.UserId
+
+	existing, err := dr.dashboardStore.GetPublicDashboardConfig(dto.OrgId, dto.DashboardUid)
+	if err != nil {
+		return nil, err
+	}
 
 	pdc, err := dr.dashboardStore.SavePublicDashboardConfig(cmd)
 	if err != nil {
 		return nil, err
 	}
 
+	// the store commits the change before returning, so a failing audit log can't roll it back
+	dr.auditPublicDashboard(ctx, dashboards.PublicDashboardAuditEntry{
+		ActorUserId:  dto.UserId,
+		OrgId:        dto.OrgId,
+		DashboardUid: dto.DashboardUid,
+		Action:       publicDashboardSaveAction(existing, pdc),
+		WasEnabled:   existing.IsPublic,
+		IsEnabled:    pdc.IsPublic,
+	})
+
 	return pdc, nil
 }
 
-func (dr *DashboardServiceImpl) BuildPublicDashboardMetricRequest(ctx context.Context, publicDashboardUid string, panelId int64) (dtos.MetricRequest, error) {
-	publicDashboardConfig, dashboard, err := dr.dashboardStore.GetPublicDashboard(publicDashboardUid)
+// DeletePublicDashboardConfig deletes the public dashboard configuration of a dashboard, which makes the
+// dashboard private again.
+func (dr *DashboardServiceImpl) DeletePublicDashboardConfig(ctx context.Context, orgId int64, userId int64, dashboardUid string) error {
+	existing, err := dr.dashboardStore.GetPublicDashboardConfig(orgId, dashboardUid)
 	if err != nil {
-		return dtos.MetricRequest{}, err
+		return err
+	}
+	if existing.PublicDashboard.Uid == "" {
+		return models.ErrPublicDashboardNotFound
+	}
+
+	if err := dr.dashboardStore.DeletePublicDashboardConfig(ctx, orgId, existing.PublicDashboard.Uid); err != nil {
+		return err
+	}
+
+	dr.auditPublicDashboard(ctx, dashboards.PublicDashboardAuditEntry{
+		ActorUserId:  userId,
+		OrgId:        orgId,
+		DashboardUid: dashboardUid,
+		Action:       dashboards.PublicDashboardDeleted,
+		WasEnabled:   existing.IsPublic,
+		IsEnabled:    false,
+	})
+
+	return nil
+}
+
+// publicDashboardSaveAction returns the audit action of saving a public dashboard config, where
+// existing is the config before it was saved.
+func publicDashboardSaveAction(existing, saved *models.PublicDashboardConfig) dashboards.PublicDashboardAuditAction {
+	switch {
+	case existing.PublicDashboard.Uid == "":
+		return dashboards.PublicDashboardCreated
+	case !existing.IsPublic && saved.IsPublic:
+		return dashboards.PublicDashboardEnabled
+	case existing.IsPublic && !saved.IsPublic:
+		return dashboards.PublicDashboardDisabled
+	default:
+		return dashboards.PublicDashboardUpdated
+	}
+}
+
+func (dr *DashboardServiceImpl) auditPublicDashboard(ctx context.Context, entry dashboards.PublicDashboardAuditEntry) {
+	if dr.publicDashboardAudit == nil {
+		return
+	}
+	dr.publicDashboardAudit.Log(ctx, entry)
+}
+
+// publicDashboardAuditLog writes the public dashboard audit trail to a dedicated logger.
+type publicDashboardAuditLog struct {
+	log log.Logger
+}
+
+func newPublicDashboardAuditLog() *publicDashboardAuditLog {
+	return &publicDashboardAuditLog{log: log.New("public-dashboards.audit")}
+}
+
+func (l *publicDashboardAuditLog) Log(ctx context.Context, entry dashboards.PublicDashboardAuditEntry) {
+	l.log.Info("Public dashboard changed",
+		"action", entry.Action,
+		"userId", entry.ActorUserId,
+		"orgId", entry.OrgId,
+		"dashboardUid", entry.DashboardUid,
+		"wasEnabled", entry.WasEnabled,
+		"isEnabled", entry.IsEnabled,
+	)
+}
+
+// BuildPublicDashboardMetricRequest builds the request for the queries of a panel of a public dashboard.
+// The time range requested by the viewer is only used if the public dashboard bounds the time range,
+// clamped to those bounds. Otherwise the saved time range of the public dashboard is used.
+// Queries of data sources the public dashboard doesn't allow aren't run, and neither are the queries of
+// authenticated public dashboards for viewers that aren't signed in to their org, or the queries of password
+// protected public dashboards for viewers that didn't enter the right password.
+// It also returns how long the queries may run before they have to be cancelled, and how long their responses
+// may be cached.
+func (dr *DashboardServiceImpl) BuildPublicDashboardMetricRequest(ctx context.Context, accessToken string, password string, panelId int64, reqDTO dtos.PublicDashboardQueryDTO, viewer *models.SignedInUser) (dtos.MetricRequest, models.PublicDashboardQueryOptions, error) {
+	publicDashboardConfig, dashboard, err := dr.dashboardStore.GetPublicDashboard(accessToken)
+	if err != nil {
+		return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, err
 	}
 
 	if !dashboard.IsPublic {
-		return dtos.MetricRequest{}, models.ErrPublicDashboardNotFound
+		return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardDisabled
+	}
+
+	if !publicDashboardConfig.IsViewableBy(viewer) {
+		return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardAuthRequired
+	}
+
+	if err := publicDashboardConfig.VerifyPassword(password); err != nil {
+		return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, err
+	}
+
+	// only the queries published with the public dashboard are run, not the ones the dashboard was edited to since
+	if publicDashboardConfig.QuerySignature != "" {
+		signature, err := models.GetQuerySignatureFromDashboard(dashboard.Data)
+		if err != nil {
+			return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, err
+		}
+		if signature != publicDashboardConfig.QuerySignature {
+			return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardQueriesChanged
+		}
 	}
 
-	var timeSettings struct {
-		From string `json:"from"`
-		To   string `json:"to"`
+	timeSettings, err := models.ParsePublicDashboardTimeSettings(publicDashboardConfig.TimeSettings)
+	if err != nil {
+		return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, err
 	}
-	err = json.Unmarshal([]byte(publicDashboardConfig.TimeSettings), &timeSettings)
+
+	from, to, err := boundTimeRange(timeSettings, reqDTO, time.Now())
 	if err != nil {
-		return dtos.MetricRequest{}, err
+		return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, err
+	}
+
+	// hidden panels aren't shown publicly, so their queries can't be run either
+	for _, hiddenId := range publicDashboardConfig.HiddenPanelIds {
+		if hiddenId == panelId {
+			return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardPanelNotFound
+		}
 	}
 
 	queriesByPanel := models.GetQueriesFromDashboard(dashboard.Data)
 
 	if _, ok := queriesByPanel[panelId]; !ok {
-		return dtos.MetricRequest{}, models.ErrPublicDashboardPanelNotFound
+		return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardPanelNotFound
+	}
+
+	// queries that don't refer to their data source by uid can't be checked, so they're only run if any is allowed
+	for _, query := range queriesByPanel[panelId] {
+		uid := query.GetPath("datasource", "uid").MustString()
+		if !publicDashboardConfig.IsDatasourceAllowed(uid) {
+			return dtos.MetricRequest{}, models.PublicDashboardQueryOptions{}, models.ErrPublicDashboardDatasourceNotAllowed
+		}
 	}
 
 	return dtos.MetricRequest{
-		From:    timeSettings.From,
-		To:      timeSettings.To,
+		From:    from,
+		To:      to,
 		Queries: queriesByPanel[panelId],
+	}, models.PublicDashboardQueryOptions{
+		MaxDuration: time.Duration(publicDashboardConfig.MaxQueryDurationSeconds) * time.Second,
+		CacheTTL:    time.Duration(publicDashboardConfig.CacheTTLSeconds) * time.Second,
 	}, nil
 }
+
+// boundTimeRange returns the time range to query a public dashboard for. The requested time range
+// falls back to the saved one for the parts that aren't set, and is clamped to the min and max time.
+// It fails with models.ErrPublicDashboardTimeRangeOutOfBounds if nothing of it is within the bounds.
+func boundTimeRange(ts models.PublicDashboardTimeSettings, reqDTO dtos.PublicDashboardQueryDTO, now time.Time) (string, string, error) {
+	if !ts.HasBounds() {
+		return ts.From, ts.To, nil
+	}
+
+	from, to := ts.From, ts.To
+	if reqDTO.From != "" {
+		from = reqDTO.From
+	}
+	if reqDTO.To != "" {
+		to = reqDTO.To
+	}
+
+	// relative times are parsed against the Now of the time range, which WithNow doesn't override
+	timeRange := legacydata.DataTimeRange{From: from, To: to, Now: now}
+	fromTime, err := timeRange.ParseFrom()
+	if err != nil {
+		return "", "", models.ErrPublicDashboardInvalidTimeSettings
+	}
+	toTime, err := timeRange.ParseTo()
+	if err != nil {
+		return "", "", models.ErrPublicDashboardInvalidTimeSettings
+	}
+
+	if ts.MinTime != "" {
+		minTime, err := legacydata.DataTimeRange{From: ts.MinTime, Now: now}.ParseFrom()
+		if err != nil {
+			return "", "", models.ErrPublicDashboardInvalidTimeBounds
+		}
+		if fromTime.Before(minTime) {
+			fromTime = minTime
+			from = strconv.FormatInt(minTime.UnixMilli(), 10)
+		}
+	}
+	if ts.MaxTime != "" {
+		maxTime, err := legacydata.DataTimeRange{To: ts.MaxTime, Now: now}.ParseTo()
+		if err != nil {
+			return "", "", models.ErrPublicDashboardInvalidTimeBounds
+		}
+		if toTime.After(maxTime) {
+			toTime = maxTime
+			to = strconv.FormatInt(maxTime.UnixMilli(), 10)
+		}
+	}
+
+	if !fromTime.Before(toTime) {
+		return "", "", models.ErrPublicDashboardTimeRangeOutOfBounds
+	}
+
+	return from, to, nil
+}
@@ -2,17 +2,22 @@ package service
 
 import (
 	"context"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/dashboards/database"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestGetPublicDashboard(t *testing.T) {
@@ -23,22 +28,22 @@ func TestGetPublicDashboard(t *testing.T) {
 	}
 
 	testCases := []struct {
-		name      string
-		uid       string
-		storeResp *storeResp
-		errResp   error
-		dashResp  *models.Dashboard
+		name        string
+		accessToken string
+		storeResp   *storeResp
+		errResp     error
+		dashResp    *models.Dashboard
 	}{
 		{
-			name:      "returns a dashboard",
-			uid:       "abc123",
-			storeResp: &storeResp{pd: &models.PublicDashboard{}, d: &models.Dashboard{IsPublic: true}, err: nil},
-			errResp:   nil,
-			dashResp:  &models.Dashboard{IsPublic: true},
+			name:        "returns a dashboard",
+			accessToken: "abc123",
+			storeResp:   &storeResp{pd: &models.PublicDashboard{}, d: &models.Dashboard{IsPublic: true}, err: nil},
+			errResp:     nil,
+			dashResp:    &models.Dashboard{IsPublic: true},
 		},
 		{
-			name: "puts pubdash time settings into dashboard",
-			uid:  "abc123",
+			name:        "puts pubdash time settings into dashboard",
+			accessToken: "abc123",
 			storeResp: &storeResp{
 				pd: &models.PublicDashboard{TimeSettings: `{"from": "now-8", "to": "now"}`},
 				d: &models.Dashboard{
@@ -50,25 +55,147 @@ func TestGetPublicDashboard(t *testing.T) {
 			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"time": map[string]interface{}{"from": "now-8", "to": "now"}})},
 		},
 		{
-			name:      "returns ErrPublicDashboardNotFound when isPublic is false",
-			uid:       "abc123",
-			storeResp: &storeResp{pd: &models.PublicDashboard{}, d: &models.Dashboard{IsPublic: false}, err: nil},
-			errResp:   models.ErrPublicDashboardNotFound,
-			dashResp:  nil,
+			name:        "strips annotations when annotations are disabled",
+			accessToken: "abc123",
+			storeResp: &storeResp{
+				pd: &models.PublicDashboard{AnnotationsEnabled: false},
+				d: &models.Dashboard{
+					IsPublic: true,
+					Data:     simplejson.NewFromAny(map[string]interface{}{"annotations": map[string]interface{}{"list": []interface{}{map[string]interface{}{"name": "Annotations & Alerts"}}}}),
+				},
+				err: nil},
+			errResp:  nil,
+			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"annotations": map[string]interface{}{"list": []interface{}{}}})},
 		},
 		{
-			name:      "returns ErrPublicDashboardNotFound if PublicDashboard missing",
-			uid:       "abc123",
-			storeResp: &storeResp{pd: nil, d: nil, err: nil},
-			errResp:   models.ErrPublicDashboardNotFound,
-			dashResp:  nil,
+			name:        "keeps annotations when annotations are enabled",
+			accessToken: "abc123",
+			storeResp: &storeResp{
+				pd: &models.PublicDashboard{AnnotationsEnabled: true},
+				d: &models.Dashboard{
+					IsPublic: true,
+					Data:     simplejson.NewFromAny(map[string]interface{}{"annotations": map[string]interface{}{"list": []interface{}{map[string]interface{}{"name": "Annotations & Alerts"}}}}),
+				},
+				err: nil},
+			errResp:  nil,
+			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"annotations": map[string]interface{}{"list": []interface{}{map[string]interface{}{"name": "Annotations & Alerts"}}}})},
 		},
 		{
-			name:      "returns ErrPublicDashboardNotFound if Dashboard missing",
-			uid:       "abc123",
-			storeResp: &storeResp{pd: nil, d: nil, err: nil},
-			errResp:   models.ErrPublicDashboardNotFound,
-			dashResp:  nil,
+			name:        "hides and locks variables that aren't allowed",
+			accessToken: "abc123",
+			storeResp: &storeResp{
+				pd: &models.PublicDashboard{AllowedVariables: []string{"env"}},
+				d: &models.Dashboard{
+					IsPublic: true,
+					Data: simplejson.NewFromAny(map[string]interface{}{"templating": map[string]interface{}{"list": []interface{}{
+						map[string]interface{}{"name": "env", "type": "custom", "query": "dev,prod", "hide": 0},
+						map[string]interface{}{"name": "metric", "type": "query", "query": "label_values(secret_metric, job)", "definition": "label_values(secret_metric, job)", "refresh": 1, "hide": 0, "current": map[string]interface{}{"text": "api", "value": "api"}},
+					}}}),
+				},
+				err: nil},
+			errResp: nil,
+			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"templating": map[string]interface{}{"list": []interface{}{
+				map[string]interface{}{"name": "env", "type": "custom", "query": "dev,prod", "hide": 0},
+				map[string]interface{}{"name": "metric", "type": "query", "refresh": 0, "hide": 2, "current": map[string]interface{}{"text": "api", "value": "api"}, "options": []interface{}{map[string]interface{}{"text": "api", "value": "api"}}},
+			}}})},
+		},
+		{
+			name:        "sets overridden variables to their override",
+			accessToken: "abc123",
+			storeResp: &storeResp{
+				pd: &models.PublicDashboard{AllowedVariables: []string{"env"}, VariableOverrides: map[string]string{"env": "prod", "metric": "web"}},
+				d: &models.Dashboard{
+					IsPublic: true,
+					Data: simplejson.NewFromAny(map[string]interface{}{"templating": map[string]interface{}{"list": []interface{}{
+						map[string]interface{}{"name": "env", "type": "custom", "query": "dev,prod", "hide": 0, "current": map[string]interface{}{"text": "dev", "value": "dev"}},
+						map[string]interface{}{"name": "metric", "type": "query", "query": "label_values(secret_metric, job)", "refresh": 1, "hide": 0, "current": map[string]interface{}{"text": "api", "value": "api"}},
+					}}}),
+				},
+				err: nil},
+			errResp: nil,
+			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"templating": map[string]interface{}{"list": []interface{}{
+				map[string]interface{}{"name": "env", "type": "custom", "query": "dev,prod", "hide": 0, "current": map[string]interface{}{"text": "prod", "value": "prod", "selected": true}},
+				map[string]interface{}{"name": "metric", "type": "query", "refresh": 0, "hide": 2, "current": map[string]interface{}{"text": "web", "value": "web", "selected": true}, "options": []interface{}{map[string]interface{}{"text": "web", "value": "web", "selected": true}}},
+			}}})},
+		},
+		{
+			name:        "removes hidden panels including the ones in collapsed rows",
+			accessToken: "abc123",
+			storeResp: &storeResp{
+				pd: &models.PublicDashboard{HiddenPanelIds: []int64{1, 4}},
+				d: &models.Dashboard{
+					IsPublic: true,
+					Data: simplejson.NewFromAny(map[string]interface{}{"panels": []interface{}{
+						map[string]interface{}{"id": 1, "type": "timeseries"},
+						map[string]interface{}{"id": 2, "type": "timeseries"},
+						map[string]interface{}{"id": 3, "type": "row", "collapsed": true, "panels": []interface{}{
+							map[string]interface{}{"id": 4, "type": "timeseries"},
+							map[string]interface{}{"id": 5, "type": "timeseries"},
+						}},
+					}}),
+				},
+				err: nil},
+			errResp: nil,
+			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"panels": []interface{}{
+				map[string]interface{}{"id": 2, "type": "timeseries"},
+				map[string]interface{}{"id": 3, "type": "row", "collapsed": true, "panels": []interface{}{
+					map[string]interface{}{"id": 5, "type": "timeseries"},
+				}},
+			}})},
+		},
+		{
+			name:        "overrides the dashboard refresh with the refresh interval",
+			accessToken: "abc123",
+			storeResp: &storeResp{
+				pd: &models.PublicDashboard{RefreshInterval: "1m"},
+				d: &models.Dashboard{
+					IsPublic: true,
+					Data:     simplejson.NewFromAny(map[string]interface{}{"refresh": "5s"}),
+				},
+				err: nil},
+			errResp:  nil,
+			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"refresh": "1m"})},
+		},
+		{
+			name:        "turns off the dashboard refresh when the refresh interval is off",
+			accessToken: "abc123",
+			storeResp: &storeResp{
+				pd: &models.PublicDashboard{RefreshInterval: models.PublicDashboardRefreshOff},
+				d: &models.Dashboard{
+					IsPublic: true,
+					Data:     simplejson.NewFromAny(map[string]interface{}{"refresh": "5s"}),
+				},
+				err: nil},
+			errResp:  nil,
+			dashResp: &models.Dashboard{IsPublic: true, Data: simplejson.NewFromAny(map[string]interface{}{"refresh": ""})},
+		},
+		{
+			name:        "returns ErrPublicDashboardDisabled when isPublic is false",
+			accessToken: "abc123",
+			storeResp:   &storeResp{pd: &models.PublicDashboard{}, d: &models.Dashboard{IsPublic: false}, err: nil},
+			errResp:     models.ErrPublicDashboardDisabled,
+			dashResp:    nil,
+		},
+		{
+			name:        "returns ErrPublicDashboardPasswordRequired when password protected",
+			accessToken: "abc123",
+			storeResp:   &storeResp{pd: &models.PublicDashboard{PasswordHash: "hash"}, d: &models.Dashboard{IsPublic: true}, err: nil},
+			errResp:     models.ErrPublicDashboardPasswordRequired,
+			dashResp:    nil,
+		},
+		{
+			name:        "returns ErrPublicDashboardNotFound if PublicDashboard missing",
+			accessToken: "abc123",
+			storeResp:   &storeResp{pd: nil, d: nil, err: nil},
+			errResp:     models.ErrPublicDashboardNotFound,
+			dashResp:    nil,
+		},
+		{
+			name:        "returns ErrPublicDashboardNotFound if Dashboard missing",
+			accessToken: "abc123",
+			storeResp:   &storeResp{pd: nil, d: nil, err: nil},
+			errResp:     models.ErrPublicDashboardNotFound,
+			dashResp:    nil,
 		},
 	}
 
@@ -82,7 +209,7 @@ func TestGetPublicDashboard(t *testing.T) {
 			fakeStore.On("GetPublicDashboard", mock.Anything).
 				Return(test.storeResp.pd, test.storeResp.d, test.storeResp.err)
 
-			dashboard, err := service.GetPublicDashboard(context.Background(), test.uid)
+			dashboard, err := service.GetPublicDashboard(context.Background(), test.accessToken, "", nil)
 			if test.errResp != nil {
 				assert.Error(t, test.errResp, err)
 			} else {
@@ -93,9 +220,176 @@ func TestGetPublicDashboard(t *testing.T) {
 	}
 }
 
+func TestGetPublicDashboard_Password(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	fakeStore := dashboards.FakeDashboardStore{}
+	fakeStore.On("GetPublicDashboard", mock.Anything).
+		Return(&models.PublicDashboard{PasswordHash: string(hash)}, &models.Dashboard{IsPublic: true}, nil)
+	service := &DashboardServiceImpl{
+		log:            log.New("test.logger"),
+		dashboardStore: &fakeStore,
+	}
+
+	t.Run("requires the password", func(t *testing.T) {
+		dashboard, err := service.GetPublicDashboard(context.Background(), "abc123", "", nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardPasswordRequired)
+		require.Nil(t, dashboard)
+	})
+
+	t.Run("rejects a wrong password", func(t *testing.T) {
+		dashboard, err := service.GetPublicDashboard(context.Background(), "abc123", "wrong", nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardWrongPassword)
+		require.Nil(t, dashboard)
+	})
+
+	t.Run("serves the dashboard once the password checks out", func(t *testing.T) {
+		dashboard, err := service.GetPublicDashboard(context.Background(), "abc123", "s3cret", nil)
+		require.NoError(t, err)
+		require.Equal(t, &models.Dashboard{IsPublic: true}, dashboard)
+	})
+}
+
+func TestGetPublicDashboard_ShareType(t *testing.T) {
+	setup := func(shareType string) *DashboardServiceImpl {
+		fakeStore := dashboards.FakeDashboardStore{}
+		fakeStore.On("GetPublicDashboard", mock.Anything).
+			Return(&models.PublicDashboard{OrgId: 1, ShareType: shareType}, &models.Dashboard{IsPublic: true}, nil)
+		return &DashboardServiceImpl{
+			log:            log.New("test.logger"),
+			dashboardStore: &fakeStore,
+		}
+	}
+
+	t.Run("anyone with the link can view public dashboards shared publicly", func(t *testing.T) {
+		_, err := setup(models.PublicDashboardShareTypePublic).GetPublicDashboard(context.Background(), "abc123", "",
+			&models.SignedInUser{IsAnonymous: true})
+		require.NoError(t, err)
+	})
+
+	t.Run("only users signed in to the org can view authenticated public dashboards", func(t *testing.T) {
+		service := setup(models.PublicDashboardShareTypeAuthenticated)
+
+		for name, viewer := range map[string]*models.SignedInUser{
+			"no viewer":           nil,
+			"anonymous":           {IsAnonymous: true, OrgId: 1},
+			"user of another org": {UserId: 2, OrgId: 2},
+		} {
+			dashboard, err := service.GetPublicDashboard(context.Background(), "abc123", "", viewer)
+			require.ErrorIs(t, err, models.ErrPublicDashboardAuthRequired, name)
+			require.Nil(t, dashboard, name)
+		}
+
+		dashboard, err := service.GetPublicDashboard(context.Background(), "abc123", "", &models.SignedInUser{UserId: 2, OrgId: 1})
+		require.NoError(t, err)
+		require.NotNil(t, dashboard)
+	})
+}
+
+func TestGetPublicDashboardForRendering(t *testing.T) {
+	setup := func(t *testing.T, pd *models.PublicDashboard, d *models.Dashboard) *DashboardServiceImpl {
+		t.Helper()
+		fakeStore := dashboards.FakeDashboardStore{}
+		fakeStore.On("GetPublicDashboard", mock.Anything).Return(pd, d, nil)
+
+		return &DashboardServiceImpl{
+			log:            log.New("test.logger"),
+			dashboardStore: &fakeStore,
+		}
+	}
+
+	t.Run("strips internal-only fields", func(t *testing.T) {
+		service := setup(t, &models.PublicDashboard{}, &models.Dashboard{
+			Id:        1,
+			Uid:       "abc123",
+			Title:     "Public",
+			FolderId:  2,
+			CreatedBy: 3,
+			UpdatedBy: 4,
+			HasAcl:    true,
+			IsPublic:  true,
+			Data: simplejson.NewFromAny(map[string]interface{}{
+				"id":    1,
+				"title": "Public",
+				"links": []interface{}{map[string]interface{}{"type": "dashboards", "tags": []interface{}{"internal"}}},
+				"panels": []interface{}{
+					map[string]interface{}{
+						"id":           1,
+						"type":         "graph",
+						"datasource":   map[string]interface{}{"uid": "secret-ds", "type": "prometheus"},
+						"alert":        map[string]interface{}{"name": "High load", "notifications": []interface{}{map[string]interface{}{"uid": "pager"}}},
+						"links":        []interface{}{map[string]interface{}{"url": "/d/internal"}},
+						"libraryPanel": map[string]interface{}{"uid": "lib", "name": "Library panel"},
+						"fieldConfig": map[string]interface{}{"defaults": map[string]interface{}{
+							"unit":  "short",
+							"links": []interface{}{map[string]interface{}{"url": "/explore"}},
+						}},
+						"targets": []interface{}{
+							map[string]interface{}{"refId": "A", "expr": "up", "datasource": map[string]interface{}{"uid": "secret-ds"}},
+						},
+					},
+					map[string]interface{}{"id": 2, "type": "row", "collapsed": true, "panels": []interface{}{
+						map[string]interface{}{"id": 3, "type": "timeseries", "datasource": "Internal MySQL", "links": []interface{}{}},
+					}},
+				},
+			}),
+		})
+
+		dashboard, err := service.GetPublicDashboardForRendering(context.Background(), "abc123", "", nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, &models.Dashboard{
+			Uid:      "abc123",
+			Title:    "Public",
+			IsPublic: true,
+			Data: simplejson.NewFromAny(map[string]interface{}{
+				"title": "Public",
+				"panels": []interface{}{
+					map[string]interface{}{
+						"id":          1,
+						"type":        "graph",
+						"fieldConfig": map[string]interface{}{"defaults": map[string]interface{}{"unit": "short"}},
+						"targets": []interface{}{
+							map[string]interface{}{"refId": "A", "expr": "up"},
+						},
+					},
+					map[string]interface{}{"id": 2, "type": "row", "collapsed": true, "panels": []interface{}{
+						map[string]interface{}{"id": 3, "type": "timeseries"},
+					}},
+				},
+			}),
+		}, dashboard)
+	})
+
+	t.Run("applies the hide rules of the public config", func(t *testing.T) {
+		service := setup(t, &models.PublicDashboard{HiddenPanelIds: []int64{1}}, &models.Dashboard{
+			IsPublic: true,
+			Data: simplejson.NewFromAny(map[string]interface{}{"panels": []interface{}{
+				map[string]interface{}{"id": 1, "type": "timeseries"},
+				map[string]interface{}{"id": 2, "type": "timeseries", "alert": map[string]interface{}{"name": "High load"}},
+			}}),
+		})
+
+		dashboard, err := service.GetPublicDashboardForRendering(context.Background(), "abc123", "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, simplejson.NewFromAny(map[string]interface{}{"panels": []interface{}{
+			map[string]interface{}{"id": 2, "type": "timeseries"},
+		}}), dashboard.Data)
+	})
+
+	t.Run("returns the errors of GetPublicDashboard", func(t *testing.T) {
+		service := setup(t, &models.PublicDashboard{}, &models.Dashboard{IsPublic: false})
+
+		dashboard, err := service.GetPublicDashboardForRendering(context.Background(), "abc123", "", nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardDisabled)
+		assert.Nil(t, dashboard)
+	})
+}
+
 func TestSavePublicDashboard(t *testing.T) {
 	t.Run("gets PublicDashboard.orgId and PublicDashboard.DashboardUid set from SavePublicDashboardConfigDTO", func(t *testing.T) {
-		sqlStore := sqlstore.InitTestDB(t)
+		sqlStore := sqlstore.InitTestDB(t, sqlstore.InitTestDBOpt{FeatureFlags: []string{featuremgmt.FlagPublicDashboards}})
 		dashboardStore := database.ProvideDashboardStore(sqlStore)
 		dashboard := insertTestDashboard(t, dashboardStore, "testDashie", 1, 0, true)
 
@@ -153,8 +447,72 @@ func TestSavePublicDashboard(t *testing.T) {
 	})
 }
 
+type fakePublicDashboardAuditLogger struct {
+	entries []dashboards.PublicDashboardAuditEntry
+}
+
+func (f *fakePublicDashboardAuditLogger) Log(_ context.Context, entry dashboards.PublicDashboardAuditEntry) {
+	f.entries = append(f.entries, entry)
+}
+
+func TestPublicDashboardAuditLog(t *testing.T) {
+	sqlStore := sqlstore.InitTestDB(t, sqlstore.InitTestDBOpt{FeatureFlags: []string{featuremgmt.FlagPublicDashboards}})
+	dashboardStore := database.ProvideDashboardStore(sqlStore)
+	dashboard := insertTestDashboard(t, dashboardStore, "testDashie", 1, 0, true)
+
+	audit := &fakePublicDashboardAuditLogger{}
+	service := &DashboardServiceImpl{
+		log:                  log.New("test.logger"),
+		dashboardStore:       dashboardStore,
+		publicDashboardAudit: audit,
+	}
+
+	save := func(t *testing.T, isPublic bool) {
+		t.Helper()
+		_, err := service.SavePublicDashboardConfig(context.Background(), &dashboards.SavePublicDashboardConfigDTO{
+			DashboardUid:          dashboard.Uid,
+			OrgId:                 dashboard.OrgId,
+			UserId:                7,
+			PublicDashboardConfig: &models.PublicDashboardConfig{IsPublic: isPublic},
+		})
+		require.NoError(t, err)
+	}
+
+	entry := func(action dashboards.PublicDashboardAuditAction, wasEnabled, isEnabled bool) dashboards.PublicDashboardAuditEntry {
+		return dashboards.PublicDashboardAuditEntry{
+			ActorUserId:  7,
+			OrgId:        dashboard.OrgId,
+			DashboardUid: dashboard.Uid,
+			Action:       action,
+			WasEnabled:   wasEnabled,
+			IsEnabled:    isEnabled,
+		}
+	}
+
+	save(t, true)
+	save(t, true)
+	save(t, false)
+	save(t, true)
+	err := service.DeletePublicDashboardConfig(context.Background(), dashboard.OrgId, 7, dashboard.Uid)
+	require.NoError(t, err)
+
+	assert.Equal(t, []dashboards.PublicDashboardAuditEntry{
+		entry(dashboards.PublicDashboardCreated, false, true),
+		entry(dashboards.PublicDashboardUpdated, true, true),
+		entry(dashboards.PublicDashboardDisabled, true, false),
+		entry(dashboards.PublicDashboardEnabled, false, true),
+		entry(dashboards.PublicDashboardDeleted, true, false),
+	}, audit.entries)
+
+	t.Run("failed changes are not logged", func(t *testing.T) {
+		err := service.DeletePublicDashboardConfig(context.Background(), dashboard.OrgId, 7, dashboard.Uid)
+		require.ErrorIs(t, err, models.ErrPublicDashboardNotFound)
+		assert.Len(t, audit.entries, 5)
+	})
+}
+
 func TestBuildPublicDashboardMetricRequest(t *testing.T) {
-	sqlStore := sqlstore.InitTestDB(t)
+	sqlStore := sqlstore.InitTestDB(t, sqlstore.InitTestDBOpt{FeatureFlags: []string{featuremgmt.FlagPublicDashboards}})
 	dashboardStore := database.ProvideDashboardStore(sqlStore)
 	dashboard := insertTestDashboard(t, dashboardStore, "testDashie", 1, 0, true)
 	nonPublicDashboard := insertTestDashboard(t, dashboardStore, "testNonPublicDashie", 1, 0, true)
@@ -172,7 +530,7 @@ func TestBuildPublicDashboardMetricRequest(t *testing.T) {
 			PublicDashboard: models.PublicDashboard{
 				DashboardUid: "NOTTHESAME",
 				OrgId:        9999999,
-				TimeSettings: `{"from": "FROM", "to": "TO"}`,
+				TimeSettings: `{"from": "now-8h", "to": "now"}`,
 			},
 		},
 	}
@@ -188,7 +546,7 @@ func TestBuildPublicDashboardMetricRequest(t *testing.T) {
 			PublicDashboard: models.PublicDashboard{
 				DashboardUid: "NOTTHESAME",
 				OrgId:        9999999,
-				TimeSettings: `{"from": "FROM", "to": "TO"}`,
+				TimeSettings: `{"from": "now-8h", "to": "now"}`,
 			},
 		},
 	}
@@ -197,15 +555,20 @@ func TestBuildPublicDashboardMetricRequest(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Run("extracts queries from provided dashboard", func(t *testing.T) {
-		reqDTO, err := service.BuildPublicDashboardMetricRequest(
+		reqDTO, queryOpts, err := service.BuildPublicDashboardMetricRequest(
 			context.Background(),
-			pdc.PublicDashboard.Uid,
+			pdc.PublicDashboard.AccessToken,
+			"",
 			1,
+			dtos.PublicDashboardQueryDTO{},
+			nil,
 		)
 		require.NoError(t, err)
 
-		require.Equal(t, "FROM", reqDTO.From)
-		require.Equal(t, "TO", reqDTO.To)
+		require.Equal(t, "now-8h", reqDTO.From)
+		require.Equal(t, "now", reqDTO.To)
+		require.Equal(t, models.DefaultPublicDashboardMaxQueryDurationSeconds*time.Second, queryOpts.MaxDuration)
+		require.Zero(t, queryOpts.CacheTTL)
 		require.Len(t, reqDTO.Queries, 2)
 		require.Equal(
 			t,
@@ -232,25 +595,230 @@ func TestBuildPublicDashboardMetricRequest(t *testing.T) {
 	})
 
 	t.Run("returns an error when panel missing", func(t *testing.T) {
-		_, err := service.BuildPublicDashboardMetricRequest(
+		_, _, err := service.BuildPublicDashboardMetricRequest(
 			context.Background(),
-			pdc.PublicDashboard.Uid,
+			pdc.PublicDashboard.AccessToken,
+			"",
 			49,
+			dtos.PublicDashboardQueryDTO{},
+			nil,
 		)
 
 		require.ErrorContains(t, err, "Panel not found")
 	})
 
 	t.Run("returns an error when dashboard not public", func(t *testing.T) {
-		_, err := service.BuildPublicDashboardMetricRequest(
+		_, _, err := service.BuildPublicDashboardMetricRequest(
 			context.Background(),
-			nonPublicPdc.PublicDashboard.Uid,
+			nonPublicPdc.PublicDashboard.AccessToken,
+			"",
 			2,
+			dtos.PublicDashboardQueryDTO{},
+			nil,
 		)
-		require.ErrorContains(t, err, "Public dashboard not found")
+		require.ErrorIs(t, err, models.ErrPublicDashboardDisabled)
+	})
+
+	t.Run("returns an error when the queries changed since the dashboard was published", func(t *testing.T) {
+		// the stored dashboard is decoded from JSON, so its panels and targets can be changed in place
+		_, stored, err := dashboardStore.GetPublicDashboard(pdc.PublicDashboard.AccessToken)
+		require.NoError(t, err)
+		stored.Data.Set("id", stored.Id)
+		stored.Data.Get("panels").GetIndex(0).Get("targets").GetIndex(0).Set("rawSql", "SELECT * FROM secrets")
+		_, err = dashboardStore.SaveDashboard(models.SaveDashboardCommand{
+			OrgId:     stored.OrgId,
+			Overwrite: true,
+			Dashboard: stored.Data,
+		})
+		require.NoError(t, err)
+
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 1, dtos.PublicDashboardQueryDTO{}, nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardQueriesChanged)
+
+		// saving the public dashboard again publishes the changed queries
+		_, err = service.SavePublicDashboardConfig(context.Background(), dto)
+		require.NoError(t, err)
+		reqDTO, _, err := service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 1, dtos.PublicDashboardQueryDTO{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "SELECT * FROM secrets", reqDTO.Queries[0].Get("rawSql").MustString())
+	})
+
+	t.Run("returns an error when panel hidden", func(t *testing.T) {
+		hiddenDto := &dashboards.SavePublicDashboardConfigDTO{
+			DashboardUid: dashboard.Uid,
+			OrgId:        dashboard.OrgId,
+			PublicDashboardConfig: &models.PublicDashboardConfig{
+				IsPublic: true,
+				PublicDashboard: models.PublicDashboard{
+					TimeSettings:   `{"from": "now-8h", "to": "now"}`,
+					HiddenPanelIds: []int64{2},
+				},
+			},
+		}
+		_, err := service.SavePublicDashboardConfig(context.Background(), hiddenDto)
+		require.NoError(t, err)
+
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 2, dtos.PublicDashboardQueryDTO{}, nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardPanelNotFound)
+
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 1, dtos.PublicDashboardQueryDTO{}, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("only runs the queries of authenticated public dashboards for users of their org", func(t *testing.T) {
+		authenticatedDto := &dashboards.SavePublicDashboardConfigDTO{
+			DashboardUid: dashboard.Uid,
+			OrgId:        dashboard.OrgId,
+			PublicDashboardConfig: &models.PublicDashboardConfig{
+				IsPublic: true,
+				PublicDashboard: models.PublicDashboard{
+					TimeSettings: `{"from": "now-8h", "to": "now"}`,
+					ShareType:    models.PublicDashboardShareTypeAuthenticated,
+				},
+			},
+		}
+		_, err := service.SavePublicDashboardConfig(context.Background(), authenticatedDto)
+		require.NoError(t, err)
+
+		for _, viewer := range []*models.SignedInUser{
+			nil,
+			{IsAnonymous: true, OrgId: dashboard.OrgId},
+			{UserId: 1, OrgId: dashboard.OrgId + 1},
+		} {
+			_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 1, dtos.PublicDashboardQueryDTO{}, viewer)
+			require.ErrorIs(t, err, models.ErrPublicDashboardAuthRequired)
+		}
+
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 1, dtos.PublicDashboardQueryDTO{},
+			&models.SignedInUser{UserId: 1, OrgId: dashboard.OrgId})
+		require.NoError(t, err)
+	})
+
+	t.Run("returns an error when the data source isn't allowed", func(t *testing.T) {
+		allowlistDto := &dashboards.SavePublicDashboardConfigDTO{
+			DashboardUid: dashboard.Uid,
+			OrgId:        dashboard.OrgId,
+			PublicDashboardConfig: &models.PublicDashboardConfig{
+				IsPublic: true,
+				PublicDashboard: models.PublicDashboard{
+					TimeSettings:          `{"from": "now-8h", "to": "now"}`,
+					AllowedDatasourceUids: []string{"ds1", "ds2"},
+				},
+			},
+		}
+		_, err := service.SavePublicDashboardConfig(context.Background(), allowlistDto)
+		require.NoError(t, err)
+
+		// panel 2 queries ds3
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 2, dtos.PublicDashboardQueryDTO{}, nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardDatasourceNotAllowed)
+
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 1, dtos.PublicDashboardQueryDTO{}, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("only runs the queries of password protected public dashboards once the viewer entered the password", func(t *testing.T) {
+		err := dashboardStore.SetPublicDashboardPassword(context.Background(), dashboard.OrgId, pdc.PublicDashboard.Uid, "s3cret")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			err := dashboardStore.SetPublicDashboardPassword(context.Background(), dashboard.OrgId, pdc.PublicDashboard.Uid, "")
+			require.NoError(t, err)
+		})
+
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "", 1, dtos.PublicDashboardQueryDTO{}, nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardPasswordRequired)
+
+		_, _, err = service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "wrong", 1, dtos.PublicDashboardQueryDTO{}, nil)
+		require.ErrorIs(t, err, models.ErrPublicDashboardWrongPassword)
+
+		reqDTO, _, err := service.BuildPublicDashboardMetricRequest(context.Background(), pdc.PublicDashboard.AccessToken, "s3cret", 1, dtos.PublicDashboardQueryDTO{}, nil)
+		require.NoError(t, err)
+		require.NotEmpty(t, reqDTO.Queries)
+
+		// the dashboard itself is served with the same password
+		d, err := service.GetPublicDashboard(context.Background(), pdc.PublicDashboard.AccessToken, "s3cret", nil)
+		require.NoError(t, err)
+		require.Equal(t, dashboard.Uid, d.Uid)
 	})
 }
 
+func TestBoundTimeRange(t *testing.T) {
+	now := time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)
+	ms := func(t time.Time) string {
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	}
+	bounded := models.PublicDashboardTimeSettings{From: "now-8h", To: "now", MinTime: "now-90d", MaxTime: "now"}
+
+	testCases := []struct {
+		name         string
+		timeSettings models.PublicDashboardTimeSettings
+		req          dtos.PublicDashboardQueryDTO
+		expectedFrom string
+		expectedTo   string
+		expectedErr  error
+	}{
+		{
+			name:         "ignores requested range without bounds",
+			timeSettings: models.PublicDashboardTimeSettings{From: "now-8h", To: "now"},
+			req:          dtos.PublicDashboardQueryDTO{From: "now-1y", To: "now"},
+			expectedFrom: "now-8h",
+			expectedTo:   "now",
+		},
+		{
+			name:         "uses saved range when none is requested",
+			timeSettings: bounded,
+			expectedFrom: "now-8h",
+			expectedTo:   "now",
+		},
+		{
+			name:         "uses requested range within bounds",
+			timeSettings: bounded,
+			req:          dtos.PublicDashboardQueryDTO{From: "now-7d", To: "now-1d"},
+			expectedFrom: "now-7d",
+			expectedTo:   "now-1d",
+		},
+		{
+			name:         "clamps requested range to min time",
+			timeSettings: bounded,
+			req:          dtos.PublicDashboardQueryDTO{From: "now-1y", To: "now"},
+			expectedFrom: ms(now.Add(-90 * 24 * time.Hour)),
+			expectedTo:   "now",
+		},
+		{
+			name:         "clamps requested range to max time",
+			timeSettings: bounded,
+			req:          dtos.PublicDashboardQueryDTO{From: "now-1h", To: "now+1h"},
+			expectedFrom: "now-1h",
+			expectedTo:   ms(now),
+		},
+		{
+			name:         "rejects requested range outside of bounds",
+			timeSettings: bounded,
+			req:          dtos.PublicDashboardQueryDTO{From: "now-2y", To: "now-1y"},
+			expectedErr:  models.ErrPublicDashboardTimeRangeOutOfBounds,
+		},
+		{
+			name:         "rejects invalid requested range",
+			timeSettings: bounded,
+			req:          dtos.PublicDashboardQueryDTO{From: "yesterday"},
+			expectedErr:  models.ErrPublicDashboardInvalidTimeSettings,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			from, to, err := boundTimeRange(test.timeSettings, test.req, now)
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedFrom, from)
+			assert.Equal(t, test.expectedTo, to)
+		})
+	}
+}
+
 func insertTestDashboard(t *testing.T, dashboardStore *database.DashboardStore, title string, orgId int64,
 	folderId int64, isFolder bool, tags ...interface{}) *models.Dashboard {
 	t.Helper()
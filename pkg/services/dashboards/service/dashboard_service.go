@@ -39,6 +39,7 @@ type DashboardServiceImpl struct {
 	folderPermissions    accesscontrol.FolderPermissionsService
 	dashboardPermissions accesscontrol.DashboardPermissionsService
 	ac                   accesscontrol.AccessControl
+	publicDashboardAudit dashboards.PublicDashboardAuditLogger
 }
 
 func ProvideDashboardService(
@@ -58,6 +59,7 @@ func ProvideDashboardService(
 		folderPermissions:    folderPermissionsService,
 		dashboardPermissions: dashboardPermissionsService,
 		ac:                   ac,
+		publicDashboardAudit: newPublicDashboardAuditLog(),
 	}
 }
 
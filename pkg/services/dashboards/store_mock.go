@@ -9,6 +9,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	testing "testing"
+
+	time "time"
 )
 
 // FakeDashboardStore is an autogenerated mock type for the Store type
@@ -44,6 +46,85 @@ func (_m *FakeDashboardStore) DeleteOrphanedProvisionedDashboards(ctx context.Co
 	return r0
 }
 
+// DeleteOrphanedPublicDashboards provides a mock function with given fields: ctx
+func (_m *FakeDashboardStore) DeleteOrphanedPublicDashboards(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeletePublicDashboardConfig provides a mock function with given fields: ctx, orgId, uid
+func (_m *FakeDashboardStore) DeletePublicDashboardConfig(ctx context.Context, orgId int64, uid string) error {
+	ret := _m.Called(ctx, orgId, uid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, orgId, uid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisableAllPublicDashboards provides a mock function with given fields: ctx, orgId
+func (_m *FakeDashboardStore) DisableAllPublicDashboards(ctx context.Context, orgId int64) (int64, error) {
+	ret := _m.Called(ctx, orgId)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, int64) int64); ok {
+		r0 = rf(ctx, orgId)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExportPublicDashboardConfig provides a mock function with given fields: ctx, orgId, uid
+func (_m *FakeDashboardStore) ExportPublicDashboardConfig(ctx context.Context, orgId int64, uid string) ([]byte, error) {
+	ret := _m.Called(ctx, orgId, uid)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) []byte); ok {
+		r0 = rf(ctx, orgId, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, orgId, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindDashboards provides a mock function with given fields: ctx, query
 func (_m *FakeDashboardStore) FindDashboards(ctx context.Context, query *models.FindPersistedDashboardsQuery) ([]DashboardSearchProjection, error) {
 	ret := _m.Called(ctx, query)
@@ -229,6 +310,52 @@ func (_m *FakeDashboardStore) GetFolderByUID(ctx context.Context, orgID int64, u
 	return r0, r1
 }
 
+// GetEnabledPublicDashboardByDashboardUid provides a mock function with given fields: ctx, orgId, dashboardUid
+func (_m *FakeDashboardStore) GetEnabledPublicDashboardByDashboardUid(ctx context.Context, orgId int64, dashboardUid string) (*models.PublicDashboard, error) {
+	ret := _m.Called(ctx, orgId, dashboardUid)
+
+	var r0 *models.PublicDashboard
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) *models.PublicDashboard); ok {
+		r0 = rf(ctx, orgId, dashboardUid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PublicDashboard)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, orgId, dashboardUid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrphanedPublicDashboards provides a mock function with given fields: ctx
+func (_m *FakeDashboardStore) GetOrphanedPublicDashboards(ctx context.Context) ([]models.PublicDashboard, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []models.PublicDashboard
+	if rf, ok := ret.Get(0).(func(context.Context) []models.PublicDashboard); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PublicDashboard)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetProvisionedDashboardData provides a mock function with given fields: name
 func (_m *FakeDashboardStore) GetProvisionedDashboardData(name string) ([]*models.DashboardProvisioning, error) {
 	ret := _m.Called(name)
@@ -298,13 +425,13 @@ func (_m *FakeDashboardStore) GetProvisionedDataByDashboardUID(orgID int64, dash
 	return r0, r1
 }
 
-// GetPublicDashboard provides a mock function with given fields: uid
-func (_m *FakeDashboardStore) GetPublicDashboard(uid string) (*models.PublicDashboard, *models.Dashboard, error) {
-	ret := _m.Called(uid)
+// GetPublicDashboard provides a mock function with given fields: accessToken
+func (_m *FakeDashboardStore) GetPublicDashboard(accessToken string) (*models.PublicDashboard, *models.Dashboard, error) {
+	ret := _m.Called(accessToken)
 
 	var r0 *models.PublicDashboard
 	if rf, ok := ret.Get(0).(func(string) *models.PublicDashboard); ok {
-		r0 = rf(uid)
+		r0 = rf(accessToken)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.PublicDashboard)
@@ -313,7 +440,7 @@ func (_m *FakeDashboardStore) GetPublicDashboard(uid string) (*models.PublicDash
 
 	var r1 *models.Dashboard
 	if rf, ok := ret.Get(1).(func(string) *models.Dashboard); ok {
-		r1 = rf(uid)
+		r1 = rf(accessToken)
 	} else {
 		if ret.Get(1) != nil {
 			r1 = ret.Get(1).(*models.Dashboard)
@@ -322,7 +449,7 @@ func (_m *FakeDashboardStore) GetPublicDashboard(uid string) (*models.PublicDash
 
 	var r2 error
 	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(uid)
+		r2 = rf(accessToken)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -353,6 +480,94 @@ func (_m *FakeDashboardStore) GetPublicDashboardConfig(orgId int64, dashboardUid
 	return r0, r1
 }
 
+// GetPublicDashboardConfigByAccessToken provides a mock function with given fields: ctx, accessToken
+func (_m *FakeDashboardStore) GetPublicDashboardConfigByAccessToken(ctx context.Context, accessToken string) (*models.PublicDashboardConfig, error) {
+	ret := _m.Called(ctx, accessToken)
+
+	var r0 *models.PublicDashboardConfig
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.PublicDashboardConfig); ok {
+		r0 = rf(ctx, accessToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PublicDashboardConfig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPublicDashboardConfigs provides a mock function with given fields: ctx, orgId, dashboardUids
+func (_m *FakeDashboardStore) GetPublicDashboardConfigs(ctx context.Context, orgId int64, dashboardUids []string) (map[string]*models.PublicDashboard, error) {
+	ret := _m.Called(ctx, orgId, dashboardUids)
+
+	var r0 map[string]*models.PublicDashboard
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []string) map[string]*models.PublicDashboard); ok {
+		r0 = rf(ctx, orgId, dashboardUids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]*models.PublicDashboard)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []string) error); ok {
+		r1 = rf(ctx, orgId, dashboardUids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPublicDashboardOrgId provides a mock function with given fields: ctx, accessToken
+func (_m *FakeDashboardStore) GetPublicDashboardOrgId(ctx context.Context, accessToken string) (int64, error) {
+	ret := _m.Called(ctx, accessToken)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, accessToken)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, accessToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPublicDashboardQuerySignature provides a mock function with given fields: ctx, orgId, dashboardUid
+func (_m *FakeDashboardStore) GetPublicDashboardQuerySignature(ctx context.Context, orgId int64, dashboardUid string) (string, error) {
+	ret := _m.Called(ctx, orgId, dashboardUid)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) string); ok {
+		r0 = rf(ctx, orgId, dashboardUid)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, orgId, dashboardUid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HasAdminPermissionInFolders provides a mock function with given fields: ctx, query
 func (_m *FakeDashboardStore) HasAdminPermissionInFolders(ctx context.Context, query *models.HasAdminPermissionInFoldersQuery) error {
 	ret := _m.Called(ctx, query)
@@ -381,6 +596,177 @@ func (_m *FakeDashboardStore) HasEditPermissionInFolders(ctx context.Context, qu
 	return r0
 }
 
+// ImportPublicDashboardConfig provides a mock function with given fields: ctx, orgId, payload
+func (_m *FakeDashboardStore) ImportPublicDashboardConfig(ctx context.Context, orgId int64, payload []byte) (*models.PublicDashboardConfig, error) {
+	ret := _m.Called(ctx, orgId, payload)
+
+	var r0 *models.PublicDashboardConfig
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []byte) *models.PublicDashboardConfig); ok {
+		r0 = rf(ctx, orgId, payload)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PublicDashboardConfig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []byte) error); ok {
+		r1 = rf(ctx, orgId, payload)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPublicDashboards provides a mock function with given fields: ctx, orgId
+func (_m *FakeDashboardStore) ListPublicDashboards(ctx context.Context, orgId int64) ([]models.PublicDashboardListItem, error) {
+	ret := _m.Called(ctx, orgId)
+
+	var r0 []models.PublicDashboardListItem
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.PublicDashboardListItem); ok {
+		r0 = rf(ctx, orgId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PublicDashboardListItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, orgId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PatchPublicDashboardConfig provides a mock function with given fields: ctx, orgId, uid, patch
+func (_m *FakeDashboardStore) PatchPublicDashboardConfig(ctx context.Context, orgId int64, uid string, patch models.PublicDashboardPatch) (*models.PublicDashboardConfig, error) {
+	ret := _m.Called(ctx, orgId, uid, patch)
+
+	var r0 *models.PublicDashboardConfig
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, models.PublicDashboardPatch) *models.PublicDashboardConfig); ok {
+		r0 = rf(ctx, orgId, uid, patch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PublicDashboardConfig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, models.PublicDashboardPatch) error); ok {
+		r1 = rf(ctx, orgId, uid, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PublicDashboardStats provides a mock function with given fields: ctx
+func (_m *FakeDashboardStore) PublicDashboardStats(ctx context.Context) ([]models.OrgPublicDashboardStats, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []models.OrgPublicDashboardStats
+	if rf, ok := ret.Get(0).(func(context.Context) []models.OrgPublicDashboardStats); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrgPublicDashboardStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeDeletedPublicDashboards provides a mock function with given fields: ctx, olderThan
+func (_m *FakeDashboardStore) PurgeDeletedPublicDashboards(ctx context.Context, olderThan time.Time) (int64, error) {
+	ret := _m.Called(ctx, olderThan)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, olderThan)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, olderThan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResolvePublicDashboardUsers provides a mock function with given fields: ctx, pdc
+func (_m *FakeDashboardStore) ResolvePublicDashboardUsers(ctx context.Context, pdc *models.PublicDashboardConfig) error {
+	ret := _m.Called(ctx, pdc)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PublicDashboardConfig) error); ok {
+		r0 = rf(ctx, pdc)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestorePublicDashboardConfig provides a mock function with given fields: ctx, orgId, uid
+func (_m *FakeDashboardStore) RestorePublicDashboardConfig(ctx context.Context, orgId int64, uid string) (*models.PublicDashboardConfig, error) {
+	ret := _m.Called(ctx, orgId, uid)
+
+	var r0 *models.PublicDashboardConfig
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) *models.PublicDashboardConfig); ok {
+		r0 = rf(ctx, orgId, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PublicDashboardConfig)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, orgId, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RotatePublicDashboardAccessToken provides a mock function with given fields: ctx, orgId, uid
+func (_m *FakeDashboardStore) RotatePublicDashboardAccessToken(ctx context.Context, orgId int64, uid string) (string, error) {
+	ret := _m.Called(ctx, orgId, uid)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) string); ok {
+		r0 = rf(ctx, orgId, uid)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, orgId, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveAlerts provides a mock function with given fields: ctx, dashID, alerts
 func (_m *FakeDashboardStore) SaveAlerts(ctx context.Context, dashID int64, alerts []*models.Alert) error {
 	ret := _m.Called(ctx, dashID, alerts)
@@ -464,6 +850,52 @@ func (_m *FakeDashboardStore) SavePublicDashboardConfig(cmd models.SavePublicDas
 	return r0, r1
 }
 
+// SavePublicDashboardConfigBatch provides a mock function with given fields: ctx, cmds
+func (_m *FakeDashboardStore) SavePublicDashboardConfigBatch(ctx context.Context, cmds []models.SavePublicDashboardConfigCommand) ([]models.PublicDashboard, []error, error) {
+	ret := _m.Called(ctx, cmds)
+
+	var r0 []models.PublicDashboard
+	if rf, ok := ret.Get(0).(func(context.Context, []models.SavePublicDashboardConfigCommand) []models.PublicDashboard); ok {
+		r0 = rf(ctx, cmds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PublicDashboard)
+		}
+	}
+
+	var r1 []error
+	if rf, ok := ret.Get(1).(func(context.Context, []models.SavePublicDashboardConfigCommand) []error); ok {
+		r1 = rf(ctx, cmds)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]error)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, []models.SavePublicDashboardConfigCommand) error); ok {
+		r2 = rf(ctx, cmds)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// SetPublicDashboardPassword provides a mock function with given fields: ctx, orgId, uid, password
+func (_m *FakeDashboardStore) SetPublicDashboardPassword(ctx context.Context, orgId int64, uid string, password string) error {
+	ret := _m.Called(ctx, orgId, uid, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) error); ok {
+		r0 = rf(ctx, orgId, uid, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UnprovisionDashboard provides a mock function with given fields: ctx, id
 func (_m *FakeDashboardStore) UnprovisionDashboard(ctx context.Context, id int64) error {
 	ret := _m.Called(ctx, id)
@@ -513,6 +945,20 @@ func (_m *FakeDashboardStore) ValidateDashboardBeforeSave(dashboard *models.Dash
 	return r0, r1
 }
 
+// VerifyPublicDashboardPassword provides a mock function with given fields: ctx, accessToken, password
+func (_m *FakeDashboardStore) VerifyPublicDashboardPassword(ctx context.Context, accessToken string, password string) error {
+	ret := _m.Called(ctx, accessToken, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, accessToken, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewFakeDashboardStore creates a new instance of FakeDashboardStore. It also registers the testing.TB interface on the mock and a cleanup function to assert the mocks expectations.
 func NewFakeDashboardStore(t testing.TB) *FakeDashboardStore {
 	mock := &FakeDashboardStore{}
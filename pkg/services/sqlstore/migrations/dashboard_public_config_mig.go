@@ -1,6 +1,11 @@
 package migrations
 
 import (
+	"sort"
+	"time"
+
+	"xorm.io/xorm"
+
 	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
 )
 
@@ -30,4 +35,194 @@ func addPublicDashboardMigration(mg *Migrator) {
 	// recreate table with proper primary key type
 	mg.AddMigration("recreate dashboard public config v1", NewAddTableMigration(dashboardPublicCfgV1))
 	addTableIndicesMigrations(mg, "v1", dashboardPublicCfgV1)
+
+	mg.AddMigration("Add created_at column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "created_at", Type: DB_DateTime, Nullable: true,
+	}))
+
+	mg.AddMigration("Add access_token column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "access_token", Type: DB_NVarchar, Length: 32, Nullable: true,
+	}))
+	mg.AddMigration("Add unique index access_token to dashboard public config v1", NewAddIndexMigration(dashboardPublicCfgV1, &Index{
+		Cols: []string{"access_token"}, Type: UniqueIndex,
+	}))
+
+	mg.AddMigration("Add deleted_at column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "deleted_at", Type: DB_DateTime, Nullable: true,
+	}))
+
+	mg.AddMigration("Add annotations_enabled column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "annotations_enabled", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("Add created_by column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "created_by", Type: DB_BigInt, Nullable: true,
+	}))
+	mg.AddMigration("Add updated_by column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "updated_by", Type: DB_BigInt, Nullable: true,
+	}))
+
+	mg.AddMigration("Add theme column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "theme", Type: DB_NVarchar, Length: 16, Nullable: true, Default: "''",
+	}))
+
+	mg.AddMigration("Add allowed_variables column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "allowed_variables", Type: DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("Add last_accessed_at column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "last_accessed_at", Type: DB_DateTime, Nullable: true,
+	}))
+
+	// a dashboard can only have a single public dashboard config
+	mg.AddMigration("Remove duplicate dashboard public configs of a dashboard", &dedupePublicDashboardConfigMigration{})
+	mg.AddMigration("Drop index org_id_dashboard_uid from dashboard public config v1", NewDropIndexMigration(dashboardPublicCfgV1, &Index{
+		Cols: []string{"org_id", "dashboard_uid"},
+	}))
+	mg.AddMigration("Add unique index org_id_dashboard_uid to dashboard public config v1", NewAddIndexMigration(dashboardPublicCfgV1, &Index{
+		Cols: []string{"org_id", "dashboard_uid"}, Type: UniqueIndex,
+	}))
+
+	// existing public dashboards are public to everyone
+	mg.AddMigration("Add share_type column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "share_type", Type: DB_NVarchar, Length: 32, Nullable: false, Default: "'public'",
+	}))
+
+	// existing public dashboards are stored in the first version of the model
+	mg.AddMigration("Add model_version column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "model_version", Type: DB_Int, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("Set model_version of existing dashboard public configs", NewRawSQLMigration(
+		"UPDATE dashboard_public_config SET model_version = 1"))
+
+	mg.AddMigration("Add max_query_duration_seconds column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "max_query_duration_seconds", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	// slugs are optional, so their uniqueness is enforced by the store rather than a unique index
+	mg.AddMigration("Add slug column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "slug", Type: DB_NVarchar, Length: 64, Nullable: true,
+	}))
+	mg.AddMigration("Add index slug to dashboard public config v1", NewAddIndexMigration(dashboardPublicCfgV1, &Index{
+		Cols: []string{"slug"},
+	}))
+
+	mg.AddMigration("Add query_signature column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "query_signature", Type: DB_NVarchar, Length: 64, Nullable: true,
+	}))
+
+	// existing public dashboards are displayed in the time zone of the viewer's browser
+	mg.AddMigration("Add timezone column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "timezone", Type: DB_NVarchar, Length: 64, Nullable: false, Default: "'browser'",
+	}))
+
+	mg.AddMigration("Add hidden_panel_ids column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "hidden_panel_ids", Type: DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("Add password_hash column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "password_hash", Type: DB_NVarchar, Length: 255, Nullable: true,
+	}))
+
+	// existing public dashboards don't auto-refresh, to not put load on data sources they didn't before
+	mg.AddMigration("Add refresh_interval column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "refresh_interval", Type: DB_NVarchar, Length: 32, Nullable: false, Default: "'off'",
+	}))
+
+	mg.AddMigration("Add allowed_origins column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "allowed_origins", Type: DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("Add variable_overrides column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "variable_overrides", Type: DB_Text, Nullable: true,
+	}))
+
+	// DATETIME columns only keep whole seconds, created and updated times are stored as TIMESTAMPZ to keep
+	// milliseconds. SQLite stores the values as they are, whatever the declared type of the column.
+	mg.AddMigration("Change created_at column of dashboard public config v1 to TIMESTAMPZ", NewRawSQLMigration("").
+		Mysql("ALTER TABLE dashboard_public_config MODIFY created_at CHAR(64) NULL;").
+		Postgres("ALTER TABLE dashboard_public_config ALTER COLUMN created_at TYPE timestamp with time zone USING created_at AT TIME ZONE 'UTC';"))
+	mg.AddMigration("Add updated_at column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "updated_at", Type: DB_TimeStampz, Nullable: true,
+	}))
+	mg.AddMigration("Set updated_at of existing dashboard public configs", NewRawSQLMigration(
+		"UPDATE dashboard_public_config SET updated_at = created_at"))
+
+	// existing public dashboards aren't rate limited beyond the limit of their org
+	mg.AddMigration("Add rate_limit_per_minute column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "rate_limit_per_minute", Type: DB_Int, Nullable: false, Default: "0",
+	}))
+
+	// existing public dashboards keep their query responses from being cached
+	mg.AddMigration("Add cache_ttl_seconds column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "cache_ttl_seconds", Type: DB_Int, Nullable: false, Default: "0",
+	}))
+
+	// existing public dashboards are served at any time
+	mg.AddMigration("Add enabled_from column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "enabled_from", Type: DB_NVarchar, Length: 64, Nullable: false, Default: "''",
+	}))
+	mg.AddMigration("Add enabled_to column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "enabled_to", Type: DB_NVarchar, Length: 64, Nullable: false, Default: "''",
+	}))
+
+	// existing public dashboards can query any data source until they're saved again
+	mg.AddMigration("Add allowed_datasource_uids column to dashboard public config v1", NewAddColumnMigration(dashboardPublicCfgV1, &Column{
+		Name: "allowed_datasource_uids", Type: DB_Text, Nullable: true,
+	}))
+}
+
+// dedupePublicDashboardConfigMigration keeps a single public dashboard config per dashboard. Configs that
+// aren't deleted are kept over deleted ones, and newer configs over older ones.
+type dedupePublicDashboardConfigMigration struct {
+	MigrationBase
+}
+
+func (m *dedupePublicDashboardConfigMigration) SQL(dialect Dialect) string {
+	return "code migration"
+}
+
+type publicDashboardConfigDTO struct {
+	Uid          string
+	OrgId        int64
+	DashboardUid string
+	CreatedAt    time.Time
+	DeletedAt    time.Time
+}
+
+func (m *dedupePublicDashboardConfigMigration) Exec(sess *xorm.Session, mg *Migrator) error {
+	configs := make([]*publicDashboardConfigDTO, 0)
+	err := sess.SQL("SELECT uid, org_id, dashboard_uid, created_at, deleted_at FROM dashboard_public_config").Find(&configs)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(configs, func(i, j int) bool {
+		a, b := configs[i], configs[j]
+		if a.OrgId != b.OrgId {
+			return a.OrgId < b.OrgId
+		}
+		if a.DashboardUid != b.DashboardUid {
+			return a.DashboardUid < b.DashboardUid
+		}
+		if a.DeletedAt.IsZero() != b.DeletedAt.IsZero() {
+			return a.DeletedAt.IsZero()
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.Uid > b.Uid
+	})
+
+	for i, config := range configs {
+		if i == 0 || config.OrgId != configs[i-1].OrgId || config.DashboardUid != configs[i-1].DashboardUid {
+			continue
+		}
+
+		if _, err := sess.Exec("DELETE FROM dashboard_public_config WHERE uid = ?", config.Uid); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
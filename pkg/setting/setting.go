@@ -267,8 +267,46 @@ type Cfg struct {
 	PluginCatalogHiddenPlugins       []string
 	PluginAdminEnabled               bool
 	PluginAdminExternalManageEnabled bool
-	DisableSanitizeHtml              bool
-	EnterpriseLicensePath            string
+	// PluginURLInstallAllowedHosts are the hosts plugin archives may be installed from by URL, such as the
+	// source archives of git refs. Entries like *.example.com match the subdomains of example.com.
+	PluginURLInstallAllowedHosts []string
+	// PluginURLInstallRequireAllowlist denies URL installs from any host if PluginURLInstallAllowedHosts is empty
+	PluginURLInstallRequireAllowlist bool
+	// PluginInstallAllowList are patterns of the plugin IDs that may be installed, such as myorg-*. If empty, all
+	// plugins that aren't denied may be installed.
+	PluginInstallAllowList []string
+	// PluginInstallDenyList are patterns of the plugin IDs that may never be installed, even if allowed
+	PluginInstallDenyList []string
+	// PluginBundlePath is a directory of plugin archives that plugins and their dependencies are installed from
+	// instead of the plugin repository, for air-gapped installs
+	PluginBundlePath string
+	// PluginBundleAllowRepoFallback resolves plugins missing in the plugin bundle from the plugin repository
+	PluginBundleAllowRepoFallback bool
+	// PluginDependencyConcurrency is how many plugin archives are downloaded in parallel when installing dependencies
+	PluginDependencyConcurrency int
+	// PluginRepositoryRetryAttempts is how often requests to the plugin repository are attempted if they fail
+	// transiently, waiting PluginRepositoryRetryBaseDelay before the first retry and twice as long before each further one
+	PluginRepositoryRetryAttempts  int
+	PluginRepositoryRetryBaseDelay time.Duration
+	// PluginRepositoryPrivateHost is the host of a private plugin repository that PluginRepositoryPrivateHeaders,
+	// such as Authorization, are sent to. The headers are never sent to any other host.
+	PluginRepositoryPrivateHost    string
+	PluginRepositoryPrivateHeaders map[string]string
+	// PluginUninstallCheckDashboards keeps data source plugins from being uninstalled while dashboards use data
+	// sources of them, unless forced
+	PluginUninstallCheckDashboards bool
+	DisableSanitizeHtml            bool
+	EnterpriseLicensePath          string
+
+	// Public dashboards
+	// PublicDashboardsUnsupportedPanels are the panel types that keep a dashboard from being made public
+	PublicDashboardsUnsupportedPanels []string
+	// PublicDashboardsMaxQueryDurationSeconds is the query duration limit of public dashboards that don't set their own
+	PublicDashboardsMaxQueryDurationSeconds int64
+	// PublicDashboardsConfigCacheTTL is how long public dashboard configurations are cached, 0 disables the cache
+	PublicDashboardsConfigCacheTTL time.Duration
+	// PublicDashboardsConfigCacheSize is the maximum number of cached public dashboard configurations
+	PublicDashboardsConfigCacheSize int
 
 	// Metrics
 	MetricsEndpointEnabled           bool
@@ -487,6 +525,7 @@ func RedactedValue(key, value string) string {
 		"ACCOUNT_KEY",
 		"ENCRYPTION_KEY",
 		"VAULT_TOKEN",
+		"PRIVATE_REPOSITORY_HEADERS",
 	} {
 		if match, err := regexp.MatchString(pattern, uppercased); match && err == nil {
 			return RedactedPassword
@@ -977,6 +1016,12 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 	panelsSection := iniFile.Section("panels")
 	cfg.DisableSanitizeHtml = panelsSection.Key("disable_sanitize_html").MustBool(false)
 
+	publicDashboards := iniFile.Section("public_dashboards")
+	cfg.PublicDashboardsUnsupportedPanels = util.SplitString(publicDashboards.Key("unsupported_panels").MustString("alertlist annolist dashlist"))
+	cfg.PublicDashboardsMaxQueryDurationSeconds = publicDashboards.Key("max_query_duration_seconds").MustInt64(30)
+	cfg.PublicDashboardsConfigCacheTTL = publicDashboards.Key("config_cache_ttl").MustDuration(10 * time.Second)
+	cfg.PublicDashboardsConfigCacheSize = publicDashboards.Key("config_cache_size").MustInt(1000)
+
 	if err := cfg.readPluginSettings(iniFile); err != nil {
 		return err
 	}
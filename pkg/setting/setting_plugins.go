@@ -1,7 +1,10 @@
 package setting
 
 import (
+	"fmt"
+	"path"
 	"strings"
+	"time"
 
 	"gopkg.in/ini.v1"
 )
@@ -43,5 +46,51 @@ func (cfg *Cfg) readPluginSettings(iniFile *ini.File) error {
 		plug = strings.TrimSpace(plug)
 		cfg.PluginCatalogHiddenPlugins = append(cfg.PluginCatalogHiddenPlugins, plug)
 	}
+
+	allowedHosts := pluginsSection.Key("plugin_url_install_allowed_hosts").MustString("")
+	for _, host := range strings.Split(allowedHosts, ",") {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host != "" {
+			cfg.PluginURLInstallAllowedHosts = append(cfg.PluginURLInstallAllowedHosts, host)
+		}
+	}
+	cfg.PluginURLInstallRequireAllowlist = pluginsSection.Key("plugin_url_install_require_allowlist").MustBool(false)
+
+	var err error
+	cfg.PluginInstallAllowList, err = readPluginIDPatterns(pluginsSection, "plugin_install_allow_list")
+	if err != nil {
+		return err
+	}
+	cfg.PluginInstallDenyList, err = readPluginIDPatterns(pluginsSection, "plugin_install_deny_list")
+	if err != nil {
+		return err
+	}
+
+	cfg.PluginBundlePath = strings.TrimSpace(pluginsSection.Key("plugin_bundle_path").MustString(""))
+	cfg.PluginBundleAllowRepoFallback = pluginsSection.Key("plugin_bundle_allow_repo_fallback").MustBool(false)
+	cfg.PluginDependencyConcurrency = pluginsSection.Key("plugin_dependency_download_concurrency").MustInt(4)
+	cfg.PluginRepositoryRetryAttempts = pluginsSection.Key("plugin_repository_retry_attempts").MustInt(3)
+	cfg.PluginRepositoryRetryBaseDelay = pluginsSection.Key("plugin_repository_retry_base_delay").MustDuration(time.Second)
+	cfg.PluginRepositoryPrivateHost = strings.ToLower(strings.TrimSpace(pluginsSection.Key("private_plugin_repository_host").MustString("")))
+	// header values commonly are credentials, which can be read from the environment or files with $__env and $__file
+	cfg.PluginRepositoryPrivateHeaders = iniFile.Section("plugins.private_repository_headers").KeysHash()
+	cfg.PluginUninstallCheckDashboards = pluginsSection.Key("plugin_uninstall_check_dashboards").MustBool(false)
 	return nil
 }
+
+// readPluginIDPatterns reads a comma-separated list of plugin ID patterns, in the syntax of path.Match
+func readPluginIDPatterns(section *ini.Section, key string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(section.Key(key).MustString(""), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid plugin ID pattern %q in %s: %w", pattern, key, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
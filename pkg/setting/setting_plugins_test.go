@@ -41,3 +41,64 @@ func TestPluginSettings(t *testing.T) {
 	require.Equal(t, ps["plugin2"]["key3"], "value3")
 	require.Equal(t, ps["plugin2"]["key4"], "value4")
 }
+
+func TestReadPluginSettings_URLInstallAllowedHosts(t *testing.T) {
+	cfg := NewCfg()
+	sec, err := cfg.Raw.NewSection("plugins")
+	require.NoError(t, err)
+	_, err = sec.NewKey("plugin_url_install_allowed_hosts", " GitHub.com, *.example.com,, ")
+	require.NoError(t, err)
+
+	err = cfg.readPluginSettings(cfg.Raw)
+	require.NoError(t, err)
+	require.Equal(t, []string{"github.com", "*.example.com"}, cfg.PluginURLInstallAllowedHosts)
+	require.False(t, cfg.PluginURLInstallRequireAllowlist)
+}
+
+func TestReadPluginSettings_InstallLists(t *testing.T) {
+	cfg := NewCfg()
+	sec, err := cfg.Raw.NewSection("plugins")
+	require.NoError(t, err)
+	_, err = sec.NewKey("plugin_install_allow_list", " myorg-*, grafana-clock-panel,, ")
+	require.NoError(t, err)
+	_, err = sec.NewKey("plugin_install_deny_list", "myorg-legacy-*")
+	require.NoError(t, err)
+
+	err = cfg.readPluginSettings(cfg.Raw)
+	require.NoError(t, err)
+	require.Equal(t, []string{"myorg-*", "grafana-clock-panel"}, cfg.PluginInstallAllowList)
+	require.Equal(t, []string{"myorg-legacy-*"}, cfg.PluginInstallDenyList)
+
+	t.Run("Invalid pattern fails", func(t *testing.T) {
+		cfg := NewCfg()
+		sec, err := cfg.Raw.NewSection("plugins")
+		require.NoError(t, err)
+		_, err = sec.NewKey("plugin_install_deny_list", "myorg-[")
+		require.NoError(t, err)
+
+		err = cfg.readPluginSettings(cfg.Raw)
+		require.Error(t, err)
+	})
+}
+
+func TestReadPluginSettings_PrivateRepository(t *testing.T) {
+	cfg := NewCfg()
+	sec, err := cfg.Raw.NewSection("plugins")
+	require.NoError(t, err)
+	_, err = sec.NewKey("private_plugin_repository_host", " Plugins.Example.com:8443 ")
+	require.NoError(t, err)
+	headers, err := cfg.Raw.NewSection("plugins.private_repository_headers")
+	require.NoError(t, err)
+	_, err = headers.NewKey("Authorization", "Bearer secret")
+	require.NoError(t, err)
+
+	err = cfg.readPluginSettings(cfg.Raw)
+	require.NoError(t, err)
+	require.Equal(t, "plugins.example.com:8443", cfg.PluginRepositoryPrivateHost)
+	require.Equal(t, map[string]string{"Authorization": "Bearer secret"}, cfg.PluginRepositoryPrivateHeaders)
+
+	t.Run("Header values are redacted", func(t *testing.T) {
+		envKey := EnvKey("plugins.private_repository_headers", "Authorization")
+		require.Equal(t, RedactedPassword, RedactedValue(envKey, "Bearer secret"))
+	})
+}
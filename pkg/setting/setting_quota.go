@@ -10,6 +10,8 @@ type OrgQuota struct {
 	Dashboard  int64 `target:"dashboard"`
 	ApiKey     int64 `target:"api_key"`
 	AlertRule  int64 `target:"alert_rule"`
+	// enforced by the dashboard store when public dashboards are saved
+	PublicDashboard int64 `target:"-"`
 }
 
 type UserQuota struct {
@@ -74,11 +76,12 @@ func (cfg *Cfg) readQuotaSettings() {
 	}
 	// per ORG Limits
 	Quota.Org = &OrgQuota{
-		User:       quota.Key("org_user").MustInt64(10),
-		DataSource: quota.Key("org_data_source").MustInt64(10),
-		Dashboard:  quota.Key("org_dashboard").MustInt64(10),
-		ApiKey:     quota.Key("org_api_key").MustInt64(10),
-		AlertRule:  alertOrgQuota,
+		User:            quota.Key("org_user").MustInt64(10),
+		DataSource:      quota.Key("org_data_source").MustInt64(10),
+		Dashboard:       quota.Key("org_dashboard").MustInt64(10),
+		ApiKey:          quota.Key("org_api_key").MustInt64(10),
+		AlertRule:       alertOrgQuota,
+		PublicDashboard: quota.Key("org_public_dashboard").MustInt64(-1),
 	}
 
 	// per User limits
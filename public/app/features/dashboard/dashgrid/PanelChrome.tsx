@@ -361,7 +361,7 @@ export class PanelChrome extends PureComponent<Props, State> {
         dashboard.getTimezone(),
         timeData,
         width,
-        dashboard.meta.publicDashboardUid
+        dashboard.meta.publicDashboardAccessToken
       );
     } else {
       // The panel should render on refresh as well if it doesn't have a query, like clock panel
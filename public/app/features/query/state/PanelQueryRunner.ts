@@ -47,7 +47,7 @@ export interface QueryRunnerOptions<
   queries: TQuery[];
   panelId?: number;
   dashboardId?: number;
-  publicDashboardUid?: string;
+  publicDashboardAccessToken?: string;
   timezone: TimeZone;
   timeRange: TimeRange;
   timeInfo?: string; // String description of time range for display
@@ -203,7 +203,7 @@ export class PanelQueryRunner {
       datasource,
       panelId,
       dashboardId,
-      publicDashboardUid,
+      publicDashboardAccessToken,
       timeRange,
       timeInfo,
       cacheTimeout,
@@ -223,7 +223,7 @@ export class PanelQueryRunner {
       timezone,
       panelId,
       dashboardId,
-      publicDashboardUid,
+      publicDashboardAccessToken,
       range: timeRange,
       timeInfo,
       interval: '',
@@ -239,7 +239,7 @@ export class PanelQueryRunner {
     (request as any).rangeRaw = timeRange.raw;
 
     try {
-      const ds = await getDataSource(datasource, request.scopedVars, publicDashboardUid);
+      const ds = await getDataSource(datasource, request.scopedVars, publicDashboardAccessToken);
       const isMixedDS = ds.meta?.mixed;
 
       // Attach the data source to each query
@@ -359,9 +359,9 @@ export class PanelQueryRunner {
 async function getDataSource(
   datasource: DataSourceRef | string | DataSourceApi | null,
   scopedVars: ScopedVars,
-  publicDashboardUid?: string
+  publicDashboardAccessToken?: string
 ): Promise<DataSourceApi> {
-  if (publicDashboardUid) {
+  if (publicDashboardAccessToken) {
     return new PublicDashboardDataSource();
   }
 